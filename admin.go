@@ -0,0 +1,173 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/HemahWeb/chirpy/internal/admin"
+	"github.com/HemahWeb/chirpy/internal/cleanup"
+	"github.com/HemahWeb/chirpy/internal/config"
+	"github.com/HemahWeb/chirpy/internal/database"
+	"github.com/HemahWeb/chirpy/internal/migrate"
+	"github.com/HemahWeb/chirpy/internal/service"
+)
+
+const adminUsage = `usage: chirpy <command> [flags] [args]
+
+commands:
+  users create EMAIL            sign up a user; the password is read from stdin
+  users set-password EMAIL      replace a user's password, read from stdin
+  users grant-red EMAIL         upgrade a user to Chirpy Red
+  users grant-moderator EMAIL   let a user work the moderation queue
+  users revoke-moderator EMAIL  take away a user's moderator role
+  users revoke-tokens EMAIL     revoke all of a user's refresh tokens
+  chirps delete ID...           delete chirps for good, unless hidden or under review
+  refresh-tokens cleanup        delete stale refresh tokens now
+  webhooks tail [-n N] [-f]     print recent webhook events, and with -f new ones
+  stats                         print counts of users, chirps, tokens and events
+
+Every command also takes the server's config flags, e.g. -db-url.`
+
+// adminCommands maps each command to the number of arguments it takes; -1
+// means one or more.
+var adminCommands = map[string]int{
+	"users create":           1,
+	"users set-password":     1,
+	"users grant-red":        1,
+	"users grant-moderator":  1,
+	"users revoke-moderator": 1,
+	"users revoke-tokens":    1,
+	"chirps delete":          -1,
+	"refresh-tokens cleanup": 0,
+	"webhooks tail":          0,
+	"stats":                  0,
+}
+
+func isAdminCommand(name string) bool {
+	for cmd := range adminCommands {
+		if strings.HasPrefix(cmd+" ", name+" ") {
+			return true
+		}
+	}
+	return false
+}
+
+func adminUsageError(msg string) {
+	if msg != "" {
+		fmt.Fprintln(os.Stderr, msg)
+	}
+	fmt.Fprintln(os.Stderr, adminUsage)
+	os.Exit(2)
+}
+
+func runAdmin(args []string) {
+	cmd, rest := args[0], args[1:]
+	if _, ok := adminCommands[cmd]; !ok {
+		if len(rest) == 0 {
+			adminUsageError("")
+		}
+		cmd, rest = cmd+" "+rest[0], rest[1:]
+	}
+	nargs, ok := adminCommands[cmd]
+	if !ok {
+		adminUsageError("unknown command: chirpy " + cmd)
+	}
+
+	fs := flag.NewFlagSet("chirpy "+cmd, flag.ContinueOnError)
+	tailN := fs.Int("n", 10, "number of recent events to print (webhooks tail)")
+	follow := fs.Bool("f", false, "keep printing new events until interrupted (webhooks tail)")
+	interval := fs.Duration("interval", 2*time.Second, "how often to poll for new events with -f (webhooks tail)")
+	cfg, err := config.LoadCommand(fs, rest)
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
+	if err != nil {
+		fatal("Error loading config", "error", err)
+	}
+	setupLogging(cfg)
+
+	rest = fs.Args()
+	if nargs >= 0 && len(rest) != nargs || nargs < 0 && len(rest) == 0 {
+		adminUsageError("wrong number of arguments for chirpy " + cmd)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	dbConn, dialect := openDB(cfg)
+	defer dbConn.Close()
+
+	migrator, err := migrate.New(dbConn, dialect)
+	if err != nil {
+		fatal("Error loading migrations", "error", err)
+	}
+	if err := migrator.CheckCurrent(ctx); err != nil {
+		fatal("Database schema isn't current; run `chirpy migrate up`", "error", err)
+	}
+
+	q := newQuerier(dbConn, dialect)
+	transactor := service.NewSQLTransactor(dbConn, func(tx *sql.Tx) database.Querier {
+		return newQuerier(tx, dialect)
+	}, retryable(dialect))
+	a := admin.New(q, service.New(q, transactor, string(cfg.JWTSecret)), os.Stdout)
+
+	switch cmd {
+	case "users create":
+		err = a.CreateUser(ctx, rest[0], readPassword(os.Stdin))
+	case "users set-password":
+		err = a.SetPassword(ctx, rest[0], readPassword(os.Stdin))
+	case "users grant-red":
+		err = a.GrantRed(ctx, rest[0])
+	case "users grant-moderator":
+		err = a.GrantModerator(ctx, rest[0])
+	case "users revoke-moderator":
+		err = a.RevokeModerator(ctx, rest[0])
+	case "users revoke-tokens":
+		err = a.RevokeTokens(ctx, rest[0])
+	case "chirps delete":
+		ids := make([]uuid.UUID, len(rest))
+		for i, arg := range rest {
+			if ids[i], err = uuid.Parse(arg); err != nil {
+				adminUsageError(fmt.Sprintf("invalid chirp ID %q: %v", arg, err))
+			}
+		}
+		err = a.DeleteChirps(ctx, ids)
+	case "refresh-tokens cleanup":
+		err = a.CleanupRefreshTokens(ctx, cleanup.Config{
+			Retention: cfg.RefreshTokenRetention,
+			BatchSize: int32(cfg.RefreshTokenCleanupBatchSize),
+		})
+	case "webhooks tail":
+		err = a.TailWebhooks(ctx, *tailN, *follow, *interval)
+	case "stats":
+		err = a.Stats(ctx)
+	}
+	if err != nil {
+		fatal("Command failed", "command", cmd, "error", err)
+	}
+}
+
+// readPassword reads the first line of r, prompting if it's a terminal.
+// Taking the password from stdin keeps it out of shell history and ps.
+func readPassword(r *os.File) string {
+	if info, err := r.Stat(); err == nil && info.Mode()&os.ModeCharDevice != 0 {
+		fmt.Fprint(os.Stderr, "Password: ")
+	}
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		fatal("Error reading password", "error", err)
+	}
+	return strings.TrimRight(line, "\r\n")
+}
@@ -0,0 +1,61 @@
+package client
+
+import (
+	"context"
+	"net/http"
+)
+
+type credentials struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+// Login signs in and keeps the returned tokens for later requests.
+func (c *Client) Login(ctx context.Context, email, password string) (*Session, error) {
+	var s Session
+	err := c.do(ctx, request{
+		method: http.MethodPost,
+		path:   "/api/login",
+		body:   credentials{Email: email, Password: password},
+	}, &s)
+	if err != nil {
+		return nil, err
+	}
+	c.updateTokens(func(t *Tokens) {
+		*t = Tokens{AccessToken: s.Token, RefreshToken: s.RefreshToken}
+	})
+	return &s, nil
+}
+
+// Refresh replaces the access token using the refresh token. Requests do
+// this themselves when the access token expires, so it's rarely needed.
+func (c *Client) Refresh(ctx context.Context) error {
+	var res struct {
+		Token string `json:"token"`
+	}
+	err := c.do(ctx, request{
+		method: http.MethodPost,
+		path:   "/api/refresh",
+		auth:   refreshAuth,
+	}, &res)
+	if err != nil {
+		return err
+	}
+	c.updateTokens(func(t *Tokens) { t.AccessToken = res.Token })
+	return nil
+}
+
+// Revoke revokes the refresh token and forgets both tokens. The access
+// token stays valid on the server until it expires.
+func (c *Client) Revoke(ctx context.Context) error {
+	err := c.do(ctx, request{
+		method: http.MethodPost,
+		path:   "/api/revoke",
+		auth:   refreshAuth,
+	}, nil)
+	if err != nil {
+		return err
+	}
+	c.updateTokens(func(t *Tokens) { *t = Tokens{} })
+	return nil
+}
@@ -0,0 +1,92 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"github.com/google/uuid"
+)
+
+// CreateChirp posts a chirp as the logged-in user.
+func (c *Client) CreateChirp(ctx context.Context, body string) (*Chirp, error) {
+	var chirp Chirp
+	err := c.do(ctx, request{
+		method: http.MethodPost,
+		path:   "/api/chirps",
+		body:   map[string]string{"body": body},
+		auth:   accessAuth,
+	}, &chirp)
+	if err != nil {
+		return nil, err
+	}
+	return &chirp, nil
+}
+
+// ReplyToChirp posts a chirp answering parentID as the logged-in user.
+func (c *Client) ReplyToChirp(ctx context.Context, parentID uuid.UUID, body string) (*Chirp, error) {
+	var chirp Chirp
+	err := c.do(ctx, request{
+		method: http.MethodPost,
+		path:   "/api/chirps",
+		body: map[string]any{
+			"body":        body,
+			"reply_to_id": parentID,
+		},
+		auth: accessAuth,
+	}, &chirp)
+	if err != nil {
+		return nil, err
+	}
+	return &chirp, nil
+}
+
+type ListChirpsParams struct {
+	// only chirps by this user; all users if uuid.Nil
+	AuthorID uuid.UUID
+	// newest first instead of oldest first
+	Descending bool
+}
+
+// ListChirps returns chirps ordered by creation time.
+func (c *Client) ListChirps(ctx context.Context, params ListChirpsParams) ([]Chirp, error) {
+	query := url.Values{}
+	if params.AuthorID != uuid.Nil {
+		query.Set("author_id", params.AuthorID.String())
+	}
+	if params.Descending {
+		query.Set("sort", "desc")
+	}
+
+	var chirps []Chirp
+	err := c.do(ctx, request{
+		method: http.MethodGet,
+		path:   "/api/chirps",
+		query:  query,
+	}, &chirps)
+	if err != nil {
+		return nil, err
+	}
+	return chirps, nil
+}
+
+func (c *Client) GetChirp(ctx context.Context, id uuid.UUID) (*Chirp, error) {
+	var chirp Chirp
+	err := c.do(ctx, request{
+		method: http.MethodGet,
+		path:   "/api/chirps/" + id.String(),
+	}, &chirp)
+	if err != nil {
+		return nil, err
+	}
+	return &chirp, nil
+}
+
+// DeleteChirp moves one of the logged-in user's chirps to their trash.
+func (c *Client) DeleteChirp(ctx context.Context, id uuid.UUID) error {
+	return c.do(ctx, request{
+		method: http.MethodDelete,
+		path:   "/api/chirps/" + id.String(),
+		auth:   accessAuth,
+	}, nil)
+}
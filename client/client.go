@@ -0,0 +1,306 @@
+// Package client is a Go client for the Chirpy API.
+//
+// A Client keeps the tokens from Login and sends the access token with
+// every request that needs one. When the server says the access token has
+// expired, the client gets a new one with the refresh token and repeats the
+// request once. GET, PUT and DELETE requests are retried with backoff after
+// network errors, 429s and 5xx responses; POSTs never are, since repeating
+// one could create a second chirp or user.
+//
+// Error responses are returned as *Error, which carries the API's error
+// code:
+//
+//	_, err := c.GetChirp(ctx, id)
+//	if client.ErrorCode(err) == client.CodeNotFound {
+//		...
+//	}
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultMaxRetries is how many times an idempotent request is retried when
+// Config.MaxRetries is 0.
+const DefaultMaxRetries = 3
+
+type Config struct {
+	// server root, e.g. http://localhost:8080
+	BaseURL string
+	// sends the requests; http.DefaultClient if nil
+	HTTPClient *http.Client
+	// retries for GET, PUT and DELETE; 0 means DefaultMaxRetries and a
+	// negative value disables retries
+	MaxRetries int
+	// called with the new tokens after Login, an automatic refresh or
+	// Revoke, so callers can persist them
+	OnTokens func(Tokens)
+}
+
+// Tokens are the credentials a Client sends. Either may be empty.
+type Tokens struct {
+	AccessToken  string
+	RefreshToken string
+}
+
+// Client is safe for concurrent use.
+type Client struct {
+	baseURL    *url.URL
+	http       *http.Client
+	maxRetries int
+	onTokens   func(Tokens)
+
+	// backoff returns the wait before retry n (starting at 1)
+	backoff func(n int) time.Duration
+
+	mu     sync.Mutex
+	tokens Tokens
+	// refreshing serializes refreshes so concurrent requests that all see
+	// an expired token only refresh it once
+	refreshing sync.Mutex
+}
+
+func New(cfg Config) (*Client, error) {
+	base, err := url.Parse(cfg.BaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base URL: %w", err)
+	}
+	if base.Scheme != "http" && base.Scheme != "https" {
+		return nil, fmt.Errorf("base URL %q must be http or https", cfg.BaseURL)
+	}
+	base.Path = strings.TrimSuffix(base.Path, "/")
+
+	c := &Client{
+		baseURL:    base,
+		http:       cfg.HTTPClient,
+		maxRetries: cfg.MaxRetries,
+		onTokens:   cfg.OnTokens,
+		backoff:    exponentialBackoff,
+	}
+	if c.http == nil {
+		c.http = http.DefaultClient
+	}
+	if c.maxRetries == 0 {
+		c.maxRetries = DefaultMaxRetries
+	} else if c.maxRetries < 0 {
+		c.maxRetries = 0
+	}
+	return c, nil
+}
+
+// Tokens returns the tokens the client is currently using.
+func (c *Client) Tokens() Tokens {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.tokens
+}
+
+// SetTokens replaces the client's tokens, e.g. with ones saved by OnTokens
+// in an earlier run. It doesn't call OnTokens.
+func (c *Client) SetTokens(t Tokens) {
+	c.mu.Lock()
+	c.tokens = t
+	c.mu.Unlock()
+}
+
+func (c *Client) updateTokens(update func(t *Tokens)) {
+	c.mu.Lock()
+	update(&c.tokens)
+	t := c.tokens
+	c.mu.Unlock()
+	if c.onTokens != nil {
+		c.onTokens(t)
+	}
+}
+
+// authKind says which credential a request sends.
+type authKind int
+
+const (
+	noAuth authKind = iota
+	accessAuth
+	refreshAuth
+)
+
+type request struct {
+	method string
+	path   string
+	query  url.Values
+	body   any
+	auth   authKind
+	// sent as "ApiKey <key>" instead of a bearer token
+	apiKey string
+}
+
+// do sends req and decodes a successful response into out, if it's not nil.
+func (c *Client) do(ctx context.Context, req request, out any) error {
+	var body []byte
+	if req.body != nil {
+		var err error
+		if body, err = json.Marshal(req.body); err != nil {
+			return fmt.Errorf("couldn't encode request body: %w", err)
+		}
+	}
+
+	token := c.credential(req.auth)
+	if req.auth == accessAuth && token == "" && c.Tokens().RefreshToken != "" {
+		if err := c.refreshAfter(ctx, ""); err != nil {
+			return err
+		}
+		token = c.credential(req.auth)
+	}
+
+	res, err := c.send(ctx, req, body, token)
+	if err != nil {
+		return err
+	}
+	if req.auth == accessAuth && res.StatusCode == http.StatusUnauthorized {
+		apiErr := readError(res)
+		if apiErr.Code != CodeTokenExpired || c.Tokens().RefreshToken == "" {
+			return apiErr
+		}
+		if err := c.refreshAfter(ctx, token); err != nil {
+			return err
+		}
+		if res, err = c.send(ctx, req, body, c.credential(req.auth)); err != nil {
+			return err
+		}
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= 300 {
+		return readError(res)
+	}
+	if out == nil || res.StatusCode == http.StatusNoContent {
+		io.Copy(io.Discard, res.Body)
+		return nil
+	}
+	if err := json.NewDecoder(res.Body).Decode(out); err != nil {
+		return fmt.Errorf("couldn't decode %s %s response: %w", req.method, req.path, err)
+	}
+	return nil
+}
+
+func (c *Client) credential(kind authKind) string {
+	t := c.Tokens()
+	switch kind {
+	case accessAuth:
+		return t.AccessToken
+	case refreshAuth:
+		return t.RefreshToken
+	}
+	return ""
+}
+
+// refreshAfter gets a new access token unless another request already
+// replaced stale while this one waited.
+func (c *Client) refreshAfter(ctx context.Context, stale string) error {
+	c.refreshing.Lock()
+	defer c.refreshing.Unlock()
+	if current := c.Tokens().AccessToken; current != "" && current != stale {
+		return nil
+	}
+	return c.Refresh(ctx)
+}
+
+// send makes the request, retrying idempotent methods. The caller closes
+// the returned response's body.
+func (c *Client) send(ctx context.Context, req request, body []byte, token string) (*http.Response, error) {
+	u := *c.baseURL
+	u.Path += req.path
+	u.RawQuery = req.query.Encode()
+
+	retries := 0
+	if idempotent(req.method) {
+		retries = c.maxRetries
+	}
+	for attempt := 0; ; attempt++ {
+		r, err := http.NewRequestWithContext(ctx, req.method, u.String(), bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		r.Header.Set("Accept", "application/json")
+		if body != nil {
+			r.Header.Set("Content-Type", "application/json")
+		}
+		switch {
+		case req.apiKey != "":
+			r.Header.Set("Authorization", "ApiKey "+req.apiKey)
+		case token != "":
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+
+		res, err := c.http.Do(r)
+		if attempt >= retries || !retryable(res, err) || ctx.Err() != nil {
+			if err != nil {
+				return nil, fmt.Errorf("%s %s: %w", req.method, req.path, err)
+			}
+			return res, nil
+		}
+
+		wait := c.backoff(attempt + 1)
+		if res != nil {
+			if after := retryAfter(res); after > 0 {
+				wait = after
+			}
+			io.Copy(io.Discard, res.Body)
+			res.Body.Close()
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, fmt.Errorf("%s %s: %w", req.method, req.path, ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
+
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+func retryable(res *http.Response, err error) bool {
+	if err != nil {
+		return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+	}
+	switch res.StatusCode {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// retryAfter reads a Retry-After header given in seconds, which is the only
+// form the server sends.
+func retryAfter(res *http.Response) time.Duration {
+	secs, err := strconv.Atoi(res.Header.Get("Retry-After"))
+	if err != nil || secs < 0 {
+		return 0
+	}
+	return time.Duration(secs) * time.Second
+}
+
+// exponentialBackoff waits 100ms, 200ms, 400ms, ... up to 5s, with full
+// jitter so clients that failed together don't retry together.
+func exponentialBackoff(n int) time.Duration {
+	d := min(100*time.Millisecond<<min(n-1, 6), 5*time.Second)
+	return rand.N(d) + time.Millisecond
+}
@@ -0,0 +1,435 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+
+	"github.com/HemahWeb/chirpy/internal/cleanup"
+	"github.com/HemahWeb/chirpy/internal/handlers"
+	"github.com/HemahWeb/chirpy/internal/health"
+	"github.com/HemahWeb/chirpy/internal/memstore"
+	"github.com/HemahWeb/chirpy/internal/metrics"
+	"github.com/HemahWeb/chirpy/internal/problem"
+	"github.com/HemahWeb/chirpy/internal/service"
+	"github.com/HemahWeb/chirpy/internal/types"
+)
+
+const (
+	testJWTSecret = "0123456789abcdef0123456789abcdef"
+	testPolkaKey  = "f271c81ff7084ee5b99a5091b42d486e"
+)
+
+// newServer starts the API on an in-memory store. refreshes counts calls
+// to POST /api/refresh.
+func newServer(t *testing.T) (url string, refreshes *atomic.Int64) {
+	t.Helper()
+	store := memstore.New()
+	cfg := &types.ApiConfig{
+		Service:      service.New(store, store, testJWTSecret),
+		Platform:     "dev",
+		JWTSecret:    testJWTSecret,
+		PolkaKey:     testPolkaKey,
+		TokenCleaner: cleanup.NewRefreshTokenCleaner(store, cleanup.DefaultConfig()),
+		Readiness:    health.NewChecker(time.Second, 0),
+		Metrics:      metrics.New(),
+	}
+	mux := http.NewServeMux()
+	handlers.New(cfg).Register(mux, http.FS(fstest.MapFS{}))
+
+	refreshes = new(atomic.Int64)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/refresh" {
+			refreshes.Add(1)
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL, refreshes
+}
+
+func newClient(t *testing.T, cfg Config) *Client {
+	t.Helper()
+	c, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	c.backoff = func(int) time.Duration { return time.Millisecond }
+	return c
+}
+
+func expectCode(t *testing.T, err error, code Code) *Error {
+	t.Helper()
+	var apiErr *Error
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("err = %v, want an *Error with code %q", err, code)
+	}
+	if apiErr.Code != code {
+		t.Fatalf("code = %q, want %q (%v)", apiErr.Code, code, err)
+	}
+	return apiErr
+}
+
+// expiredJWT is an access token for userID that expired a minute ago.
+func expiredJWT(t *testing.T, userID uuid.UUID) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
+		Issuer:    "chirpy",
+		Subject:   userID.String(),
+		IssuedAt:  jwt.NewNumericDate(time.Now().Add(-time.Hour)),
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(-time.Minute)),
+	}).SignedString([]byte(testJWTSecret))
+	if err != nil {
+		t.Fatalf("couldn't sign token: %v", err)
+	}
+	return token
+}
+
+func TestNew(t *testing.T) {
+	for _, url := range []string{"", "localhost:8080", "ftp://example.com", "http://a b"} {
+		if _, err := New(Config{BaseURL: url}); err == nil {
+			t.Errorf("New(%q) succeeded", url)
+		}
+	}
+	c, err := New(Config{BaseURL: "https://chirpy.example.com/"})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	if c.maxRetries != DefaultMaxRetries || c.http != http.DefaultClient || c.baseURL.Path != "" {
+		t.Errorf("defaults not applied: %+v", c)
+	}
+}
+
+func TestUsersAndChirps(t *testing.T) {
+	url, _ := newServer(t)
+	var saved []Tokens
+	c := newClient(t, Config{BaseURL: url, OnTokens: func(t Tokens) { saved = append(saved, t) }})
+	ctx := context.Background()
+
+	user, err := c.CreateUser(ctx, "walt@breakingbad.com", "04234")
+	if err != nil {
+		t.Fatalf("CreateUser() failed: %v", err)
+	}
+	_, err = c.CreateUser(ctx, "walt@breakingbad.com", "04234")
+	expectCode(t, err, CodeEmailTaken)
+
+	_, err = c.Login(ctx, "walt@breakingbad.com", "wrong")
+	expectCode(t, err, CodeInvalidCredentials)
+	_, err = c.CreateChirp(ctx, "not logged in")
+	expectCode(t, err, CodeUnauthenticated)
+
+	session, err := c.Login(ctx, "walt@breakingbad.com", "04234")
+	if err != nil {
+		t.Fatalf("Login() failed: %v", err)
+	}
+	if session.ID != user.ID || session.Token == "" || session.RefreshToken == "" {
+		t.Errorf("session = %+v", session)
+	}
+	if len(saved) != 1 || saved[0] != (Tokens{session.Token, session.RefreshToken}) {
+		t.Errorf("OnTokens got %+v", saved)
+	}
+
+	first, err := c.CreateChirp(ctx, "I'm the one who knocks!")
+	if err != nil {
+		t.Fatalf("CreateChirp() failed: %v", err)
+	}
+	second, err := c.CreateChirp(ctx, "Say my name.")
+	if err != nil {
+		t.Fatalf("CreateChirp() failed: %v", err)
+	}
+	_, err = c.CreateChirp(ctx, string(make([]byte, 141)))
+	if apiErr := expectCode(t, err, CodeValidationFailed); len(apiErr.Fields) != 1 || apiErr.Fields[0].Field != "body" {
+		t.Errorf("fields = %+v", apiErr.Fields)
+	}
+
+	reply, err := c.ReplyToChirp(ctx, first.ID, "You're goddamn right.")
+	if err != nil || reply.ReplyToID == nil || *reply.ReplyToID != first.ID || reply.ThreadID != first.ThreadID || first.ThreadID != first.ID {
+		t.Fatalf("ReplyToChirp() = %+v, %v", reply, err)
+	}
+	_, err = c.ReplyToChirp(ctx, uuid.New(), "Hello?")
+	if apiErr := expectCode(t, err, CodeValidationFailed); len(apiErr.Fields) != 1 || apiErr.Fields[0].Field != "reply_to_id" {
+		t.Errorf("fields = %+v", apiErr.Fields)
+	}
+
+	chirps, err := c.ListChirps(ctx, ListChirpsParams{AuthorID: user.ID, Descending: true})
+	if err != nil {
+		t.Fatalf("ListChirps() failed: %v", err)
+	}
+	if len(chirps) != 3 || chirps[0].ID != reply.ID || chirps[1].ID != second.ID || chirps[2].ID != first.ID {
+		t.Errorf("ListChirps() = %+v", chirps)
+	}
+	if chirps, err := c.ListChirps(ctx, ListChirpsParams{AuthorID: uuid.New()}); err != nil || len(chirps) != 0 {
+		t.Errorf("ListChirps(other author) = %v, %v", chirps, err)
+	}
+
+	got, err := c.GetChirp(ctx, first.ID)
+	if err != nil || got.Body != first.Body || got.UserID != user.ID {
+		t.Errorf("GetChirp() = %+v, %v", got, err)
+	}
+	if err := c.DeleteChirp(ctx, first.ID); err != nil {
+		t.Fatalf("DeleteChirp() failed: %v", err)
+	}
+	_, err = c.GetChirp(ctx, first.ID)
+	if apiErr := expectCode(t, err, CodeNotFound); apiErr.StatusCode != http.StatusNotFound || apiErr.Detail == "" {
+		t.Errorf("error = %+v", apiErr)
+	}
+
+	updated, err := c.UpdateUser(ctx, "heisenberg@breakingbad.com", "blue")
+	if err != nil || updated.Email != "heisenberg@breakingbad.com" {
+		t.Errorf("UpdateUser() = %+v, %v", updated, err)
+	}
+
+	expectCode(t, c.UpgradeUser(ctx, "wrong-key", user.ID), CodeInvalidAPIKey)
+	if err := c.UpgradeUser(ctx, testPolkaKey, user.ID); err != nil {
+		t.Fatalf("UpgradeUser() failed: %v", err)
+	}
+	if session, err := c.Login(ctx, "heisenberg@breakingbad.com", "blue"); err != nil || !session.IsChirpyRed {
+		t.Errorf("after upgrade Login() = %+v, %v", session, err)
+	}
+
+	if err := c.Revoke(ctx); err != nil {
+		t.Fatalf("Revoke() failed: %v", err)
+	}
+	if c.Tokens() != (Tokens{}) || saved[len(saved)-1] != (Tokens{}) {
+		t.Errorf("tokens after Revoke = %+v, saved %+v", c.Tokens(), saved)
+	}
+}
+
+func TestAutoRefresh(t *testing.T) {
+	url, refreshes := newServer(t)
+	c := newClient(t, Config{BaseURL: url})
+	ctx := context.Background()
+
+	if _, err := c.CreateUser(ctx, "jesse@breakingbad.com", "yo"); err != nil {
+		t.Fatalf("CreateUser() failed: %v", err)
+	}
+	session, err := c.Login(ctx, "jesse@breakingbad.com", "yo")
+	if err != nil {
+		t.Fatalf("Login() failed: %v", err)
+	}
+	expired := expiredJWT(t, session.ID)
+
+	t.Run("expired", func(t *testing.T) {
+		refreshes.Store(0)
+		c.SetTokens(Tokens{AccessToken: expired, RefreshToken: session.RefreshToken})
+
+		var wg sync.WaitGroup
+		errs := make(chan error, 10)
+		for range 10 {
+			wg.Go(func() {
+				_, err := c.CreateChirp(ctx, "Yeah, science!")
+				errs <- err
+			})
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			if err != nil {
+				t.Errorf("CreateChirp() with expired token failed: %v", err)
+			}
+		}
+		if n := refreshes.Load(); n != 1 {
+			t.Errorf("refreshed %d times, want 1", n)
+		}
+		if c.Tokens().AccessToken == expired {
+			t.Error("access token wasn't replaced")
+		}
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		refreshes.Store(0)
+		c.SetTokens(Tokens{RefreshToken: session.RefreshToken})
+		if _, err := c.CreateChirp(ctx, "No access token yet"); err != nil {
+			t.Errorf("CreateChirp() without access token failed: %v", err)
+		}
+		if n := refreshes.Load(); n != 1 {
+			t.Errorf("refreshed %d times, want 1", n)
+		}
+	})
+
+	t.Run("no refresh token", func(t *testing.T) {
+		c.SetTokens(Tokens{AccessToken: expired})
+		_, err := c.CreateChirp(ctx, "Stuck")
+		expectCode(t, err, CodeTokenExpired)
+	})
+
+	t.Run("revoked", func(t *testing.T) {
+		c.SetTokens(Tokens{AccessToken: expired, RefreshToken: session.RefreshToken})
+		if err := c.Revoke(ctx); err != nil {
+			t.Fatalf("Revoke() failed: %v", err)
+		}
+		c.SetTokens(Tokens{AccessToken: expired, RefreshToken: session.RefreshToken})
+		_, err := c.CreateChirp(ctx, "Revoked")
+		expectCode(t, err, CodeTokenRevoked)
+	})
+}
+
+// flaky serves status for the first failures requests, then 200 with an
+// empty JSON list.
+func flaky(t *testing.T, failures int, status int, header http.Header) (url string, calls *atomic.Int64) {
+	t.Helper()
+	calls = new(atomic.Int64)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) <= int64(failures) {
+			for k, v := range header {
+				w.Header()[k] = v
+			}
+			w.Header().Set("Content-Type", "application/problem+json")
+			w.WriteHeader(status)
+			fmt.Fprintf(w, `{"status":%d,"code":"unavailable","detail":"Try again"}`, status)
+			return
+		}
+		w.Write([]byte(`[]`))
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL, calls
+}
+
+func TestRetries(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("idempotent", func(t *testing.T) {
+		url, calls := flaky(t, 2, http.StatusServiceUnavailable, nil)
+		c := newClient(t, Config{BaseURL: url})
+		if _, err := c.ListChirps(ctx, ListChirpsParams{}); err != nil {
+			t.Fatalf("ListChirps() failed: %v", err)
+		}
+		if n := calls.Load(); n != 3 {
+			t.Errorf("calls = %d, want 3", n)
+		}
+	})
+
+	t.Run("gives up", func(t *testing.T) {
+		url, calls := flaky(t, 100, http.StatusServiceUnavailable, nil)
+		c := newClient(t, Config{BaseURL: url, MaxRetries: 2})
+		_, err := c.ListChirps(ctx, ListChirpsParams{})
+		expectCode(t, err, CodeUnavailable)
+		if n := calls.Load(); n != 3 {
+			t.Errorf("calls = %d, want 3", n)
+		}
+	})
+
+	t.Run("post", func(t *testing.T) {
+		url, calls := flaky(t, 1, http.StatusServiceUnavailable, nil)
+		c := newClient(t, Config{BaseURL: url})
+		_, err := c.CreateUser(ctx, "saul@bettercall.com", "jimmy")
+		expectCode(t, err, CodeUnavailable)
+		if n := calls.Load(); n != 1 {
+			t.Errorf("calls = %d, want 1", n)
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		url, calls := flaky(t, 1, http.StatusTooManyRequests, http.Header{"Retry-After": {"7"}})
+		c := newClient(t, Config{BaseURL: url, MaxRetries: -1})
+		_, err := c.ListChirps(ctx, ListChirpsParams{})
+		if apiErr := expectCode(t, err, CodeUnavailable); apiErr.RetryAfter != 7*time.Second || apiErr.StatusCode != http.StatusTooManyRequests {
+			t.Errorf("error = %+v", apiErr)
+		}
+		if n := calls.Load(); n != 1 {
+			t.Errorf("calls = %d, want 1", n)
+		}
+	})
+
+	t.Run("context", func(t *testing.T) {
+		url, _ := flaky(t, 100, http.StatusServiceUnavailable, nil)
+		c := newClient(t, Config{BaseURL: url})
+		c.backoff = func(int) time.Duration { return time.Hour }
+		ctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+		if _, err := c.ListChirps(ctx, ListChirpsParams{}); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("err = %v, want context.DeadlineExceeded", err)
+		}
+	})
+
+	t.Run("network", func(t *testing.T) {
+		url, _ := flaky(t, 0, 0, nil)
+		c := newClient(t, Config{BaseURL: url})
+		c.http = &http.Client{Transport: failingTransport{failures: new(atomic.Int64), limit: 2}}
+		if _, err := c.ListChirps(ctx, ListChirpsParams{}); err != nil {
+			t.Errorf("ListChirps() after network errors failed: %v", err)
+		}
+	})
+}
+
+// failingTransport fails the first limit requests, then uses the default
+// transport.
+type failingTransport struct {
+	failures *atomic.Int64
+	limit    int64
+}
+
+func (f failingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	if f.failures.Add(1) <= f.limit {
+		return nil, errors.New("connection reset by peer")
+	}
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+func TestErrorCodes(t *testing.T) {
+	// every code the server sends, so none is missing here
+	codes := map[problem.Code]Code{
+		problem.CodeInvalidJSON:        CodeInvalidJSON,
+		problem.CodeInvalidForm:        CodeInvalidForm,
+		problem.CodeValidationFailed:   CodeValidationFailed,
+		problem.CodeBodyTooLarge:       CodeBodyTooLarge,
+		problem.CodeUnauthenticated:    CodeUnauthenticated,
+		problem.CodeInvalidToken:       CodeInvalidToken,
+		problem.CodeTokenExpired:       CodeTokenExpired,
+		problem.CodeTokenRevoked:       CodeTokenRevoked,
+		problem.CodeInvalidCredentials: CodeInvalidCredentials,
+		problem.CodeInvalidAPIKey:      CodeInvalidAPIKey,
+		problem.CodeForbidden:          CodeForbidden,
+		problem.CodeAccountSuspended:   CodeAccountSuspended,
+		problem.CodeNotFound:           CodeNotFound,
+		problem.CodeConflict:           CodeConflict,
+		problem.CodeEmailTaken:         CodeEmailTaken,
+		problem.CodeRateLimited:        CodeRateLimited,
+		problem.CodeInternal:           CodeInternal,
+		problem.CodeUnavailable:        CodeUnavailable,
+	}
+	for sent, want := range codes {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", problem.ContentType)
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(problem.New(http.StatusForbidden, sent, "Nope"))
+		}))
+		c := newClient(t, Config{BaseURL: srv.URL, MaxRetries: -1})
+		_, err := c.GetChirp(context.Background(), uuid.New())
+		if got := ErrorCode(err); got != want {
+			t.Errorf("ErrorCode() for %q = %q, want %q", sent, got, want)
+		}
+		srv.Close()
+	}
+}
+
+func TestNonProblemError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "upstream connect error", http.StatusBadGateway)
+	}))
+	t.Cleanup(srv.Close)
+
+	c := newClient(t, Config{BaseURL: srv.URL, MaxRetries: -1})
+	_, err := c.GetChirp(context.Background(), uuid.New())
+	var apiErr *Error
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("err = %v, want *Error", err)
+	}
+	if apiErr.Code != "" || apiErr.StatusCode != http.StatusBadGateway || apiErr.Detail != "upstream connect error" {
+		t.Errorf("error = %+v", apiErr)
+	}
+	if ErrorCode(err) != "" || ErrorCode(errors.New("other")) != "" {
+		t.Error("ErrorCode() should be empty for non-problem errors")
+	}
+}
@@ -0,0 +1,97 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Code identifies a kind of API error. The server never changes or reuses
+// a code, so they're safe to match on.
+type Code string
+
+const (
+	CodeInvalidJSON        Code = "invalid_json"
+	CodeInvalidForm        Code = "invalid_form"
+	CodeValidationFailed   Code = "validation_failed"
+	CodeBodyTooLarge       Code = "body_too_large"
+	CodeUnauthenticated    Code = "unauthenticated"
+	CodeInvalidToken       Code = "invalid_token"
+	CodeTokenExpired       Code = "token_expired"
+	CodeTokenRevoked       Code = "token_revoked"
+	CodeInvalidCredentials Code = "invalid_credentials"
+	CodeInvalidAPIKey      Code = "invalid_api_key"
+	CodeForbidden          Code = "forbidden"
+	CodeAccountSuspended   Code = "account_suspended"
+	CodeNotFound           Code = "not_found"
+	CodeConflict           Code = "conflict"
+	CodeEmailTaken         Code = "email_taken"
+	CodeRateLimited        Code = "rate_limited"
+	CodeInternal           Code = "internal_error"
+	CodeUnavailable        Code = "unavailable"
+)
+
+// FieldError describes one invalid field of a request body.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Error is an error response from the API. Responses that aren't problem
+// documents, e.g. from a proxy, have an empty Code and the body as Detail.
+type Error struct {
+	StatusCode int          `json:"status"`
+	Code       Code         `json:"code"`
+	Title      string       `json:"title"`
+	Detail     string       `json:"detail"`
+	RequestID  string       `json:"request_id"`
+	Fields     []FieldError `json:"errors"`
+	// from the Retry-After header of a 429 or 503
+	RetryAfter time.Duration `json:"-"`
+}
+
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("chirpy: %d", e.StatusCode)
+	if e.Code != "" {
+		msg += " " + string(e.Code)
+	}
+	if e.Detail != "" {
+		msg += ": " + e.Detail
+	}
+	for _, f := range e.Fields {
+		msg += fmt.Sprintf("; %s: %s", f.Field, f.Message)
+	}
+	return msg
+}
+
+// ErrorCode returns the API error code in err's chain, or "" if err isn't
+// an API error.
+func ErrorCode(err error) Code {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr.Code
+	}
+	return ""
+}
+
+// readError builds an *Error from a failed response and closes its body.
+func readError(res *http.Response) *Error {
+	defer res.Body.Close()
+	data, _ := io.ReadAll(io.LimitReader(res.Body, 64<<10))
+
+	e := &Error{}
+	if err := json.Unmarshal(data, e); err != nil || e.Code == "" {
+		e = &Error{Detail: strings.TrimSpace(string(data))}
+	}
+	e.StatusCode = res.StatusCode
+	if e.Title == "" {
+		e.Title = http.StatusText(res.StatusCode)
+	}
+	e.RetryAfter = retryAfter(res)
+	return e
+}
@@ -0,0 +1,66 @@
+package client
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type User struct {
+	ID          uuid.UUID `json:"id"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	Email       string    `json:"email"`
+	IsChirpyRed bool      `json:"is_chirpy_red"`
+}
+
+type Chirp struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Body      string    `json:"body"`
+	UserID    uuid.UUID `json:"user_id"`
+	// the chirp this answers, nil if none or it was deleted
+	ReplyToID *uuid.UUID `json:"reply_to_id"`
+	// the first chirp of the conversation
+	ThreadID uuid.UUID `json:"thread_id"`
+	// the images posted with it, in order
+	Attachments []Attachment `json:"attachments"`
+}
+
+// Attachment is an image on a chirp. The URLs are paths on the server.
+type Attachment struct {
+	ID          uuid.UUID `json:"id"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	Width       int       `json:"width"`
+	Height      int       `json:"height"`
+	URL         string    `json:"url"`
+	Thumbnail   struct {
+		ContentType string `json:"content_type"`
+		Width       int    `json:"width"`
+		Height      int    `json:"height"`
+		URL         string `json:"url"`
+	} `json:"thumbnail"`
+}
+
+// Session is the response to Login: the user and their new tokens.
+type Session struct {
+	User
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"` // empty if Suspended
+	// a suspended user's Token only lists and appeals their sanctions
+	Suspended      bool       `json:"suspended"`
+	SuspendedUntil *time.Time `json:"suspended_until"`
+}
+
+// EventUserUpgraded is the Polka event that makes a user Chirpy Red.
+const EventUserUpgraded = "user.upgraded"
+
+// PolkaEvent is the body Polka posts to the webhook.
+type PolkaEvent struct {
+	Event string `json:"event"`
+	Data  struct {
+		UserID uuid.UUID `json:"user_id"`
+	} `json:"data"`
+}
@@ -0,0 +1,35 @@
+package client
+
+import (
+	"context"
+	"net/http"
+)
+
+// CreateUser signs up a new user. It doesn't log in; call Login for that.
+func (c *Client) CreateUser(ctx context.Context, email, password string) (*User, error) {
+	var u User
+	err := c.do(ctx, request{
+		method: http.MethodPost,
+		path:   "/api/users",
+		body:   credentials{Email: email, Password: password},
+	}, &u)
+	if err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+// UpdateUser changes the logged-in user's email and password.
+func (c *Client) UpdateUser(ctx context.Context, email, password string) (*User, error) {
+	var u User
+	err := c.do(ctx, request{
+		method: http.MethodPut,
+		path:   "/api/users",
+		body:   credentials{Email: email, Password: password},
+		auth:   accessAuth,
+	}, &u)
+	if err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
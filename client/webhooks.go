@@ -0,0 +1,26 @@
+package client
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// SendPolkaEvent delivers event to the webhook as Polka would, signed with
+// apiKey. Events other than EventUserUpgraded are accepted and ignored.
+func (c *Client) SendPolkaEvent(ctx context.Context, apiKey string, event PolkaEvent) error {
+	return c.do(ctx, request{
+		method: http.MethodPost,
+		path:   "/api/polka/webhooks",
+		body:   event,
+		apiKey: apiKey,
+	}, nil)
+}
+
+// UpgradeUser sends a user.upgraded event for userID.
+func (c *Client) UpgradeUser(ctx context.Context, apiKey string, userID uuid.UUID) error {
+	event := PolkaEvent{Event: EventUserUpgraded}
+	event.Data.UserID = userID
+	return c.SendPolkaEvent(ctx, apiKey, event)
+}
@@ -0,0 +1,252 @@
+// Package admin implements the chirpy administration commands. They work on
+// the database directly, so operators don't need a running server, and go
+// through the service layer wherever it has rules to apply, so a user
+// created here is validated like one created over HTTP.
+package admin
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/HemahWeb/chirpy/internal/cleanup"
+	"github.com/HemahWeb/chirpy/internal/database"
+	"github.com/HemahWeb/chirpy/internal/service"
+)
+
+type Admin struct {
+	db  database.Querier
+	svc *service.Service
+	out io.Writer
+	now func() time.Time
+}
+
+// New returns commands that run on db and svc and print to out.
+func New(db database.Querier, svc *service.Service, out io.Writer) *Admin {
+	return &Admin{db: db, svc: svc, out: out, now: time.Now}
+}
+
+func (a *Admin) user(ctx context.Context, email string) (database.GetUserByEmailRow, error) {
+	user, err := a.db.GetUserByEmail(ctx, email)
+	if errors.Is(err, sql.ErrNoRows) {
+		return user, fmt.Errorf("no user with email %q", email)
+	}
+	return user, err
+}
+
+// CreateUser signs up a user.
+func (a *Admin) CreateUser(ctx context.Context, email, password string) error {
+	user, err := a.svc.CreateUser(ctx, email, password)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(a.out, "Created user %s (%s)\n", user.Email, user.ID)
+	return nil
+}
+
+// SetPassword replaces a user's password. Existing sessions stay signed in;
+// RevokeTokens ends them.
+func (a *Admin) SetPassword(ctx context.Context, email, password string) error {
+	user, err := a.user(ctx, email)
+	if err != nil {
+		return err
+	}
+	if _, err := a.svc.UpdateUser(ctx, user.ID, user.Email, password); err != nil {
+		return err
+	}
+	fmt.Fprintf(a.out, "Set password for %s\n", user.Email)
+	return nil
+}
+
+// GrantRed upgrades a user to Chirpy Red.
+func (a *Admin) GrantRed(ctx context.Context, email string) error {
+	user, err := a.user(ctx, email)
+	if err != nil {
+		return err
+	}
+	if user.IsChirpyRed {
+		fmt.Fprintf(a.out, "%s is already Chirpy Red\n", user.Email)
+		return nil
+	}
+	if err := a.svc.UpgradeToChirpyRed(ctx, user.ID); err != nil {
+		return err
+	}
+	fmt.Fprintf(a.out, "Upgraded %s to Chirpy Red\n", user.Email)
+	return nil
+}
+
+// GrantModerator lets a user work the moderation queue.
+func (a *Admin) GrantModerator(ctx context.Context, email string) error {
+	user, err := a.user(ctx, email)
+	if err != nil {
+		return err
+	}
+	if err := a.db.AddModerator(ctx, user.ID); err != nil {
+		return err
+	}
+	fmt.Fprintf(a.out, "%s is a moderator\n", user.Email)
+	return nil
+}
+
+// RevokeModerator takes away a user's moderator role. Reports they claimed
+// stay claimed by them.
+func (a *Admin) RevokeModerator(ctx context.Context, email string) error {
+	user, err := a.user(ctx, email)
+	if err != nil {
+		return err
+	}
+	if err := a.db.RemoveModerator(ctx, user.ID); err != nil {
+		return err
+	}
+	fmt.Fprintf(a.out, "%s is no longer a moderator\n", user.Email)
+	return nil
+}
+
+// RevokeTokens revokes every refresh token a user holds. Access tokens
+// can't be revoked and stay valid until they expire.
+func (a *Admin) RevokeTokens(ctx context.Context, email string) error {
+	user, err := a.user(ctx, email)
+	if err != nil {
+		return err
+	}
+	n, err := a.db.RevokeUserRefreshTokens(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(a.out, "Revoked %d refresh tokens for %s\n", n, user.Email)
+	return nil
+}
+
+// CleanupRefreshTokens deletes the refresh tokens that expired or were
+// revoked longer ago than cfg's retention, as the server's cleaner does
+// every interval.
+func (a *Admin) CleanupRefreshTokens(ctx context.Context, cfg cleanup.Config) error {
+	n, err := cleanup.NewRefreshTokenCleaner(a.db, cfg).RunOnce(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(a.out, "Removed %d stale refresh tokens\n", n)
+	return nil
+}
+
+// DeleteChirps permanently deletes chirps whoever wrote them, skipping the
+// trash, and stops at the first one that can't be deleted. Chirps a
+// moderator hid are refused: they're kept for the moderation log. So are
+// chirps with reports waiting for a moderator.
+func (a *Admin) DeleteChirps(ctx context.Context, ids []uuid.UUID) error {
+	for _, id := range ids {
+		chirp, err := a.db.GetChirpByID(ctx, id)
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("no chirp with ID %s", id)
+		}
+		if err != nil {
+			return err
+		}
+		hidden, err := a.db.IsChirpHidden(ctx, id)
+		if err != nil {
+			return err
+		}
+		if hidden {
+			return fmt.Errorf("chirp %s was hidden by a moderator and is kept for the moderation log", id)
+		}
+		reported, err := a.db.IsChirpUnderReview(ctx, uuid.NullUUID{UUID: id, Valid: true})
+		if err != nil {
+			return err
+		}
+		if reported {
+			return fmt.Errorf("chirp %s has reports waiting for a moderator", id)
+		}
+		if err := a.db.PurgeChirp(ctx, id); err != nil {
+			return err
+		}
+		fmt.Fprintf(a.out, "Deleted chirp %s by %s\n", chirp.ID, chirp.UserID)
+	}
+	return nil
+}
+
+// Stats prints row counts.
+func (a *Admin) Stats(ctx context.Context) error {
+	stats, err := a.db.GetStats(ctx, a.now())
+	if err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(a.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "Users\t%d\n", stats.Users)
+	fmt.Fprintf(tw, "Chirpy Red users\t%d\n", stats.ChirpyRedUsers)
+	fmt.Fprintf(tw, "Chirps\t%d\n", stats.Chirps)
+	fmt.Fprintf(tw, "Active refresh tokens\t%d\n", stats.ActiveRefreshTokens)
+	fmt.Fprintf(tw, "Webhook events\t%d\n", stats.WebhookEvents)
+	return tw.Flush()
+}
+
+// tailBatch is how many events one poll reads while following.
+const tailBatch = 100
+
+// TailWebhooks prints the last n webhook events, oldest first. With
+// follow, it then polls for new events every interval until ctx is done.
+func (a *Admin) TailWebhooks(ctx context.Context, n int, follow bool, interval time.Duration) error {
+	recent, err := a.db.ListRecentWebhookEvents(ctx, int32(n))
+	if err != nil {
+		return err
+	}
+	var last int64
+	for i := len(recent) - 1; i >= 0; i-- {
+		a.printEvent(recent[i])
+	}
+	if len(recent) > 0 {
+		last = recent[0].ID
+	} else if follow {
+		// the table may be non-empty with n == 0; start after what's there
+		latest, err := a.db.ListRecentWebhookEvents(ctx, 1)
+		if err != nil {
+			return err
+		}
+		if len(latest) > 0 {
+			last = latest[0].ID
+		}
+	}
+	if !follow {
+		return nil
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+		for {
+			events, err := a.db.ListWebhookEvents(ctx, database.ListWebhookEventsParams{AfterID: last, MaxRows: tailBatch})
+			if err != nil {
+				if ctx.Err() != nil {
+					return nil
+				}
+				return err
+			}
+			for _, e := range events {
+				a.printEvent(e)
+				last = e.ID
+			}
+			if len(events) < tailBatch {
+				break
+			}
+		}
+	}
+}
+
+func (a *Admin) printEvent(e database.WebhookEvent) {
+	user := "-"
+	if e.UserID.Valid {
+		user = e.UserID.UUID.String()
+	}
+	fmt.Fprintf(a.out, "%s  %-6s  %-16s  %-36s  %s\n",
+		e.ReceivedAt.UTC().Format(time.RFC3339), e.Source, e.Event, user, e.Outcome)
+}
@@ -0,0 +1,271 @@
+package admin
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/HemahWeb/chirpy/internal/cleanup"
+	"github.com/HemahWeb/chirpy/internal/database"
+	"github.com/HemahWeb/chirpy/internal/dburl"
+	"github.com/HemahWeb/chirpy/internal/memstore"
+	"github.com/HemahWeb/chirpy/internal/migrate"
+	"github.com/HemahWeb/chirpy/internal/service"
+	"github.com/HemahWeb/chirpy/internal/sqlite"
+)
+
+const testSecret = "0123456789abcdef0123456789abcdef"
+
+// syncBuffer lets the test read output while TailWebhooks writes it.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+// Take returns what was written since the last call.
+func (b *syncBuffer) Take() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	s := b.buf.String()
+	b.buf.Reset()
+	return s
+}
+
+type testAdmin struct {
+	*Admin
+	db  database.Querier
+	svc *service.Service
+	out *syncBuffer
+}
+
+func forEachBackend(t *testing.T, test func(t *testing.T, a testAdmin)) {
+	backends := []struct {
+		name string
+		open func(t *testing.T) (database.Querier, service.Transactor)
+	}{
+		{"memory", func(t *testing.T) (database.Querier, service.Transactor) {
+			store := memstore.New()
+			return store, store
+		}},
+		{"sqlite", func(t *testing.T) (database.Querier, service.Transactor) {
+			db, err := sqlite.Open("file:" + filepath.Join(t.TempDir(), "chirpy.db"))
+			if err != nil {
+				t.Fatalf("sqlite.Open() failed: %v", err)
+			}
+			t.Cleanup(func() { db.Close() })
+			m, err := migrate.New(db, dburl.SQLite)
+			if err != nil {
+				t.Fatalf("migrate.New() failed: %v", err)
+			}
+			if _, err := m.Up(context.Background()); err != nil {
+				t.Fatalf("Up() failed: %v", err)
+			}
+			return sqlite.New(db), service.NewSQLTransactor(db, func(tx *sql.Tx) database.Querier { return sqlite.New(tx) }, sqlite.Busy)
+		}},
+	}
+	for _, b := range backends {
+		t.Run(b.name, func(t *testing.T) {
+			db, tx := b.open(t)
+			svc := service.New(db, tx, testSecret)
+			out := &syncBuffer{}
+			test(t, testAdmin{Admin: New(db, svc, out), db: db, svc: svc, out: out})
+		})
+	}
+}
+
+func expectOutput(t *testing.T, a testAdmin, err error, want string) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("command failed: %v", err)
+	}
+	if got := a.out.Take(); !strings.Contains(got, want) {
+		t.Errorf("output = %q, want it to contain %q", got, want)
+	}
+}
+
+func TestUsers(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, a testAdmin) {
+		ctx := context.Background()
+
+		expectOutput(t, a, a.CreateUser(ctx, "walt@example.com", "hunter2"), "Created user walt@example.com")
+		if err := a.CreateUser(ctx, "walt@example.com", "hunter2"); err == nil {
+			t.Error("CreateUser() with a taken email succeeded")
+		}
+		if err := a.CreateUser(ctx, "not an email", "hunter2"); err == nil {
+			t.Error("CreateUser() with an invalid email succeeded")
+		}
+
+		session, err := a.svc.Login(ctx, "walt@example.com", "hunter2")
+		if err != nil {
+			t.Fatalf("Login() failed: %v", err)
+		}
+		expectOutput(t, a, a.SetPassword(ctx, "walt@example.com", "heisenberg"), "Set password for walt@example.com")
+		if _, err := a.svc.Login(ctx, "walt@example.com", "heisenberg"); err != nil {
+			t.Errorf("Login() with the new password failed: %v", err)
+		}
+
+		expectOutput(t, a, a.GrantRed(ctx, "walt@example.com"), "Upgraded walt@example.com")
+		expectOutput(t, a, a.GrantRed(ctx, "walt@example.com"), "already Chirpy Red")
+		if user, _ := a.db.GetUserByEmail(ctx, "walt@example.com"); !user.IsChirpyRed {
+			t.Error("user should be Chirpy Red")
+		}
+
+		expectOutput(t, a, a.GrantModerator(ctx, "walt@example.com"), "walt@example.com is a moderator")
+		expectOutput(t, a, a.GrantModerator(ctx, "walt@example.com"), "walt@example.com is a moderator")
+		if ok, _ := a.db.IsModerator(ctx, session.User.ID); !ok {
+			t.Error("user should be a moderator")
+		}
+		expectOutput(t, a, a.RevokeModerator(ctx, "walt@example.com"), "no longer a moderator")
+		if ok, _ := a.db.IsModerator(ctx, session.User.ID); ok {
+			t.Error("user should no longer be a moderator")
+		}
+
+		// the two logins above each issued a refresh token
+		expectOutput(t, a, a.RevokeTokens(ctx, "walt@example.com"), "Revoked 2 refresh tokens")
+		if _, _, err := a.svc.Refresh(ctx, session.RefreshToken); err == nil {
+			t.Error("Refresh() with a revoked token succeeded")
+		}
+		expectOutput(t, a, a.RevokeTokens(ctx, "walt@example.com"), "Revoked 0 refresh tokens")
+
+		expectOutput(t, a, a.CleanupRefreshTokens(ctx, cleanup.DefaultConfig()), "Removed 0 stale refresh tokens")
+		time.Sleep(10 * time.Millisecond)
+		expectOutput(t, a, a.CleanupRefreshTokens(ctx, cleanup.Config{Retention: time.Millisecond}), "Removed 2 stale refresh tokens")
+
+		for name, err := range map[string]error{
+			"SetPassword":     a.SetPassword(ctx, "nobody@example.com", "x"),
+			"GrantRed":        a.GrantRed(ctx, "nobody@example.com"),
+			"GrantModerator":  a.GrantModerator(ctx, "nobody@example.com"),
+			"RevokeModerator": a.RevokeModerator(ctx, "nobody@example.com"),
+			"RevokeTokens":    a.RevokeTokens(ctx, "nobody@example.com"),
+		} {
+			if err == nil || !strings.Contains(err.Error(), "no user with email") {
+				t.Errorf("%s(unknown user) error = %v", name, err)
+			}
+		}
+	})
+}
+
+func TestDeleteChirps(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, a testAdmin) {
+		ctx := context.Background()
+		user, _ := a.svc.CreateUser(ctx, "jesse@example.com", "hunter2")
+		first, _ := a.svc.CreateChirp(ctx, user.ID, "Yeah, science!")
+		second, _ := a.svc.CreateChirp(ctx, user.ID, "Yo")
+
+		expectOutput(t, a, a.DeleteChirps(ctx, []uuid.UUID{first.ID, second.ID}), "Deleted chirp "+second.ID.String())
+		if chirps, _ := a.db.GetChirps(ctx); len(chirps) != 0 {
+			t.Errorf("chirps left: %v", chirps)
+		}
+		if err := a.DeleteChirps(ctx, []uuid.UUID{first.ID}); err == nil || !strings.Contains(err.Error(), "no chirp") {
+			t.Errorf("DeleteChirps(deleted) error = %v", err)
+		}
+
+		hidden, _ := a.svc.CreateChirp(ctx, user.ID, "I am the danger")
+		action, err := a.db.CreateModerationAction(ctx, database.CreateModerationActionParams{
+			ModeratorID: user.ID,
+			Action:      "hide_chirp",
+			UserID:      user.ID,
+			ChirpID:     uuid.NullUUID{UUID: hidden.ID, Valid: true},
+		})
+		if err != nil {
+			t.Fatalf("CreateModerationAction() failed: %v", err)
+		}
+		if err := a.db.HideChirp(ctx, database.HideChirpParams{ChirpID: hidden.ID, ActionID: action.ID}); err != nil {
+			t.Fatalf("HideChirp() failed: %v", err)
+		}
+		if err := a.DeleteChirps(ctx, []uuid.UUID{hidden.ID}); err == nil || !strings.Contains(err.Error(), "moderation log") {
+			t.Errorf("DeleteChirps(hidden) error = %v", err)
+		}
+		if isHidden, _ := a.db.IsChirpHidden(ctx, hidden.ID); !isHidden {
+			t.Error("the hidden chirp should be kept")
+		}
+
+		reporter, _ := a.svc.CreateUser(ctx, "hank@example.com", "hunter2")
+		reported, _ := a.svc.CreateChirp(ctx, user.ID, "Say my name")
+		if _, err := a.svc.Report(ctx, reporter.ID, service.ReportParams{ChirpID: reported.ID, Reason: "violence"}); err != nil {
+			t.Fatalf("Report() failed: %v", err)
+		}
+		if err := a.DeleteChirps(ctx, []uuid.UUID{reported.ID}); err == nil || !strings.Contains(err.Error(), "reports waiting") {
+			t.Errorf("DeleteChirps(reported) error = %v", err)
+		}
+	})
+}
+
+func TestStats(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, a testAdmin) {
+		ctx := context.Background()
+		red, _ := a.svc.CreateUser(ctx, "red@example.com", "hunter2")
+		a.svc.CreateUser(ctx, "plain@example.com", "hunter2")
+		a.svc.CreateChirp(ctx, red.ID, "hello")
+		a.svc.HandlePolkaEvent(ctx, service.EventUserUpgraded, red.ID)
+		a.svc.Login(ctx, "red@example.com", "hunter2")
+		session, _ := a.svc.Login(ctx, "plain@example.com", "hunter2")
+		a.svc.Revoke(ctx, session.RefreshToken)
+
+		if err := a.Stats(ctx); err != nil {
+			t.Fatalf("Stats() failed: %v", err)
+		}
+		got := strings.Fields(a.out.Take())
+		want := strings.Fields(`Users 2 Chirpy Red users 1 Chirps 1 Active refresh tokens 1 Webhook events 1`)
+		if strings.Join(got, " ") != strings.Join(want, " ") {
+			t.Errorf("Stats() printed %q, want %q", got, want)
+		}
+	})
+}
+
+func TestTailWebhooks(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, a testAdmin) {
+		ctx := context.Background()
+		user, _ := a.svc.CreateUser(ctx, "saul@example.com", "hunter2")
+		a.svc.HandlePolkaEvent(ctx, "user.payment_failed", uuid.Nil)
+		a.svc.HandlePolkaEvent(ctx, service.EventUserUpgraded, uuid.New())
+		a.svc.HandlePolkaEvent(ctx, service.EventUserUpgraded, user.ID)
+
+		if err := a.TailWebhooks(ctx, 2, false, 0); err != nil {
+			t.Fatalf("TailWebhooks() failed: %v", err)
+		}
+		lines := strings.Split(strings.TrimSpace(a.out.Take()), "\n")
+		if len(lines) != 2 || !strings.HasSuffix(lines[0], "not_found") ||
+			!strings.Contains(lines[1], user.ID.String()) || !strings.HasSuffix(lines[1], "applied") {
+			t.Errorf("TailWebhooks(2) printed %q, want the last two events oldest first", lines)
+		}
+
+		ctx, cancel := context.WithCancel(ctx)
+		done := make(chan error)
+		go func() { done <- a.TailWebhooks(ctx, 1, true, 5*time.Millisecond) }()
+
+		// waitFor collects output until it contains want
+		var printed string
+		waitFor := func(want string) {
+			deadline := time.Now().Add(5 * time.Second)
+			for !strings.Contains(printed, want) && time.Now().Before(deadline) {
+				time.Sleep(5 * time.Millisecond)
+				printed += a.out.Take()
+			}
+		}
+		// once the backlog is printed, the tail has its starting point
+		waitFor("applied")
+		a.svc.HandlePolkaEvent(ctx, "user.refunded", uuid.Nil)
+		waitFor("user.refunded")
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("TailWebhooks(follow) returned %v after cancel", err)
+		}
+		if lines := strings.Split(strings.TrimSpace(printed), "\n"); len(lines) != 2 || !strings.HasSuffix(lines[1], "ignored") {
+			t.Errorf("following printed %q, want the last event and then the new one", printed)
+		}
+	})
+}
@@ -1,17 +1,14 @@
 package auth
 
-import (
-	"errors"
-	"net/http"
-)
+import "net/http"
 
 func GetBearerToken(headers http.Header) (string, error) {
 	authHeader := headers.Get("Authorization")
 	if authHeader == "" {
-		return "", errors.New("no authorization header")
+		return "", ErrNoAuthHeader
 	}
 	if len(authHeader) < 7 || authHeader[:7] != "Bearer " {
-		return "", errors.New("invalid authorization header")
+		return "", ErrInvalidAuthHeader
 	}
 	return authHeader[7:], nil
 }
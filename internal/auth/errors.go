@@ -0,0 +1,14 @@
+package auth
+
+import "errors"
+
+var (
+	ErrNoAuthHeader        = errors.New("no authorization header")
+	ErrInvalidAuthHeader   = errors.New("invalid authorization header")
+	ErrMalformedAuthHeader = errors.New("malformed authorization header")
+	ErrEmptyAPIKey         = errors.New("empty API key")
+
+	// ErrInvalidToken wraps every reason an access token is rejected; check
+	// for jwt.ErrTokenExpired to tell expiry apart.
+	ErrInvalidToken = errors.New("invalid token")
+)
@@ -1,7 +1,6 @@
 package auth
 
 import (
-	"errors"
 	"net/http"
 	"strings"
 )
@@ -9,12 +8,16 @@ import (
 func GetAPIKey(headers http.Header) (string, error) {
 	authHeader := headers.Get("Authorization")
 	if authHeader == "" {
-		return "", errors.New("no authorization header")
+		return "", ErrNoAuthHeader
 	}
 
 	splitAuth := strings.Split(authHeader, " ")
 	if len(splitAuth) < 2 || splitAuth[0] != "ApiKey" {
-		return "", errors.New("malformed authorization header")
+		return "", ErrMalformedAuthHeader
+	}
+
+	if splitAuth[1] == "" {
+		return "", ErrEmptyAPIKey
 	}
 
 	return splitAuth[1], nil
@@ -0,0 +1,68 @@
+package auth
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestGetAPIKey(t *testing.T) {
+	tests := []struct {
+		name          string
+		authHeader    string
+		expectedKey   string
+		expectedError string
+	}{
+		{
+			name:        "Valid API Key",
+			authHeader:  "ApiKey f271c81ff7084ee5b99a5091b42d486e",
+			expectedKey: "f271c81ff7084ee5b99a5091b42d486e",
+		},
+		{
+			name:          "Missing Header",
+			authHeader:    "",
+			expectedError: "no authorization header",
+		},
+		{
+			name:          "Wrong Scheme",
+			authHeader:    "Bearer f271c81ff7084ee5b99a5091b42d486e",
+			expectedError: "malformed authorization header",
+		},
+		{
+			name:          "Scheme Only",
+			authHeader:    "ApiKey",
+			expectedError: "malformed authorization header",
+		},
+		{
+			name:          "Empty Key",
+			authHeader:    "ApiKey ",
+			expectedError: "empty API key",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			headers := http.Header{}
+			if tt.authHeader != "" {
+				headers.Set("Authorization", tt.authHeader)
+			}
+
+			key, err := GetAPIKey(headers)
+
+			if tt.expectedError != "" {
+				if err == nil {
+					t.Fatalf("Expected error %q, got nil", tt.expectedError)
+				}
+				if err.Error() != tt.expectedError {
+					t.Errorf("Expected error %q, got %q", tt.expectedError, err.Error())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if key != tt.expectedKey {
+				t.Errorf("Expected key %q, got %q", tt.expectedKey, key)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -19,11 +20,28 @@ func MakeJWT(userID uuid.UUID, tokenSecret string) (string, error) {
 }
 
 func ValidateJWT(tokenString, tokenSecret string) (uuid.UUID, error) {
+	userID, _, err := ValidateJWTWithExpiry(tokenString, tokenSecret)
+	return userID, err
+}
+
+// ValidateJWTWithExpiry is ValidateJWT that also returns when the token
+// expires, for connections that outlive a request. The time is zero for a
+// token that never expires.
+func ValidateJWTWithExpiry(tokenString, tokenSecret string) (uuid.UUID, time.Time, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &jwt.RegisteredClaims{}, func(token *jwt.Token) (any, error) {
 		return []byte(tokenSecret), nil
 	})
 	if err != nil {
-		return uuid.UUID{}, err
+		return uuid.UUID{}, time.Time{}, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+	claims := token.Claims.(*jwt.RegisteredClaims)
+	userID, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		return uuid.UUID{}, time.Time{}, fmt.Errorf("%w: subject is not a user ID", ErrInvalidToken)
+	}
+	var expiresAt time.Time
+	if claims.ExpiresAt != nil {
+		expiresAt = claims.ExpiresAt.Time
 	}
-	return uuid.MustParse(token.Claims.(*jwt.RegisteredClaims).Subject), nil
+	return userID, expiresAt, nil
 }
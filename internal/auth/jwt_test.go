@@ -1,6 +1,8 @@
 package auth
 
 import (
+	"errors"
+	"strings"
 	"testing"
 	"time"
 
@@ -120,8 +122,15 @@ func TestValidateJWTWithTamperedToken(t *testing.T) {
 		t.Fatalf("Failed to create test token: %v", err)
 	}
 
-	// Tamper with the token by changing a character
-	tamperedToken := token[:len(token)-1] + "X"
+	// Tamper with the token by changing the first character of the
+	// signature; the last one partly encodes padding bits, so changing it
+	// may not change the signature at all
+	sig := strings.LastIndex(token, ".") + 1
+	replacement := "A"
+	if token[sig] == 'A' {
+		replacement = "B"
+	}
+	tamperedToken := token[:sig] + replacement + token[sig+1:]
 
 	_, err = ValidateJWT(tamperedToken, secret)
 	if err == nil {
@@ -231,3 +240,55 @@ func TestJWTWithNilUUID(t *testing.T) {
 		t.Errorf("Expected nil UUID, got %v", parsedUserID)
 	}
 }
+
+func TestValidateJWTExpired(t *testing.T) {
+	secret := "test-secret"
+	claims := jwt.RegisteredClaims{
+		Issuer:    "chirpy",
+		Subject:   uuid.New().String(),
+		IssuedAt:  jwt.NewNumericDate(time.Now().Add(-2 * time.Hour)),
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(-time.Hour)),
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+	if err != nil {
+		t.Fatalf("Failed to create test token: %v", err)
+	}
+
+	_, err = ValidateJWT(token, secret)
+	if !errors.Is(err, ErrInvalidToken) || !errors.Is(err, jwt.ErrTokenExpired) {
+		t.Errorf("ValidateJWT() error = %v, want ErrInvalidToken wrapping jwt.ErrTokenExpired", err)
+	}
+}
+
+func TestValidateJWTWithBadSubject(t *testing.T) {
+	secret := "test-secret"
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{Subject: "admin"}).SignedString([]byte(secret))
+	if err != nil {
+		t.Fatalf("Failed to create test token: %v", err)
+	}
+
+	if _, err := ValidateJWT(token, secret); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("ValidateJWT() error = %v, want ErrInvalidToken instead of a panic", err)
+	}
+}
+
+func TestValidateJWTWithExpiry(t *testing.T) {
+	secret := "test-secret"
+	userID := uuid.New()
+	token, err := MakeJWT(userID, secret)
+	if err != nil {
+		t.Fatalf("MakeJWT() failed: %v", err)
+	}
+
+	gotID, expiresAt, err := ValidateJWTWithExpiry(token, secret)
+	if err != nil || gotID != userID {
+		t.Fatalf("ValidateJWTWithExpiry() = %v, %v; want %v", gotID, err, userID)
+	}
+	if left := time.Until(expiresAt); left <= 59*time.Minute || left > time.Hour {
+		t.Errorf("token expires in %v, want an hour", left)
+	}
+
+	if _, _, err := ValidateJWTWithExpiry(token, "wrong-secret"); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("ValidateJWTWithExpiry() with the wrong secret error = %v, want ErrInvalidToken", err)
+	}
+}
@@ -0,0 +1,63 @@
+package cleanup
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/HemahWeb/chirpy/internal/database"
+	"github.com/HemahWeb/chirpy/internal/media"
+)
+
+// AttachmentStore is the subset of database.Queries the cleaner needs.
+type AttachmentStore interface {
+	ListOrphanedAttachments(ctx context.Context, arg database.ListOrphanedAttachmentsParams) ([]database.Attachment, error)
+	DeleteAttachment(ctx context.Context, id uuid.UUID) error
+}
+
+// DefaultAttachmentConfig is the attachment cleaner's configuration. The
+// retention only has to outlast an upload, which attaches its images to
+// the chirp moments after storing them.
+func DefaultAttachmentConfig() Config {
+	return Config{
+		Interval:  1 * time.Hour,
+		Retention: 1 * time.Hour,
+		BatchSize: 100,
+	}
+}
+
+// AttachmentCleaner deletes the attachments on no chirp, and their blobs:
+// those left by an upload that failed part way and those of purged chirps.
+type AttachmentCleaner struct {
+	job
+}
+
+func NewAttachmentCleaner(store AttachmentStore, blobs media.BlobStore, cfg Config) *AttachmentCleaner {
+	c := &AttachmentCleaner{}
+	c.init("attachments", cfg, DefaultAttachmentConfig(), func(ctx context.Context, cutoff time.Time, batchSize int32) (int64, error) {
+		orphans, err := store.ListOrphanedAttachments(ctx, database.ListOrphanedAttachmentsParams{
+			Cutoff:    cutoff,
+			BatchSize: batchSize,
+		})
+		if err != nil {
+			return 0, err
+		}
+		var removed int64
+		for _, a := range orphans {
+			// the blobs go first, so a failure leaves the row to retry with
+			if err := blobs.Delete(ctx, media.Key(a.ID)); err != nil {
+				return removed, err
+			}
+			if err := blobs.Delete(ctx, media.ThumbnailKey(a.ID)); err != nil {
+				return removed, err
+			}
+			if err := store.DeleteAttachment(ctx, a.ID); err != nil {
+				return removed, err
+			}
+			removed++
+		}
+		return removed, nil
+	})
+	return c
+}
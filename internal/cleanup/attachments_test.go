@@ -0,0 +1,70 @@
+package cleanup
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/HemahWeb/chirpy/internal/database"
+	"github.com/HemahWeb/chirpy/internal/media"
+)
+
+type fakeAttachmentStore struct {
+	orphans []database.Attachment
+	cutoff  time.Time
+}
+
+func (f *fakeAttachmentStore) ListOrphanedAttachments(ctx context.Context, arg database.ListOrphanedAttachmentsParams) ([]database.Attachment, error) {
+	f.cutoff = arg.Cutoff
+	return f.orphans[:min(len(f.orphans), int(arg.BatchSize))], nil
+}
+
+func (f *fakeAttachmentStore) DeleteAttachment(ctx context.Context, id uuid.UUID) error {
+	for i, a := range f.orphans {
+		if a.ID == id {
+			f.orphans = append(f.orphans[:i], f.orphans[i+1:]...)
+		}
+	}
+	return nil
+}
+
+func TestAttachmentCleaner(t *testing.T) {
+	blobs, err := media.NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocalStore() failed: %v", err)
+	}
+	ctx := context.Background()
+	store := &fakeAttachmentStore{}
+	for range 3 {
+		a := database.Attachment{ID: uuid.New()}
+		store.orphans = append(store.orphans, a)
+		blobs.Put(ctx, media.Key(a.ID), "image/png", []byte("image"))
+		blobs.Put(ctx, media.ThumbnailKey(a.ID), "image/png", []byte("thumb"))
+	}
+	// one whose upload failed before its blobs were stored
+	store.orphans = append(store.orphans, database.Attachment{ID: uuid.New()})
+	first := store.orphans[0]
+
+	c := NewAttachmentCleaner(store, blobs, Config{BatchSize: 2})
+	now := time.Date(2025, 1, 31, 12, 0, 0, 0, time.UTC)
+	c.now = func() time.Time { return now }
+
+	removed, err := c.RunOnce(ctx)
+	if err != nil || removed != 4 {
+		t.Fatalf("RunOnce() = %d, %v; want 4 removed", removed, err)
+	}
+	if want := now.Add(-time.Hour); !store.cutoff.Equal(want) {
+		t.Errorf("cutoff = %v, want %v", store.cutoff, want)
+	}
+	if len(store.orphans) != 0 {
+		t.Errorf("%d attachments left", len(store.orphans))
+	}
+	for _, key := range []string{media.Key(first.ID), media.ThumbnailKey(first.ID)} {
+		if _, err := blobs.Get(ctx, key); !errors.Is(err, media.ErrNotFound) {
+			t.Errorf("Get(%s) error = %v, want ErrNotFound", key, err)
+		}
+	}
+}
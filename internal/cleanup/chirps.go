@@ -0,0 +1,39 @@
+package cleanup
+
+import (
+	"context"
+	"time"
+
+	"github.com/HemahWeb/chirpy/internal/database"
+)
+
+// ChirpStore is the subset of database.Queries the purger needs.
+type ChirpStore interface {
+	PurgeTrashedChirps(ctx context.Context, arg database.PurgeTrashedChirpsParams) (int64, error)
+}
+
+// DefaultChirpTrashConfig is the chirp trash purger's configuration.
+func DefaultChirpTrashConfig() Config {
+	return Config{
+		Interval:  1 * time.Hour,
+		Retention: 30 * 24 * time.Hour,
+		BatchSize: 1000,
+	}
+}
+
+// ChirpTrashPurger permanently deletes chirps that have been in the trash
+// longer than the configured retention. Chirps a moderator hid are kept.
+type ChirpTrashPurger struct {
+	job
+}
+
+func NewChirpTrashPurger(store ChirpStore, cfg Config) *ChirpTrashPurger {
+	p := &ChirpTrashPurger{}
+	p.init("chirp_trash", cfg, DefaultChirpTrashConfig(), func(ctx context.Context, cutoff time.Time, batchSize int32) (int64, error) {
+		return store.PurgeTrashedChirps(ctx, database.PurgeTrashedChirpsParams{
+			Cutoff:    cutoff,
+			BatchSize: batchSize,
+		})
+	})
+	return p
+}
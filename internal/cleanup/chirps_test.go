@@ -0,0 +1,41 @@
+package cleanup
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/HemahWeb/chirpy/internal/database"
+)
+
+type fakeChirpStore struct {
+	calls []database.PurgeTrashedChirpsParams
+}
+
+func (f *fakeChirpStore) PurgeTrashedChirps(ctx context.Context, arg database.PurgeTrashedChirpsParams) (int64, error) {
+	f.calls = append(f.calls, arg)
+	return 0, nil
+}
+
+func TestChirpTrashPurger(t *testing.T) {
+	store := &fakeChirpStore{}
+	p := NewChirpTrashPurger(store, Config{})
+	if p.cfg != DefaultChirpTrashConfig() {
+		t.Errorf("cfg = %+v, want defaults %+v", p.cfg, DefaultChirpTrashConfig())
+	}
+	now := time.Date(2025, 1, 31, 12, 0, 0, 0, time.UTC)
+	p.now = func() time.Time { return now }
+
+	if _, err := p.RunOnce(context.Background()); err != nil {
+		t.Fatalf("RunOnce() failed: %v", err)
+	}
+	if len(store.calls) != 1 {
+		t.Fatalf("expected 1 batch, got %d", len(store.calls))
+	}
+	if want := now.Add(-30 * 24 * time.Hour); !store.calls[0].Cutoff.Equal(want) {
+		t.Errorf("cutoff = %v, want %v", store.calls[0].Cutoff, want)
+	}
+	if stats := p.Stats(); stats.Runs != 1 || stats.RowsRemoved != 0 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+}
@@ -0,0 +1,131 @@
+// Package cleanup runs background jobs that delete rows once they've been
+// kept long enough, in batches so no one statement holds its locks for long.
+package cleanup
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrAlreadyRunning is returned by RunOnce when another run is in progress.
+var ErrAlreadyRunning = errors.New("cleanup already running")
+
+type Config struct {
+	// how often the background loop runs
+	Interval time.Duration
+	// how long rows are kept before being deleted
+	Retention time.Duration
+	// max rows per DELETE, keeps each statement's locks short
+	BatchSize int32
+}
+
+type Stats struct {
+	Runs        int64     `json:"runs"`
+	Failures    int64     `json:"failures"`
+	RowsRemoved int64     `json:"rows_removed"`
+	LastRun     time.Time `json:"last_run"`
+	LastRemoved int64     `json:"last_removed"`
+}
+
+// job deletes rows older than the retention with deleteBatch, which
+// removes at most batchSize of them older than cutoff and says how many it
+// did.
+type job struct {
+	name        string
+	cfg         Config
+	now         func() time.Time
+	deleteBatch func(ctx context.Context, cutoff time.Time, batchSize int32) (int64, error)
+
+	running sync.Mutex
+
+	runs        atomic.Int64
+	failures    atomic.Int64
+	rowsRemoved atomic.Int64
+	lastRun     atomic.Int64 // unix nanos
+	lastRemoved atomic.Int64
+}
+
+// init sets up j, filling in whatever cfg leaves zero from def.
+func (j *job) init(name string, cfg, def Config, deleteBatch func(context.Context, time.Time, int32) (int64, error)) {
+	if cfg.Interval <= 0 {
+		cfg.Interval = def.Interval
+	}
+	if cfg.Retention <= 0 {
+		cfg.Retention = def.Retention
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = def.BatchSize
+	}
+	j.name = name
+	j.cfg = cfg
+	j.now = time.Now
+	j.deleteBatch = deleteBatch
+}
+
+// Start runs the job every Interval until ctx is cancelled.
+func (j *job) Start(ctx context.Context) {
+	ticker := time.NewTicker(j.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := j.RunOnce(ctx); err != nil && !errors.Is(err, ErrAlreadyRunning) && ctx.Err() == nil {
+			slog.ErrorContext(ctx, "Cleanup failed", "job", j.name, "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce deletes in batches until a batch comes back short, returning the
+// number of rows removed.
+func (j *job) RunOnce(ctx context.Context) (int64, error) {
+	if !j.running.TryLock() {
+		return 0, ErrAlreadyRunning
+	}
+	defer j.running.Unlock()
+
+	cutoff := j.now().Add(-j.cfg.Retention)
+
+	var removed int64
+	var err error
+	for {
+		var n int64
+		n, err = j.deleteBatch(ctx, cutoff, j.cfg.BatchSize)
+		removed += n
+		if err != nil || n < int64(j.cfg.BatchSize) {
+			break
+		}
+		if err = ctx.Err(); err != nil {
+			break
+		}
+	}
+
+	j.runs.Add(1)
+	j.rowsRemoved.Add(removed)
+	j.lastRemoved.Store(removed)
+	j.lastRun.Store(j.now().UnixNano())
+	if err != nil {
+		j.failures.Add(1)
+	}
+	return removed, err
+}
+
+func (j *job) Stats() Stats {
+	s := Stats{
+		Runs:        j.runs.Load(),
+		Failures:    j.failures.Load(),
+		RowsRemoved: j.rowsRemoved.Load(),
+		LastRemoved: j.lastRemoved.Load(),
+	}
+	if last := j.lastRun.Load(); last != 0 {
+		s.LastRun = time.Unix(0, last).UTC()
+	}
+	return s
+}
@@ -0,0 +1,39 @@
+package cleanup
+
+import (
+	"context"
+	"time"
+
+	"github.com/HemahWeb/chirpy/internal/database"
+)
+
+// Store is the subset of database.Queries the cleaner needs.
+type Store interface {
+	DeleteStaleRefreshTokens(ctx context.Context, arg database.DeleteStaleRefreshTokensParams) (int64, error)
+}
+
+// DefaultConfig is the refresh token cleaner's configuration.
+func DefaultConfig() Config {
+	return Config{
+		Interval:  1 * time.Hour,
+		Retention: 7 * 24 * time.Hour,
+		BatchSize: 1000,
+	}
+}
+
+// RefreshTokenCleaner deletes refresh tokens that expired or were revoked
+// longer ago than the configured retention.
+type RefreshTokenCleaner struct {
+	job
+}
+
+func NewRefreshTokenCleaner(store Store, cfg Config) *RefreshTokenCleaner {
+	c := &RefreshTokenCleaner{}
+	c.init("refresh_tokens", cfg, DefaultConfig(), func(ctx context.Context, cutoff time.Time, batchSize int32) (int64, error) {
+		return store.DeleteStaleRefreshTokens(ctx, database.DeleteStaleRefreshTokensParams{
+			Cutoff:    cutoff,
+			BatchSize: batchSize,
+		})
+	})
+	return c
+}
@@ -0,0 +1,106 @@
+package cleanup
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/HemahWeb/chirpy/internal/database"
+)
+
+type fakeStore struct {
+	remaining int64
+	calls     []database.DeleteStaleRefreshTokensParams
+	err       error
+}
+
+func (f *fakeStore) DeleteStaleRefreshTokens(ctx context.Context, arg database.DeleteStaleRefreshTokensParams) (int64, error) {
+	f.calls = append(f.calls, arg)
+	if f.err != nil {
+		return 0, f.err
+	}
+	n := min(f.remaining, int64(arg.BatchSize))
+	f.remaining -= n
+	return n, nil
+}
+
+func TestRunOnceDeletesInBatches(t *testing.T) {
+	store := &fakeStore{remaining: 25}
+	c := NewRefreshTokenCleaner(store, Config{Retention: time.Hour, BatchSize: 10})
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	c.now = func() time.Time { return now }
+
+	removed, err := c.RunOnce(context.Background())
+	if err != nil {
+		t.Fatalf("RunOnce() failed: %v", err)
+	}
+	if removed != 25 {
+		t.Errorf("RunOnce() removed %d rows, want 25", removed)
+	}
+	if len(store.calls) != 3 {
+		t.Errorf("expected 3 batches, got %d", len(store.calls))
+	}
+	for _, call := range store.calls {
+		if want := now.Add(-time.Hour); !call.Cutoff.Equal(want) {
+			t.Errorf("cutoff = %v, want %v", call.Cutoff, want)
+		}
+		if call.BatchSize != 10 {
+			t.Errorf("batch size = %d, want 10", call.BatchSize)
+		}
+	}
+
+	stats := c.Stats()
+	if stats.Runs != 1 || stats.RowsRemoved != 25 || stats.LastRemoved != 25 || stats.Failures != 0 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+	if !stats.LastRun.Equal(now) {
+		t.Errorf("LastRun = %v, want %v", stats.LastRun, now)
+	}
+}
+
+func TestRunOnceExactMultipleOfBatch(t *testing.T) {
+	store := &fakeStore{remaining: 20}
+	c := NewRefreshTokenCleaner(store, Config{BatchSize: 10})
+
+	removed, err := c.RunOnce(context.Background())
+	if err != nil {
+		t.Fatalf("RunOnce() failed: %v", err)
+	}
+	if removed != 20 {
+		t.Errorf("RunOnce() removed %d rows, want 20", removed)
+	}
+	// the third, empty batch is what tells the cleaner it's done
+	if len(store.calls) != 3 {
+		t.Errorf("expected 3 batches, got %d", len(store.calls))
+	}
+}
+
+func TestRunOnceRecordsFailure(t *testing.T) {
+	store := &fakeStore{err: errors.New("db down")}
+	c := NewRefreshTokenCleaner(store, Config{})
+
+	if _, err := c.RunOnce(context.Background()); err == nil {
+		t.Fatal("RunOnce() should fail when the store fails")
+	}
+	if stats := c.Stats(); stats.Failures != 1 || stats.Runs != 1 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestRunOnceRejectsConcurrentRuns(t *testing.T) {
+	c := NewRefreshTokenCleaner(&fakeStore{}, Config{})
+	c.running.Lock()
+	defer c.running.Unlock()
+
+	if _, err := c.RunOnce(context.Background()); !errors.Is(err, ErrAlreadyRunning) {
+		t.Errorf("RunOnce() error = %v, want ErrAlreadyRunning", err)
+	}
+}
+
+func TestNewRefreshTokenCleanerDefaults(t *testing.T) {
+	c := NewRefreshTokenCleaner(&fakeStore{}, Config{})
+	if c.cfg != DefaultConfig() {
+		t.Errorf("cfg = %+v, want defaults %+v", c.cfg, DefaultConfig())
+	}
+}
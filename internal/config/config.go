@@ -0,0 +1,464 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// Secret is a string that never prints its value. Use string(s) to get at it.
+type Secret string
+
+const redacted = "[REDACTED]"
+
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+	return redacted
+}
+
+func (s Secret) LogValue() slog.Value {
+	return slog.StringValue(s.String())
+}
+
+func (s Secret) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+type Config struct {
+	Port     int
+	DBURL    Secret // may contain a password
+	Platform string
+
+	LogFormat string
+	LogLevel  string
+
+	JWTSecret    Secret
+	PolkaKey     Secret
+	MetricsToken Secret
+
+	ReadTimeout       time.Duration
+	ReadHeaderTimeout time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	MaxHeaderBytes    int
+	MaxBodyBytes      int64
+
+	ShutdownDelay   time.Duration
+	ShutdownTimeout time.Duration
+
+	ReadinessTimeout  time.Duration
+	ReadinessCacheTTL time.Duration
+
+	DBMaxOpenConns    int
+	DBMaxIdleConns    int
+	DBConnMaxLifetime time.Duration
+	DBConnMaxIdleTime time.Duration
+
+	SkipMigrations bool
+
+	RefreshTokenRetention        time.Duration
+	RefreshTokenCleanupInterval  time.Duration
+	RefreshTokenCleanupBatchSize int
+
+	ChirpTrashRetention      time.Duration
+	ChirpTrashPurgeInterval  time.Duration
+	ChirpTrashPurgeBatchSize int
+
+	MediaStore             string
+	MediaDir               string
+	MediaS3Endpoint        string
+	MediaS3Region          string
+	MediaS3Bucket          string
+	MediaS3AccessKeyID     string
+	MediaS3SecretAccessKey Secret
+	MediaMaxBytes          int64
+	MediaMaxImages         int
+
+	MediaOrphanRetention        time.Duration
+	MediaOrphanCleanupInterval  time.Duration
+	MediaOrphanCleanupBatchSize int
+
+	TracingExporter    string
+	TracingFile        string
+	TracingSampleRatio float64
+
+	RateLimitEnabled bool
+	RateLimitStore   string
+	TrustedProxies   string // comma-separated CIDRs or addresses, see ParseTrustedProxies
+
+	StreamBufferSize        int
+	StreamHeartbeatInterval time.Duration
+}
+
+func Default() Config {
+	return Config{
+		Port:     8080,
+		Platform: "prod",
+
+		LogFormat: "json",
+		LogLevel:  "info",
+
+		ReadTimeout:       10 * time.Second,
+		ReadHeaderTimeout: 5 * time.Second,
+		WriteTimeout:      30 * time.Second,
+		IdleTimeout:       120 * time.Second,
+		MaxHeaderBytes:    64 << 10,
+		MaxBodyBytes:      1 << 20,
+
+		ShutdownTimeout: 30 * time.Second,
+
+		ReadinessTimeout:  2 * time.Second,
+		ReadinessCacheTTL: 5 * time.Second,
+
+		DBMaxOpenConns:    25,
+		DBMaxIdleConns:    25,
+		DBConnMaxLifetime: 30 * time.Minute,
+		DBConnMaxIdleTime: 5 * time.Minute,
+
+		RefreshTokenRetention:        7 * 24 * time.Hour,
+		RefreshTokenCleanupInterval:  1 * time.Hour,
+		RefreshTokenCleanupBatchSize: 1000,
+
+		ChirpTrashRetention:      30 * 24 * time.Hour,
+		ChirpTrashPurgeInterval:  1 * time.Hour,
+		ChirpTrashPurgeBatchSize: 1000,
+
+		MediaStore:     "local",
+		MediaDir:       "media",
+		MediaMaxBytes:  5 << 20,
+		MediaMaxImages: 4,
+
+		MediaOrphanRetention:        1 * time.Hour,
+		MediaOrphanCleanupInterval:  1 * time.Hour,
+		MediaOrphanCleanupBatchSize: 100,
+
+		TracingExporter:    "none",
+		TracingFile:        "traces.jsonl",
+		TracingSampleRatio: 1,
+
+		RateLimitEnabled: true,
+		RateLimitStore:   "memory",
+
+		StreamBufferSize:        1000,
+		StreamHeartbeatInterval: 15 * time.Second,
+	}
+}
+
+// field describes one setting. key is the environment variable name; file
+// keys are matched case-insensitively with nested tables joined by "_", and
+// flags use the lower-case, dash-separated form of key.
+type field struct {
+	key   string
+	usage string
+	flag  bool // secrets are never accepted as flags, they'd show up in ps
+	set   func(c *Config, v string) error
+}
+
+// flagValue records a raw flag value so flags go through the same parsing
+// as every other source.
+type flagValue struct {
+	key    string
+	value  string
+	isBool bool
+}
+
+func (f *flagValue) String() string     { return f.value }
+func (f *flagValue) Set(v string) error { f.value = v; return nil }
+func (f *flagValue) IsBoolFlag() bool   { return f.isBool }
+
+var fields = []field{
+	{"PORT", "port to listen on", true, intField(func(c *Config) *int { return &c.Port })},
+	{"DB_URL", "postgres://... or sqlite:PATH connection string", false, func(c *Config, v string) error { c.DBURL = Secret(v); return nil }},
+	{"PLATFORM", "dev or prod; dev exposes destructive admin endpoints", true, func(c *Config, v string) error { c.Platform = v; return nil }},
+	{"LOG_FORMAT", "json or text", true, func(c *Config, v string) error { c.LogFormat = v; return nil }},
+	{"LOG_LEVEL", "debug, info, warn or error", true, func(c *Config, v string) error { c.LogLevel = v; return nil }},
+	{"JWT_SECRET", "secret used to sign access tokens", false, func(c *Config, v string) error { c.JWTSecret = Secret(v); return nil }},
+	{"POLKA_KEY", "API key Polka uses to call our webhooks", false, func(c *Config, v string) error { c.PolkaKey = Secret(v); return nil }},
+	{"METRICS_TOKEN", "bearer token that scrapes /metrics; unset, only PLATFORM=dev serves it", false, func(c *Config, v string) error { c.MetricsToken = Secret(v); return nil }},
+
+	{"HTTP_READ_TIMEOUT", "max time to read a request, including the body", true, durationField(func(c *Config) *time.Duration { return &c.ReadTimeout })},
+	{"HTTP_READ_HEADER_TIMEOUT", "max time to read request headers", true, durationField(func(c *Config) *time.Duration { return &c.ReadHeaderTimeout })},
+	{"HTTP_WRITE_TIMEOUT", "max time to write a response", true, durationField(func(c *Config) *time.Duration { return &c.WriteTimeout })},
+	{"HTTP_IDLE_TIMEOUT", "max time to keep an idle keep-alive connection open", true, durationField(func(c *Config) *time.Duration { return &c.IdleTimeout })},
+	{"HTTP_MAX_HEADER_BYTES", "max size of request headers", true, intField(func(c *Config) *int { return &c.MaxHeaderBytes })},
+	{"HTTP_MAX_BODY_BYTES", "max size of a request body", true, int64Field(func(c *Config) *int64 { return &c.MaxBodyBytes })},
+
+	{"SHUTDOWN_DELAY", "time to report unready before draining, so load balancers stop sending traffic", true, durationField(func(c *Config) *time.Duration { return &c.ShutdownDelay })},
+	{"SHUTDOWN_TIMEOUT", "max time to wait for in-flight requests to finish on shutdown", true, durationField(func(c *Config) *time.Duration { return &c.ShutdownTimeout })},
+
+	{"READINESS_TIMEOUT", "max time the readiness probe waits for each dependency", true, durationField(func(c *Config) *time.Duration { return &c.ReadinessTimeout })},
+	{"READINESS_CACHE_TTL", "how long a readiness result is reused", true, durationField(func(c *Config) *time.Duration { return &c.ReadinessCacheTTL })},
+
+	{"DB_MAX_OPEN_CONNS", "max open database connections (0 = unlimited)", true, intField(func(c *Config) *int { return &c.DBMaxOpenConns })},
+	{"DB_MAX_IDLE_CONNS", "max idle database connections", true, intField(func(c *Config) *int { return &c.DBMaxIdleConns })},
+	{"DB_CONN_MAX_LIFETIME", "max time a database connection is reused (0 = forever)", true, durationField(func(c *Config) *time.Duration { return &c.DBConnMaxLifetime })},
+	{"DB_CONN_MAX_IDLE_TIME", "max time a database connection sits idle before being closed (0 = forever)", true, durationField(func(c *Config) *time.Duration { return &c.DBConnMaxIdleTime })},
+
+	{"SKIP_MIGRATIONS", "don't apply pending migrations on startup", true, boolField(func(c *Config) *bool { return &c.SkipMigrations })},
+
+	{"REFRESH_TOKEN_RETENTION", "how long expired or revoked refresh tokens are kept", true, durationField(func(c *Config) *time.Duration { return &c.RefreshTokenRetention })},
+	{"REFRESH_TOKEN_CLEANUP_INTERVAL", "how often stale refresh tokens are deleted", true, durationField(func(c *Config) *time.Duration { return &c.RefreshTokenCleanupInterval })},
+	{"REFRESH_TOKEN_CLEANUP_BATCH_SIZE", "max refresh tokens deleted per statement", true, intField(func(c *Config) *int { return &c.RefreshTokenCleanupBatchSize })},
+
+	{"CHIRP_TRASH_RETENTION", "how long deleted chirps can be restored before they're purged", true, durationField(func(c *Config) *time.Duration { return &c.ChirpTrashRetention })},
+	{"CHIRP_TRASH_PURGE_INTERVAL", "how often expired chirps are purged from the trash", true, durationField(func(c *Config) *time.Duration { return &c.ChirpTrashPurgeInterval })},
+	{"CHIRP_TRASH_PURGE_BATCH_SIZE", "max chirps purged per statement", true, intField(func(c *Config) *int { return &c.ChirpTrashPurgeBatchSize })},
+
+	{"MEDIA_STORE", "where chirp images are kept: local or s3", true, func(c *Config, v string) error { c.MediaStore = v; return nil }},
+	{"MEDIA_DIR", "directory chirp images are kept in when MEDIA_STORE is local", true, func(c *Config, v string) error { c.MediaDir = v; return nil }},
+	{"MEDIA_S3_ENDPOINT", "URL of the S3-compatible service when MEDIA_STORE is s3", true, func(c *Config, v string) error { c.MediaS3Endpoint = v; return nil }},
+	{"MEDIA_S3_REGION", "region requests to MEDIA_S3_ENDPOINT are signed for", true, func(c *Config, v string) error { c.MediaS3Region = v; return nil }},
+	{"MEDIA_S3_BUCKET", "bucket chirp images are kept in when MEDIA_STORE is s3", true, func(c *Config, v string) error { c.MediaS3Bucket = v; return nil }},
+	{"MEDIA_S3_ACCESS_KEY_ID", "access key ID for MEDIA_S3_ENDPOINT", true, func(c *Config, v string) error { c.MediaS3AccessKeyID = v; return nil }},
+	{"MEDIA_S3_SECRET_ACCESS_KEY", "secret access key for MEDIA_S3_ENDPOINT", false, func(c *Config, v string) error { c.MediaS3SecretAccessKey = Secret(v); return nil }},
+	{"MEDIA_MAX_BYTES", "max size of an uploaded image", true, int64Field(func(c *Config) *int64 { return &c.MediaMaxBytes })},
+	{"MEDIA_MAX_IMAGES", "max images on a chirp", true, intField(func(c *Config) *int { return &c.MediaMaxImages })},
+	{"MEDIA_ORPHAN_RETENTION", "how long images on no chirp are kept before they're deleted", true, durationField(func(c *Config) *time.Duration { return &c.MediaOrphanRetention })},
+	{"MEDIA_ORPHAN_CLEANUP_INTERVAL", "how often images on no chirp are deleted", true, durationField(func(c *Config) *time.Duration { return &c.MediaOrphanCleanupInterval })},
+	{"MEDIA_ORPHAN_CLEANUP_BATCH_SIZE", "max images on no chirp deleted per batch", true, intField(func(c *Config) *int { return &c.MediaOrphanCleanupBatchSize })},
+
+	{"TRACING_EXPORTER", "where spans go: none, stdout, file or otlp", true, func(c *Config, v string) error { c.TracingExporter = v; return nil }},
+	{"TRACING_FILE", "file spans are appended to when TRACING_EXPORTER is file", true, func(c *Config, v string) error { c.TracingFile = v; return nil }},
+	{"TRACING_SAMPLE_RATIO", "fraction of new traces recorded, between 0 and 1", true, floatField(func(c *Config) *float64 { return &c.TracingSampleRatio })},
+
+	{"RATE_LIMIT_ENABLED", "limit request rates per client and user", true, boolField(func(c *Config) *bool { return &c.RateLimitEnabled })},
+	{"RATE_LIMIT_STORE", "where rate limits are kept: memory or postgres", true, func(c *Config, v string) error { c.RateLimitStore = v; return nil }},
+	{"TRUSTED_PROXIES", "comma-separated proxy CIDRs or addresses whose X-Forwarded-For is trusted", true, func(c *Config, v string) error { c.TrustedProxies = v; return nil }},
+
+	{"STREAM_BUFFER_SIZE", "recent events kept so reconnecting stream clients can resume", true, intField(func(c *Config) *int { return &c.StreamBufferSize })},
+	{"STREAM_HEARTBEAT_INTERVAL", "how often idle event streams send a heartbeat and WebSockets are pinged", true, durationField(func(c *Config) *time.Duration { return &c.StreamHeartbeatInterval })},
+}
+
+func intField(ptr func(c *Config) *int) func(c *Config, v string) error {
+	return func(c *Config, v string) error {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("not an integer: %q", v)
+		}
+		*ptr(c) = n
+		return nil
+	}
+}
+
+func int64Field(ptr func(c *Config) *int64) func(c *Config, v string) error {
+	return func(c *Config, v string) error {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return fmt.Errorf("not an integer: %q", v)
+		}
+		*ptr(c) = n
+		return nil
+	}
+}
+
+func durationField(ptr func(c *Config) *time.Duration) func(c *Config, v string) error {
+	return func(c *Config, v string) error {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("not a duration: %q", v)
+		}
+		*ptr(c) = d
+		return nil
+	}
+}
+
+func floatField(ptr func(c *Config) *float64) func(c *Config, v string) error {
+	return func(c *Config, v string) error {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return fmt.Errorf("not a number: %q", v)
+		}
+		*ptr(c) = f
+		return nil
+	}
+}
+
+func boolField(ptr func(c *Config) *bool) func(c *Config, v string) error {
+	return func(c *Config, v string) error {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("not a boolean: %q", v)
+		}
+		*ptr(c) = b
+		return nil
+	}
+}
+
+// boolFields can be passed as bare flags, e.g. -skip-migrations
+var boolFields = map[string]bool{"SKIP_MIGRATIONS": true, "RATE_LIMIT_ENABLED": true}
+
+// ParseTrustedProxies parses a TRUSTED_PROXIES value. Bare addresses are
+// treated as single-host prefixes.
+func ParseTrustedProxies(s string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		if !strings.Contains(part, "/") {
+			addr, err := netip.ParseAddr(part)
+			if err != nil {
+				return nil, fmt.Errorf("not an address or CIDR: %q", part)
+			}
+			prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(part)
+		if err != nil {
+			return nil, fmt.Errorf("not an address or CIDR: %q", part)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+func flagName(key string) string {
+	return strings.ReplaceAll(strings.ToLower(key), "_", "-")
+}
+
+// Load builds the configuration from, in increasing order of precedence:
+// defaults, the config file (-config or CHIRPY_CONFIG, YAML or TOML), the
+// .env file, the process environment and command-line flags. It does not
+// validate the result; call Validate for that.
+func Load(name string, args []string) (Config, error) {
+	return LoadCommand(flag.NewFlagSet(name, flag.ContinueOnError), args)
+}
+
+// LoadCommand is Load for subcommands with flags of their own: it adds the
+// config flags to fs and parses args with it, so the caller can read its
+// flags and fs.Args() afterwards.
+func LoadCommand(fs *flag.FlagSet, args []string) (Config, error) {
+	cfg := Default()
+
+	configPath := fs.String("config", os.Getenv("CHIRPY_CONFIG"), "path to a YAML or TOML config file")
+	envFile := fs.String("env-file", ".env", "path to a .env file; missing files are ignored")
+	flagValues := map[string]*flagValue{}
+	for _, f := range fields {
+		if f.flag {
+			v := &flagValue{key: f.key, isBool: boolFields[f.key]}
+			fs.Var(v, flagName(f.key), f.usage+" (env "+f.key+")")
+			flagValues[flagName(f.key)] = v
+		}
+	}
+	if err := fs.Parse(args); err != nil {
+		return cfg, err
+	}
+
+	if *configPath != "" {
+		values, err := readFile(*configPath)
+		if err != nil {
+			return cfg, err
+		}
+		if err := apply(&cfg, values, "config file "+*configPath); err != nil {
+			return cfg, err
+		}
+	}
+
+	dotenv, err := godotenv.Read(*envFile)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return cfg, fmt.Errorf("couldn't read %s: %w", *envFile, err)
+	}
+	if err := apply(&cfg, dotenv, *envFile); err != nil {
+		return cfg, err
+	}
+
+	env := map[string]string{}
+	for _, f := range fields {
+		if v, ok := os.LookupEnv(f.key); ok {
+			env[f.key] = v
+		}
+	}
+	if err := apply(&cfg, env, "environment"); err != nil {
+		return cfg, err
+	}
+
+	set := map[string]string{}
+	fs.Visit(func(fl *flag.Flag) {
+		if v, ok := flagValues[fl.Name]; ok {
+			set[v.key] = v.value
+		}
+	})
+	if err := apply(&cfg, set, "flags"); err != nil {
+		return cfg, err
+	}
+
+	return cfg, nil
+}
+
+func apply(cfg *Config, values map[string]string, source string) error {
+	var errs []error
+	for _, f := range fields {
+		v, ok := values[f.key]
+		if !ok {
+			continue
+		}
+		if err := f.set(cfg, v); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %s: %w", source, f.key, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// readFile parses a YAML or TOML file into a flat map keyed like the
+// environment, so {http: {read_timeout: 5s}} becomes HTTP_READ_TIMEOUT.
+func readFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read config file: %w", err)
+	}
+
+	raw := map[string]any{}
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &raw)
+	case ".toml":
+		err = toml.Unmarshal(data, &raw)
+	default:
+		return nil, fmt.Errorf("unsupported config file type %q, use .yaml, .yml or .toml", ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("couldn't parse config file %s: %w", path, err)
+	}
+
+	known := map[string]bool{}
+	for _, f := range fields {
+		known[f.key] = true
+	}
+
+	values := map[string]string{}
+	flatten("", raw, values)
+	for key := range values {
+		if !known[key] {
+			return nil, fmt.Errorf("config file %s: unknown setting %q", path, key)
+		}
+	}
+	return values, nil
+}
+
+func flatten(prefix string, raw map[string]any, out map[string]string) {
+	for k, v := range raw {
+		key := strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(k))
+		if prefix != "" {
+			key = prefix + "_" + key
+		}
+		if nested, ok := v.(map[string]any); ok {
+			flatten(key, nested, out)
+			continue
+		}
+		out[key] = fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,275 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testSecret = "0123456789abcdef0123456789abcdef"
+
+func writeFile(t *testing.T, name, contents string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+		t.Fatalf("couldn't write %s: %v", name, err)
+	}
+	return path
+}
+
+func validConfig() Config {
+	cfg := Default()
+	cfg.DBURL = "postgres://localhost/chirpy"
+	cfg.JWTSecret = testSecret
+	cfg.PolkaKey = "f271c81ff7084ee5b99a5091b42d486e"
+	return cfg
+}
+
+func TestLoadDefaults(t *testing.T) {
+	cfg, err := Load("test", []string{"-env-file", filepath.Join(t.TempDir(), "missing.env")})
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if cfg != Default() {
+		t.Errorf("Load() = %+v, want defaults %+v", cfg, Default())
+	}
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	file := writeFile(t, "chirpy.yaml", `
+port: 1000
+platform: dev
+db_url: postgres://file/chirpy
+http:
+  read_timeout: 1s
+  write_timeout: 2s
+db:
+  max_open_conns: 3
+`)
+	dotenv := writeFile(t, ".env", `
+PORT=2000
+HTTP_WRITE_TIMEOUT=20s
+DB_URL=postgres://dotenv/chirpy
+`)
+	t.Setenv("PORT", "3000")
+	t.Setenv("DB_URL", "postgres://env/chirpy")
+
+	cfg, err := Load("test", []string{"-config", file, "-env-file", dotenv, "-port", "4000", "-skip-migrations"})
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+
+	if cfg.Port != 4000 {
+		t.Errorf("Port = %d, flags should win", cfg.Port)
+	}
+	if cfg.DBURL != "postgres://env/chirpy" {
+		t.Errorf("DBURL = %q, environment should beat .env", string(cfg.DBURL))
+	}
+	if cfg.WriteTimeout != 20*time.Second {
+		t.Errorf("WriteTimeout = %v, .env should beat the config file", cfg.WriteTimeout)
+	}
+	if cfg.ReadTimeout != time.Second || cfg.Platform != "dev" || cfg.DBMaxOpenConns != 3 {
+		t.Errorf("config file values not applied: %+v", cfg)
+	}
+	if !cfg.SkipMigrations {
+		t.Error("-skip-migrations should work as a bare flag")
+	}
+}
+
+func TestLoadTOML(t *testing.T) {
+	file := writeFile(t, "chirpy.toml", `
+port = 9000
+
+[refresh_token]
+retention = "48h"
+cleanup_batch_size = 50
+`)
+	cfg, err := Load("test", []string{"-config", file, "-env-file", filepath.Join(t.TempDir(), "missing.env")})
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if cfg.Port != 9000 || cfg.RefreshTokenRetention != 48*time.Hour || cfg.RefreshTokenCleanupBatchSize != 50 {
+		t.Errorf("TOML values not applied: %+v", cfg)
+	}
+}
+
+func TestLoadTracingAndRateLimits(t *testing.T) {
+	t.Setenv("TRACING_EXPORTER", "file")
+	t.Setenv("TRACING_SAMPLE_RATIO", "0.25")
+	t.Setenv("TRUSTED_PROXIES", "10.0.0.0/8")
+	cfg, err := Load("test", []string{"-env-file", filepath.Join(t.TempDir(), "missing.env"), "-rate-limit-enabled=false", "-rate-limit-store", "postgres", "-tracing-file", "spans.jsonl"})
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if cfg.TracingExporter != "file" || cfg.TracingFile != "spans.jsonl" || cfg.TracingSampleRatio != 0.25 {
+		t.Errorf("tracing settings not applied: %+v", cfg)
+	}
+	if cfg.RateLimitEnabled || cfg.RateLimitStore != "postgres" || cfg.TrustedProxies != "10.0.0.0/8" {
+		t.Errorf("rate limit settings not applied: %+v", cfg)
+	}
+}
+
+func TestLoadCommand(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	n := fs.Int("n", 10, "")
+	cfg, err := LoadCommand(fs, []string{"-env-file", filepath.Join(t.TempDir(), "missing.env"), "-port", "4000", "-n", "3", "walt@example.com"})
+	if err != nil {
+		t.Fatalf("LoadCommand() failed: %v", err)
+	}
+	if cfg.Port != 4000 || *n != 3 {
+		t.Errorf("Port = %d, n = %d; want both flags applied", cfg.Port, *n)
+	}
+	if args := fs.Args(); len(args) != 1 || args[0] != "walt@example.com" {
+		t.Errorf("Args() = %q", args)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "missing.env")
+	tests := []struct {
+		name string
+		file string
+		args []string
+		want string
+	}{
+		{
+			name: "unknown file key",
+			file: writeFile(t, "bad.yaml", "prot: 8080\n"),
+			want: `unknown setting "PROT"`,
+		},
+		{
+			name: "unsupported extension",
+			file: writeFile(t, "chirpy.json", "{}"),
+			want: "unsupported config file type",
+		},
+		{
+			name: "bad duration flag",
+			args: []string{"-http-read-timeout", "soon"},
+			want: "HTTP_READ_TIMEOUT: not a duration",
+		},
+		{
+			name: "secrets are not flags",
+			args: []string{"-jwt-secret", testSecret},
+			want: "flag provided but not defined",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := []string{"-env-file", missing}
+			if tt.file != "" {
+				args = append(args, "-config", tt.file)
+			}
+			args = append(args, tt.args...)
+
+			_, err := Load("test", args)
+			if err == nil {
+				t.Fatalf("Load() should fail with %q", tt.want)
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Load() error = %q, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(c *Config)
+		want   string
+	}{
+		{"valid", func(c *Config) {}, ""},
+		{"missing DB_URL", func(c *Config) { c.DBURL = "" }, "DB_URL is required"},
+		{"sqlite DB_URL", func(c *Config) { c.DBURL = "sqlite:chirpy.db" }, ""},
+		{"unknown DB_URL scheme", func(c *Config) { c.DBURL = "mysql://localhost/chirpy" }, `DB_URL: unsupported scheme "mysql"`},
+		{"missing JWT_SECRET", func(c *Config) { c.JWTSecret = "" }, "JWT_SECRET is required"},
+		{"short JWT_SECRET", func(c *Config) { c.JWTSecret = "hunter2" }, "JWT_SECRET must be at least 32 bytes"},
+		{"example JWT_SECRET", func(c *Config) { c.JWTSecret = "ReplaceMeWithYourSecret" }, "JWT_SECRET is still set to the example value"},
+		{"missing POLKA_KEY", func(c *Config) { c.PolkaKey = "" }, "POLKA_KEY is required"},
+		{"example POLKA_KEY", func(c *Config) { c.PolkaKey = "YourPolkaAPIKey" }, "POLKA_KEY is still set to the example value"},
+		{"bad platform", func(c *Config) { c.Platform = "staging" }, "PLATFORM must be dev or prod"},
+		{"bad log format", func(c *Config) { c.LogFormat = "xml" }, "LOG_FORMAT must be json or text"},
+		{"bad log level", func(c *Config) { c.LogLevel = "loud" }, "LOG_LEVEL must be debug, info, warn or error"},
+		{"bad port", func(c *Config) { c.Port = 70000 }, "PORT must be between 1 and 65535"},
+		{"zero timeout", func(c *Config) { c.WriteTimeout = 0 }, "HTTP_WRITE_TIMEOUT must be positive"},
+		{"zero shutdown timeout", func(c *Config) { c.ShutdownTimeout = 0 }, "SHUTDOWN_TIMEOUT must be positive"},
+		{"tiny header limit", func(c *Config) { c.MaxHeaderBytes = 10 }, "HTTP_MAX_HEADER_BYTES must be at least 1024"},
+		{"zero body limit", func(c *Config) { c.MaxBodyBytes = 0 }, "HTTP_MAX_BODY_BYTES must be positive"},
+		{"bad trace exporter", func(c *Config) { c.TracingExporter = "jaeger" }, "TRACING_EXPORTER must be none, stdout, file or otlp"},
+		{"file exporter without path", func(c *Config) { c.TracingExporter, c.TracingFile = "file", "" }, "TRACING_FILE is required"},
+		{"sample ratio above one", func(c *Config) { c.TracingSampleRatio = 1.5 }, "TRACING_SAMPLE_RATIO must be between 0 and 1"},
+		{"bad rate limit store", func(c *Config) { c.RateLimitStore = "redis" }, "RATE_LIMIT_STORE must be memory or postgres"},
+		{"postgres rate limits on sqlite", func(c *Config) { c.DBURL, c.RateLimitStore = "sqlite:chirpy.db", "postgres" }, "RATE_LIMIT_STORE=postgres needs a postgres DB_URL"},
+		{"zero orphan retention", func(c *Config) { c.MediaOrphanRetention = 0 }, "MEDIA_ORPHAN_RETENTION must be positive"},
+		{"zero orphan batch", func(c *Config) { c.MediaOrphanCleanupBatchSize = 0 }, "MEDIA_ORPHAN_CLEANUP_BATCH_SIZE must be positive"},
+		{"padded metrics token", func(c *Config) { c.MetricsToken = "scrape-me " }, "METRICS_TOKEN must not have leading or trailing whitespace"},
+		{"negative stream buffer", func(c *Config) { c.StreamBufferSize = -1 }, "STREAM_BUFFER_SIZE must not be negative"},
+		{"zero heartbeat", func(c *Config) { c.StreamHeartbeatInterval = 0 }, "STREAM_HEARTBEAT_INTERVAL must be positive"},
+		{"bad trusted proxy", func(c *Config) { c.TrustedProxies = "10.0.0.0/8, proxy.internal" }, `TRUSTED_PROXIES: not an address or CIDR: "proxy.internal"`},
+		{"idle above open", func(c *Config) { c.DBMaxOpenConns, c.DBMaxIdleConns = 5, 10 }, "must not exceed DB_MAX_OPEN_CONNS"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig()
+			tt.mutate(&cfg)
+			err := cfg.Validate()
+			if tt.want == "" {
+				if err != nil {
+					t.Fatalf("Validate() failed: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Validate() error = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateReportsEveryProblem(t *testing.T) {
+	err := Config{}.Validate()
+	if err == nil {
+		t.Fatal("Validate() should fail on an empty config")
+	}
+	for _, want := range []string{"DB_URL", "JWT_SECRET", "POLKA_KEY", "PLATFORM", "PORT"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Validate() error should mention %s, got %v", want, err)
+		}
+	}
+}
+
+func TestParseTrustedProxies(t *testing.T) {
+	got, err := ParseTrustedProxies(" 10.1.2.3/8, 192.0.2.1,, ::1 ")
+	if err != nil {
+		t.Fatalf("ParseTrustedProxies() failed: %v", err)
+	}
+	want := []string{"10.0.0.0/8", "192.0.2.1/32", "::1/128"}
+	if len(got) != len(want) {
+		t.Fatalf("ParseTrustedProxies() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i].String() != want[i] {
+			t.Errorf("prefix %d = %s, want %s", i, got[i], want[i])
+		}
+	}
+}
+
+func TestSecretsAreRedacted(t *testing.T) {
+	cfg := validConfig()
+	cfg.MetricsToken = "scrape-me"
+	out := fmt.Sprintf("%v %+v %s", cfg, cfg, cfg.JWTSecret)
+	for _, secret := range []string{string(cfg.JWTSecret), string(cfg.PolkaKey), string(cfg.MetricsToken), string(cfg.DBURL)} {
+		if strings.Contains(out, secret) {
+			t.Errorf("formatted config leaks %q: %s", secret, out)
+		}
+	}
+	if !strings.Contains(out, redacted) {
+		t.Errorf("formatted config should show %s: %s", redacted, out)
+	}
+}
@@ -0,0 +1,194 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/HemahWeb/chirpy/internal/dburl"
+)
+
+// MinJWTSecretLength is the shortest JWT secret we accept. HS256 keys
+// shorter than the 256-bit hash output weaken the signature.
+const MinJWTSecretLength = 32
+
+// placeholders shipped in .env.example and the README
+var placeholderSecrets = map[string]bool{
+	"replacemewithyoursecret":   true,
+	"your-super-secret-jwt-key": true,
+	"yourpolkaapikey":           true,
+	"your-polka-api-key":        true,
+}
+
+// Validate reports every problem with the configuration at once.
+func (c Config) Validate() error {
+	var errs []error
+	add := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	var dialect dburl.Dialect
+	if c.DBURL == "" {
+		add("DB_URL is required")
+	} else if d, _, err := dburl.Parse(string(c.DBURL)); err != nil {
+		add("DB_URL: %v", err)
+	} else {
+		dialect = d
+	}
+
+	switch {
+	case c.JWTSecret == "":
+		add("JWT_SECRET is required")
+	case placeholderSecrets[strings.ToLower(string(c.JWTSecret))]:
+		add("JWT_SECRET is still set to the example value")
+	case len(c.JWTSecret) < MinJWTSecretLength:
+		add("JWT_SECRET must be at least %d bytes, got %d; generate one with `openssl rand -base64 64`", MinJWTSecretLength, len(c.JWTSecret))
+	}
+
+	switch {
+	case c.PolkaKey == "":
+		add("POLKA_KEY is required")
+	case placeholderSecrets[strings.ToLower(string(c.PolkaKey))]:
+		add("POLKA_KEY is still set to the example value")
+	case strings.TrimSpace(string(c.PolkaKey)) != string(c.PolkaKey):
+		add("POLKA_KEY must not have leading or trailing whitespace")
+	}
+
+	if strings.TrimSpace(string(c.MetricsToken)) != string(c.MetricsToken) {
+		add("METRICS_TOKEN must not have leading or trailing whitespace")
+	}
+
+	if c.Platform != "dev" && c.Platform != "prod" {
+		add("PLATFORM must be dev or prod, got %q", c.Platform)
+	}
+
+	if c.LogFormat != "json" && c.LogFormat != "text" {
+		add("LOG_FORMAT must be json or text, got %q", c.LogFormat)
+	}
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(c.LogLevel)); err != nil {
+		add("LOG_LEVEL must be debug, info, warn or error, got %q", c.LogLevel)
+	}
+
+	if c.Port < 1 || c.Port > 65535 {
+		add("PORT must be between 1 and 65535, got %d", c.Port)
+	}
+
+	for _, d := range []struct {
+		name  string
+		value time.Duration
+	}{
+		{"HTTP_READ_TIMEOUT", c.ReadTimeout},
+		{"HTTP_READ_HEADER_TIMEOUT", c.ReadHeaderTimeout},
+		{"HTTP_WRITE_TIMEOUT", c.WriteTimeout},
+		{"HTTP_IDLE_TIMEOUT", c.IdleTimeout},
+		{"SHUTDOWN_TIMEOUT", c.ShutdownTimeout},
+		{"READINESS_TIMEOUT", c.ReadinessTimeout},
+		{"REFRESH_TOKEN_RETENTION", c.RefreshTokenRetention},
+		{"REFRESH_TOKEN_CLEANUP_INTERVAL", c.RefreshTokenCleanupInterval},
+		{"CHIRP_TRASH_RETENTION", c.ChirpTrashRetention},
+		{"CHIRP_TRASH_PURGE_INTERVAL", c.ChirpTrashPurgeInterval},
+		{"MEDIA_ORPHAN_RETENTION", c.MediaOrphanRetention},
+		{"MEDIA_ORPHAN_CLEANUP_INTERVAL", c.MediaOrphanCleanupInterval},
+		{"STREAM_HEARTBEAT_INTERVAL", c.StreamHeartbeatInterval},
+	} {
+		if d.value <= 0 {
+			add("%s must be positive, got %v", d.name, d.value)
+		}
+	}
+
+	if c.ReadinessCacheTTL < 0 {
+		add("READINESS_CACHE_TTL must not be negative, got %v", c.ReadinessCacheTTL)
+	}
+
+	if c.ShutdownDelay < 0 {
+		add("SHUTDOWN_DELAY must not be negative, got %v", c.ShutdownDelay)
+	}
+
+	if c.MaxHeaderBytes < 1<<10 {
+		add("HTTP_MAX_HEADER_BYTES must be at least 1024, got %d", c.MaxHeaderBytes)
+	}
+	if c.MaxBodyBytes < 1 {
+		add("HTTP_MAX_BODY_BYTES must be positive, got %d", c.MaxBodyBytes)
+	}
+
+	if c.DBMaxOpenConns < 0 {
+		add("DB_MAX_OPEN_CONNS must not be negative, got %d", c.DBMaxOpenConns)
+	}
+	if c.DBMaxIdleConns < 0 {
+		add("DB_MAX_IDLE_CONNS must not be negative, got %d", c.DBMaxIdleConns)
+	}
+	if c.DBMaxOpenConns > 0 && c.DBMaxIdleConns > c.DBMaxOpenConns {
+		add("DB_MAX_IDLE_CONNS (%d) must not exceed DB_MAX_OPEN_CONNS (%d)", c.DBMaxIdleConns, c.DBMaxOpenConns)
+	}
+	if c.DBConnMaxLifetime < 0 {
+		add("DB_CONN_MAX_LIFETIME must not be negative, got %v", c.DBConnMaxLifetime)
+	}
+	if c.DBConnMaxIdleTime < 0 {
+		add("DB_CONN_MAX_IDLE_TIME must not be negative, got %v", c.DBConnMaxIdleTime)
+	}
+
+	if c.RefreshTokenCleanupBatchSize < 1 {
+		add("REFRESH_TOKEN_CLEANUP_BATCH_SIZE must be positive, got %d", c.RefreshTokenCleanupBatchSize)
+	}
+	if c.ChirpTrashPurgeBatchSize < 1 {
+		add("CHIRP_TRASH_PURGE_BATCH_SIZE must be positive, got %d", c.ChirpTrashPurgeBatchSize)
+	}
+	if c.MediaOrphanCleanupBatchSize < 1 {
+		add("MEDIA_ORPHAN_CLEANUP_BATCH_SIZE must be positive, got %d", c.MediaOrphanCleanupBatchSize)
+	}
+	if c.StreamBufferSize < 0 {
+		add("STREAM_BUFFER_SIZE must not be negative, got %d", c.StreamBufferSize)
+	}
+
+	switch c.MediaStore {
+	case "local":
+		if c.MediaDir == "" {
+			add("MEDIA_DIR is required when MEDIA_STORE is local")
+		}
+	case "s3":
+		if c.MediaS3Endpoint == "" {
+			add("MEDIA_S3_ENDPOINT is required when MEDIA_STORE is s3")
+		}
+		if c.MediaS3Bucket == "" {
+			add("MEDIA_S3_BUCKET is required when MEDIA_STORE is s3")
+		}
+		if c.MediaS3AccessKeyID == "" || c.MediaS3SecretAccessKey == "" {
+			add("MEDIA_S3_ACCESS_KEY_ID and MEDIA_S3_SECRET_ACCESS_KEY are required when MEDIA_STORE is s3")
+		}
+	default:
+		add("MEDIA_STORE must be local or s3, got %q", c.MediaStore)
+	}
+	if c.MediaMaxBytes < 1 {
+		add("MEDIA_MAX_BYTES must be positive, got %d", c.MediaMaxBytes)
+	}
+	if c.MediaMaxImages < 0 {
+		add("MEDIA_MAX_IMAGES must not be negative, got %d", c.MediaMaxImages)
+	}
+
+	switch c.TracingExporter {
+	case "none", "stdout", "otlp":
+	case "file":
+		if c.TracingFile == "" {
+			add("TRACING_FILE is required when TRACING_EXPORTER is file")
+		}
+	default:
+		add("TRACING_EXPORTER must be none, stdout, file or otlp, got %q", c.TracingExporter)
+	}
+	if c.TracingSampleRatio < 0 || c.TracingSampleRatio > 1 {
+		add("TRACING_SAMPLE_RATIO must be between 0 and 1, got %v", c.TracingSampleRatio)
+	}
+
+	if c.RateLimitStore != "memory" && c.RateLimitStore != "postgres" {
+		add("RATE_LIMIT_STORE must be memory or postgres, got %q", c.RateLimitStore)
+	} else if c.RateLimitStore == "postgres" && dialect == dburl.SQLite {
+		add("RATE_LIMIT_STORE=postgres needs a postgres DB_URL")
+	}
+	if _, err := ParseTrustedProxies(c.TrustedProxies); err != nil {
+		add("TRUSTED_PROXIES: %v", err)
+	}
+
+	return errors.Join(errs...)
+}
@@ -7,21 +7,29 @@ package database
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 )
 
 const createChirp = `-- name: CreateChirp :one
-INSERT INTO chirps (body, user_id) VALUES ($1, $2) RETURNING id, created_at, updated_at, body, user_id
+INSERT INTO chirps (body, user_id, reply_to_id, thread_id) VALUES ($1, $2, $3, $4) RETURNING id, created_at, updated_at, body, user_id, reply_to_id, thread_id, deleted_at
 `
 
 type CreateChirpParams struct {
-	Body   string
-	UserID uuid.UUID
+	Body      string
+	UserID    uuid.UUID
+	ReplyToID uuid.NullUUID
+	ThreadID  uuid.NullUUID
 }
 
 func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp, error) {
-	row := q.db.QueryRowContext(ctx, createChirp, arg.Body, arg.UserID)
+	row := q.db.QueryRowContext(ctx, createChirp,
+		arg.Body,
+		arg.UserID,
+		arg.ReplyToID,
+		arg.ThreadID,
+	)
 	var i Chirp
 	err := row.Scan(
 		&i.ID,
@@ -29,23 +37,28 @@ func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.ReplyToID,
+		&i.ThreadID,
+		&i.DeletedAt,
 	)
 	return i, err
 }
 
 const deleteChirp = `-- name: DeleteChirp :exec
-DELETE FROM chirps WHERE id = $1
+UPDATE chirps SET deleted_at = CURRENT_TIMESTAMP WHERE id = $1 AND deleted_at IS NULL
 `
 
+// Moves a chirp to the trash; PurgeChirp removes it for good.
 func (q *Queries) DeleteChirp(ctx context.Context, id uuid.UUID) error {
 	_, err := q.db.ExecContext(ctx, deleteChirp, id)
 	return err
 }
 
 const getChirpByID = `-- name: GetChirpByID :one
-SELECT id, created_at, updated_at, body, user_id FROM chirps WHERE id = $1 LIMIT 1
+SELECT id, created_at, updated_at, body, user_id, reply_to_id, thread_id, deleted_at FROM chirps WHERE id = $1 LIMIT 1
 `
 
+// Finds deleted chirps too.
 func (q *Queries) GetChirpByID(ctx context.Context, id uuid.UUID) (Chirp, error) {
 	row := q.db.QueryRowContext(ctx, getChirpByID, id)
 	var i Chirp
@@ -55,14 +68,18 @@ func (q *Queries) GetChirpByID(ctx context.Context, id uuid.UUID) (Chirp, error)
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.ReplyToID,
+		&i.ThreadID,
+		&i.DeletedAt,
 	)
 	return i, err
 }
 
 const getChirps = `-- name: GetChirps :many
-SELECT id, created_at, updated_at, body, user_id FROM chirps ORDER BY created_at ASC
+SELECT id, created_at, updated_at, body, user_id, reply_to_id, thread_id, deleted_at FROM chirps WHERE deleted_at IS NULL AND id NOT IN (SELECT chirp_id FROM hidden_chirps) ORDER BY created_at ASC
 `
 
+// Leaves out deleted chirps and those a moderator hid.
 func (q *Queries) GetChirps(ctx context.Context) ([]Chirp, error) {
 	rows, err := q.db.QueryContext(ctx, getChirps)
 	if err != nil {
@@ -78,6 +95,9 @@ func (q *Queries) GetChirps(ctx context.Context) ([]Chirp, error) {
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.ReplyToID,
+			&i.ThreadID,
+			&i.DeletedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -93,9 +113,10 @@ func (q *Queries) GetChirps(ctx context.Context) ([]Chirp, error) {
 }
 
 const getChirpsByUserID = `-- name: GetChirpsByUserID :many
-SELECT id, created_at, updated_at, body, user_id FROM chirps WHERE user_id = $1 ORDER BY created_at ASC
+SELECT id, created_at, updated_at, body, user_id, reply_to_id, thread_id, deleted_at FROM chirps WHERE user_id = $1 AND deleted_at IS NULL AND id NOT IN (SELECT chirp_id FROM hidden_chirps) ORDER BY created_at ASC
 `
 
+// Leaves out deleted chirps and those a moderator hid.
 func (q *Queries) GetChirpsByUserID(ctx context.Context, userID uuid.UUID) ([]Chirp, error) {
 	rows, err := q.db.QueryContext(ctx, getChirpsByUserID, userID)
 	if err != nil {
@@ -111,6 +132,9 @@ func (q *Queries) GetChirpsByUserID(ctx context.Context, userID uuid.UUID) ([]Ch
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.ReplyToID,
+			&i.ThreadID,
+			&i.DeletedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -125,8 +149,110 @@ func (q *Queries) GetChirpsByUserID(ctx context.Context, userID uuid.UUID) ([]Ch
 	return items, nil
 }
 
+const listTrashedChirps = `-- name: ListTrashedChirps :many
+SELECT id, created_at, updated_at, body, user_id, reply_to_id, thread_id, deleted_at FROM chirps
+WHERE user_id = $1
+  AND deleted_at >= $2::timestamp
+  AND id NOT IN (SELECT chirp_id FROM hidden_chirps)
+ORDER BY deleted_at DESC, id DESC
+`
+
+type ListTrashedChirpsParams struct {
+	UserID uuid.UUID
+	Cutoff time.Time
+}
+
+// The chirps user_id deleted since cutoff, most recently deleted first.
+// Chirps a moderator hid aren't theirs to restore, so they're left out.
+func (q *Queries) ListTrashedChirps(ctx context.Context, arg ListTrashedChirpsParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, listTrashedChirps, arg.UserID, arg.Cutoff)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.ReplyToID,
+			&i.ThreadID,
+			&i.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const purgeChirp = `-- name: PurgeChirp :exec
+DELETE FROM chirps WHERE id = $1
+`
+
+func (q *Queries) PurgeChirp(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, purgeChirp, id)
+	return err
+}
+
+const purgeTrashedChirps = `-- name: PurgeTrashedChirps :execrows
+DELETE FROM chirps
+WHERE id IN (
+    SELECT id FROM chirps
+    WHERE deleted_at < $1::timestamp
+      AND id NOT IN (SELECT chirp_id FROM hidden_chirps)
+      AND NOT EXISTS (SELECT 1 FROM reports WHERE chirp_id = chirps.id AND status <> 'resolved')
+    LIMIT $2
+)
+`
+
+type PurgeTrashedChirpsParams struct {
+	Cutoff    time.Time
+	BatchSize int32
+}
+
+// Chirps a moderator hid are kept for the moderation log, and reported ones
+// until the reports are resolved.
+func (q *Queries) PurgeTrashedChirps(ctx context.Context, arg PurgeTrashedChirpsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, purgeTrashedChirps, arg.Cutoff, arg.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const restoreChirp = `-- name: RestoreChirp :one
+UPDATE chirps SET deleted_at = NULL WHERE id = $1 AND deleted_at IS NOT NULL RETURNING id, created_at, updated_at, body, user_id, reply_to_id, thread_id, deleted_at
+`
+
+func (q *Queries) RestoreChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, restoreChirp, id)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.ReplyToID,
+		&i.ThreadID,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
 const updateChirp = `-- name: UpdateChirp :one
-UPDATE chirps SET body = $2 WHERE id = $1 RETURNING id, created_at, updated_at, body, user_id
+UPDATE chirps SET body = $2 WHERE id = $1 RETURNING id, created_at, updated_at, body, user_id, reply_to_id, thread_id, deleted_at
 `
 
 type UpdateChirpParams struct {
@@ -143,6 +269,9 @@ func (q *Queries) UpdateChirp(ctx context.Context, arg UpdateChirpParams) (Chirp
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.ReplyToID,
+		&i.ThreadID,
+		&i.DeletedAt,
 	)
 	return i, err
 }
@@ -0,0 +1,39 @@
+package database
+
+import (
+	"errors"
+
+	"github.com/lib/pq"
+)
+
+// UniqueViolationError is returned by Querier implementations that aren't
+// backed by Postgres when an insert or update would break a unique
+// constraint. Constraint uses the Postgres name, e.g. users_email_key.
+type UniqueViolationError struct {
+	Constraint string
+}
+
+func (e *UniqueViolationError) Error() string {
+	return "duplicate key value violates unique constraint " + `"` + e.Constraint + `"`
+}
+
+// UniqueViolation reports whether err is a unique constraint violation from
+// any backend, and which constraint was violated.
+func UniqueViolation(err error) (constraint string, ok bool) {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return pqErr.Constraint, true
+	}
+	var uvErr *UniqueViolationError
+	if errors.As(err, &uvErr) {
+		return uvErr.Constraint, true
+	}
+	return "", false
+}
+
+// SerializationFailure reports whether err is a Postgres serialization
+// failure or deadlock, after which the whole transaction can be retried.
+func SerializationFailure(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && (pqErr.Code == "40001" || pqErr.Code == "40P01")
+}
@@ -11,12 +11,133 @@ import (
 	"github.com/google/uuid"
 )
 
+type Appeal struct {
+	ID        int64
+	CreatedAt time.Time
+	ActionID  int64
+	UserID    uuid.UUID
+	Body      string
+	Status    string
+	DecidedBy uuid.NullUUID
+	DecidedAt sql.NullTime
+}
+
+type Attachment struct {
+	ID                   uuid.UUID
+	CreatedAt            time.Time
+	ChirpID              uuid.NullUUID
+	Position             int32
+	ContentType          string
+	SizeBytes            int64
+	Width                int32
+	Height               int32
+	ThumbnailContentType string
+	ThumbnailSizeBytes   int64
+	ThumbnailWidth       int32
+	ThumbnailHeight      int32
+}
+
+type Block struct {
+	BlockerID uuid.UUID
+	BlockedID uuid.UUID
+	CreatedAt time.Time
+}
+
 type Chirp struct {
 	ID        uuid.UUID
 	CreatedAt time.Time
 	UpdatedAt time.Time
 	Body      string
 	UserID    uuid.UUID
+	ReplyToID uuid.NullUUID
+	ThreadID  uuid.NullUUID
+	DeletedAt sql.NullTime
+}
+
+type ChirpLike struct {
+	UserID    uuid.UUID
+	ChirpID   uuid.UUID
+	CreatedAt time.Time
+}
+
+type Conversation struct {
+	ID            uuid.UUID
+	CreatedAt     time.Time
+	LastMessageAt sql.NullTime
+	DirectKey     sql.NullString
+}
+
+type ConversationMember struct {
+	ConversationID uuid.UUID
+	UserID         uuid.UUID
+	LastReadID     int64
+	Muted          bool
+}
+
+type Follow struct {
+	FollowerID uuid.UUID
+	FolloweeID uuid.UUID
+	CreatedAt  time.Time
+}
+
+type HiddenChirp struct {
+	ChirpID   uuid.UUID
+	ActionID  int64
+	CreatedAt time.Time
+}
+
+type Message struct {
+	ID             int64
+	ConversationID uuid.UUID
+	SenderID       uuid.UUID
+	Body           string
+	CreatedAt      time.Time
+}
+
+type ModerationAction struct {
+	ID          int64
+	CreatedAt   time.Time
+	ModeratorID uuid.UUID
+	Action      string
+	ReportID    sql.NullInt64
+	AppealID    sql.NullInt64
+	UserID      uuid.UUID
+	ChirpID     uuid.NullUUID
+	Note        string
+}
+
+type Moderator struct {
+	UserID    uuid.UUID
+	CreatedAt time.Time
+}
+
+type Mute struct {
+	MuterID   uuid.UUID
+	MutedID   uuid.UUID
+	CreatedAt time.Time
+}
+
+type Notification struct {
+	ID        int64
+	CreatedAt time.Time
+	UserID    uuid.UUID
+	Type      string
+	ActorID   uuid.UUID
+	ChirpID   uuid.NullUUID
+	ReadAt    sql.NullTime
+}
+
+type NotificationPreference struct {
+	UserID  uuid.UUID
+	Type    string
+	Enabled bool
+}
+
+type RateLimitBucket struct {
+	Key       string
+	Tokens    float64
+	Allowed   bool
+	UpdatedAt time.Time
 }
 
 type RefreshToken struct {
@@ -28,11 +149,39 @@ type RefreshToken struct {
 	RevokedAt sql.NullTime
 }
 
+type Report struct {
+	ID         int64
+	CreatedAt  time.Time
+	ReporterID uuid.UUID
+	UserID     uuid.UUID
+	ChirpID    uuid.NullUUID
+	Reason     string
+	Details    string
+	Status     string
+	ClaimedBy  uuid.NullUUID
+	ClaimedAt  sql.NullTime
+	Resolution sql.NullString
+	ResolvedAt sql.NullTime
+}
+
 type User struct {
-	ID             uuid.UUID
-	CreatedAt      time.Time
-	UpdatedAt      time.Time
-	Email          string
-	HashedPassword string
-	IsChirpyRed    bool
+	ID                 uuid.UUID
+	CreatedAt          time.Time
+	UpdatedAt          time.Time
+	Email              string
+	HashedPassword     string
+	IsChirpyRed        bool
+	SuspendedAt        sql.NullTime
+	SuspendedUntil     sql.NullTime
+	LimitedAt          sql.NullTime
+	SuspensionActionID sql.NullInt64
+}
+
+type WebhookEvent struct {
+	ID         int64
+	ReceivedAt time.Time
+	Source     string
+	Event      string
+	UserID     uuid.NullUUID
+	Outcome    string
 }
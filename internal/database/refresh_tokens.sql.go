@@ -25,6 +25,28 @@ func (q *Queries) CreateRefreshToken(ctx context.Context, userID uuid.UUID) (str
 	return token, err
 }
 
+const deleteStaleRefreshTokens = `-- name: DeleteStaleRefreshTokens :execrows
+DELETE FROM refresh_tokens
+WHERE token IN (
+    SELECT token FROM refresh_tokens
+    WHERE expires_at < $1 OR revoked_at < $1
+    LIMIT $2
+)
+`
+
+type DeleteStaleRefreshTokensParams struct {
+	Cutoff    time.Time
+	BatchSize int32
+}
+
+func (q *Queries) DeleteStaleRefreshTokens(ctx context.Context, arg DeleteStaleRefreshTokensParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteStaleRefreshTokens, arg.Cutoff, arg.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const getUserIDFromRefreshToken = `-- name: GetUserIDFromRefreshToken :one
 SELECT user_id, expires_at, revoked_at FROM refresh_tokens WHERE token = $1
 `
@@ -51,3 +73,16 @@ func (q *Queries) RevokeRefreshToken(ctx context.Context, token string) error {
 	_, err := q.db.ExecContext(ctx, revokeRefreshToken, token)
 	return err
 }
+
+const revokeUserRefreshTokens = `-- name: RevokeUserRefreshTokens :execrows
+UPDATE refresh_tokens SET revoked_at = CURRENT_TIMESTAMP
+WHERE user_id = $1 AND revoked_at IS NULL
+`
+
+func (q *Queries) RevokeUserRefreshTokens(ctx context.Context, userID uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, revokeUserRefreshTokens, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -81,7 +81,7 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email string) (GetUserByEm
 }
 
 const getUserByEmailForAuth = `-- name: GetUserByEmailForAuth :one
-SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red FROM users WHERE email = $1 LIMIT 1
+SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red, suspended_at, suspended_until, limited_at, suspension_action_id FROM users WHERE email = $1 LIMIT 1
 `
 
 // auth-only
@@ -95,6 +95,10 @@ func (q *Queries) GetUserByEmailForAuth(ctx context.Context, email string) (User
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.SuspendedAt,
+		&i.SuspendedUntil,
+		&i.LimitedAt,
+		&i.SuspensionActionID,
 	)
 	return i, err
 }
@@ -137,7 +141,7 @@ func (q *Queries) ResetUsers(ctx context.Context) error {
 
 const updateUserEmailAndPassword = `-- name: UpdateUserEmailAndPassword :one
 UPDATE users SET email = $2, hashed_password = $3 WHERE id = $1 
-RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, suspended_at, suspended_until, limited_at, suspension_action_id
 `
 
 type UpdateUserEmailAndPasswordParams struct {
@@ -156,6 +160,10 @@ func (q *Queries) UpdateUserEmailAndPassword(ctx context.Context, arg UpdateUser
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.SuspendedAt,
+		&i.SuspendedUntil,
+		&i.LimitedAt,
+		&i.SuspensionActionID,
 	)
 	return i, err
 }
@@ -0,0 +1,46 @@
+// Package dburl reads the DB_URL setting, which picks the storage backend.
+package dburl
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Dialect is the kind of database a DB_URL points at.
+type Dialect string
+
+const (
+	Postgres Dialect = "postgres"
+	SQLite   Dialect = "sqlite3"
+)
+
+// Parse picks the dialect from the scheme of a DB_URL and returns the
+// data source name to pass to sql.Open. Postgres URLs are passed through;
+// sqlite:PATH and sqlite://PATH become a go-sqlite3 file: DSN, keeping any
+// query parameters.
+func Parse(dbURL string) (Dialect, string, error) {
+	scheme, rest, ok := strings.Cut(dbURL, ":")
+	if !ok {
+		return "", "", errors.New("no scheme; want postgres://... or sqlite:PATH")
+	}
+	switch strings.ToLower(scheme) {
+	case "postgres", "postgresql":
+		return Postgres, dbURL, nil
+	case "sqlite", "sqlite3":
+		path, query, _ := strings.Cut(strings.TrimPrefix(rest, "//"), "?")
+		if path == "" {
+			return "", "", errors.New("sqlite URL has no file path")
+		}
+		if _, err := url.ParseQuery(query); err != nil {
+			return "", "", fmt.Errorf("sqlite URL has invalid parameters: %w", err)
+		}
+		dsn := "file:" + path
+		if query != "" {
+			dsn += "?" + query
+		}
+		return SQLite, dsn, nil
+	}
+	return "", "", fmt.Errorf("unsupported scheme %q; want postgres or sqlite", scheme)
+}
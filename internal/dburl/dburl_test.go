@@ -0,0 +1,29 @@
+package dburl
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		url     string
+		dialect Dialect
+		dsn     string
+	}{
+		{"postgres://chirpy:pw@localhost:5432/chirpy?sslmode=disable", Postgres, "postgres://chirpy:pw@localhost:5432/chirpy?sslmode=disable"},
+		{"postgresql://localhost/chirpy", Postgres, "postgresql://localhost/chirpy"},
+		{"sqlite:chirpy.db", SQLite, "file:chirpy.db"},
+		{"sqlite:///var/lib/chirpy/chirpy.db", SQLite, "file:/var/lib/chirpy/chirpy.db"},
+		{"sqlite3:chirpy.db?_busy_timeout=10000", SQLite, "file:chirpy.db?_busy_timeout=10000"},
+	}
+	for _, tt := range tests {
+		dialect, dsn, err := Parse(tt.url)
+		if err != nil || dialect != tt.dialect || dsn != tt.dsn {
+			t.Errorf("Parse(%q) = %q, %q, %v; want %q, %q", tt.url, dialect, dsn, err, tt.dialect, tt.dsn)
+		}
+	}
+
+	for _, bad := range []string{"chirpy.db", "sqlite:", "sqlite://", "mysql://localhost/chirpy", "sqlite:x.db?%zz"} {
+		if _, _, err := Parse(bad); err == nil {
+			t.Errorf("Parse(%q) should fail", bad)
+		}
+	}
+}
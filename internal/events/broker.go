@@ -0,0 +1,212 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// subscriberBuffer is how many live events a subscriber can fall behind
+// before it's dropped.
+const subscriberBuffer = 64
+
+// Why a Subscription ended, from Err.
+var (
+	ErrClosed = errors.New("events: broker closed")
+	ErrReset  = errors.New("events: broker reset")
+	ErrSlow   = errors.New("events: subscriber fell behind")
+)
+
+// Filter reports whether a subscriber wants e. A nil Filter wants
+// everything.
+type Filter func(e Event) bool
+
+// Chirps matches every chirp event.
+func Chirps(e Event) bool {
+	return e.Type == ChirpCreated || e.Type == ChirpDeleted
+}
+
+// ByAuthors matches events about chirps written by any of ids.
+func ByAuthors(ids ...uuid.UUID) Filter {
+	return func(e Event) bool { return Chirps(e) && slices.Contains(ids, e.UserID) }
+}
+
+// InThread matches events about the chirps in the thread started by id.
+func InThread(id uuid.UUID) Filter {
+	return func(e Event) bool { return Chirps(e) && e.ThreadID == id }
+}
+
+// NotificationsFor matches the notifications sent to userID.
+func NotificationsFor(userID uuid.UUID) Filter {
+	return func(e Event) bool { return e.Type == NotificationCreated && e.UserID == userID }
+}
+
+// MessagesFor matches the direct message events sent to userID.
+func MessagesFor(userID uuid.UUID) Filter {
+	return func(e Event) bool {
+		return (e.Type == MessageCreated || e.Type == MessageDeleted) && e.UserID == userID
+	}
+}
+
+// SuspensionOf matches userID being suspended.
+func SuspensionOf(userID uuid.UUID) Filter {
+	return func(e Event) bool { return e.Type == UserSuspended && e.UserID == userID }
+}
+
+// Broker fans events out to subscribers on this instance and remembers the
+// last few for replay. Publishing never blocks: a subscriber whose buffer is
+// full is dropped, and can resume from the replay buffer when it
+// reconnects.
+type Broker struct {
+	mu     sync.Mutex
+	recent []Event // ring buffer, oldest at next once full
+	next   int
+	subs   map[*Subscription]struct{}
+	closed bool
+}
+
+// NewBroker returns a broker that keeps the last size events for replay.
+func NewBroker(size int) *Broker {
+	return &Broker{
+		recent: make([]Event, 0, size),
+		subs:   map[*Subscription]struct{}{},
+	}
+}
+
+// Subscription receives events on C until it's closed, dropped for falling
+// behind, or the broker is reset or closed; C is closed in every case.
+type Subscription struct {
+	C <-chan Event
+
+	ch     chan Event
+	filter Filter
+	broker *Broker
+	err    error
+}
+
+// Close unsubscribes. It's safe to call more than once.
+func (s *Subscription) Close() {
+	s.broker.mu.Lock()
+	defer s.broker.mu.Unlock()
+	s.broker.drop(s, nil)
+}
+
+// Err returns why the broker ended the subscription once C is closed:
+// ErrSlow, ErrReset or ErrClosed. It's nil if it's still open or was ended
+// by Close.
+func (s *Subscription) Err() error {
+	s.broker.mu.Lock()
+	defer s.broker.mu.Unlock()
+	return s.err
+}
+
+// Publish records e and sends it to every subscriber whose filter wants it.
+func (b *Broker) Publish(ctx context.Context, e Event) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return ErrClosed
+	}
+	if cap(b.recent) > 0 {
+		if len(b.recent) < cap(b.recent) {
+			b.recent = append(b.recent, e)
+		} else {
+			b.recent[b.next] = e
+			b.next = (b.next + 1) % cap(b.recent)
+		}
+	}
+	for s := range b.subs {
+		if s.filter != nil && !s.filter(e) {
+			continue
+		}
+		select {
+		case s.ch <- e:
+		default:
+			b.drop(s, ErrSlow)
+		}
+	}
+	return nil
+}
+
+// Subscribe starts a subscription. If lastID is set, the events after it
+// that match filter are delivered first; resumed is false if lastID is no
+// longer in the replay buffer, so events may have been missed.
+func (b *Broker) Subscribe(lastID string, filter Filter) (s *Subscription, resumed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var backlog []Event
+	resumed = lastID == ""
+	if !resumed {
+		ordered := append(slices.Clone(b.recent[b.next:]), b.recent[:b.next]...)
+		if i := slices.IndexFunc(ordered, func(e Event) bool { return e.ID == lastID }); i >= 0 {
+			resumed = true
+			for _, e := range ordered[i+1:] {
+				if filter == nil || filter(e) {
+					backlog = append(backlog, e)
+				}
+			}
+		}
+	}
+
+	ch := make(chan Event, len(backlog)+subscriberBuffer)
+	for _, e := range backlog {
+		ch <- e
+	}
+	s = &Subscription{C: ch, ch: ch, filter: filter, broker: b}
+	if b.closed {
+		close(ch)
+		s.err = ErrClosed
+		return s, resumed
+	}
+	b.subs[s] = struct{}{}
+	return s, resumed
+}
+
+// Reset forgets the replay buffer and ends every subscription. Call it when
+// events may have been missed, e.g. after the LISTEN connection was lost,
+// so clients reconnect and learn they have to reload.
+func (b *Broker) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	clear(b.recent)
+	b.recent = b.recent[:0]
+	b.next = 0
+	for s := range b.subs {
+		b.drop(s, ErrReset)
+	}
+}
+
+// Close ends every subscription; later ones end immediately and Publish
+// fails. Call it before shutting the server down, so open streams don't
+// hold up draining.
+func (b *Broker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for s := range b.subs {
+		b.drop(s, ErrClosed)
+	}
+}
+
+// Subscribers returns the number of open subscriptions.
+func (b *Broker) Subscribers() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.subs)
+}
+
+// drop ends s because of err; b.mu must be held.
+func (b *Broker) drop(s *Subscription, err error) {
+	if _, ok := b.subs[s]; ok {
+		delete(b.subs, s)
+		s.err = err
+		close(s.ch)
+	}
+}
@@ -0,0 +1,175 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func event(n int, author uuid.UUID) Event {
+	return Event{ID: fmt.Sprint(n), Type: ChirpCreated, UserID: author}
+}
+
+// received drains what's buffered on s without blocking.
+func received(s *Subscription) (ids []string, open bool) {
+	for {
+		select {
+		case e, ok := <-s.C:
+			if !ok {
+				return ids, false
+			}
+			ids = append(ids, e.ID)
+		default:
+			return ids, true
+		}
+	}
+}
+
+func TestPublishFiltersByAuthor(t *testing.T) {
+	b := NewBroker(10)
+	walt, jesse := uuid.New(), uuid.New()
+	all, _ := b.Subscribe("", nil)
+	onlyWalt, _ := b.Subscribe("", ByAuthors(walt))
+
+	b.Publish(context.Background(), event(1, walt))
+	b.Publish(context.Background(), event(2, jesse))
+
+	if ids, _ := received(all); fmt.Sprint(ids) != "[1 2]" {
+		t.Errorf("unfiltered subscriber got %v", ids)
+	}
+	if ids, _ := received(onlyWalt); fmt.Sprint(ids) != "[1]" {
+		t.Errorf("filtered subscriber got %v", ids)
+	}
+}
+
+func TestFilters(t *testing.T) {
+	walt, jesse := uuid.New(), uuid.New()
+	root := uuid.New()
+	chirp := Event{Type: ChirpCreated, UserID: walt, ThreadID: root}
+	notification := Event{Type: NotificationCreated, UserID: walt}
+	message := Event{Type: MessageCreated, UserID: walt}
+
+	tests := []struct {
+		name   string
+		filter Filter
+		e      Event
+		want   bool
+	}{
+		{"chirps", Chirps, chirp, true},
+		{"chirps skip notifications", Chirps, notification, false},
+		{"chirps skip messages", Chirps, message, false},
+		{"by author", ByAuthors(jesse, walt), chirp, true},
+		{"by another author", ByAuthors(jesse), chirp, false},
+		{"by author skips notifications", ByAuthors(walt), notification, false},
+		{"in thread", InThread(root), chirp, true},
+		{"in another thread", InThread(uuid.New()), chirp, false},
+		{"notifications", NotificationsFor(walt), notification, true},
+		{"someone else's notifications", NotificationsFor(jesse), notification, false},
+		{"notifications skip chirps", NotificationsFor(walt), chirp, false},
+		{"messages", MessagesFor(walt), message, true},
+		{"deleted messages", MessagesFor(walt), Event{Type: MessageDeleted, UserID: walt}, true},
+		{"someone else's messages", MessagesFor(jesse), message, false},
+		{"messages skip notifications", MessagesFor(walt), notification, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter(tt.e); got != tt.want {
+				t.Errorf("filter(%+v) = %v, want %v", tt.e, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSubscribeReplays(t *testing.T) {
+	b := NewBroker(3)
+	walt, jesse := uuid.New(), uuid.New()
+	for i := 1; i <= 5; i++ {
+		author := walt
+		if i == 4 {
+			author = jesse
+		}
+		b.Publish(context.Background(), event(i, author))
+	}
+
+	tests := []struct {
+		name        string
+		lastID      string
+		filter      Filter
+		want        string
+		wantResumed bool
+	}{
+		{"new subscriber", "", nil, "[]", true},
+		{"resume", "3", nil, "[4 5]", true},
+		{"resume filtered", "3", ByAuthors(walt), "[5]", true},
+		{"up to date", "5", nil, "[]", true},
+		{"evicted from the buffer", "2", nil, "[]", false},
+		{"unknown ID", "nope", nil, "[]", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, resumed := b.Subscribe(tt.lastID, tt.filter)
+			defer s.Close()
+			ids, _ := received(s)
+			if fmt.Sprint(ids) != tt.want {
+				t.Errorf("replayed %v, want %s", ids, tt.want)
+			}
+			if resumed != tt.wantResumed {
+				t.Errorf("resumed = %v, want %v", resumed, tt.wantResumed)
+			}
+		})
+	}
+}
+
+func TestSlowSubscriberIsDropped(t *testing.T) {
+	b := NewBroker(0)
+	s, _ := b.Subscribe("", nil)
+	for i := range subscriberBuffer + 1 {
+		if err := b.Publish(context.Background(), event(i, uuid.Nil)); err != nil {
+			t.Fatalf("Publish() failed: %v", err)
+		}
+	}
+	ids, open := received(s)
+	if open || len(ids) != subscriberBuffer {
+		t.Errorf("got %d events and open = %v, want %d and the subscription closed", len(ids), open, subscriberBuffer)
+	}
+	if n := b.Subscribers(); n != 0 {
+		t.Errorf("Subscribers() = %d after the drop", n)
+	}
+	if err := s.Err(); !errors.Is(err, ErrSlow) {
+		t.Errorf("Err() = %v, want ErrSlow", err)
+	}
+	s.Close() // after a drop, Close must not close C again
+}
+
+func TestReset(t *testing.T) {
+	b := NewBroker(10)
+	b.Publish(context.Background(), event(1, uuid.Nil))
+	s, _ := b.Subscribe("", nil)
+
+	b.Reset()
+	if _, open := received(s); open || !errors.Is(s.Err(), ErrReset) {
+		t.Errorf("Reset() should end subscriptions with ErrReset, got %v", s.Err())
+	}
+	if _, resumed := b.Subscribe("1", nil); resumed {
+		t.Error("Subscribe() resumed from an event from before Reset()")
+	}
+}
+
+func TestClose(t *testing.T) {
+	b := NewBroker(10)
+	before, _ := b.Subscribe("", nil)
+	b.Close()
+	after, _ := b.Subscribe("", nil)
+
+	for name, s := range map[string]*Subscription{"before": before, "after": after} {
+		if _, open := received(s); open || !errors.Is(s.Err(), ErrClosed) {
+			t.Errorf("subscription made %s Close() is still open or ended with %v", name, s.Err())
+		}
+	}
+	if err := b.Publish(context.Background(), event(1, uuid.Nil)); !errors.Is(err, ErrClosed) {
+		t.Errorf("Publish() after Close() error = %v, want ErrClosed", err)
+	}
+}
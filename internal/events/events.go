@@ -0,0 +1,234 @@
+// Package events carries changes to live subscribers. The service
+// publishes an Event when a chirp is created or deleted, a user gets a
+// notification or direct message, or a user is suspended; a Broker fans it out to the SSE streams and WebSockets on
+// this instance and keeps the most recent events so a reconnecting client
+// can resume where it left off.
+//
+// With one instance the Broker is the publisher. With Postgres, events are
+// published with NOTIFY and every instance feeds its Broker from LISTEN, so
+// all instances see the same events in the same order.
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/HemahWeb/chirpy/internal/database"
+)
+
+type Type string
+
+const (
+	ChirpCreated        Type = "chirp.created"
+	ChirpDeleted        Type = "chirp.deleted"
+	NotificationCreated Type = "notification.created"
+	MessageCreated      Type = "message.created"
+	MessageDeleted      Type = "message.deleted"
+	// UserSuspended closes the suspended user's WebSockets; it isn't sent
+	// to clients
+	UserSuspended Type = "user.suspended"
+)
+
+// Event is one change. ID is assigned where the event is published, so it
+// is the same on every instance and can be sent back as Last-Event-ID.
+type Event struct {
+	ID   string `json:"id"`
+	Type Type   `json:"type"`
+	// UserID is the chirp's author, the notification's or message's
+	// recipient or the suspended user, and
+	// ThreadID the first chirp of the chirp's thread, for filtering.
+	UserID   uuid.UUID       `json:"user_id"`
+	ThreadID uuid.UUID       `json:"thread_id,omitzero"`
+	Data     json.RawMessage `json:"data"`
+}
+
+// Publisher delivers events to every subscriber, on this instance or all of
+// them.
+type Publisher interface {
+	Publish(ctx context.Context, e Event) error
+}
+
+// chirp is the chirp as the API returns it, matching types.Chirp.
+type chirp struct {
+	ID        uuid.UUID  `json:"id"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	Body      string     `json:"body"`
+	UserID    uuid.UUID  `json:"user_id"`
+	ReplyToID *uuid.UUID `json:"reply_to_id"`
+	ThreadID  uuid.UUID  `json:"thread_id"`
+
+	Attachments []attachment `json:"attachments"`
+}
+
+// attachment is an image on a chirp, matching types.Attachment.
+type attachment struct {
+	ID          uuid.UUID `json:"id"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	Width       int       `json:"width"`
+	Height      int       `json:"height"`
+	URL         string    `json:"url"`
+	Thumbnail   thumbnail `json:"thumbnail"`
+}
+
+// thumbnail matches types.Thumbnail.
+type thumbnail struct {
+	ContentType string `json:"content_type"`
+	Width       int    `json:"width"`
+	Height      int    `json:"height"`
+	URL         string `json:"url"`
+}
+
+// AttachmentURL is where the API serves the image of the attachment with
+// the ID, and ThumbnailURL its thumbnail.
+func AttachmentURL(id uuid.UUID) string {
+	return "/api/attachments/" + id.String()
+}
+
+func ThumbnailURL(id uuid.UUID) string {
+	return AttachmentURL(id) + "/thumbnail"
+}
+
+// ThreadOf returns the first chirp of c's thread, which is c itself unless
+// it's a reply.
+func ThreadOf(c database.Chirp) uuid.UUID {
+	if c.ThreadID.Valid {
+		return c.ThreadID.UUID
+	}
+	return c.ID
+}
+
+// NewChirpEvent returns a t event carrying c with its attachments.
+func NewChirpEvent(t Type, c database.Chirp, attachments []database.Attachment) (Event, error) {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return Event{}, err
+	}
+	payload := chirp{
+		ID:        c.ID,
+		CreatedAt: c.CreatedAt,
+		UpdatedAt: c.UpdatedAt,
+		Body:      c.Body,
+		UserID:    c.UserID,
+		ThreadID:  ThreadOf(c),
+
+		Attachments: []attachment{},
+	}
+	if c.ReplyToID.Valid {
+		payload.ReplyToID = &c.ReplyToID.UUID
+	}
+	for _, a := range attachments {
+		payload.Attachments = append(payload.Attachments, attachment{
+			ID:          a.ID,
+			ContentType: a.ContentType,
+			Size:        a.SizeBytes,
+			Width:       int(a.Width),
+			Height:      int(a.Height),
+			URL:         AttachmentURL(a.ID),
+			Thumbnail: thumbnail{
+				ContentType: a.ThumbnailContentType,
+				Width:       int(a.ThumbnailWidth),
+				Height:      int(a.ThumbnailHeight),
+				URL:         ThumbnailURL(a.ID),
+			},
+		})
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return Event{}, err
+	}
+	return Event{ID: id.String(), Type: t, UserID: c.UserID, ThreadID: payload.ThreadID, Data: data}, nil
+}
+
+// Notification types, for Notification.Type.
+const (
+	NotifyFollow  = "follow"
+	NotifyLike    = "like"
+	NotifyMention = "mention"
+	NotifyReply   = "reply"
+)
+
+// NotificationTypes lists every notification type, in the order the API
+// shows them.
+var NotificationTypes = []string{NotifyFollow, NotifyLike, NotifyMention, NotifyReply}
+
+// Notification is the notification as the API returns it, matching
+// types.Notification: what happened to the recipient and who did it.
+type Notification struct {
+	ID        int64      `json:"id"`
+	CreatedAt time.Time  `json:"created_at"`
+	Type      string     `json:"type"`
+	ActorID   uuid.UUID  `json:"actor_id"`
+	ChirpID   *uuid.UUID `json:"chirp_id"`
+	ReadAt    *time.Time `json:"read_at"`
+}
+
+// NewNotificationEvent returns an event telling n's recipient about it.
+func NewNotificationEvent(n database.Notification) (Event, error) {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return Event{}, err
+	}
+	payload := Notification{
+		ID:        n.ID,
+		CreatedAt: n.CreatedAt,
+		Type:      n.Type,
+		ActorID:   n.ActorID,
+	}
+	if n.ChirpID.Valid {
+		payload.ChirpID = &n.ChirpID.UUID
+	}
+	if n.ReadAt.Valid {
+		payload.ReadAt = &n.ReadAt.Time
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return Event{}, err
+	}
+	return Event{ID: id.String(), Type: NotificationCreated, UserID: n.UserID, Data: data}, nil
+}
+
+// Message is a direct message as the API returns it, matching
+// types.Message.
+type Message struct {
+	ID             int64     `json:"id"`
+	ConversationID uuid.UUID `json:"conversation_id"`
+	SenderID       uuid.UUID `json:"sender_id"`
+	Body           string    `json:"body"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// NewMessageEvent returns a t event telling recipient about m. Each member
+// of a conversation gets their own, so that private messages only ever
+// match their recipients' filters.
+func NewMessageEvent(t Type, recipient uuid.UUID, m database.Message) (Event, error) {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return Event{}, err
+	}
+	data, err := json.Marshal(Message{
+		ID:             m.ID,
+		ConversationID: m.ConversationID,
+		SenderID:       m.SenderID,
+		Body:           m.Body,
+		CreatedAt:      m.CreatedAt,
+	})
+	if err != nil {
+		return Event{}, err
+	}
+	return Event{ID: id.String(), Type: t, UserID: recipient, Data: data}, nil
+}
+
+// NewUserSuspendedEvent returns an event telling userID's connections that
+// they were suspended.
+func NewUserSuspendedEvent(userID uuid.UUID) (Event, error) {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return Event{}, err
+	}
+	return Event{ID: id.String(), Type: UserSuspended, UserID: userID, Data: json.RawMessage("{}")}, nil
+}
@@ -0,0 +1,94 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/HemahWeb/chirpy/internal/database"
+)
+
+// Channel is the Postgres NOTIFY channel events are sent on.
+const Channel = "chirpy_events"
+
+// PostgresPublisher publishes events with NOTIFY, so they reach the Broker
+// of every instance running Listen, this one included. Postgres caps
+// payloads at 8000 bytes, well above a chirp event.
+type PostgresPublisher struct {
+	db database.DBTX
+}
+
+func NewPostgresPublisher(db database.DBTX) *PostgresPublisher {
+	return &PostgresPublisher{db: db}
+}
+
+func (p *PostgresPublisher) Publish(ctx context.Context, e Event) error {
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	_, err = p.db.ExecContext(ctx, "SELECT pg_notify($1, $2)", Channel, string(payload))
+	return err
+}
+
+// listenPingInterval is how often an idle LISTEN connection is checked, so
+// a dead one is noticed and replaced.
+const listenPingInterval = 90 * time.Second
+
+// Listen feeds b with the events published on Channel until ctx is done.
+// Notifications sent while the connection was down are lost, so b is reset
+// whenever it reconnects.
+func Listen(ctx context.Context, dsn string, b *Broker) error {
+	l := pq.NewListener(dsn, time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
+		switch ev {
+		case pq.ListenerEventDisconnected:
+			slog.WarnContext(ctx, "Lost the event listener connection", "error", err)
+		case pq.ListenerEventConnectionAttemptFailed:
+			slog.WarnContext(ctx, "Couldn't connect the event listener", "error", err)
+		case pq.ListenerEventReconnected:
+			slog.InfoContext(ctx, "Event listener reconnected; resetting live streams")
+		}
+	})
+	defer l.Close()
+
+	// Listen waits for the first connection, which Close ends early
+	go func() {
+		<-ctx.Done()
+		l.Close()
+	}()
+	if err := l.Listen(Channel); err != nil {
+		if ctx.Err() != nil {
+			return nil
+		}
+		return err
+	}
+
+	ping := time.NewTicker(listenPingInterval)
+	defer ping.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case n, ok := <-l.Notify:
+			if !ok {
+				return nil
+			}
+			// nil means the connection was re-established
+			if n == nil {
+				b.Reset()
+				continue
+			}
+			var e Event
+			if err := json.Unmarshal([]byte(n.Extra), &e); err != nil {
+				slog.WarnContext(ctx, "Ignoring malformed event notification", "error", err)
+				continue
+			}
+			b.Publish(ctx, e)
+		case <-ping.C:
+			go l.Ping()
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+
+	"github.com/HemahWeb/chirpy/internal/utils"
+)
+
+func (h *Handler) AttachmentsGet(w http.ResponseWriter, r *http.Request) {
+	h.serveAttachment(w, r, false)
+}
+
+func (h *Handler) AttachmentsGetThumbnail(w http.ResponseWriter, r *http.Request) {
+	h.serveAttachment(w, r, true)
+}
+
+// serveAttachment sends an attachment's image, or its thumbnail, to whoever
+// can see its chirp.
+func (h *Handler) serveAttachment(w http.ResponseWriter, r *http.Request, thumbnail bool) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		utils.RespondWithError(w, r, invalidUUID("id", err))
+		return
+	}
+
+	// signing in is optional; it shows a limited user the images on their
+	// own chirps
+	var viewerID uuid.UUID
+	if r.Header.Get("Authorization") != "" {
+		viewerID, err = h.authenticate(r)
+		if err != nil {
+			utils.RespondWithError(w, r, err)
+			return
+		}
+	}
+
+	a, blob, err := h.config.Service.GetAttachment(r.Context(), viewerID, id, thumbnail)
+	if err != nil {
+		utils.RespondWithError(w, r, err)
+		return
+	}
+	defer blob.Close()
+
+	contentType, size := a.ContentType, a.SizeBytes
+	if thumbnail {
+		contentType, size = a.ThumbnailContentType, a.ThumbnailSizeBytes
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(size, 10))
+	// the bytes under an ID never change, but the chirp can be deleted or
+	// hidden, so shared caches mustn't keep them
+	w.Header().Set("Cache-Control", "private, max-age=86400")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+	if _, err := io.Copy(w, blob); err != nil {
+		slog.WarnContext(r.Context(), "Couldn't send attachment", "attachment_id", id, "error", err)
+	}
+}
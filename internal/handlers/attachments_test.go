@@ -0,0 +1,222 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/HemahWeb/chirpy/internal/media"
+	"github.com/HemahWeb/chirpy/internal/problem"
+	"github.com/HemahWeb/chirpy/internal/types"
+)
+
+// postForm sends a multipart/form-data POST with fields and an images file
+// for each of images.
+func (s *testServer) postForm(path string, fields map[string]string, images [][]byte, auth string) *httptest.ResponseRecorder {
+	s.t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for name, value := range fields {
+		mw.WriteField(name, value)
+	}
+	for i, data := range images {
+		fw, err := mw.CreateFormFile("images", "image"+string(rune('a'+i)))
+		if err != nil {
+			s.t.Fatalf("couldn't build the form: %v", err)
+		}
+		fw.Write(data)
+	}
+	mw.Close()
+
+	req := httptest.NewRequest(http.MethodPost, path, &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	if auth != "" {
+		req.Header.Set("Authorization", auth)
+	}
+	s.cover(req)
+
+	rec := httptest.NewRecorder()
+	s.mux.ServeHTTP(rec, req)
+	return rec
+}
+
+func testPNG(t *testing.T, w, h int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for x := range w {
+		img.Set(x, 0, color.RGBA{R: 255, A: 255})
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("png.Encode() failed: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// testJPEG is a photo as a phone would upload it, with an EXIF block
+// giving away where it was taken.
+func testJPEG(t *testing.T, w, h int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, image.NewGray(image.Rect(0, 0, w, h)), nil); err != nil {
+		t.Fatalf("jpeg.Encode() failed: %v", err)
+	}
+	payload := []byte("Exif\x00\x00MM\x00\x2a\x00\x00\x00\x08\x00\x00GPS 35.0844N 106.6504W")
+	exif := binary.BigEndian.AppendUint16([]byte{0xFF, 0xE1}, uint16(len(payload)+2))
+	exif = append(exif, payload...)
+	data := buf.Bytes()
+	return append(append(append([]byte{}, data[:2]...), exif...), data[2:]...)
+}
+
+func TestPostChirpsWithImages(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, s *testServer) {
+		walt := s.signUp("walt@breakingbad.com")
+
+		rec := s.postForm("/api/chirps", map[string]string{"body": "Blue sky"}, [][]byte{testJPEG(t, 640, 480), testPNG(t, 10, 20)}, walt.bearer())
+		expectStatus(t, rec, http.StatusCreated)
+		chirp := decode[types.Chirp](t, rec)
+		if chirp.Body != "Blue sky" || len(chirp.Attachments) != 2 {
+			t.Fatalf("chirp = %+v, want two attachments", chirp)
+		}
+		photo, icon := chirp.Attachments[0], chirp.Attachments[1]
+		if photo.ContentType != "image/jpeg" || photo.Width != 640 || photo.Height != 480 || photo.URL != "/api/attachments/"+photo.ID.String() {
+			t.Errorf("first attachment = %+v", photo)
+		}
+		if photo.Thumbnail.Width != media.ThumbnailSize || photo.Thumbnail.Height != 240 || photo.Thumbnail.URL != photo.URL+"/thumbnail" {
+			t.Errorf("first thumbnail = %+v", photo.Thumbnail)
+		}
+		if icon.ContentType != "image/png" || icon.Width != 10 || icon.Height != 20 || icon.Thumbnail.Width != 10 {
+			t.Errorf("second attachment = %+v", icon)
+		}
+
+		// the chirp comes back the same however it's fetched
+		got := decode[types.Chirp](t, s.do(http.MethodGet, "/api/chirps/"+chirp.ID.String(), nil, ""))
+		if len(got.Attachments) != 2 || got.Attachments[0] != photo || got.Attachments[1] != icon {
+			t.Errorf("fetched attachments = %+v", got.Attachments)
+		}
+		list := decode[[]types.Chirp](t, s.do(http.MethodGet, "/api/chirps", nil, ""))
+		if len(list) != 1 || len(list[0].Attachments) != 2 {
+			t.Errorf("listed chirps = %+v", list)
+		}
+
+		// a chirp without images has an empty list
+		if plain := s.postChirp(walt, "Say my name"); plain.Attachments == nil || len(plain.Attachments) != 0 {
+			t.Errorf("attachments = %#v, want empty", plain.Attachments)
+		}
+
+		// replies can carry images too
+		rec = s.postForm("/api/chirps", map[string]string{"body": "Nice", "reply_to_id": chirp.ID.String()}, [][]byte{testPNG(t, 5, 5)}, walt.bearer())
+		expectStatus(t, rec, http.StatusCreated)
+		if reply := decode[types.Chirp](t, rec); reply.ReplyToID == nil || *reply.ReplyToID != chirp.ID || len(reply.Attachments) != 1 {
+			t.Errorf("reply = %+v", reply)
+		}
+
+		// a form without images is an ordinary chirp
+		rec = s.postForm("/api/chirps", map[string]string{"body": "Just text"}, nil, walt.bearer())
+		expectStatus(t, rec, http.StatusCreated)
+	})
+}
+
+func TestPostChirpsRejectsImages(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, s *testServer) {
+		walt := s.signUp("walt@breakingbad.com")
+		body := map[string]string{"body": "Look"}
+
+		expectProblem(t, s.postForm("/api/chirps", body, [][]byte{testPNG(t, 1, 1)}, ""), http.StatusUnauthorized, problem.CodeUnauthenticated)
+
+		p := expectProblem(t, s.postForm("/api/chirps", body, [][]byte{testPNG(t, 1, 1), []byte("#!/bin/sh\nrm -rf /\n")}, walt.bearer()), http.StatusBadRequest, problem.CodeValidationFailed)
+		if len(p.Errors) != 1 || p.Errors[0].Field != "images[1]" || p.Errors[0].Code != "unsupported_type" {
+			t.Errorf("field errors = %+v", p.Errors)
+		}
+		p = expectProblem(t, s.postForm("/api/chirps", body, [][]byte{[]byte("\x89PNG\r\n\x1a\n broken")}, walt.bearer()), http.StatusBadRequest, problem.CodeValidationFailed)
+		if len(p.Errors) != 1 || p.Errors[0].Code != "invalid_image" {
+			t.Errorf("field errors = %+v", p.Errors)
+		}
+		images := make([][]byte, 5)
+		for i := range images {
+			images[i] = testPNG(t, 1, 1)
+		}
+		p = expectProblem(t, s.postForm("/api/chirps", body, images, walt.bearer()), http.StatusBadRequest, problem.CodeValidationFailed)
+		if len(p.Errors) != 1 || p.Errors[0].Field != "images" || p.Errors[0].Code != "too_many" {
+			t.Errorf("field errors = %+v", p.Errors)
+		}
+		p = expectProblem(t, s.postForm("/api/chirps", map[string]string{"body": ""}, [][]byte{testPNG(t, 1, 1)}, walt.bearer()), http.StatusBadRequest, problem.CodeValidationFailed)
+		if len(p.Errors) != 1 || p.Errors[0].Field != "body" {
+			t.Errorf("field errors = %+v", p.Errors)
+		}
+		p = expectProblem(t, s.postForm("/api/chirps", map[string]string{"body": "Look", "reply_to_id": "nope"}, nil, walt.bearer()), http.StatusBadRequest, problem.CodeValidationFailed)
+		if len(p.Errors) != 1 || p.Errors[0].Field != "reply_to_id" {
+			t.Errorf("field errors = %+v", p.Errors)
+		}
+
+		req := httptest.NewRequest(http.MethodPost, "/api/chirps", bytes.NewReader([]byte("--x\r\nnot a part")))
+		req.Header.Set("Content-Type", "multipart/form-data; boundary=x")
+		req.Header.Set("Authorization", walt.bearer())
+		rec := httptest.NewRecorder()
+		s.mux.ServeHTTP(rec, req)
+		expectProblem(t, rec, http.StatusBadRequest, problem.CodeInvalidForm)
+
+		if chirps := decode[[]types.Chirp](t, s.do(http.MethodGet, "/api/chirps", nil, "")); len(chirps) != 0 {
+			t.Errorf("chirps = %+v, want none posted", chirps)
+		}
+	})
+}
+
+func TestAttachmentsGet(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, s *testServer) {
+		walt := s.signUp("walt@breakingbad.com")
+		rec := s.postForm("/api/chirps", map[string]string{"body": "Blue sky"}, [][]byte{testJPEG(t, 640, 480)}, walt.bearer())
+		expectStatus(t, rec, http.StatusCreated)
+		chirp := decode[types.Chirp](t, rec)
+		photo := chirp.Attachments[0]
+
+		rec = s.do(http.MethodGet, photo.URL, nil, "")
+		expectStatus(t, rec, http.StatusOK)
+		if ct := rec.Header().Get("Content-Type"); ct != "image/jpeg" {
+			t.Errorf("Content-Type = %q, want image/jpeg", ct)
+		}
+		if rec.Header().Get("X-Content-Type-Options") != "nosniff" {
+			t.Error("X-Content-Type-Options isn't nosniff")
+		}
+		if int64(rec.Body.Len()) != photo.Size {
+			t.Errorf("got %d bytes, want %d", rec.Body.Len(), photo.Size)
+		}
+		if bytes.Contains(rec.Body.Bytes(), []byte("GPS")) {
+			t.Error("served image still has its EXIF")
+		}
+		if cfg, err := jpeg.DecodeConfig(rec.Body); err != nil || cfg.Width != 640 {
+			t.Errorf("served image = %+v, %v", cfg, err)
+		}
+
+		rec = s.do(http.MethodGet, photo.Thumbnail.URL, nil, walt.bearer())
+		expectStatus(t, rec, http.StatusOK)
+		if cfg, err := jpeg.DecodeConfig(rec.Body); err != nil || cfg.Width != photo.Thumbnail.Width || cfg.Height != photo.Thumbnail.Height {
+			t.Errorf("served thumbnail = %+v, %v; want %dx%d", cfg, err, photo.Thumbnail.Width, photo.Thumbnail.Height)
+		}
+
+		expectProblem(t, s.do(http.MethodGet, "/api/attachments/nope", nil, ""), http.StatusBadRequest, problem.CodeValidationFailed)
+		expectProblem(t, s.do(http.MethodGet, "/api/attachments/"+uuid.NewString(), nil, ""), http.StatusNotFound, problem.CodeNotFound)
+		expectProblem(t, s.do(http.MethodGet, photo.URL, nil, "Bearer nope"), http.StatusUnauthorized, problem.CodeInvalidToken)
+
+		// images go with their chirp to the trash, and come back with it
+		chirpPath := "/api/chirps/" + chirp.ID.String()
+		expectStatus(t, s.do(http.MethodDelete, chirpPath, nil, walt.bearer()), http.StatusNoContent)
+		expectProblem(t, s.do(http.MethodGet, photo.URL, nil, walt.bearer()), http.StatusNotFound, problem.CodeNotFound)
+		expectProblem(t, s.do(http.MethodGet, photo.Thumbnail.URL, nil, ""), http.StatusNotFound, problem.CodeNotFound)
+		trash := decode[[]types.TrashedChirp](t, s.do(http.MethodGet, "/api/chirps/trash", nil, walt.bearer()))
+		if len(trash) != 1 || len(trash[0].Attachments) != 1 {
+			t.Errorf("trash = %+v, want the chirp with its image", trash)
+		}
+		expectStatus(t, s.do(http.MethodPost, chirpPath+"/restore", nil, walt.bearer()), http.StatusOK)
+		expectStatus(t, s.do(http.MethodGet, photo.URL, nil, ""), http.StatusOK)
+	})
+}
@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/HemahWeb/chirpy/internal/types"
+	"github.com/HemahWeb/chirpy/internal/utils"
+)
+
+func (h *Handler) UsersBlock(w http.ResponseWriter, r *http.Request) {
+	h.applyToPathID(w, r, h.config.Service.BlockUser)
+}
+
+func (h *Handler) UsersUnblock(w http.ResponseWriter, r *http.Request) {
+	h.applyToPathID(w, r, h.config.Service.UnblockUser)
+}
+
+func (h *Handler) UsersMute(w http.ResponseWriter, r *http.Request) {
+	h.applyToPathID(w, r, h.config.Service.MuteUser)
+}
+
+func (h *Handler) UsersUnmute(w http.ResponseWriter, r *http.Request) {
+	h.applyToPathID(w, r, h.config.Service.UnmuteUser)
+}
+
+func (h *Handler) UsersBlocksList(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.authenticate(r)
+	if err != nil {
+		utils.RespondWithError(w, r, err)
+		return
+	}
+
+	blocks, err := h.config.Service.ListBlocks(r.Context(), userID)
+	if err != nil {
+		utils.RespondWithError(w, r, err)
+		return
+	}
+
+	resp := []types.BlockedUser{}
+	for _, b := range blocks {
+		resp = append(resp, types.BlockedUser{UserID: b.BlockedID, CreatedAt: b.CreatedAt})
+	}
+	utils.RespondWithJSON(w, http.StatusOK, resp)
+}
+
+func (h *Handler) UsersMutesList(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.authenticate(r)
+	if err != nil {
+		utils.RespondWithError(w, r, err)
+		return
+	}
+
+	mutes, err := h.config.Service.ListMutes(r.Context(), userID)
+	if err != nil {
+		utils.RespondWithError(w, r, err)
+		return
+	}
+
+	resp := []types.BlockedUser{}
+	for _, m := range mutes {
+		resp = append(resp, types.BlockedUser{UserID: m.MutedID, CreatedAt: m.CreatedAt})
+	}
+	utils.RespondWithJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/HemahWeb/chirpy/internal/problem"
+	"github.com/HemahWeb/chirpy/internal/types"
+)
+
+// chirpAuthors lists the authors of the chirps user sees, oldest first.
+func (s *testServer) chirpAuthors(user session) []uuid.UUID {
+	s.t.Helper()
+	auth := ""
+	if user.Token != "" {
+		auth = user.bearer()
+	}
+	rec := s.do(http.MethodGet, "/api/chirps", nil, auth)
+	expectStatus(s.t, rec, http.StatusOK)
+	var authors []uuid.UUID
+	for _, c := range decode[[]types.Chirp](s.t, rec) {
+		authors = append(authors, c.UserID)
+	}
+	return authors
+}
+
+// relations fetches user's blocks or mutes.
+func (s *testServer) relations(user session, kind string) []types.BlockedUser {
+	s.t.Helper()
+	rec := s.do(http.MethodGet, "/api/users/"+kind, nil, user.bearer())
+	expectStatus(s.t, rec, http.StatusOK)
+	return decode[[]types.BlockedUser](s.t, rec)
+}
+
+func TestUsersBlock(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, s *testServer) {
+		walt := s.signUp("walt@breakingbad.com")
+		jesse := s.signUp("jesse@breakingbad.com")
+		skyler := s.signUp("skyler@breakingbad.com")
+		cook := s.postChirp(walt, "Say my name")
+		s.postChirp(jesse, "Yeah, science!")
+		s.postChirp(skyler, "We need to talk")
+		expectStatus(t, s.do(http.MethodPost, "/api/users/"+walt.ID.String()+"/follow", nil, jesse.bearer()), http.StatusNoContent)
+		blockPath := "/api/users/" + walt.ID.String() + "/block"
+
+		expectStatus(t, s.do(http.MethodPost, blockPath, nil, jesse.bearer()), http.StatusNoContent)
+		expectStatus(t, s.do(http.MethodPost, blockPath, nil, jesse.bearer()), http.StatusNoContent)
+		if blocks := s.relations(jesse, "blocks"); len(blocks) != 1 || blocks[0].UserID != walt.ID {
+			t.Errorf("jesse's blocks = %+v, want walt", blocks)
+		}
+		if ids, _ := s.cfg.Service.Followees(context.Background(), jesse.ID); len(ids) != 0 {
+			t.Errorf("jesse's followees after blocking = %v, want none", ids)
+		}
+
+		// each side's chirps are hidden from the other
+		if got := s.chirpAuthors(jesse); len(got) != 2 || got[0] != jesse.ID || got[1] != skyler.ID {
+			t.Errorf("jesse sees chirps by %v, want jesse and skyler", got)
+		}
+		if got := s.chirpAuthors(walt); len(got) != 2 || got[0] != walt.ID || got[1] != skyler.ID {
+			t.Errorf("walt sees chirps by %v, want walt and skyler", got)
+		}
+		if got := s.chirpAuthors(session{}); len(got) != 3 {
+			t.Errorf("anonymous sees chirps by %v, want all 3", got)
+		}
+		expectProblem(t, s.do(http.MethodGet, "/api/chirps", nil, "Bearer forged"), http.StatusUnauthorized, problem.CodeInvalidToken)
+
+		// and neither can reach the other
+		expectProblem(t, s.do(http.MethodPost, "/api/users/"+jesse.ID.String()+"/follow", nil, walt.bearer()), http.StatusForbidden, problem.CodeForbidden)
+		expectProblem(t, s.do(http.MethodPost, "/api/users/"+walt.ID.String()+"/follow", nil, jesse.bearer()), http.StatusForbidden, problem.CodeForbidden)
+		expectProblem(t, s.do(http.MethodPost, "/api/chirps", map[string]any{"body": "Jesse?", "reply_to_id": cook.ID}, jesse.bearer()), http.StatusForbidden, problem.CodeForbidden)
+		// a mention goes through, so it doesn't say who blocked whom, but
+		// no one hears about it
+		expectStatus(t, s.do(http.MethodPost, "/api/chirps", map[string]any{"body": "Where are you, @jesse@breakingbad.com?"}, walt.bearer()), http.StatusCreated)
+		expectStatus(t, s.do(http.MethodPost, "/api/chirps", map[string]any{"body": "Bye @walt@breakingbad.com"}, jesse.bearer()), http.StatusCreated)
+		for _, u := range []session{walt, jesse} {
+			for _, n := range s.notifications(u, "").Notifications {
+				if n.Type == "mention" {
+					t.Errorf("%s was notified of a mention across a block: %+v", u.Email, n)
+				}
+			}
+		}
+		expectProblem(t, s.do(http.MethodPost, "/api/chirps/"+cook.ID.String()+"/like", nil, jesse.bearer()), http.StatusForbidden, problem.CodeForbidden)
+
+		expectProblem(t, s.do(http.MethodPost, "/api/users/"+jesse.ID.String()+"/block", nil, jesse.bearer()), http.StatusBadRequest, problem.CodeValidationFailed)
+		expectProblem(t, s.do(http.MethodPost, "/api/users/"+uuid.NewString()+"/block", nil, jesse.bearer()), http.StatusNotFound, problem.CodeNotFound)
+		expectProblem(t, s.do(http.MethodPost, blockPath, nil, ""), http.StatusUnauthorized, problem.CodeUnauthenticated)
+		expectProblem(t, s.do(http.MethodGet, "/api/users/blocks", nil, ""), http.StatusUnauthorized, problem.CodeUnauthenticated)
+
+		expectStatus(t, s.do(http.MethodDelete, blockPath, nil, jesse.bearer()), http.StatusNoContent)
+		expectStatus(t, s.do(http.MethodDelete, blockPath, nil, jesse.bearer()), http.StatusNoContent)
+		if blocks := s.relations(jesse, "blocks"); len(blocks) != 0 {
+			t.Errorf("jesse's blocks after unblocking = %+v", blocks)
+		}
+		expectStatus(t, s.do(http.MethodPost, "/api/users/"+walt.ID.String()+"/follow", nil, jesse.bearer()), http.StatusNoContent)
+		expectStatus(t, s.do(http.MethodPost, "/api/chirps", map[string]any{"body": "Back, @walt@breakingbad.com"}, jesse.bearer()), http.StatusCreated)
+	})
+}
+
+func TestUsersMute(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, s *testServer) {
+		walt := s.signUp("walt@breakingbad.com")
+		jesse := s.signUp("jesse@breakingbad.com")
+		cook := s.postChirp(walt, "Say my name")
+		mutePath := "/api/users/" + jesse.ID.String() + "/mute"
+
+		expectStatus(t, s.do(http.MethodPost, mutePath, nil, walt.bearer()), http.StatusNoContent)
+		expectStatus(t, s.do(http.MethodPost, mutePath, nil, walt.bearer()), http.StatusNoContent)
+		if mutes := s.relations(walt, "mutes"); len(mutes) != 1 || mutes[0].UserID != jesse.ID {
+			t.Errorf("walt's mutes = %+v, want jesse", mutes)
+		}
+
+		// jesse can still interact, but walt doesn't hear about it
+		s.postChirp(jesse, "Yeah, science!")
+		expectStatus(t, s.do(http.MethodPost, "/api/chirps/"+cook.ID.String()+"/like", nil, jesse.bearer()), http.StatusNoContent)
+		expectStatus(t, s.do(http.MethodPost, "/api/users/"+walt.ID.String()+"/follow", nil, jesse.bearer()), http.StatusNoContent)
+		if page := s.notifications(walt, ""); len(page.Notifications) != 0 {
+			t.Errorf("walt's notifications = %+v, want none from jesse", page.Notifications)
+		}
+		if got := s.chirpAuthors(walt); len(got) != 1 || got[0] != walt.ID {
+			t.Errorf("walt sees chirps by %v, want only his own", got)
+		}
+		// muting is one-way
+		if got := s.chirpAuthors(jesse); len(got) != 2 {
+			t.Errorf("jesse sees chirps by %v, want both", got)
+		}
+
+		expectProblem(t, s.do(http.MethodPost, "/api/users/"+walt.ID.String()+"/mute", nil, walt.bearer()), http.StatusBadRequest, problem.CodeValidationFailed)
+		expectProblem(t, s.do(http.MethodPost, "/api/users/"+uuid.NewString()+"/mute", nil, walt.bearer()), http.StatusNotFound, problem.CodeNotFound)
+		expectProblem(t, s.do(http.MethodGet, "/api/users/mutes", nil, "Bearer forged"), http.StatusUnauthorized, problem.CodeInvalidToken)
+
+		expectStatus(t, s.do(http.MethodDelete, mutePath, nil, walt.bearer()), http.StatusNoContent)
+		if got := s.chirpAuthors(walt); len(got) != 2 {
+			t.Errorf("walt sees chirps by %v after unmuting, want both", got)
+		}
+		if mutes := s.relations(walt, "mutes"); len(mutes) != 0 {
+			t.Errorf("walt's mutes after unmuting = %+v", mutes)
+		}
+	})
+}
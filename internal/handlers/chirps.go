@@ -1,171 +1,292 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
-	"errors"
+	"io"
+	"mime"
 	"net/http"
-	"sort"
 
 	"github.com/google/uuid"
 
-	"github.com/HemahWeb/chirpy/internal/auth"
 	"github.com/HemahWeb/chirpy/internal/database"
+	"github.com/HemahWeb/chirpy/internal/events"
+	"github.com/HemahWeb/chirpy/internal/problem"
+	"github.com/HemahWeb/chirpy/internal/service"
 	"github.com/HemahWeb/chirpy/internal/types"
 	"github.com/HemahWeb/chirpy/internal/utils"
 )
 
-func (h *Handler) PostChirps(w http.ResponseWriter, r *http.Request) {
-	type parameters struct {
-		Body string `json:"body"`
+// map DB -> API (stable keys, decoupled from schema)
+func chirpResponse(chirp database.Chirp, attachments []database.Attachment) types.Chirp {
+	resp := types.Chirp{
+		ID:          chirp.ID,
+		CreatedAt:   chirp.CreatedAt,
+		UpdatedAt:   chirp.UpdatedAt,
+		Body:        chirp.Body,
+		UserID:      chirp.UserID,
+		ThreadID:    events.ThreadOf(chirp),
+		Attachments: []types.Attachment{},
+	}
+	if chirp.ReplyToID.Valid {
+		resp.ReplyToID = &chirp.ReplyToID.UUID
 	}
+	for _, a := range attachments {
+		resp.Attachments = append(resp.Attachments, types.Attachment{
+			ID:          a.ID,
+			ContentType: a.ContentType,
+			Size:        a.SizeBytes,
+			Width:       int(a.Width),
+			Height:      int(a.Height),
+			URL:         events.AttachmentURL(a.ID),
+			Thumbnail: types.Thumbnail{
+				ContentType: a.ThumbnailContentType,
+				Width:       int(a.ThumbnailWidth),
+				Height:      int(a.ThumbnailHeight),
+				URL:         events.ThumbnailURL(a.ID),
+			},
+		})
+	}
+	return resp
+}
 
-	token, err := auth.GetBearerToken(r.Header)
+// chirpResponses maps chirps to the API with their attachments, looked up
+// in one go.
+func (h *Handler) chirpResponses(ctx context.Context, chirps ...database.Chirp) ([]types.Chirp, error) {
+	attachments, err := h.config.Service.ChirpAttachments(ctx, chirps...)
 	if err != nil {
-		utils.RespondWithError(w, http.StatusUnauthorized, "Couldn't get token", err)
-		return
+		return nil, err
+	}
+	resp := make([]types.Chirp, 0, len(chirps))
+	for _, chirp := range chirps {
+		resp = append(resp, chirpResponse(chirp, attachments[chirp.ID]))
 	}
+	return resp, nil
+}
 
-	userID, err := auth.ValidateJWT(token, h.config.JWTSecret)
+// respondWithChirp sends chirp with its attachments.
+func (h *Handler) respondWithChirp(w http.ResponseWriter, r *http.Request, status int, chirp database.Chirp) {
+	resp, err := h.chirpResponses(r.Context(), chirp)
 	if err != nil {
-		utils.RespondWithError(w, http.StatusUnauthorized, "Couldn't validate token", err)
+		utils.RespondWithError(w, r, err)
 		return
 	}
+	utils.RespondWithJSON(w, status, resp[0])
+}
+
+type chirpParams struct {
+	Body      string     `json:"body"`
+	ReplyToID *uuid.UUID `json:"reply_to_id"`
+	// Images only come in a multipart/form-data request.
+	Images [][]byte `json:"-"`
+}
 
-	decoder := json.NewDecoder(r.Body)
-	params := parameters{}
-	err = decoder.Decode(&params)
+// decodeChirpParams reads a JSON body or, to post images, a
+// multipart/form-data one with body and reply_to_id fields and an images
+// file for each image. The body size limit bounds what's read.
+func decodeChirpParams(r *http.Request) (chirpParams, error) {
+	var params chirpParams
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "multipart/form-data" {
+		if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+			return params, problem.InvalidJSON(err)
+		}
+		return params, nil
+	}
+
+	mr, err := r.MultipartReader()
 	if err != nil {
-		utils.RespondWithError(w, http.StatusInternalServerError, "Couldn't decode parameters", err)
+		return params, problem.InvalidForm(err)
+	}
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return params, problem.InvalidForm(err)
+		}
+		data, err := io.ReadAll(part)
+		if err != nil {
+			return params, problem.InvalidForm(err)
+		}
+		switch part.FormName() {
+		case "body":
+			params.Body = string(data)
+		case "reply_to_id":
+			id, err := uuid.Parse(string(data))
+			if err != nil {
+				return params, invalidUUID("reply_to_id", err)
+			}
+			params.ReplyToID = &id
+		case "images":
+			params.Images = append(params.Images, data)
+		}
+	}
+	return params, nil
+}
+
+func (h *Handler) PostChirps(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.authenticate(r)
+	if err != nil {
+		utils.RespondWithError(w, r, err)
 		return
 	}
 
-	// validate chirp for length and bad words
-	cleaned, err := utils.ValidateChirp(params.Body)
+	params, err := decodeChirpParams(r)
 	if err != nil {
-		utils.RespondWithError(w, http.StatusBadRequest, "Couldn't validate chirp: "+err.Error(), nil)
+		utils.RespondWithError(w, r, err)
 		return
 	}
 
-	chirp, err := h.config.DB.CreateChirp(r.Context(), database.CreateChirpParams{
-		Body:   cleaned,
-		UserID: userID,
-	})
+	var chirp database.Chirp
+	if params.ReplyToID != nil {
+		chirp, err = h.config.Service.ReplyToChirp(r.Context(), userID, *params.ReplyToID, params.Body, params.Images...)
+	} else {
+		chirp, err = h.config.Service.CreateChirp(r.Context(), userID, params.Body, params.Images...)
+	}
 	if err != nil {
-		utils.RespondWithError(w, http.StatusInternalServerError, "Couldn't create chirp: "+err.Error(), err)
+		utils.RespondWithError(w, r, err)
 		return
 	}
+	h.config.Metrics.ChirpsCreated.Inc()
 
-	// map DB -> API (stable keys, decoupled from schema)
-	utils.RespondWithJSON(w, http.StatusCreated, types.Chirp{
-		ID:        chirp.ID,
-		CreatedAt: chirp.CreatedAt,
-		UpdatedAt: chirp.UpdatedAt,
-		Body:      chirp.Body,
-		UserID:    chirp.UserID,
-	})
+	h.respondWithChirp(w, r, http.StatusCreated, chirp)
 }
 
 func (h *Handler) GetChirps(w http.ResponseWriter, r *http.Request) {
-	author := r.URL.Query().Get("author_id")
-	sortOrder := r.URL.Query().Get("sort")
-
-	var chirps []database.Chirp
-	var err error
-	if author != "" {
-		authorID, parseErr := uuid.Parse(author)
-		if parseErr != nil {
-			utils.RespondWithError(w, http.StatusBadRequest, "Invalid author ID", parseErr)
+	params := service.ListChirpsParams{
+		Descending: r.URL.Query().Get("sort") == "desc",
+	}
+	if author := r.URL.Query().Get("author_id"); author != "" {
+		authorID, err := uuid.Parse(author)
+		if err != nil {
+			utils.RespondWithError(w, r, invalidUUID("author_id", err))
 			return
 		}
-		chirps, err = h.config.DB.GetChirpsByUserID(r.Context(), authorID)
-	} else {
-		chirps, err = h.config.DB.GetChirps(r.Context())
+		params.AuthorID = authorID
 	}
-	if err != nil {
-		utils.RespondWithError(w, http.StatusInternalServerError, "Error getting chirps", err)
-		return
+	// signing in is optional; it hides the authors you blocked or muted,
+	// and shows a limited user their own chirps
+	if r.Header.Get("Authorization") != "" {
+		viewerID, err := h.authenticate(r)
+		if err != nil {
+			utils.RespondWithError(w, r, err)
+			return
+		}
+		params.ViewerID = viewerID
 	}
 
-	if sortOrder == "desc" {
-		sort.Slice(chirps, func(i, j int) bool {
-			return chirps[i].CreatedAt.After(chirps[j].CreatedAt)
-		})
+	chirps, err := h.config.Service.ListChirps(r.Context(), params)
+	if err != nil {
+		utils.RespondWithError(w, r, err)
+		return
 	}
 
-	// map DB -> API (stable keys, decoupled from schema)
-	chirpsAPI := []types.Chirp{}
-	for _, chirp := range chirps {
-		chirpsAPI = append(chirpsAPI, types.Chirp{
-			ID:        chirp.ID,
-			CreatedAt: chirp.CreatedAt,
-			UpdatedAt: chirp.UpdatedAt,
-			Body:      chirp.Body,
-			UserID:    chirp.UserID,
-		})
+	chirpsAPI, err := h.chirpResponses(r.Context(), chirps...)
+	if err != nil {
+		utils.RespondWithError(w, r, err)
+		return
 	}
 
 	utils.RespondWithJSON(w, http.StatusOK, chirpsAPI)
 }
 
 func (h *Handler) GetChirpsByID(w http.ResponseWriter, r *http.Request) {
-
 	id, err := uuid.Parse(r.PathValue("id"))
 	if err != nil {
-		utils.RespondWithError(w, http.StatusBadRequest, "Invalid chirp ID: "+err.Error(), err)
+		utils.RespondWithError(w, r, invalidUUID("id", err))
 		return
 	}
 
-	chirp, err := h.config.DB.GetChirpByID(r.Context(), id)
+	// signing in is optional; it shows a limited user their own chirps
+	var viewerID uuid.UUID
+	if r.Header.Get("Authorization") != "" {
+		viewerID, err = h.authenticate(r)
+		if err != nil {
+			utils.RespondWithError(w, r, err)
+			return
+		}
+	}
+
+	chirp, err := h.config.Service.GetChirp(r.Context(), viewerID, id)
 	if err != nil {
-		utils.RespondWithError(w, http.StatusNotFound, "Chirp with ID "+id.String()+" does not exist", err)
+		utils.RespondWithError(w, r, err)
 		return
 	}
 
-	// map DB -> API (stable keys, decoupled from schema)
-	utils.RespondWithJSON(w, http.StatusOK, types.Chirp{
-		ID:        chirp.ID,
-		CreatedAt: chirp.CreatedAt,
-		UpdatedAt: chirp.UpdatedAt,
-		Body:      chirp.Body,
-		UserID:    chirp.UserID,
-	})
+	h.respondWithChirp(w, r, http.StatusOK, chirp)
 }
 
 func (h *Handler) ChirpsDeleteByID(w http.ResponseWriter, r *http.Request) {
-	token, err := auth.GetBearerToken(r.Header)
+	userID, err := h.authenticate(r)
 	if err != nil {
-		utils.RespondWithError(w, http.StatusUnauthorized, "Couldn't get token: "+err.Error(), err)
+		utils.RespondWithError(w, r, err)
 		return
 	}
 
-	userID, err := auth.ValidateJWT(token, h.config.JWTSecret)
+	id, err := uuid.Parse(r.PathValue("id"))
 	if err != nil {
-		utils.RespondWithError(w, http.StatusUnauthorized, "Couldn't validate token", err)
+		utils.RespondWithError(w, r, invalidUUID("id", err))
 		return
 	}
 
-	id, err := uuid.Parse(r.PathValue("id"))
+	err = h.config.Service.DeleteChirp(r.Context(), userID, id)
+	if err != nil {
+		utils.RespondWithError(w, r, err)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusNoContent, nil)
+}
+
+func (h *Handler) ChirpsTrashList(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.authenticate(r)
 	if err != nil {
-		utils.RespondWithError(w, http.StatusBadRequest, "Invalid chirp ID: "+err.Error(), err)
+		utils.RespondWithError(w, r, err)
 		return
 	}
 
-	chirp, err := h.config.DB.GetChirpByID(r.Context(), id)
+	chirps, err := h.config.Service.ListTrash(r.Context(), userID)
 	if err != nil {
-		utils.RespondWithError(w, http.StatusNotFound, "Chirp with ID "+id.String()+" does not exist", err)
+		utils.RespondWithError(w, r, err)
 		return
 	}
 
-	if chirp.UserID != userID {
-		utils.RespondWithError(w, http.StatusForbidden, "You are not allowed to delete this chirp", errors.New("could not delete chirp: user ID mismatch"))
+	chirpsAPI, err := h.chirpResponses(r.Context(), chirps...)
+	if err != nil {
+		utils.RespondWithError(w, r, err)
 		return
 	}
+	trash := []types.TrashedChirp{}
+	for i, chirp := range chirps {
+		trash = append(trash, types.TrashedChirp{
+			Chirp:           chirpsAPI[i],
+			DeletedAt:       chirp.DeletedAt.Time,
+			RestorableUntil: h.config.Service.RestorableUntil(chirp),
+		})
+	}
 
-	err = h.config.DB.DeleteChirp(r.Context(), id)
+	utils.RespondWithJSON(w, http.StatusOK, trash)
+}
+
+func (h *Handler) ChirpsRestore(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.authenticate(r)
 	if err != nil {
-		utils.RespondWithError(w, http.StatusInternalServerError, "Couldn't delete chirp", err)
+		utils.RespondWithError(w, r, err)
 		return
 	}
 
-	utils.RespondWithJSON(w, http.StatusNoContent, nil)
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		utils.RespondWithError(w, r, invalidUUID("id", err))
+		return
+	}
+
+	chirp, err := h.config.Service.RestoreChirp(r.Context(), userID, id)
+	if err != nil {
+		utils.RespondWithError(w, r, err)
+		return
+	}
+
+	h.respondWithChirp(w, r, http.StatusOK, chirp)
 }
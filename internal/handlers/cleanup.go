@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/HemahWeb/chirpy/internal/cleanup"
+	"github.com/HemahWeb/chirpy/internal/problem"
+	"github.com/HemahWeb/chirpy/internal/utils"
+)
+
+func (h *Handler) RefreshTokensCleanup(w http.ResponseWriter, r *http.Request) {
+	type responseVals struct {
+		Removed int64         `json:"removed"`
+		Stats   cleanup.Stats `json:"stats"`
+	}
+
+	if !h.devPlatformOnly(w, r) {
+		return
+	}
+	if h.config.TokenCleaner == nil {
+		utils.RespondWithError(w, r, problem.New(http.StatusServiceUnavailable, problem.CodeUnavailable, "Refresh token cleaner not configured"))
+		return
+	}
+
+	removed, err := h.config.TokenCleaner.RunOnce(r.Context())
+	if errors.Is(err, cleanup.ErrAlreadyRunning) {
+		utils.RespondWithError(w, r, problem.New(http.StatusConflict, problem.CodeConflict, "Refresh token cleanup already running"))
+		return
+	}
+	if err != nil {
+		utils.RespondWithError(w, r, err)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, responseVals{
+		Removed: removed,
+		Stats:   h.config.TokenCleaner.Stats(),
+	})
+}
+
+func (h *Handler) ChirpTrashPurge(w http.ResponseWriter, r *http.Request) {
+	type responseVals struct {
+		Removed int64         `json:"removed"`
+		Stats   cleanup.Stats `json:"stats"`
+	}
+
+	if !h.devPlatformOnly(w, r) {
+		return
+	}
+	if h.config.TrashPurger == nil {
+		utils.RespondWithError(w, r, problem.New(http.StatusServiceUnavailable, problem.CodeUnavailable, "Chirp trash purger not configured"))
+		return
+	}
+
+	removed, err := h.config.TrashPurger.RunOnce(r.Context())
+	if errors.Is(err, cleanup.ErrAlreadyRunning) {
+		utils.RespondWithError(w, r, problem.New(http.StatusConflict, problem.CodeConflict, "Chirp trash purge already running"))
+		return
+	}
+	if err != nil {
+		utils.RespondWithError(w, r, err)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, responseVals{
+		Removed: removed,
+		Stats:   h.config.TrashPurger.Stats(),
+	})
+}
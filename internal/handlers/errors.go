@@ -0,0 +1,8 @@
+package handlers
+
+import "github.com/HemahWeb/chirpy/internal/problem"
+
+// invalidUUID reports a malformed ID in the path, query or body.
+func invalidUUID(field string, err error) error {
+	return problem.Validation(problem.FieldError{Field: field, Code: "invalid_uuid", Message: "must be a UUID"}).WithCause(err)
+}
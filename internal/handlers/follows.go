@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+
+	"github.com/HemahWeb/chirpy/internal/utils"
+)
+
+func (h *Handler) UsersFollow(w http.ResponseWriter, r *http.Request) {
+	h.applyToPathID(w, r, h.config.Service.Follow)
+}
+
+func (h *Handler) UsersUnfollow(w http.ResponseWriter, r *http.Request) {
+	h.applyToPathID(w, r, h.config.Service.Unfollow)
+}
+
+func (h *Handler) ChirpsLike(w http.ResponseWriter, r *http.Request) {
+	h.applyToPathID(w, r, h.config.Service.LikeChirp)
+}
+
+func (h *Handler) ChirpsUnlike(w http.ResponseWriter, r *http.Request) {
+	h.applyToPathID(w, r, h.config.Service.UnlikeChirp)
+}
+
+// applyToPathID authenticates the user and applies change to them and the
+// user, chirp or conversation in the path.
+func (h *Handler) applyToPathID(w http.ResponseWriter, r *http.Request, change func(ctx context.Context, userID, id uuid.UUID) error) {
+	userID, err := h.authenticate(r)
+	if err != nil {
+		utils.RespondWithError(w, r, err)
+		return
+	}
+
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		utils.RespondWithError(w, r, invalidUUID("id", err))
+		return
+	}
+
+	if err := change(r.Context(), userID, id); err != nil {
+		utils.RespondWithError(w, r, err)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusNoContent, nil)
+}
@@ -0,0 +1,706 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/google/uuid"
+	_ "github.com/lib/pq"
+
+	"github.com/HemahWeb/chirpy/internal/auth"
+	"github.com/HemahWeb/chirpy/internal/cleanup"
+	"github.com/HemahWeb/chirpy/internal/database"
+	"github.com/HemahWeb/chirpy/internal/dburl"
+	"github.com/HemahWeb/chirpy/internal/events"
+	"github.com/HemahWeb/chirpy/internal/health"
+	"github.com/HemahWeb/chirpy/internal/media"
+	"github.com/HemahWeb/chirpy/internal/memstore"
+	"github.com/HemahWeb/chirpy/internal/metrics"
+	"github.com/HemahWeb/chirpy/internal/migrate"
+	"github.com/HemahWeb/chirpy/internal/problem"
+	"github.com/HemahWeb/chirpy/internal/service"
+	"github.com/HemahWeb/chirpy/internal/sqlite"
+	"github.com/HemahWeb/chirpy/internal/types"
+)
+
+const (
+	testJWTSecret = "0123456789abcdef0123456789abcdef"
+	testPolkaKey  = "f271c81ff7084ee5b99a5091b42d486e"
+)
+
+// registered and covered track which route patterns exist and which the
+// suite has exercised, so a new route without tests fails TestMain.
+var (
+	coverageMu sync.Mutex
+	registered = map[string]bool{}
+	covered    = map[string]bool{}
+)
+
+func TestMain(m *testing.M) {
+	flag.Parse()
+	code := m.Run()
+
+	if code == 0 && flag.Lookup("test.run").Value.String() == "" {
+		coverageMu.Lock()
+		for pattern := range registered {
+			if !covered[pattern] {
+				fmt.Fprintf(os.Stderr, "route %q has no handler tests\n", pattern)
+				code = 1
+			}
+		}
+		coverageMu.Unlock()
+	}
+	os.Exit(code)
+}
+
+// recordingMux notes every pattern registered on it.
+type recordingMux struct {
+	*http.ServeMux
+}
+
+func (m recordingMux) Handle(pattern string, handler http.Handler) {
+	coverageMu.Lock()
+	registered[pattern] = true
+	coverageMu.Unlock()
+	m.ServeMux.Handle(pattern, handler)
+}
+
+func (m recordingMux) HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request)) {
+	m.Handle(pattern, http.HandlerFunc(handler))
+}
+
+// backends are the database.Querier implementations every test runs
+// against. Postgres needs a server, so it only runs when
+// CHIRPY_TEST_DB_URL points at a database the suite may wipe.
+var backends = []struct {
+	name string
+	open func(t *testing.T) (database.Querier, service.Transactor)
+}{
+	{"memory", openMemory},
+	{"sqlite", openSQLite},
+	{"postgres", openPostgres},
+}
+
+func forEachBackend(t *testing.T, test func(t *testing.T, s *testServer)) {
+	for _, b := range backends {
+		t.Run(b.name, func(t *testing.T) {
+			test(t, newTestServer(t, b.open))
+		})
+	}
+}
+
+func openMemory(t *testing.T) (database.Querier, service.Transactor) {
+	store := memstore.New()
+	return store, store
+}
+
+func openSQLite(t *testing.T) (database.Querier, service.Transactor) {
+	_, dsn, err := dburl.Parse("sqlite:" + filepath.Join(t.TempDir(), "chirpy.db"))
+	if err != nil {
+		t.Fatalf("dburl.Parse() failed: %v", err)
+	}
+	db, err := sqlite.Open(dsn)
+	if err != nil {
+		t.Fatalf("couldn't open SQLite: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	migrateUp(t, db, dburl.SQLite)
+	return sqlite.New(db), service.NewSQLTransactor(db, func(tx *sql.Tx) database.Querier { return sqlite.New(tx) }, sqlite.Busy)
+}
+
+func openPostgres(t *testing.T) (database.Querier, service.Transactor) {
+	url := os.Getenv("CHIRPY_TEST_DB_URL")
+	if url == "" {
+		t.Skip("CHIRPY_TEST_DB_URL is not set")
+	}
+	db, err := sql.Open("postgres", url)
+	if err != nil {
+		t.Fatalf("couldn't open Postgres: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	migrateUp(t, db, dburl.Postgres)
+
+	q := database.New(db)
+	if err := q.ResetUsers(context.Background()); err != nil {
+		t.Fatalf("couldn't reset the database: %v", err)
+	}
+	return q, service.NewSQLTransactor(db, func(tx *sql.Tx) database.Querier { return q.WithTx(tx) }, database.SerializationFailure)
+}
+
+func migrateUp(t *testing.T, db *sql.DB, dialect dburl.Dialect) {
+	t.Helper()
+	m, err := migrate.New(db, dialect)
+	if err != nil {
+		t.Fatalf("couldn't load migrations: %v", err)
+	}
+	if _, err := m.Up(context.Background()); err != nil {
+		t.Fatalf("couldn't apply migrations: %v", err)
+	}
+}
+
+type testServer struct {
+	t   *testing.T
+	mux *http.ServeMux
+	cfg *types.ApiConfig
+	db  database.Querier
+}
+
+func newTestServer(t *testing.T, open func(t *testing.T) (database.Querier, service.Transactor)) *testServer {
+	t.Helper()
+	db, tx := open(t)
+	broker := events.NewBroker(100)
+	svc := service.New(db, tx, testJWTSecret)
+	svc.SetPublisher(broker)
+	blobs, err := media.NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("couldn't create the media store: %v", err)
+	}
+	svc.SetMedia(blobs, media.DefaultLimits(), service.DefaultMaxImages)
+	cfg := &types.ApiConfig{
+		Service:         svc,
+		Platform:        "dev",
+		JWTSecret:       testJWTSecret,
+		PolkaKey:        testPolkaKey,
+		TokenCleaner:    cleanup.NewRefreshTokenCleaner(db, cleanup.DefaultConfig()),
+		TrashPurger:     cleanup.NewChirpTrashPurger(db, cleanup.DefaultChirpTrashConfig()),
+		Readiness:       health.NewChecker(time.Second, 0),
+		Metrics:         metrics.New(),
+		Stream:          broker,
+		StreamHeartbeat: time.Hour,
+	}
+	mux := http.NewServeMux()
+	static := fstest.MapFS{"index.html": {Data: []byte("<h1>Chirpy</h1>")}}
+	New(cfg).Register(recordingMux{mux}, http.FS(static))
+	return &testServer{t: t, mux: mux, cfg: cfg, db: db}
+}
+
+// do sends a request; body is JSON-encoded unless it's a string, and auth
+// is the whole Authorization header.
+func (s *testServer) do(method, path string, body any, auth string) *httptest.ResponseRecorder {
+	s.t.Helper()
+	var r io.Reader
+	switch b := body.(type) {
+	case nil:
+	case string:
+		r = strings.NewReader(b)
+	default:
+		data, err := json.Marshal(b)
+		if err != nil {
+			s.t.Fatalf("couldn't encode request body: %v", err)
+		}
+		r = bytes.NewReader(data)
+	}
+
+	req := httptest.NewRequest(method, path, r)
+	if auth != "" {
+		req.Header.Set("Authorization", auth)
+	}
+	s.cover(req)
+
+	rec := httptest.NewRecorder()
+	s.mux.ServeHTTP(rec, req)
+	return rec
+}
+
+// cover marks the route req matches as tested.
+func (s *testServer) cover(req *http.Request) {
+	if _, pattern := s.mux.Handler(req); pattern != "" {
+		coverageMu.Lock()
+		covered[pattern] = true
+		coverageMu.Unlock()
+	}
+}
+
+func decode[T any](t *testing.T, rec *httptest.ResponseRecorder) T {
+	t.Helper()
+	var v T
+	if err := json.Unmarshal(rec.Body.Bytes(), &v); err != nil {
+		t.Fatalf("couldn't decode response %q: %v", rec.Body.String(), err)
+	}
+	return v
+}
+
+func expectStatus(t *testing.T, rec *httptest.ResponseRecorder, status int) {
+	t.Helper()
+	if rec.Code != status {
+		t.Fatalf("status = %d, want %d; body: %s", rec.Code, status, rec.Body.String())
+	}
+}
+
+func expectProblem(t *testing.T, rec *httptest.ResponseRecorder, status int, code problem.Code) problem.Problem {
+	t.Helper()
+	expectStatus(t, rec, status)
+	if ct := rec.Header().Get("Content-Type"); ct != problem.ContentType {
+		t.Errorf("Content-Type = %q, want %q", ct, problem.ContentType)
+	}
+	p := decode[problem.Problem](t, rec)
+	if p.Code != code {
+		t.Errorf("code = %q, want %q", p.Code, code)
+	}
+	return p
+}
+
+type session struct {
+	ID           uuid.UUID `json:"id"`
+	Email        string    `json:"email"`
+	Token        string    `json:"token"`
+	RefreshToken string    `json:"refresh_token"`
+	IsChirpyRed  bool      `json:"is_chirpy_red"`
+
+	Suspended      bool       `json:"suspended"`
+	SuspendedUntil *time.Time `json:"suspended_until"`
+}
+
+func (s session) bearer() string        { return "Bearer " + s.Token }
+func (s session) refreshBearer() string { return "Bearer " + s.RefreshToken }
+
+// signUp creates a user and logs them in.
+func (s *testServer) signUp(email string) session {
+	s.t.Helper()
+	creds := map[string]string{"email": email, "password": "hunter2"}
+	expectStatus(s.t, s.do(http.MethodPost, "/api/users", creds, ""), http.StatusCreated)
+	rec := s.do(http.MethodPost, "/api/login", creds, "")
+	expectStatus(s.t, rec, http.StatusOK)
+	return decode[session](s.t, rec)
+}
+
+func (s *testServer) postChirp(user session, body string) types.Chirp {
+	s.t.Helper()
+	rec := s.do(http.MethodPost, "/api/chirps", map[string]string{"body": body}, user.bearer())
+	expectStatus(s.t, rec, http.StatusCreated)
+	return decode[types.Chirp](s.t, rec)
+}
+
+func TestHealth(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, s *testServer) {
+		rec := s.do(http.MethodGet, "/api/livez", nil, "")
+		expectStatus(t, rec, http.StatusOK)
+
+		s.cfg.Readiness.Add("database", func(context.Context) error { return nil })
+		for _, path := range []string{"/api/readyz", "/api/healthz"} {
+			rec = s.do(http.MethodGet, path, nil, "")
+			expectStatus(t, rec, http.StatusOK)
+			if report := decode[health.Report](t, rec); report.Status != health.StatusOK {
+				t.Errorf("%s status = %q", path, report.Status)
+			}
+		}
+
+		s.cfg.Readiness.Add("cache", func(context.Context) error { return errors.New("dial tcp 10.0.0.5:6379: connection refused") })
+		rec = s.do(http.MethodGet, "/api/readyz", nil, "")
+		expectStatus(t, rec, http.StatusServiceUnavailable)
+		if strings.Contains(rec.Body.String(), "10.0.0.5") {
+			t.Errorf("readiness report leaks the check's error: %s", rec.Body.String())
+		}
+		if report := decode[health.Report](t, rec); report.Checks["cache"].Status != health.StatusFail {
+			t.Errorf("cache check = %+v, want fail", report.Checks["cache"])
+		}
+
+		s.cfg.ShuttingDown.Store(true)
+		rec = s.do(http.MethodGet, "/api/healthz", nil, "")
+		expectStatus(t, rec, http.StatusServiceUnavailable)
+		if report := decode[health.Report](t, rec); report.Status != "shutting_down" {
+			t.Errorf("status while shutting down = %q", report.Status)
+		}
+	})
+}
+
+func TestUsersCreate(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, s *testServer) {
+		rec := s.do(http.MethodPost, "/api/users", map[string]string{"email": "walt@breakingbad.com", "password": "04234"}, "")
+		expectStatus(t, rec, http.StatusCreated)
+		user := decode[types.User](t, rec)
+		if user.Email != "walt@breakingbad.com" || user.ID == uuid.Nil || user.IsChirpyRed {
+			t.Errorf("created user = %+v", user)
+		}
+		if strings.Contains(rec.Body.String(), "password") {
+			t.Errorf("response leaks the password hash: %s", rec.Body.String())
+		}
+
+		rec = s.do(http.MethodPost, "/api/users", map[string]string{"email": "walt@breakingbad.com", "password": "other"}, "")
+		expectProblem(t, rec, http.StatusConflict, problem.CodeEmailTaken)
+
+		rec = s.do(http.MethodPost, "/api/users", map[string]string{"email": "not an email"}, "")
+		p := expectProblem(t, rec, http.StatusBadRequest, problem.CodeValidationFailed)
+		if len(p.Errors) != 2 {
+			t.Errorf("field errors = %+v, want email and password", p.Errors)
+		}
+
+		expectProblem(t, s.do(http.MethodPost, "/api/users", "{", ""), http.StatusBadRequest, problem.CodeInvalidJSON)
+	})
+}
+
+func TestLogin(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, s *testServer) {
+		user := s.signUp("saul@bcs.com")
+
+		if user.Token == "" || user.RefreshToken == "" {
+			t.Fatalf("login response = %+v, want both tokens", user)
+		}
+		if id, err := auth.ValidateJWT(user.Token, testJWTSecret); err != nil || id != user.ID {
+			t.Errorf("access token is for %v (%v), want %v", id, err, user.ID)
+		}
+
+		rec := s.do(http.MethodPost, "/api/login", map[string]string{"email": "saul@bcs.com", "password": "wrong"}, "")
+		expectProblem(t, rec, http.StatusUnauthorized, problem.CodeInvalidCredentials)
+
+		rec = s.do(http.MethodPost, "/api/login", map[string]string{"email": "nobody@bcs.com", "password": "hunter2"}, "")
+		expectProblem(t, rec, http.StatusUnauthorized, problem.CodeInvalidCredentials)
+
+		expectProblem(t, s.do(http.MethodPost, "/api/login", "nope", ""), http.StatusBadRequest, problem.CodeInvalidJSON)
+	})
+}
+
+func TestUsersUpdate(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, s *testServer) {
+		user := s.signUp("jesse@breakingbad.com")
+		s.signUp("skyler@breakingbad.com")
+
+		update := map[string]string{"email": "cap.n.cook@breakingbad.com", "password": "newpass"}
+		rec := s.do(http.MethodPut, "/api/users", update, user.bearer())
+		expectStatus(t, rec, http.StatusOK)
+		if got := decode[types.User](t, rec); got.Email != update["email"] || got.ID != user.ID {
+			t.Errorf("updated user = %+v", got)
+		}
+		expectStatus(t, s.do(http.MethodPost, "/api/login", update, ""), http.StatusOK)
+
+		rec = s.do(http.MethodPut, "/api/users", map[string]string{"email": "skyler@breakingbad.com", "password": "x"}, user.bearer())
+		expectProblem(t, rec, http.StatusConflict, problem.CodeEmailTaken)
+
+		rec = s.do(http.MethodPut, "/api/users", map[string]string{"email": "jesse"}, user.bearer())
+		expectProblem(t, rec, http.StatusBadRequest, problem.CodeValidationFailed)
+
+		expectProblem(t, s.do(http.MethodPut, "/api/users", update, ""), http.StatusUnauthorized, problem.CodeUnauthenticated)
+		expectProblem(t, s.do(http.MethodPut, "/api/users", update, "Bearer forged"), http.StatusUnauthorized, problem.CodeInvalidToken)
+		expectProblem(t, s.do(http.MethodPut, "/api/users", "{", user.bearer()), http.StatusBadRequest, problem.CodeInvalidJSON)
+	})
+}
+
+func TestRefreshAndRevoke(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, s *testServer) {
+		user := s.signUp("mike@lospollos.com")
+
+		rec := s.do(http.MethodPost, "/api/refresh", nil, user.refreshBearer())
+		expectStatus(t, rec, http.StatusOK)
+		refreshed := decode[struct {
+			Token string `json:"token"`
+		}](t, rec)
+		if id, err := auth.ValidateJWT(refreshed.Token, testJWTSecret); err != nil || id != user.ID {
+			t.Errorf("refreshed token is for %v (%v), want %v", id, err, user.ID)
+		}
+
+		expectProblem(t, s.do(http.MethodPost, "/api/refresh", nil, "Bearer unknown"), http.StatusUnauthorized, problem.CodeInvalidToken)
+		expectProblem(t, s.do(http.MethodPost, "/api/refresh", nil, ""), http.StatusUnauthorized, problem.CodeUnauthenticated)
+
+		expectStatus(t, s.do(http.MethodPost, "/api/revoke", nil, user.refreshBearer()), http.StatusNoContent)
+		expectProblem(t, s.do(http.MethodPost, "/api/refresh", nil, user.refreshBearer()), http.StatusUnauthorized, problem.CodeTokenRevoked)
+		expectProblem(t, s.do(http.MethodPost, "/api/revoke", nil, ""), http.StatusUnauthorized, problem.CodeUnauthenticated)
+	})
+}
+
+func TestPostChirps(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, s *testServer) {
+		user := s.signUp("gus@lospollos.com")
+
+		chirp := s.postChirp(user, "I had a Kerfuffle with sharbert")
+		if chirp.Body != "I had a **** with ****" || chirp.UserID != user.ID {
+			t.Errorf("chirp = %+v, want bad words masked and the author set", chirp)
+		}
+		if chirp.ThreadID != chirp.ID || chirp.ReplyToID != nil {
+			t.Errorf("chirp = %+v, want it to start its own thread", chirp)
+		}
+
+		rec := s.do(http.MethodPost, "/api/chirps", map[string]any{"body": "Hello", "reply_to_id": chirp.ID}, user.bearer())
+		expectStatus(t, rec, http.StatusCreated)
+		var reply types.Chirp
+		json.NewDecoder(rec.Body).Decode(&reply)
+		if reply.ReplyToID == nil || *reply.ReplyToID != chirp.ID || reply.ThreadID != chirp.ID {
+			t.Errorf("reply = %+v, want it to answer %v in its thread", reply, chirp.ID)
+		}
+		p := expectProblem(t, s.do(http.MethodPost, "/api/chirps", map[string]any{"body": "Hello", "reply_to_id": uuid.New()}, user.bearer()), http.StatusBadRequest, problem.CodeValidationFailed)
+		if len(p.Errors) != 1 || p.Errors[0].Field != "reply_to_id" {
+			t.Errorf("field errors = %+v", p.Errors)
+		}
+
+		rec = s.do(http.MethodPost, "/api/chirps", map[string]string{"body": strings.Repeat("x", 141)}, user.bearer())
+		p = expectProblem(t, rec, http.StatusBadRequest, problem.CodeValidationFailed)
+		if len(p.Errors) != 1 || p.Errors[0].Field != "body" || p.Errors[0].Code != "too_long" {
+			t.Errorf("field errors = %+v", p.Errors)
+		}
+
+		expectProblem(t, s.do(http.MethodPost, "/api/chirps", "{", user.bearer()), http.StatusBadRequest, problem.CodeInvalidJSON)
+		expectProblem(t, s.do(http.MethodPost, "/api/chirps", map[string]string{"body": "hi"}, ""), http.StatusUnauthorized, problem.CodeUnauthenticated)
+		expectProblem(t, s.do(http.MethodPost, "/api/chirps", map[string]string{"body": "hi"}, "Bearer forged"), http.StatusUnauthorized, problem.CodeInvalidToken)
+	})
+}
+
+func TestGetChirps(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, s *testServer) {
+		if store, ok := s.db.(interface{ SetClock(func() time.Time) }); ok {
+			now := time.Now()
+			store.SetClock(func() time.Time { now = now.Add(time.Second); return now })
+		}
+		walt := s.signUp("walt@breakingbad.com")
+		jesse := s.signUp("jesse@breakingbad.com")
+		first := s.postChirp(walt, "first")
+		second := s.postChirp(jesse, "second")
+		third := s.postChirp(walt, "third")
+
+		ids := func(rec *httptest.ResponseRecorder) []uuid.UUID {
+			expectStatus(t, rec, http.StatusOK)
+			var out []uuid.UUID
+			for _, c := range decode[[]types.Chirp](t, rec) {
+				out = append(out, c.ID)
+			}
+			return out
+		}
+
+		if got := ids(s.do(http.MethodGet, "/api/chirps", nil, "")); !slices.Equal(got, []uuid.UUID{first.ID, second.ID, third.ID}) {
+			t.Errorf("chirps = %v, want oldest first", got)
+		}
+		if got := ids(s.do(http.MethodGet, "/api/chirps?sort=desc", nil, "")); !slices.Equal(got, []uuid.UUID{third.ID, second.ID, first.ID}) {
+			t.Errorf("chirps = %v, want newest first", got)
+		}
+		if got := ids(s.do(http.MethodGet, "/api/chirps?author_id="+walt.ID.String(), nil, "")); !slices.Equal(got, []uuid.UUID{first.ID, third.ID}) {
+			t.Errorf("chirps = %v, want only walt's", got)
+		}
+		if got := ids(s.do(http.MethodGet, "/api/chirps?author_id="+uuid.NewString(), nil, "")); len(got) != 0 {
+			t.Errorf("chirps = %v, want none for an unknown author", got)
+		}
+		if rec := s.do(http.MethodGet, "/api/chirps?author_id="+uuid.NewString(), nil, ""); strings.TrimSpace(rec.Body.String()) != "[]" {
+			t.Errorf("body = %s, want an empty array rather than null", rec.Body.String())
+		}
+
+		p := expectProblem(t, s.do(http.MethodGet, "/api/chirps?author_id=walt", nil, ""), http.StatusBadRequest, problem.CodeValidationFailed)
+		if len(p.Errors) != 1 || p.Errors[0].Field != "author_id" {
+			t.Errorf("field errors = %+v", p.Errors)
+		}
+	})
+}
+
+func TestGetChirpsByID(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, s *testServer) {
+		chirp := s.postChirp(s.signUp("hank@dea.gov"), "minerals")
+
+		rec := s.do(http.MethodGet, "/api/chirps/"+chirp.ID.String(), nil, "")
+		expectStatus(t, rec, http.StatusOK)
+		if got := decode[types.Chirp](t, rec); !reflect.DeepEqual(got, chirp) {
+			t.Errorf("chirp = %+v, want %+v", got, chirp)
+		}
+
+		expectProblem(t, s.do(http.MethodGet, "/api/chirps/"+uuid.NewString(), nil, ""), http.StatusNotFound, problem.CodeNotFound)
+		expectProblem(t, s.do(http.MethodGet, "/api/chirps/nope", nil, ""), http.StatusBadRequest, problem.CodeValidationFailed)
+	})
+}
+
+func TestChirpsDeleteByID(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, s *testServer) {
+		owner := s.signUp("todd@vamonos.com")
+		other := s.signUp("lydia@madrigal.com")
+		chirp := s.postChirp(owner, "stevia")
+		path := "/api/chirps/" + chirp.ID.String()
+
+		expectProblem(t, s.do(http.MethodDelete, path, nil, ""), http.StatusUnauthorized, problem.CodeUnauthenticated)
+		expectProblem(t, s.do(http.MethodDelete, path, nil, other.bearer()), http.StatusForbidden, problem.CodeForbidden)
+		expectProblem(t, s.do(http.MethodDelete, "/api/chirps/bad-id", nil, owner.bearer()), http.StatusBadRequest, problem.CodeValidationFailed)
+
+		expectStatus(t, s.do(http.MethodDelete, path, nil, owner.bearer()), http.StatusNoContent)
+		expectProblem(t, s.do(http.MethodGet, path, nil, ""), http.StatusNotFound, problem.CodeNotFound)
+		expectProblem(t, s.do(http.MethodDelete, path, nil, owner.bearer()), http.StatusNotFound, problem.CodeNotFound)
+	})
+}
+
+func TestChirpsTrash(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, s *testServer) {
+		owner := s.signUp("skyler@a1a.com")
+		other := s.signUp("ted@beneke.com")
+		chirp := s.postChirp(owner, "car wash")
+		path := "/api/chirps/" + chirp.ID.String()
+
+		expectProblem(t, s.do(http.MethodGet, "/api/chirps/trash", nil, ""), http.StatusUnauthorized, problem.CodeUnauthenticated)
+		rec := s.do(http.MethodGet, "/api/chirps/trash", nil, owner.bearer())
+		expectStatus(t, rec, http.StatusOK)
+		if got := decode[[]types.TrashedChirp](t, rec); len(got) != 0 {
+			t.Errorf("trash = %+v, want empty", got)
+		}
+
+		expectStatus(t, s.do(http.MethodDelete, path, nil, owner.bearer()), http.StatusNoContent)
+		rec = s.do(http.MethodGet, "/api/chirps/trash", nil, owner.bearer())
+		expectStatus(t, rec, http.StatusOK)
+		trash := decode[[]types.TrashedChirp](t, rec)
+		if len(trash) != 1 || trash[0].ID != chirp.ID || trash[0].Body != chirp.Body {
+			t.Fatalf("trash = %+v, want the deleted chirp", trash)
+		}
+		if want := trash[0].DeletedAt.Add(cleanup.DefaultChirpTrashConfig().Retention); !trash[0].RestorableUntil.Equal(want) {
+			t.Errorf("restorable_until = %v, want %v", trash[0].RestorableUntil, want)
+		}
+		rec = s.do(http.MethodGet, "/api/chirps/trash", nil, other.bearer())
+		if got := decode[[]types.TrashedChirp](t, rec); len(got) != 0 {
+			t.Errorf("someone else's trash = %+v, want empty", got)
+		}
+
+		expectProblem(t, s.do(http.MethodPost, path+"/restore", nil, ""), http.StatusUnauthorized, problem.CodeUnauthenticated)
+		expectProblem(t, s.do(http.MethodPost, "/api/chirps/bad-id/restore", nil, owner.bearer()), http.StatusBadRequest, problem.CodeValidationFailed)
+		expectProblem(t, s.do(http.MethodPost, path+"/restore", nil, other.bearer()), http.StatusNotFound, problem.CodeNotFound)
+		rec = s.do(http.MethodPost, path+"/restore", nil, owner.bearer())
+		expectStatus(t, rec, http.StatusOK)
+		if got := decode[types.Chirp](t, rec); !reflect.DeepEqual(got, chirp) {
+			t.Errorf("restored chirp = %+v, want %+v", got, chirp)
+		}
+		expectStatus(t, s.do(http.MethodGet, path, nil, ""), http.StatusOK)
+		expectProblem(t, s.do(http.MethodPost, path+"/restore", nil, owner.bearer()), http.StatusNotFound, problem.CodeNotFound)
+	})
+}
+
+func TestPolkaWebhooks(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, s *testServer) {
+		user := s.signUp("tuco@salamanca.com")
+		apiKey := "ApiKey " + testPolkaKey
+		event := func(name, userID string) map[string]any {
+			return map[string]any{"event": name, "data": map[string]string{"user_id": userID}}
+		}
+
+		expectStatus(t, s.do(http.MethodPost, "/api/polka/webhooks", event("user.payment_failed", user.ID.String()), apiKey), http.StatusNoContent)
+		if got := s.signUpLogin(user.Email); got.IsChirpyRed {
+			t.Error("other events should not upgrade the user")
+		}
+
+		expectStatus(t, s.do(http.MethodPost, "/api/polka/webhooks", event("user.upgraded", user.ID.String()), apiKey), http.StatusNoContent)
+		if got := s.signUpLogin(user.Email); !got.IsChirpyRed {
+			t.Error("user.upgraded should make the user Chirpy Red")
+		}
+
+		expectProblem(t, s.do(http.MethodPost, "/api/polka/webhooks", event("user.upgraded", uuid.NewString()), apiKey), http.StatusNotFound, problem.CodeNotFound)
+		expectProblem(t, s.do(http.MethodPost, "/api/polka/webhooks", event("user.upgraded", "tuco"), apiKey), http.StatusBadRequest, problem.CodeValidationFailed)
+		expectProblem(t, s.do(http.MethodPost, "/api/polka/webhooks", "{", apiKey), http.StatusBadRequest, problem.CodeInvalidJSON)
+		expectProblem(t, s.do(http.MethodPost, "/api/polka/webhooks", event("user.upgraded", user.ID.String()), "ApiKey wrong"), http.StatusUnauthorized, problem.CodeInvalidAPIKey)
+		expectProblem(t, s.do(http.MethodPost, "/api/polka/webhooks", event("user.upgraded", user.ID.String()), ""), http.StatusUnauthorized, problem.CodeUnauthenticated)
+
+		metrics := s.do(http.MethodGet, "/metrics", nil, "").Body.String()
+		if strings.Contains(metrics, "user.payment_failed") || !strings.Contains(metrics, `chirpy_webhooks_received_total{event="other",source="polka"} 1`) {
+			t.Errorf("unhandled events should be counted as other:\n%s", metrics)
+		}
+	})
+}
+
+func TestUsersFollow(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, s *testServer) {
+		fan := s.signUp("badger@example.com")
+		walt := s.signUp("walt@breakingbad.com")
+		path := "/api/users/" + walt.ID.String() + "/follow"
+
+		expectStatus(t, s.do(http.MethodPost, path, nil, fan.bearer()), http.StatusNoContent)
+		expectStatus(t, s.do(http.MethodPost, path, nil, fan.bearer()), http.StatusNoContent)
+		if ids, _ := s.cfg.Service.Followees(context.Background(), fan.ID); !slices.Equal(ids, []uuid.UUID{walt.ID}) {
+			t.Errorf("followees = %v, want walt", ids)
+		}
+
+		expectProblem(t, s.do(http.MethodPost, "/api/users/"+fan.ID.String()+"/follow", nil, fan.bearer()), http.StatusBadRequest, problem.CodeValidationFailed)
+		expectProblem(t, s.do(http.MethodPost, "/api/users/"+uuid.NewString()+"/follow", nil, fan.bearer()), http.StatusNotFound, problem.CodeNotFound)
+		expectProblem(t, s.do(http.MethodPost, "/api/users/walt/follow", nil, fan.bearer()), http.StatusBadRequest, problem.CodeValidationFailed)
+		expectProblem(t, s.do(http.MethodPost, path, nil, ""), http.StatusUnauthorized, problem.CodeUnauthenticated)
+
+		expectStatus(t, s.do(http.MethodDelete, path, nil, fan.bearer()), http.StatusNoContent)
+		expectStatus(t, s.do(http.MethodDelete, path, nil, fan.bearer()), http.StatusNoContent)
+		if ids, _ := s.cfg.Service.Followees(context.Background(), fan.ID); len(ids) != 0 {
+			t.Errorf("followees after unfollowing = %v", ids)
+		}
+		expectProblem(t, s.do(http.MethodDelete, path, nil, "Bearer forged"), http.StatusUnauthorized, problem.CodeInvalidToken)
+	})
+}
+
+func TestChirpsLike(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, s *testServer) {
+		fan := s.signUp("badger@example.com")
+		walt := s.signUp("walt@breakingbad.com")
+		chirp := s.postChirp(walt, "Say my name")
+		path := "/api/chirps/" + chirp.ID.String() + "/like"
+
+		expectStatus(t, s.do(http.MethodPost, path, nil, fan.bearer()), http.StatusNoContent)
+		expectStatus(t, s.do(http.MethodPost, path, nil, fan.bearer()), http.StatusNoContent)
+		expectProblem(t, s.do(http.MethodPost, "/api/chirps/"+uuid.NewString()+"/like", nil, fan.bearer()), http.StatusNotFound, problem.CodeNotFound)
+		expectProblem(t, s.do(http.MethodPost, "/api/chirps/cook/like", nil, fan.bearer()), http.StatusBadRequest, problem.CodeValidationFailed)
+		expectProblem(t, s.do(http.MethodPost, path, nil, ""), http.StatusUnauthorized, problem.CodeUnauthenticated)
+
+		expectStatus(t, s.do(http.MethodDelete, path, nil, fan.bearer()), http.StatusNoContent)
+		expectStatus(t, s.do(http.MethodDelete, path, nil, fan.bearer()), http.StatusNoContent)
+		expectProblem(t, s.do(http.MethodDelete, path, nil, "Bearer forged"), http.StatusUnauthorized, problem.CodeInvalidToken)
+	})
+}
+
+// signUpLogin logs an existing user back in to read their current state.
+func (s *testServer) signUpLogin(email string) session {
+	s.t.Helper()
+	rec := s.do(http.MethodPost, "/api/login", map[string]string{"email": email, "password": "hunter2"}, "")
+	expectStatus(s.t, rec, http.StatusOK)
+	return decode[session](s.t, rec)
+}
+
+func TestAdmin(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, s *testServer) {
+		user := s.signUp("gale@lab.com")
+
+		expectStatus(t, s.do(http.MethodGet, "/app/", nil, ""), http.StatusOK)
+		rec := s.do(http.MethodGet, "/admin/metrics", nil, "")
+		expectStatus(t, rec, http.StatusOK)
+		if !strings.Contains(rec.Body.String(), "visited 1 times") {
+			t.Errorf("metrics page = %s, want one fileserver hit", rec.Body.String())
+		}
+
+		rec = s.do(http.MethodGet, "/metrics", nil, "")
+		expectStatus(t, rec, http.StatusOK)
+		if !strings.Contains(rec.Body.String(), "chirpy_fileserver_hits_total 1") {
+			t.Error("Prometheus metrics should include the fileserver hit")
+		}
+
+		rec = s.do(http.MethodPost, "/admin/refresh_tokens/cleanup", nil, "")
+		expectStatus(t, rec, http.StatusOK)
+		if got := decode[struct {
+			Removed int64 `json:"removed"`
+		}](t, rec); got.Removed != 0 {
+			t.Errorf("removed = %d, want 0 with only fresh tokens", got.Removed)
+		}
+
+		rec = s.do(http.MethodPost, "/admin/chirps/trash/purge", nil, "")
+		expectStatus(t, rec, http.StatusOK)
+		if got := decode[struct {
+			Removed int64 `json:"removed"`
+		}](t, rec); got.Removed != 0 {
+			t.Errorf("removed = %d, want 0 with an empty trash", got.Removed)
+		}
+
+		expectStatus(t, s.do(http.MethodPost, "/admin/reset", nil, ""), http.StatusOK)
+		rec = s.do(http.MethodPost, "/api/login", map[string]string{"email": user.Email, "password": "hunter2"}, "")
+		expectProblem(t, rec, http.StatusUnauthorized, problem.CodeInvalidCredentials)
+		if rec := s.do(http.MethodGet, "/admin/metrics", nil, ""); !strings.Contains(rec.Body.String(), "visited 0 times") {
+			t.Errorf("metrics page = %s, want hits reset", rec.Body.String())
+		}
+
+		s.cfg.Platform = "prod"
+		expectProblem(t, s.do(http.MethodPost, "/admin/reset", nil, ""), http.StatusForbidden, problem.CodeForbidden)
+		expectProblem(t, s.do(http.MethodPost, "/admin/refresh_tokens/cleanup", nil, ""), http.StatusForbidden, problem.CodeForbidden)
+		expectProblem(t, s.do(http.MethodPost, "/admin/chirps/trash/purge", nil, ""), http.StatusForbidden, problem.CodeForbidden)
+		expectProblem(t, s.do(http.MethodGet, "/metrics", nil, ""), http.StatusForbidden, problem.CodeForbidden)
+
+		s.cfg.MetricsToken = "scrape-me"
+		expectProblem(t, s.do(http.MethodGet, "/metrics", nil, ""), http.StatusUnauthorized, problem.CodeUnauthenticated)
+		expectProblem(t, s.do(http.MethodGet, "/metrics", nil, "Bearer scrape-you"), http.StatusUnauthorized, problem.CodeInvalidToken)
+		expectStatus(t, s.do(http.MethodGet, "/metrics", nil, "Bearer scrape-me"), http.StatusOK)
+	})
+}
@@ -3,10 +3,43 @@ package handlers
 import (
 	"fmt"
 	"net/http"
+	"time"
+
+	"github.com/HemahWeb/chirpy/internal/health"
+	"github.com/HemahWeb/chirpy/internal/problem"
+	"github.com/HemahWeb/chirpy/internal/utils"
 )
 
-func (h *Handler) Healthz(w http.ResponseWriter, r *http.Request) {
+// Livez reports whether the process is up. It never touches dependencies,
+// so a database outage doesn't get the server restarted.
+func (h *Handler) Livez(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
 	w.WriteHeader(http.StatusOK)
 	fmt.Fprintf(w, "OK")
 }
+
+// Readyz reports whether the server should receive traffic.
+func (h *Handler) Readyz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+
+	if h.config.ShuttingDown.Load() {
+		utils.RespondWithJSON(w, http.StatusServiceUnavailable, health.Report{
+			Status:    "shutting_down",
+			Checks:    map[string]health.CheckResult{},
+			CheckedAt: time.Now().UTC(),
+		})
+		return
+	}
+
+	if h.config.Readiness == nil {
+		utils.RespondWithError(w, r, problem.New(http.StatusServiceUnavailable, problem.CodeUnavailable, "Readiness checks not configured"))
+		return
+	}
+
+	report := h.config.Readiness.Run(r.Context())
+	code := http.StatusOK
+	if !report.OK() {
+		code = http.StatusServiceUnavailable
+	}
+	utils.RespondWithJSON(w, code, report)
+}
@@ -7,7 +7,8 @@ import (
 
 	"github.com/google/uuid"
 
-	"github.com/HemahWeb/chirpy/internal/auth"
+	"github.com/HemahWeb/chirpy/internal/logging"
+	"github.com/HemahWeb/chirpy/internal/problem"
 	"github.com/HemahWeb/chirpy/internal/utils"
 )
 
@@ -23,48 +24,43 @@ func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
 		UpdatedAt    time.Time `json:"updated_at"`
 		Email        string    `json:"email"`
 		Token        string    `json:"token"`
-		RefreshToken string    `json:"refresh_token"`
+		RefreshToken string    `json:"refresh_token,omitempty"`
 		IsChirpyRed  bool      `json:"is_chirpy_red"`
+		// only set for a suspended user, whose token can only appeal
+		Suspended      bool       `json:"suspended,omitempty"`
+		SuspendedUntil *time.Time `json:"suspended_until,omitempty"`
 	}
 
 	var params parameters
 	err := json.NewDecoder(r.Body).Decode(&params)
 	if err != nil {
-		utils.RespondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
+		utils.RespondWithError(w, r, problem.InvalidJSON(err))
 		return
 	}
 
-	user, err := h.config.DB.GetUserByEmailForAuth(r.Context(), params.Email)
+	session, err := h.config.Service.Login(r.Context(), params.Email, params.Password)
 	if err != nil {
-		utils.RespondWithError(w, http.StatusUnauthorized, "Incorrect email or password", err)
+		if problem.From(err).Code == problem.CodeInvalidCredentials {
+			h.config.Metrics.LoginsFailed.Inc()
+		}
+		utils.RespondWithError(w, r, err)
 		return
 	}
+	user := session.User
+	logging.SetUserID(r.Context(), user.ID)
 
-	err = auth.CheckPasswordHash(params.Password, user.HashedPassword)
-	if err != nil {
-		utils.RespondWithError(w, http.StatusUnauthorized, "Incorrect email or password", err)
-		return
-	}
-
-	token, err := auth.MakeJWT(user.ID, h.config.JWTSecret)
-	if err != nil {
-		utils.RespondWithError(w, http.StatusInternalServerError, "Couldn't create token", err)
-		return
-	}
-
-	refreshToken, err := h.config.DB.CreateRefreshToken(r.Context(), user.ID)
-	if err != nil {
-		utils.RespondWithError(w, http.StatusInternalServerError, "Couldn't create refresh token", err)
-		return
-	}
-
-	utils.RespondWithJSON(w, http.StatusOK, responseVals{
+	resp := responseVals{
 		ID:           user.ID,
 		CreatedAt:    user.CreatedAt,
 		UpdatedAt:    user.UpdatedAt,
 		Email:        user.Email,
-		Token:        token,
-		RefreshToken: refreshToken,
+		Token:        session.AccessToken,
+		RefreshToken: session.RefreshToken,
 		IsChirpyRed:  user.IsChirpyRed,
-	})
+		Suspended:    session.Suspended,
+	}
+	if session.Suspended && user.SuspendedUntil.Valid {
+		resp.SuspendedUntil = &user.SuspendedUntil.Time
+	}
+	utils.RespondWithJSON(w, http.StatusOK, resp)
 }
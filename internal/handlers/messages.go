@@ -0,0 +1,267 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+
+	"github.com/HemahWeb/chirpy/internal/database"
+	"github.com/HemahWeb/chirpy/internal/problem"
+	"github.com/HemahWeb/chirpy/internal/service"
+	"github.com/HemahWeb/chirpy/internal/types"
+	"github.com/HemahWeb/chirpy/internal/utils"
+)
+
+func conversationResponse(c service.Conversation) types.Conversation {
+	resp := types.Conversation{
+		ID:          c.ID,
+		CreatedAt:   c.CreatedAt,
+		Members:     []types.ConversationMember{},
+		Muted:       c.Muted,
+		UnreadCount: c.UnreadCount,
+	}
+	if c.LastMessageAt.Valid {
+		resp.LastMessageAt = &c.LastMessageAt.Time
+	}
+	for _, m := range c.Members {
+		resp.Members = append(resp.Members, types.ConversationMember{
+			UserID:            m.UserID,
+			LastReadMessageID: m.LastReadID,
+		})
+	}
+	return resp
+}
+
+func messageResponse(m database.Message) types.Message {
+	return types.Message{
+		ID:             m.ID,
+		ConversationID: m.ConversationID,
+		SenderID:       m.SenderID,
+		Body:           m.Body,
+		CreatedAt:      m.CreatedAt,
+	}
+}
+
+// conversationID reads the conversation ID from the path.
+func conversationID(r *http.Request) (uuid.UUID, error) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		return uuid.Nil, invalidUUID("id", err)
+	}
+	return id, nil
+}
+
+// ConversationsCreate starts a conversation, or returns the existing one
+// with a 200 if it's between two users who already have one.
+func (h *Handler) ConversationsCreate(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		MemberIDs []uuid.UUID `json:"member_ids"`
+	}
+
+	userID, err := h.authenticate(r)
+	if err != nil {
+		utils.RespondWithError(w, r, err)
+		return
+	}
+
+	params := parameters{}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		utils.RespondWithError(w, r, problem.InvalidJSON(err))
+		return
+	}
+
+	conv, created, err := h.config.Service.CreateConversation(r.Context(), userID, params.MemberIDs)
+	if err != nil {
+		utils.RespondWithError(w, r, err)
+		return
+	}
+
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	utils.RespondWithJSON(w, status, conversationResponse(conv))
+}
+
+func (h *Handler) ConversationsList(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.authenticate(r)
+	if err != nil {
+		utils.RespondWithError(w, r, err)
+		return
+	}
+
+	convs, err := h.config.Service.ListConversations(r.Context(), userID)
+	if err != nil {
+		utils.RespondWithError(w, r, err)
+		return
+	}
+
+	resp := []types.Conversation{}
+	for _, c := range convs {
+		resp = append(resp, conversationResponse(c))
+	}
+	utils.RespondWithJSON(w, http.StatusOK, resp)
+}
+
+func (h *Handler) ConversationsGet(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.authenticate(r)
+	if err != nil {
+		utils.RespondWithError(w, r, err)
+		return
+	}
+
+	id, err := conversationID(r)
+	if err != nil {
+		utils.RespondWithError(w, r, err)
+		return
+	}
+
+	conv, err := h.config.Service.GetConversation(r.Context(), userID, id)
+	if err != nil {
+		utils.RespondWithError(w, r, err)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, conversationResponse(conv))
+}
+
+func (h *Handler) MessagesCreate(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Body string `json:"body"`
+	}
+
+	userID, err := h.authenticate(r)
+	if err != nil {
+		utils.RespondWithError(w, r, err)
+		return
+	}
+
+	id, err := conversationID(r)
+	if err != nil {
+		utils.RespondWithError(w, r, err)
+		return
+	}
+
+	params := parameters{}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		utils.RespondWithError(w, r, problem.InvalidJSON(err))
+		return
+	}
+
+	msg, err := h.config.Service.SendMessage(r.Context(), userID, id, params.Body)
+	if err != nil {
+		utils.RespondWithError(w, r, err)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusCreated, messageResponse(msg))
+}
+
+func (h *Handler) MessagesList(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.authenticate(r)
+	if err != nil {
+		utils.RespondWithError(w, r, err)
+		return
+	}
+
+	id, err := conversationID(r)
+	if err != nil {
+		utils.RespondWithError(w, r, err)
+		return
+	}
+
+	before, limit, fields := pageParams(r)
+	if len(fields) > 0 {
+		utils.RespondWithError(w, r, problem.Validation(fields...))
+		return
+	}
+
+	page, err := h.config.Service.ListMessages(r.Context(), userID, id, service.ListMessagesParams{Before: before, Limit: limit})
+	if err != nil {
+		utils.RespondWithError(w, r, err)
+		return
+	}
+
+	resp := types.MessagePage{Messages: []types.Message{}}
+	for _, m := range page.Messages {
+		resp.Messages = append(resp.Messages, messageResponse(m))
+	}
+	if page.Next != 0 {
+		cursor := strconv.FormatInt(page.Next, 10)
+		resp.NextCursor = &cursor
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, resp)
+}
+
+func (h *Handler) MessagesDelete(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.authenticate(r)
+	if err != nil {
+		utils.RespondWithError(w, r, err)
+		return
+	}
+
+	id, err := conversationID(r)
+	if err != nil {
+		utils.RespondWithError(w, r, err)
+		return
+	}
+
+	messageID, err := strconv.ParseInt(r.PathValue("message_id"), 10, 64)
+	if err != nil {
+		utils.RespondWithError(w, r, problem.Validation(problem.FieldError{Field: "message_id", Code: "invalid_id", Message: "must be a message ID"}).WithCause(err))
+		return
+	}
+
+	if err := h.config.Service.DeleteMessage(r.Context(), userID, id, messageID); err != nil {
+		utils.RespondWithError(w, r, err)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusNoContent, nil)
+}
+
+// ConversationsMarkRead marks a conversation read up to the message_id in
+// the body, or all of it if there's no body.
+func (h *Handler) ConversationsMarkRead(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		MessageID int64 `json:"message_id"`
+	}
+
+	userID, err := h.authenticate(r)
+	if err != nil {
+		utils.RespondWithError(w, r, err)
+		return
+	}
+
+	id, err := conversationID(r)
+	if err != nil {
+		utils.RespondWithError(w, r, err)
+		return
+	}
+
+	params := parameters{}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil && !errors.Is(err, io.EOF) {
+		utils.RespondWithError(w, r, problem.InvalidJSON(err))
+		return
+	}
+
+	if err := h.config.Service.MarkConversationRead(r.Context(), userID, id, params.MessageID); err != nil {
+		utils.RespondWithError(w, r, err)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusNoContent, nil)
+}
+
+func (h *Handler) ConversationsMute(w http.ResponseWriter, r *http.Request) {
+	h.applyToPathID(w, r, h.config.Service.MuteConversation)
+}
+
+func (h *Handler) ConversationsUnmute(w http.ResponseWriter, r *http.Request) {
+	h.applyToPathID(w, r, h.config.Service.UnmuteConversation)
+}
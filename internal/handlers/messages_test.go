@@ -0,0 +1,215 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/HemahWeb/chirpy/internal/events"
+	"github.com/HemahWeb/chirpy/internal/problem"
+	"github.com/HemahWeb/chirpy/internal/types"
+)
+
+// startConversation has user start a conversation with members.
+func (s *testServer) startConversation(user session, status int, members ...session) types.Conversation {
+	s.t.Helper()
+	ids := []uuid.UUID{}
+	for _, m := range members {
+		ids = append(ids, m.ID)
+	}
+	rec := s.do(http.MethodPost, "/api/conversations", map[string]any{"member_ids": ids}, user.bearer())
+	expectStatus(s.t, rec, status)
+	return decode[types.Conversation](s.t, rec)
+}
+
+// sendMessage has user send body to conversation id.
+func (s *testServer) sendMessage(user session, id uuid.UUID, body string) types.Message {
+	s.t.Helper()
+	rec := s.do(http.MethodPost, "/api/conversations/"+id.String()+"/messages", map[string]any{"body": body}, user.bearer())
+	expectStatus(s.t, rec, http.StatusCreated)
+	return decode[types.Message](s.t, rec)
+}
+
+// conversation fetches conversation id as user sees it.
+func (s *testServer) conversation(user session, id uuid.UUID) types.Conversation {
+	s.t.Helper()
+	rec := s.do(http.MethodGet, "/api/conversations/"+id.String(), nil, user.bearer())
+	expectStatus(s.t, rec, http.StatusOK)
+	return decode[types.Conversation](s.t, rec)
+}
+
+func TestConversations(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, s *testServer) {
+		walt := s.signUp("walt@breakingbad.com")
+		jesse := s.signUp("jesse@breakingbad.com")
+		skyler := s.signUp("skyler@breakingbad.com")
+
+		direct := s.startConversation(walt, http.StatusCreated, jesse)
+		if len(direct.Members) != 2 || direct.LastMessageAt != nil || direct.UnreadCount != 0 {
+			t.Errorf("new conversation = %+v, want walt and jesse and no messages", direct)
+		}
+		// there's only one conversation between two people, whoever starts it
+		if again := s.startConversation(jesse, http.StatusOK, walt, jesse); again.ID != direct.ID {
+			t.Errorf("jesse started %s, want the existing %s", again.ID, direct.ID)
+		}
+		group := s.startConversation(skyler, http.StatusCreated, walt, jesse)
+		if len(group.Members) != 3 {
+			t.Errorf("group members = %+v, want 3", group.Members)
+		}
+
+		s.sendMessage(walt, direct.ID, "We need to cook")
+		rec := s.do(http.MethodGet, "/api/conversations", nil, jesse.bearer())
+		expectStatus(t, rec, http.StatusOK)
+		convs := decode[[]types.Conversation](t, rec)
+		if len(convs) != 2 || convs[0].ID != direct.ID || convs[0].UnreadCount != 1 || convs[0].LastMessageAt == nil {
+			t.Errorf("jesse's conversations = %+v, want the direct one first with 1 unread", convs)
+		}
+		if convs[1].ID != group.ID || convs[1].UnreadCount != 0 {
+			t.Errorf("jesse's second conversation = %+v, want the group with none unread", convs[1])
+		}
+
+		expectProblem(t, s.do(http.MethodGet, "/api/conversations/"+direct.ID.String(), nil, skyler.bearer()), http.StatusNotFound, problem.CodeNotFound)
+		expectProblem(t, s.do(http.MethodGet, "/api/conversations/"+uuid.NewString(), nil, walt.bearer()), http.StatusNotFound, problem.CodeNotFound)
+		expectProblem(t, s.do(http.MethodGet, "/api/conversations/heisenberg", nil, walt.bearer()), http.StatusBadRequest, problem.CodeValidationFailed)
+
+		p := expectProblem(t, s.do(http.MethodPost, "/api/conversations", map[string]any{"member_ids": []uuid.UUID{walt.ID}}, walt.bearer()), http.StatusBadRequest, problem.CodeValidationFailed)
+		if len(p.Errors) != 1 || p.Errors[0].Code != "required" {
+			t.Errorf("talking to yourself = %+v, want member_ids required", p.Errors)
+		}
+		p = expectProblem(t, s.do(http.MethodPost, "/api/conversations", map[string]any{"member_ids": []uuid.UUID{uuid.New()}}, walt.bearer()), http.StatusBadRequest, problem.CodeValidationFailed)
+		if len(p.Errors) != 1 || p.Errors[0].Code != "not_found" {
+			t.Errorf("unknown member = %+v, want member_ids not_found", p.Errors)
+		}
+		expectProblem(t, s.do(http.MethodPost, "/api/conversations", "{", walt.bearer()), http.StatusBadRequest, problem.CodeInvalidJSON)
+		expectProblem(t, s.do(http.MethodGet, "/api/conversations", nil, ""), http.StatusUnauthorized, problem.CodeUnauthenticated)
+	})
+}
+
+func TestMessages(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, s *testServer) {
+		walt := s.signUp("walt@breakingbad.com")
+		jesse := s.signUp("jesse@breakingbad.com")
+		skyler := s.signUp("skyler@breakingbad.com")
+		conv := s.startConversation(walt, http.StatusCreated, jesse)
+		messagesPath := "/api/conversations/" + conv.ID.String() + "/messages"
+
+		first := s.sendMessage(walt, conv.ID, "Jesse, we need to cook")
+		s.sendMessage(jesse, conv.ID, "Yeah, science!")
+		last := s.sendMessage(walt, conv.ID, "Bring the van")
+		if last.SenderID != walt.ID || last.ConversationID != conv.ID || last.ID <= first.ID {
+			t.Errorf("message = %+v, want walt's, after %d", last, first.ID)
+		}
+
+		rec := s.do(http.MethodGet, messagesPath+"?limit=2", nil, jesse.bearer())
+		expectStatus(t, rec, http.StatusOK)
+		page := decode[types.MessagePage](t, rec)
+		if len(page.Messages) != 2 || page.Messages[0].ID != last.ID || page.NextCursor == nil {
+			t.Fatalf("first page = %+v, want the 2 newest and a cursor", page)
+		}
+		rec = s.do(http.MethodGet, messagesPath+"?limit=2&cursor="+*page.NextCursor, nil, jesse.bearer())
+		expectStatus(t, rec, http.StatusOK)
+		if page := decode[types.MessagePage](t, rec); len(page.Messages) != 1 || page.Messages[0].ID != first.ID || page.NextCursor != nil {
+			t.Errorf("last page = %+v, want the first message and no cursor", page)
+		}
+
+		// read receipts: sending a message reads everything before it
+		if c := s.conversation(jesse, conv.ID); c.UnreadCount != 1 {
+			t.Errorf("jesse's unread = %d, want 1", c.UnreadCount)
+		}
+		readPath := "/api/conversations/" + conv.ID.String() + "/read"
+		expectStatus(t, s.do(http.MethodPost, readPath, map[string]any{"message_id": first.ID}, jesse.bearer()), http.StatusNoContent)
+		if c := s.conversation(jesse, conv.ID); c.UnreadCount != 1 {
+			t.Errorf("jesse's unread after rereading the first = %d, want still 1", c.UnreadCount)
+		}
+		expectStatus(t, s.do(http.MethodPost, readPath, nil, jesse.bearer()), http.StatusNoContent)
+		if c := s.conversation(jesse, conv.ID); c.UnreadCount != 0 {
+			t.Errorf("jesse's unread after reading all = %d, want 0", c.UnreadCount)
+		}
+		c := s.conversation(walt, conv.ID)
+		for _, m := range c.Members {
+			if m.LastReadMessageID != last.ID {
+				t.Errorf("member %+v, want everything read", m)
+			}
+		}
+		expectProblem(t, s.do(http.MethodPost, readPath, map[string]any{"message_id": last.ID + 100}, jesse.bearer()), http.StatusBadRequest, problem.CodeValidationFailed)
+		expectProblem(t, s.do(http.MethodPost, readPath, nil, skyler.bearer()), http.StatusNotFound, problem.CodeNotFound)
+
+		deletePath := messagesPath + "/" + strconv.FormatInt(first.ID, 10)
+		expectProblem(t, s.do(http.MethodDelete, deletePath, nil, jesse.bearer()), http.StatusForbidden, problem.CodeForbidden)
+		expectProblem(t, s.do(http.MethodDelete, deletePath, nil, skyler.bearer()), http.StatusNotFound, problem.CodeNotFound)
+		expectStatus(t, s.do(http.MethodDelete, deletePath, nil, walt.bearer()), http.StatusNoContent)
+		expectProblem(t, s.do(http.MethodDelete, deletePath, nil, walt.bearer()), http.StatusNotFound, problem.CodeNotFound)
+		expectProblem(t, s.do(http.MethodDelete, messagesPath+"/first", nil, walt.bearer()), http.StatusBadRequest, problem.CodeValidationFailed)
+
+		expectProblem(t, s.do(http.MethodPost, messagesPath, map[string]any{"body": "  "}, walt.bearer()), http.StatusBadRequest, problem.CodeValidationFailed)
+		expectProblem(t, s.do(http.MethodPost, messagesPath, map[string]any{"body": strings.Repeat("a", 1001)}, walt.bearer()), http.StatusBadRequest, problem.CodeValidationFailed)
+		expectProblem(t, s.do(http.MethodPost, messagesPath, map[string]any{"body": "Let me in"}, skyler.bearer()), http.StatusNotFound, problem.CodeNotFound)
+		expectProblem(t, s.do(http.MethodGet, messagesPath, nil, skyler.bearer()), http.StatusNotFound, problem.CodeNotFound)
+		expectProblem(t, s.do(http.MethodGet, messagesPath+"?cursor=soon", nil, walt.bearer()), http.StatusBadRequest, problem.CodeValidationFailed)
+
+		// a block in either direction stops new messages
+		expectStatus(t, s.do(http.MethodPost, "/api/users/"+walt.ID.String()+"/block", nil, jesse.bearer()), http.StatusNoContent)
+		expectProblem(t, s.do(http.MethodPost, messagesPath, map[string]any{"body": "Jesse?"}, walt.bearer()), http.StatusForbidden, problem.CodeForbidden)
+		expectProblem(t, s.do(http.MethodPost, messagesPath, map[string]any{"body": "Leave me alone"}, jesse.bearer()), http.StatusForbidden, problem.CodeForbidden)
+		expectProblem(t, s.do(http.MethodPost, "/api/conversations", map[string]any{"member_ids": []uuid.UUID{jesse.ID, skyler.ID}}, walt.bearer()), http.StatusForbidden, problem.CodeForbidden)
+	})
+}
+
+func TestMessagesLive(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, s *testServer) {
+		walt := s.signUp("walt@breakingbad.com")
+		jesse := s.signUp("jesse@breakingbad.com")
+		skyler := s.signUp("skyler@breakingbad.com")
+		conv := s.startConversation(walt, http.StatusCreated, jesse, skyler)
+		mutePath := "/api/conversations/" + conv.ID.String() + "/mute"
+
+		var clients []*wsClient
+		for _, user := range []session{walt, jesse, skyler} {
+			ws := s.dial(user.bearer())
+			ws.next("ready")
+			ws.send(wsClientMessage{Type: "subscribe", Topic: "messages"})
+			ws.next("subscribed")
+			clients = append(clients, ws)
+		}
+		waltWS, jesseWS, skylerWS := clients[0], clients[1], clients[2]
+
+		expectStatus(t, s.do(http.MethodPost, mutePath, nil, skyler.bearer()), http.StatusNoContent)
+		if c := s.conversation(skyler, conv.ID); !c.Muted {
+			t.Errorf("skyler's conversation = %+v, want muted", c)
+		}
+		msg := s.sendMessage(walt, conv.ID, "Family meeting")
+		for _, ws := range []*wsClient{waltWS, jesseWS} {
+			e := ws.expectEvent(events.MessageCreated, "messages")
+			var m events.Message
+			if err := json.Unmarshal(e.Data, &m); err != nil || m.ID != msg.ID || m.Body != msg.Body {
+				t.Errorf("message event = %+v, %v; want %+v", m, err, msg)
+			}
+		}
+		// muted conversations still count as unread
+		if c := s.conversation(skyler, conv.ID); c.UnreadCount != 1 {
+			t.Errorf("skyler's unread = %d, want 1", c.UnreadCount)
+		}
+
+		expectStatus(t, s.do(http.MethodDelete, mutePath, nil, skyler.bearer()), http.StatusNoContent)
+		msg = s.sendMessage(jesse, conv.ID, "Can't make it")
+		for _, ws := range []*wsClient{waltWS, jesseWS} {
+			ws.expectEvent(events.MessageCreated, "messages")
+		}
+		// skyler wasn't sent walt's message while muted, so this is jesse's
+		var m events.Message
+		if err := json.Unmarshal(skylerWS.expectEvent(events.MessageCreated, "messages").Data, &m); err != nil || m.ID != msg.ID {
+			t.Errorf("skyler's first message event = %+v, %v; want %d", m, err, msg.ID)
+		}
+		expectStatus(t, s.do(http.MethodDelete, "/api/conversations/"+conv.ID.String()+"/messages/"+strconv.FormatInt(msg.ID, 10), nil, jesse.bearer()), http.StatusNoContent)
+		for _, ws := range clients {
+			ws.expectEvent(events.MessageDeleted, "messages")
+		}
+
+		outsider := s.signUp("hank@dea.gov")
+		expectProblem(t, s.do(http.MethodPost, mutePath, nil, outsider.bearer()), http.StatusNotFound, problem.CodeNotFound)
+	})
+}
@@ -1,18 +1,28 @@
 package handlers
 
 import (
+	"crypto/subtle"
 	"fmt"
 	"net/http"
+
+	"github.com/HemahWeb/chirpy/internal/auth"
+	"github.com/HemahWeb/chirpy/internal/problem"
+	"github.com/HemahWeb/chirpy/internal/utils"
 )
 
 func (h *Handler) MiddlewareMetricsInc(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		h.config.FileserverHits.Add(1)
+		h.config.Metrics.FileserverHits.Inc()
 		next.ServeHTTP(w, r)
 	})
 }
 
 func (h *Handler) MetricsView(w http.ResponseWriter, r *http.Request) {
+	var tokensRemoved int64
+	if h.config.TokenCleaner != nil {
+		tokensRemoved = h.config.TokenCleaner.Stats().RowsRemoved
+	}
+
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	w.WriteHeader(http.StatusOK)
 	fmt.Fprintf(w, `
@@ -20,7 +30,30 @@ func (h *Handler) MetricsView(w http.ResponseWriter, r *http.Request) {
 			<body>
 				<h1>Welcome, Chirpy Admin</h1>
 				<p>Chirpy has been visited %d times!</p>
+				<p>%d stale refresh tokens have been cleaned up.</p>
 			</body>
 		</html>`,
-		h.config.FileserverHits.Load())
+		h.config.Metrics.Hits(), tokensRemoved)
+}
+
+// PrometheusMetrics serves the scrape endpoint to callers with METRICS_TOKEN
+// as their bearer token or, when it isn't set, only on the dev platform:
+// route names, pool stats and webhook counts aren't for everyone.
+func (h *Handler) PrometheusMetrics(w http.ResponseWriter, r *http.Request) {
+	if h.config.MetricsToken == "" {
+		if !h.devPlatformOnly(w, r) {
+			return
+		}
+	} else {
+		token, err := auth.GetBearerToken(r.Header)
+		if err != nil {
+			utils.RespondWithError(w, r, err)
+			return
+		}
+		if subtle.ConstantTimeCompare([]byte(token), []byte(h.config.MetricsToken)) != 1 {
+			utils.RespondWithError(w, r, problem.New(http.StatusUnauthorized, problem.CodeInvalidToken, "Invalid metrics token"))
+			return
+		}
+	}
+	h.config.Metrics.Handler().ServeHTTP(w, r)
 }
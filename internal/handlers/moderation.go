@@ -0,0 +1,480 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/HemahWeb/chirpy/internal/database"
+	"github.com/HemahWeb/chirpy/internal/problem"
+	"github.com/HemahWeb/chirpy/internal/service"
+	"github.com/HemahWeb/chirpy/internal/types"
+	"github.com/HemahWeb/chirpy/internal/utils"
+)
+
+func reportResponse(r database.Report) types.Report {
+	resp := types.Report{
+		ID:         r.ID,
+		CreatedAt:  r.CreatedAt,
+		ReporterID: r.ReporterID,
+		UserID:     r.UserID,
+		Reason:     r.Reason,
+		Details:    r.Details,
+		Status:     r.Status,
+	}
+	if r.ChirpID.Valid {
+		resp.ChirpID = &r.ChirpID.UUID
+	}
+	if r.ClaimedBy.Valid {
+		resp.ClaimedBy = &r.ClaimedBy.UUID
+	}
+	if r.ClaimedAt.Valid {
+		resp.ClaimedAt = &r.ClaimedAt.Time
+	}
+	if r.Resolution.Valid {
+		resp.Resolution = &r.Resolution.String
+	}
+	if r.ResolvedAt.Valid {
+		resp.ResolvedAt = &r.ResolvedAt.Time
+	}
+	return resp
+}
+
+func moderationActionResponse(a database.ModerationAction) types.ModerationAction {
+	resp := types.ModerationAction{
+		ID:          a.ID,
+		CreatedAt:   a.CreatedAt,
+		ModeratorID: a.ModeratorID,
+		Action:      a.Action,
+		UserID:      a.UserID,
+		Note:        a.Note,
+	}
+	if a.ReportID.Valid {
+		resp.ReportID = &a.ReportID.Int64
+	}
+	if a.AppealID.Valid {
+		resp.AppealID = &a.AppealID.Int64
+	}
+	if a.ChirpID.Valid {
+		resp.ChirpID = &a.ChirpID.UUID
+	}
+	return resp
+}
+
+func appealResponse(a database.Appeal) types.Appeal {
+	resp := types.Appeal{
+		ID:        a.ID,
+		CreatedAt: a.CreatedAt,
+		ActionID:  a.ActionID,
+		UserID:    a.UserID,
+		Body:      a.Body,
+		Status:    a.Status,
+	}
+	if a.DecidedBy.Valid {
+		resp.DecidedBy = &a.DecidedBy.UUID
+	}
+	if a.DecidedAt.Valid {
+		resp.DecidedAt = &a.DecidedAt.Time
+	}
+	return resp
+}
+
+// int64ID reads a numeric ID from the path; what names it in the error.
+func int64ID(r *http.Request, what string) (int64, error) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		return 0, problem.Validation(problem.FieldError{Field: "id", Code: "invalid_id", Message: "must be " + what}).WithCause(err)
+	}
+	return id, nil
+}
+
+// statusPageParams reads the status, cursor and limit query parameters of
+// a moderation list. status defaults to open and must be one of statuses.
+func statusPageParams(r *http.Request, statuses ...string) (status string, cursor int64, limit int32, err error) {
+	cursor, limit, fields := pageParams(r)
+	status = r.URL.Query().Get("status")
+	if status == "" {
+		status = statuses[0]
+	}
+	if !slices.Contains(statuses, status) {
+		fields = append(fields, problem.FieldError{Field: "status", Code: "invalid_value", Message: "must be one of " + strings.Join(statuses, ", ")})
+	}
+	if len(fields) > 0 {
+		return "", 0, 0, problem.Validation(fields...)
+	}
+	return status, cursor, limit, nil
+}
+
+func (h *Handler) ReportsCreate(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		ChirpID uuid.UUID `json:"chirp_id"`
+		UserID  uuid.UUID `json:"user_id"`
+		Reason  string    `json:"reason"`
+		Details string    `json:"details"`
+	}
+
+	userID, err := h.authenticate(r)
+	if err != nil {
+		utils.RespondWithError(w, r, err)
+		return
+	}
+
+	params := parameters{}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		utils.RespondWithError(w, r, problem.InvalidJSON(err))
+		return
+	}
+
+	report, err := h.config.Service.Report(r.Context(), userID, service.ReportParams(params))
+	if err != nil {
+		utils.RespondWithError(w, r, err)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusCreated, reportResponse(report))
+}
+
+func (h *Handler) SanctionsList(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.authenticateAllowSuspended(r)
+	if err != nil {
+		utils.RespondWithError(w, r, err)
+		return
+	}
+
+	sanctions, err := h.config.Service.ListSanctions(r.Context(), userID)
+	if err != nil {
+		utils.RespondWithError(w, r, err)
+		return
+	}
+
+	resp := []types.Sanction{}
+	for _, s := range sanctions {
+		sanction := types.Sanction{
+			ID:        s.ID,
+			CreatedAt: s.CreatedAt,
+			Action:    s.Action,
+			Note:      s.Note,
+		}
+		if s.ChirpID.Valid {
+			sanction.ChirpID = &s.ChirpID.UUID
+		}
+		if s.Reason.Valid {
+			sanction.Reason = &s.Reason.String
+		}
+		if s.AppealStatus.Valid {
+			sanction.AppealStatus = &s.AppealStatus.String
+		}
+		resp = append(resp, sanction)
+	}
+	utils.RespondWithJSON(w, http.StatusOK, resp)
+}
+
+func (h *Handler) SanctionsAppeal(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Body string `json:"body"`
+	}
+
+	userID, err := h.authenticateAllowSuspended(r)
+	if err != nil {
+		utils.RespondWithError(w, r, err)
+		return
+	}
+
+	id, err := int64ID(r, "a sanction ID")
+	if err != nil {
+		utils.RespondWithError(w, r, err)
+		return
+	}
+
+	params := parameters{}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		utils.RespondWithError(w, r, problem.InvalidJSON(err))
+		return
+	}
+
+	appeal, err := h.config.Service.Appeal(r.Context(), userID, id, params.Body)
+	if err != nil {
+		utils.RespondWithError(w, r, err)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusCreated, appealResponse(appeal))
+}
+
+// ModerationReportsList returns the moderation queue: reports with the
+// status query parameter, open by default, oldest first.
+func (h *Handler) ModerationReportsList(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.authenticate(r)
+	if err != nil {
+		utils.RespondWithError(w, r, err)
+		return
+	}
+
+	status, after, limit, err := statusPageParams(r, service.ReportOpen, service.ReportClaimed, service.ReportResolved)
+	if err != nil {
+		utils.RespondWithError(w, r, err)
+		return
+	}
+
+	page, err := h.config.Service.ListReports(r.Context(), userID, service.ListReportsParams{Status: status, After: after, Limit: limit})
+	if err != nil {
+		utils.RespondWithError(w, r, err)
+		return
+	}
+
+	resp := types.ReportPage{Reports: []types.Report{}}
+	for _, report := range page.Reports {
+		resp.Reports = append(resp.Reports, reportResponse(report))
+	}
+	if page.Next != 0 {
+		cursor := strconv.FormatInt(page.Next, 10)
+		resp.NextCursor = &cursor
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, resp)
+}
+
+func (h *Handler) ModerationReportsClaim(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.authenticate(r)
+	if err != nil {
+		utils.RespondWithError(w, r, err)
+		return
+	}
+
+	id, err := int64ID(r, "a report ID")
+	if err != nil {
+		utils.RespondWithError(w, r, err)
+		return
+	}
+
+	report, err := h.config.Service.ClaimReport(r.Context(), userID, id)
+	if err != nil {
+		utils.RespondWithError(w, r, err)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, reportResponse(report))
+}
+
+func (h *Handler) ModerationReportsResolve(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Action string    `json:"action"`
+		Until  time.Time `json:"until"`
+		Note   string    `json:"note"`
+	}
+
+	userID, err := h.authenticate(r)
+	if err != nil {
+		utils.RespondWithError(w, r, err)
+		return
+	}
+
+	id, err := int64ID(r, "a report ID")
+	if err != nil {
+		utils.RespondWithError(w, r, err)
+		return
+	}
+
+	params := parameters{}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		utils.RespondWithError(w, r, problem.InvalidJSON(err))
+		return
+	}
+
+	report, err := h.config.Service.ResolveReport(r.Context(), userID, id, service.ResolveReportParams(params))
+	if err != nil {
+		utils.RespondWithError(w, r, err)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, reportResponse(report))
+}
+
+// ModerationActionsList returns the moderation log, newest first.
+func (h *Handler) ModerationActionsList(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.authenticate(r)
+	if err != nil {
+		utils.RespondWithError(w, r, err)
+		return
+	}
+
+	before, limit, fields := pageParams(r)
+	if len(fields) > 0 {
+		utils.RespondWithError(w, r, problem.Validation(fields...))
+		return
+	}
+
+	page, err := h.config.Service.ListModerationActions(r.Context(), userID, service.ListModerationActionsParams{Before: before, Limit: limit})
+	if err != nil {
+		utils.RespondWithError(w, r, err)
+		return
+	}
+
+	resp := types.ModerationActionPage{Actions: []types.ModerationAction{}}
+	for _, a := range page.Actions {
+		resp.Actions = append(resp.Actions, moderationActionResponse(a))
+	}
+	if page.Next != 0 {
+		cursor := strconv.FormatInt(page.Next, 10)
+		resp.NextCursor = &cursor
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, resp)
+}
+
+// ModerationAppealsList returns appeals with the status query parameter,
+// open by default, oldest first.
+func (h *Handler) ModerationAppealsList(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.authenticate(r)
+	if err != nil {
+		utils.RespondWithError(w, r, err)
+		return
+	}
+
+	status, after, limit, err := statusPageParams(r, service.AppealOpen, service.AppealUpheld, service.AppealOverturned)
+	if err != nil {
+		utils.RespondWithError(w, r, err)
+		return
+	}
+
+	page, err := h.config.Service.ListAppeals(r.Context(), userID, service.ListAppealsParams{Status: status, After: after, Limit: limit})
+	if err != nil {
+		utils.RespondWithError(w, r, err)
+		return
+	}
+
+	resp := types.AppealPage{Appeals: []types.Appeal{}}
+	for _, a := range page.Appeals {
+		resp.Appeals = append(resp.Appeals, appealResponse(a))
+	}
+	if page.Next != 0 {
+		cursor := strconv.FormatInt(page.Next, 10)
+		resp.NextCursor = &cursor
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, resp)
+}
+
+func (h *Handler) ModerationAppealsDecide(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Decision string `json:"decision"`
+		Note     string `json:"note"`
+	}
+
+	userID, err := h.authenticate(r)
+	if err != nil {
+		utils.RespondWithError(w, r, err)
+		return
+	}
+
+	id, err := int64ID(r, "an appeal ID")
+	if err != nil {
+		utils.RespondWithError(w, r, err)
+		return
+	}
+
+	params := parameters{}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		utils.RespondWithError(w, r, problem.InvalidJSON(err))
+		return
+	}
+	if params.Decision != "uphold" && params.Decision != "overturn" {
+		utils.RespondWithError(w, r, problem.Validation(problem.FieldError{Field: "decision", Code: "invalid_value", Message: "must be uphold or overturn"}))
+		return
+	}
+
+	appeal, err := h.config.Service.DecideAppeal(r.Context(), userID, id, params.Decision == "overturn", params.Note)
+	if err != nil {
+		utils.RespondWithError(w, r, err)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, appealResponse(appeal))
+}
+
+// ModerationUsersSuspend suspends a user without a report. The body is
+// optional: without an until, the suspension lasts until it's lifted.
+func (h *Handler) ModerationUsersSuspend(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Until time.Time `json:"until"`
+		Note  string    `json:"note"`
+	}
+
+	moderatorID, err := h.authenticate(r)
+	if err != nil {
+		utils.RespondWithError(w, r, err)
+		return
+	}
+
+	userID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		utils.RespondWithError(w, r, invalidUUID("id", err))
+		return
+	}
+
+	params := parameters{}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil && !errors.Is(err, io.EOF) {
+		utils.RespondWithError(w, r, problem.InvalidJSON(err))
+		return
+	}
+
+	if err := h.config.Service.SuspendUser(r.Context(), moderatorID, userID, service.SuspendUserParams(params)); err != nil {
+		utils.RespondWithError(w, r, err)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusNoContent, nil)
+}
+
+func (h *Handler) ModerationUsersUnsuspend(w http.ResponseWriter, r *http.Request) {
+	h.moderateUser(w, r, h.config.Service.UnsuspendUser)
+}
+
+func (h *Handler) ModerationUsersLimit(w http.ResponseWriter, r *http.Request) {
+	h.moderateUser(w, r, h.config.Service.LimitUser)
+}
+
+func (h *Handler) ModerationUsersUnlimit(w http.ResponseWriter, r *http.Request) {
+	h.moderateUser(w, r, h.config.Service.UnlimitUser)
+}
+
+// moderateUser has the signed-in moderator act on the user with the path
+// ID. The body is optional: a note for the action log.
+func (h *Handler) moderateUser(w http.ResponseWriter, r *http.Request, act func(ctx context.Context, moderatorID, userID uuid.UUID, note string) error) {
+	type parameters struct {
+		Note string `json:"note"`
+	}
+
+	moderatorID, err := h.authenticate(r)
+	if err != nil {
+		utils.RespondWithError(w, r, err)
+		return
+	}
+
+	userID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		utils.RespondWithError(w, r, invalidUUID("id", err))
+		return
+	}
+
+	params := parameters{}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil && !errors.Is(err, io.EOF) {
+		utils.RespondWithError(w, r, problem.InvalidJSON(err))
+		return
+	}
+
+	if err := act(r.Context(), moderatorID, userID, params.Note); err != nil {
+		utils.RespondWithError(w, r, err)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusNoContent, nil)
+}
@@ -0,0 +1,361 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/HemahWeb/chirpy/internal/problem"
+	"github.com/HemahWeb/chirpy/internal/types"
+)
+
+// moderator signs up a user and makes them a moderator.
+func (s *testServer) moderator(email string) session {
+	s.t.Helper()
+	user := s.signUp(email)
+	if err := s.db.AddModerator(context.Background(), user.ID); err != nil {
+		s.t.Fatalf("AddModerator() failed: %v", err)
+	}
+	return user
+}
+
+// report files a report as user and returns it.
+func (s *testServer) report(user session, body map[string]any) types.Report {
+	s.t.Helper()
+	rec := s.do(http.MethodPost, "/api/reports", body, user.bearer())
+	expectStatus(s.t, rec, http.StatusCreated)
+	return decode[types.Report](s.t, rec)
+}
+
+// queue fetches the reports a moderator sees with a query string.
+func (s *testServer) queue(mod session, query string) types.ReportPage {
+	s.t.Helper()
+	rec := s.do(http.MethodGet, "/api/moderation/reports"+query, nil, mod.bearer())
+	expectStatus(s.t, rec, http.StatusOK)
+	return decode[types.ReportPage](s.t, rec)
+}
+
+// claimAndResolve takes a report through the moderation workflow.
+func (s *testServer) claimAndResolve(mod session, reportID int64, action, note string) types.Report {
+	s.t.Helper()
+	path := fmt.Sprintf("/api/moderation/reports/%d/", reportID)
+	expectStatus(s.t, s.do(http.MethodPost, path+"claim", nil, mod.bearer()), http.StatusOK)
+	rec := s.do(http.MethodPost, path+"resolve", map[string]any{"action": action, "note": note}, mod.bearer())
+	expectStatus(s.t, rec, http.StatusOK)
+	return decode[types.Report](s.t, rec)
+}
+
+func TestReportsCreate(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, s *testServer) {
+		walt := s.signUp("walt@breakingbad.com")
+		jesse := s.signUp("jesse@breakingbad.com")
+		cook := s.postChirp(walt, "Say my name")
+
+		r := s.report(jesse, map[string]any{"chirp_id": cook.ID, "reason": "harassment", "details": "He's threatening people"})
+		if r.UserID != walt.ID || r.ChirpID == nil || *r.ChirpID != cook.ID || r.Status != "open" || r.ClaimedBy != nil {
+			t.Errorf("chirp report = %+v", r)
+		}
+		// a chirp report is separate from one about its author
+		r = s.report(jesse, map[string]any{"user_id": walt.ID, "reason": "impersonation"})
+		if r.UserID != walt.ID || r.ChirpID != nil {
+			t.Errorf("user report = %+v", r)
+		}
+
+		expectProblem(t, s.do(http.MethodPost, "/api/reports", map[string]any{"chirp_id": cook.ID, "reason": "spam"}, jesse.bearer()), http.StatusConflict, problem.CodeConflict)
+		expectProblem(t, s.do(http.MethodPost, "/api/reports", map[string]any{"chirp_id": cook.ID, "reason": "spam"}, walt.bearer()), http.StatusBadRequest, problem.CodeValidationFailed)
+		expectProblem(t, s.do(http.MethodPost, "/api/reports", map[string]any{"user_id": jesse.ID, "reason": "spam"}, jesse.bearer()), http.StatusBadRequest, problem.CodeValidationFailed)
+		for name, body := range map[string]map[string]any{
+			"neither":     {"reason": "spam"},
+			"both":        {"chirp_id": cook.ID, "user_id": walt.ID, "reason": "spam"},
+			"bad reason":  {"user_id": walt.ID, "reason": "rude"},
+			"no chirp":    {"chirp_id": uuid.New(), "reason": "spam"},
+			"no user":     {"user_id": uuid.New(), "reason": "spam"},
+			"long detail": {"user_id": walt.ID, "reason": "other", "details": string(make([]byte, 501))},
+		} {
+			p := expectProblem(t, s.do(http.MethodPost, "/api/reports", body, jesse.bearer()), http.StatusBadRequest, problem.CodeValidationFailed)
+			if len(p.Errors) == 0 {
+				t.Errorf("%s: problem has no field errors", name)
+			}
+		}
+		expectProblem(t, s.do(http.MethodPost, "/api/reports", "{", jesse.bearer()), http.StatusBadRequest, problem.CodeInvalidJSON)
+		expectProblem(t, s.do(http.MethodPost, "/api/reports", map[string]any{"user_id": walt.ID, "reason": "spam"}, ""), http.StatusUnauthorized, problem.CodeUnauthenticated)
+	})
+}
+
+func TestModerationReports(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, s *testServer) {
+		walt := s.signUp("walt@breakingbad.com")
+		jesse := s.signUp("jesse@breakingbad.com")
+		hank := s.moderator("hank@dea.gov")
+		gomez := s.moderator("gomez@dea.gov")
+		cook := s.postChirp(walt, "Say my name")
+		s.postChirp(walt, "I am the one who knocks")
+
+		chirpReport := s.report(jesse, map[string]any{"chirp_id": cook.ID, "reason": "violence"})
+		userReport := s.report(jesse, map[string]any{"user_id": walt.ID, "reason": "harassment"})
+		spamReport := s.report(walt, map[string]any{"user_id": jesse.ID, "reason": "spam"})
+
+		// only moderators see the queue
+		expectProblem(t, s.do(http.MethodGet, "/api/moderation/reports", nil, jesse.bearer()), http.StatusForbidden, problem.CodeForbidden)
+		expectProblem(t, s.do(http.MethodPost, fmt.Sprintf("/api/moderation/reports/%d/claim", chirpReport.ID), nil, jesse.bearer()), http.StatusForbidden, problem.CodeForbidden)
+		expectProblem(t, s.do(http.MethodGet, "/api/moderation/reports", nil, ""), http.StatusUnauthorized, problem.CodeUnauthenticated)
+
+		page := s.queue(hank, "?limit=2")
+		if len(page.Reports) != 2 || page.Reports[0].ID != chirpReport.ID || page.NextCursor == nil {
+			t.Fatalf("first page = %+v", page)
+		}
+		page = s.queue(hank, "?limit=2&cursor="+*page.NextCursor)
+		if len(page.Reports) != 1 || page.Reports[0].ID != spamReport.ID || page.NextCursor != nil {
+			t.Errorf("second page = %+v", page)
+		}
+		expectProblem(t, s.do(http.MethodGet, "/api/moderation/reports?status=closed", nil, hank.bearer()), http.StatusBadRequest, problem.CodeValidationFailed)
+
+		// claims are exclusive, and you must hold one to resolve
+		claimPath := fmt.Sprintf("/api/moderation/reports/%d/claim", chirpReport.ID)
+		resolvePath := fmt.Sprintf("/api/moderation/reports/%d/resolve", chirpReport.ID)
+		expectProblem(t, s.do(http.MethodPost, resolvePath, map[string]any{"action": "dismiss"}, hank.bearer()), http.StatusConflict, problem.CodeConflict)
+		rec := s.do(http.MethodPost, claimPath, nil, hank.bearer())
+		expectStatus(t, rec, http.StatusOK)
+		if r := decode[types.Report](t, rec); r.Status != "claimed" || r.ClaimedBy == nil || *r.ClaimedBy != hank.ID || r.ClaimedAt == nil {
+			t.Errorf("claimed report = %+v", r)
+		}
+		expectStatus(t, s.do(http.MethodPost, claimPath, nil, hank.bearer()), http.StatusOK)
+		expectProblem(t, s.do(http.MethodPost, claimPath, nil, gomez.bearer()), http.StatusConflict, problem.CodeConflict)
+		expectProblem(t, s.do(http.MethodPost, resolvePath, map[string]any{"action": "dismiss"}, gomez.bearer()), http.StatusConflict, problem.CodeConflict)
+		expectProblem(t, s.do(http.MethodPost, resolvePath, map[string]any{"action": "ban"}, hank.bearer()), http.StatusBadRequest, problem.CodeValidationFailed)
+		if page := s.queue(gomez, "?status=claimed"); len(page.Reports) != 1 || page.Reports[0].ID != chirpReport.ID {
+			t.Errorf("claimed queue = %+v", page)
+		}
+
+		// hiding the chirp takes it out of every listing
+		r := s.claimAndResolve(hank, chirpReport.ID, "hide_chirp", "Threat of violence")
+		if r.Status != "resolved" || r.Resolution == nil || *r.Resolution != "hide_chirp" || r.ResolvedAt == nil {
+			t.Errorf("resolved report = %+v", r)
+		}
+		expectProblem(t, s.do(http.MethodGet, "/api/chirps/"+cook.ID.String(), nil, ""), http.StatusNotFound, problem.CodeNotFound)
+		expectProblem(t, s.do(http.MethodPost, "/api/chirps/"+cook.ID.String()+"/like", nil, jesse.bearer()), http.StatusNotFound, problem.CodeNotFound)
+		if got := s.chirpAuthors(session{}); len(got) != 1 {
+			t.Errorf("chirps after hiding one = %v, want 1", got)
+		}
+		expectProblem(t, s.do(http.MethodPost, claimPath, nil, hank.bearer()), http.StatusConflict, problem.CodeConflict)
+
+		// a user report can't hide a chirp, but it can suspend them
+		userPath := fmt.Sprintf("/api/moderation/reports/%d/", userReport.ID)
+		expectStatus(t, s.do(http.MethodPost, userPath+"claim", nil, gomez.bearer()), http.StatusOK)
+		expectProblem(t, s.do(http.MethodPost, userPath+"resolve", map[string]any{"action": "hide_chirp"}, gomez.bearer()), http.StatusBadRequest, problem.CodeValidationFailed)
+		expectStatus(t, s.do(http.MethodPost, userPath+"resolve", map[string]any{"action": "suspend_user"}, gomez.bearer()), http.StatusOK)
+
+		// a suspended user is locked out: their tokens stop working, their
+		// refresh tokens are revoked and signing in again only gets them a
+		// token that can't do any of it either
+		expectProblem(t, s.do(http.MethodPost, "/api/chirps", map[string]any{"body": "Hello?"}, walt.bearer()), http.StatusForbidden, problem.CodeAccountSuspended)
+		expectProblem(t, s.do(http.MethodPost, "/api/users/"+jesse.ID.String()+"/follow", nil, walt.bearer()), http.StatusForbidden, problem.CodeAccountSuspended)
+		expectProblem(t, s.do(http.MethodGet, "/api/chirps", nil, walt.bearer()), http.StatusForbidden, problem.CodeAccountSuspended)
+		expectProblem(t, s.do(http.MethodPost, "/api/refresh", nil, walt.refreshBearer()), http.StatusUnauthorized, problem.CodeTokenRevoked)
+		suspended := s.signUpLogin(walt.Email)
+		if !suspended.Suspended || suspended.SuspendedUntil != nil || suspended.RefreshToken != "" {
+			t.Errorf("suspended login = %+v, want only an access token", suspended)
+		}
+		expectProblem(t, s.do(http.MethodPost, "/api/chirps", map[string]any{"body": "Hello?"}, suspended.bearer()), http.StatusForbidden, problem.CodeAccountSuspended)
+		expectStatus(t, s.do(http.MethodGet, "/api/chirps", nil, ""), http.StatusOK)
+
+		// dismissing changes nothing but the report
+		s.claimAndResolve(hank, spamReport.ID, "dismiss", "")
+		expectStatus(t, s.do(http.MethodPost, "/api/chirps", map[string]any{"body": "Yo"}, jesse.bearer()), http.StatusCreated)
+		if page := s.queue(hank, ""); len(page.Reports) != 0 {
+			t.Errorf("open queue after resolving everything = %+v", page)
+		}
+		if page := s.queue(hank, "?status=resolved"); len(page.Reports) != 3 {
+			t.Errorf("resolved queue = %+v, want 3 reports", page)
+		}
+
+		// every decision is in the log, newest first
+		expectProblem(t, s.do(http.MethodGet, "/api/moderation/actions", nil, jesse.bearer()), http.StatusForbidden, problem.CodeForbidden)
+		rec = s.do(http.MethodGet, "/api/moderation/actions?limit=2", nil, gomez.bearer())
+		expectStatus(t, rec, http.StatusOK)
+		log := decode[types.ModerationActionPage](t, rec)
+		if len(log.Actions) != 2 || log.Actions[0].Action != "dismiss" || log.Actions[1].Action != "suspend_user" || log.NextCursor == nil {
+			t.Fatalf("log = %+v", log)
+		}
+		rec = s.do(http.MethodGet, "/api/moderation/actions?cursor="+*log.NextCursor, nil, gomez.bearer())
+		expectStatus(t, rec, http.StatusOK)
+		log = decode[types.ModerationActionPage](t, rec)
+		if len(log.Actions) != 1 || log.Actions[0].Action != "hide_chirp" || log.Actions[0].ModeratorID != hank.ID ||
+			log.Actions[0].Note != "Threat of violence" || log.Actions[0].ReportID == nil || *log.Actions[0].ReportID != chirpReport.ID {
+			t.Errorf("oldest action = %+v", log.Actions)
+		}
+
+		expectProblem(t, s.do(http.MethodPost, "/api/moderation/reports/999/claim", nil, hank.bearer()), http.StatusNotFound, problem.CodeNotFound)
+		expectProblem(t, s.do(http.MethodPost, "/api/moderation/reports/abc/claim", nil, hank.bearer()), http.StatusBadRequest, problem.CodeValidationFailed)
+	})
+}
+
+func TestSanctionsAppeal(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, s *testServer) {
+		walt := s.signUp("walt@breakingbad.com")
+		jesse := s.signUp("jesse@breakingbad.com")
+		hank := s.moderator("hank@dea.gov")
+		gomez := s.moderator("gomez@dea.gov")
+		cook := s.postChirp(walt, "Say my name")
+		s.claimAndResolve(hank, s.report(jesse, map[string]any{"chirp_id": cook.ID, "reason": "violence"}).ID, "hide_chirp", "")
+		s.claimAndResolve(hank, s.report(jesse, map[string]any{"user_id": walt.ID, "reason": "harassment"}).ID, "suspend_user", "Repeated threats")
+
+		rec := s.do(http.MethodGet, "/api/sanctions", nil, walt.bearer())
+		expectStatus(t, rec, http.StatusOK)
+		sanctions := decode[[]types.Sanction](t, rec)
+		if len(sanctions) != 2 || sanctions[0].Action != "suspend_user" || sanctions[0].Reason == nil || *sanctions[0].Reason != "harassment" ||
+			sanctions[1].Action != "hide_chirp" || sanctions[1].ChirpID == nil || *sanctions[1].ChirpID != cook.ID || sanctions[1].AppealStatus != nil {
+			t.Fatalf("walt's sanctions = %+v", sanctions)
+		}
+		suspension, hiding := sanctions[0], sanctions[1]
+		appealPath := func(id int64) string { return fmt.Sprintf("/api/sanctions/%d/appeal", id) }
+
+		// suspended users can't do anything else, but they can still sign
+		// in to appeal, once per sanction, and only their own
+		walt = s.signUpLogin(walt.Email)
+		if !walt.Suspended || walt.RefreshToken != "" {
+			t.Errorf("suspended login = %+v, want only an access token", walt)
+		}
+		expectProblem(t, s.do(http.MethodGet, "/api/notifications", nil, walt.bearer()), http.StatusForbidden, problem.CodeAccountSuspended)
+		expectProblem(t, s.do(http.MethodPost, appealPath(hiding.ID), map[string]any{"body": "It was a quote"}, jesse.bearer()), http.StatusNotFound, problem.CodeNotFound)
+		expectProblem(t, s.do(http.MethodPost, appealPath(hiding.ID), map[string]any{"body": " "}, walt.bearer()), http.StatusBadRequest, problem.CodeValidationFailed)
+		rec = s.do(http.MethodPost, appealPath(hiding.ID), map[string]any{"body": "It was a quote"}, walt.bearer())
+		expectStatus(t, rec, http.StatusCreated)
+		hideAppeal := decode[types.Appeal](t, rec)
+		if hideAppeal.ActionID != hiding.ID || hideAppeal.Status != "open" || hideAppeal.DecidedBy != nil {
+			t.Errorf("appeal = %+v", hideAppeal)
+		}
+		expectProblem(t, s.do(http.MethodPost, appealPath(hiding.ID), map[string]any{"body": "Again"}, walt.bearer()), http.StatusConflict, problem.CodeConflict)
+		rec = s.do(http.MethodPost, appealPath(suspension.ID), map[string]any{"body": "I'll behave"}, walt.bearer())
+		expectStatus(t, rec, http.StatusCreated)
+		suspendAppeal := decode[types.Appeal](t, rec)
+
+		expectProblem(t, s.do(http.MethodGet, "/api/moderation/appeals", nil, jesse.bearer()), http.StatusForbidden, problem.CodeForbidden)
+		rec = s.do(http.MethodGet, "/api/moderation/appeals", nil, gomez.bearer())
+		expectStatus(t, rec, http.StatusOK)
+		if page := decode[types.AppealPage](t, rec); len(page.Appeals) != 2 || page.Appeals[0].ID != hideAppeal.ID {
+			t.Errorf("open appeals = %+v", page)
+		}
+
+		// the moderator who acted can't decide the appeal
+		decidePath := func(id int64) string { return fmt.Sprintf("/api/moderation/appeals/%d/decide", id) }
+		expectProblem(t, s.do(http.MethodPost, decidePath(hideAppeal.ID), map[string]any{"decision": "overturn"}, hank.bearer()), http.StatusForbidden, problem.CodeForbidden)
+		expectProblem(t, s.do(http.MethodPost, decidePath(hideAppeal.ID), map[string]any{"decision": "maybe"}, gomez.bearer()), http.StatusBadRequest, problem.CodeValidationFailed)
+		expectProblem(t, s.do(http.MethodPost, decidePath(999), map[string]any{"decision": "uphold"}, gomez.bearer()), http.StatusNotFound, problem.CodeNotFound)
+
+		// overturning restores the chirp
+		rec = s.do(http.MethodPost, decidePath(hideAppeal.ID), map[string]any{"decision": "overturn", "note": "Fair enough"}, gomez.bearer())
+		expectStatus(t, rec, http.StatusOK)
+		if a := decode[types.Appeal](t, rec); a.Status != "overturned" || a.DecidedBy == nil || *a.DecidedBy != gomez.ID || a.DecidedAt == nil {
+			t.Errorf("overturned appeal = %+v", a)
+		}
+		expectStatus(t, s.do(http.MethodGet, "/api/chirps/"+cook.ID.String(), nil, ""), http.StatusOK)
+		expectProblem(t, s.do(http.MethodPost, decidePath(hideAppeal.ID), map[string]any{"decision": "uphold"}, gomez.bearer()), http.StatusConflict, problem.CodeConflict)
+
+		// upholding keeps the suspension
+		expectStatus(t, s.do(http.MethodPost, decidePath(suspendAppeal.ID), map[string]any{"decision": "uphold"}, gomez.bearer()), http.StatusOK)
+		expectProblem(t, s.do(http.MethodPost, "/api/chirps", map[string]any{"body": "Hello?"}, walt.bearer()), http.StatusForbidden, problem.CodeAccountSuspended)
+
+		rec = s.do(http.MethodGet, "/api/sanctions", nil, walt.bearer())
+		expectStatus(t, rec, http.StatusOK)
+		sanctions = decode[[]types.Sanction](t, rec)
+		if *sanctions[0].AppealStatus != "upheld" || *sanctions[1].AppealStatus != "overturned" {
+			t.Errorf("appeal statuses = %q, %q", *sanctions[0].AppealStatus, *sanctions[1].AppealStatus)
+		}
+		rec = s.do(http.MethodGet, "/api/moderation/appeals?status=overturned", nil, hank.bearer())
+		expectStatus(t, rec, http.StatusOK)
+		if page := decode[types.AppealPage](t, rec); len(page.Appeals) != 1 || page.Appeals[0].ID != hideAppeal.ID {
+			t.Errorf("overturned appeals = %+v", page)
+		}
+		rec = s.do(http.MethodGet, "/api/moderation/actions?limit=1", nil, hank.bearer())
+		expectStatus(t, rec, http.StatusOK)
+		if log := decode[types.ModerationActionPage](t, rec); log.Actions[0].Action != "uphold_appeal" || log.Actions[0].AppealID == nil || *log.Actions[0].AppealID != suspendAppeal.ID {
+			t.Errorf("latest action = %+v", log.Actions[0])
+		}
+	})
+}
+
+func TestModerationUsers(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, s *testServer) {
+		walt := s.signUp("walt@breakingbad.com")
+		jesse := s.signUp("jesse@breakingbad.com")
+		hank := s.moderator("hank@dea.gov")
+		userPath := func(u session, action string) string { return "/api/moderation/users/" + u.ID.String() + "/" + action }
+
+		expectProblem(t, s.do(http.MethodPost, userPath(walt, "suspend"), nil, jesse.bearer()), http.StatusForbidden, problem.CodeForbidden)
+		expectProblem(t, s.do(http.MethodPost, userPath(hank, "suspend"), nil, hank.bearer()), http.StatusBadRequest, problem.CodeValidationFailed)
+		expectProblem(t, s.do(http.MethodPost, "/api/moderation/users/"+uuid.NewString()+"/suspend", nil, hank.bearer()), http.StatusNotFound, problem.CodeNotFound)
+		expectProblem(t, s.do(http.MethodPost, "/api/moderation/users/abc/limit", nil, hank.bearer()), http.StatusBadRequest, problem.CodeValidationFailed)
+		expectProblem(t, s.do(http.MethodPost, userPath(walt, "suspend"), map[string]any{"until": time.Now().Add(-time.Hour)}, hank.bearer()), http.StatusBadRequest, problem.CodeValidationFailed)
+		expectProblem(t, s.do(http.MethodPost, userPath(walt, "unsuspend"), nil, hank.bearer()), http.StatusConflict, problem.CodeConflict)
+
+		// a time-bound suspension says when it ends
+		until := time.Now().Add(72 * time.Hour).UTC().Truncate(time.Second)
+		expectStatus(t, s.do(http.MethodPost, userPath(walt, "suspend"), map[string]any{"until": until, "note": "Cooling off"}, hank.bearer()), http.StatusNoContent)
+		suspended := s.signUpLogin(walt.Email)
+		if !suspended.Suspended || suspended.SuspendedUntil == nil || !suspended.SuspendedUntil.Equal(until) {
+			t.Errorf("suspended login = %+v, want it to say the suspension ends at %s", suspended, until)
+		}
+		p := expectProblem(t, s.do(http.MethodGet, "/api/notifications", nil, walt.bearer()), http.StatusForbidden, problem.CodeAccountSuspended)
+		if !strings.Contains(p.Detail, until.Format(time.RFC3339)) {
+			t.Errorf("detail = %q, want it to say the suspension ends at %s", p.Detail, until.Format(time.RFC3339))
+		}
+
+		// lifting it lets them sign in again, but not with their old refresh token
+		expectStatus(t, s.do(http.MethodPost, userPath(walt, "unsuspend"), nil, hank.bearer()), http.StatusNoContent)
+		expectProblem(t, s.do(http.MethodPost, "/api/refresh", nil, walt.refreshBearer()), http.StatusUnauthorized, problem.CodeTokenRevoked)
+		walt = s.signUpLogin(walt.Email)
+
+		// a limited user's chirps are only visible to them, and they
+		// notify no one
+		cook := s.postChirp(walt, "Say my name")
+		jesseChirp := s.postChirp(jesse, "Yeah, science!")
+		expectStatus(t, s.do(http.MethodPost, userPath(walt, "limit"), map[string]any{"note": "Spam ring"}, hank.bearer()), http.StatusNoContent)
+		expectProblem(t, s.do(http.MethodPost, userPath(walt, "limit"), nil, hank.bearer()), http.StatusConflict, problem.CodeConflict)
+		knock := s.postChirp(walt, "I am the one who knocks")
+		expectStatus(t, s.do(http.MethodPost, "/api/chirps/"+jesseChirp.ID.String()+"/like", nil, walt.bearer()), http.StatusNoContent)
+		for _, viewer := range []session{{}, jesse} {
+			if got := s.chirpAuthors(viewer); len(got) != 1 || got[0] != jesse.ID {
+				t.Errorf("chirps seen by %q = %v, want only jesse's", viewer.Email, got)
+			}
+		}
+		if got := s.chirpAuthors(walt); len(got) != 3 {
+			t.Errorf("chirps seen by walt = %v, want all 3", got)
+		}
+		expectProblem(t, s.do(http.MethodGet, "/api/chirps/"+knock.ID.String(), nil, jesse.bearer()), http.StatusNotFound, problem.CodeNotFound)
+		expectStatus(t, s.do(http.MethodGet, "/api/chirps/"+knock.ID.String(), nil, walt.bearer()), http.StatusOK)
+		expectProblem(t, s.do(http.MethodPost, "/api/chirps", map[string]any{"body": "Yo", "reply_to_id": cook.ID}, jesse.bearer()), http.StatusBadRequest, problem.CodeValidationFailed)
+		if page := s.notifications(jesse, ""); len(page.Notifications) != 0 {
+			t.Errorf("jesse's notifications = %+v, want none from a limited user", page.Notifications)
+		}
+
+		// limiting is secret: it isn't among their sanctions
+		rec := s.do(http.MethodGet, "/api/sanctions", nil, walt.bearer())
+		expectStatus(t, rec, http.StatusOK)
+		if sanctions := decode[[]types.Sanction](t, rec); len(sanctions) != 1 || sanctions[0].Action != "suspend_user" {
+			t.Errorf("walt's sanctions = %+v, want only the suspension", sanctions)
+		}
+
+		expectStatus(t, s.do(http.MethodPost, userPath(walt, "unlimit"), nil, hank.bearer()), http.StatusNoContent)
+		expectProblem(t, s.do(http.MethodPost, userPath(walt, "unlimit"), nil, hank.bearer()), http.StatusConflict, problem.CodeConflict)
+		if got := s.chirpAuthors(session{}); len(got) != 3 {
+			t.Errorf("chirps after unlimiting = %v, want all 3", got)
+		}
+
+		rec = s.do(http.MethodGet, "/api/moderation/actions", nil, hank.bearer())
+		expectStatus(t, rec, http.StatusOK)
+		var got []string
+		for _, a := range decode[types.ModerationActionPage](t, rec).Actions {
+			got = append(got, a.Action)
+		}
+		if want := []string{"unlimit_user", "limit_user", "unsuspend_user", "suspend_user"}; !slices.Equal(got, want) {
+			t.Errorf("actions = %v, want %v", got, want)
+		}
+	})
+}
@@ -0,0 +1,218 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+
+	"github.com/HemahWeb/chirpy/internal/auth"
+	"github.com/HemahWeb/chirpy/internal/database"
+	"github.com/HemahWeb/chirpy/internal/logging"
+	"github.com/HemahWeb/chirpy/internal/problem"
+	"github.com/HemahWeb/chirpy/internal/service"
+	"github.com/HemahWeb/chirpy/internal/types"
+	"github.com/HemahWeb/chirpy/internal/utils"
+)
+
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+func notificationResponse(n database.Notification) types.Notification {
+	resp := types.Notification{
+		ID:        n.ID,
+		CreatedAt: n.CreatedAt,
+		Type:      n.Type,
+		ActorID:   n.ActorID,
+	}
+	if n.ChirpID.Valid {
+		resp.ChirpID = &n.ChirpID.UUID
+	}
+	if n.ReadAt.Valid {
+		resp.ReadAt = &n.ReadAt.Time
+	}
+	return resp
+}
+
+// authenticate returns the user the request's access token is for. The
+// token of a suspended user is refused.
+func (h *Handler) authenticate(r *http.Request) (uuid.UUID, error) {
+	userID, err := h.authenticateAllowSuspended(r)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if err := h.config.Service.CheckActive(r.Context(), userID); err != nil {
+		return uuid.Nil, err
+	}
+	return userID, nil
+}
+
+// authenticateAllowSuspended is authenticate for the few routes a suspended
+// user still needs, to see and appeal their sanctions.
+func (h *Handler) authenticateAllowSuspended(r *http.Request) (uuid.UUID, error) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	userID, err := auth.ValidateJWT(token, h.config.JWTSecret)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	logging.SetUserID(r.Context(), userID)
+	return userID, nil
+}
+
+func (h *Handler) NotificationsList(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.authenticate(r)
+	if err != nil {
+		utils.RespondWithError(w, r, err)
+		return
+	}
+
+	params, err := notificationListParams(r)
+	if err != nil {
+		utils.RespondWithError(w, r, err)
+		return
+	}
+
+	page, err := h.config.Service.ListNotifications(r.Context(), userID, params)
+	if err != nil {
+		utils.RespondWithError(w, r, err)
+		return
+	}
+
+	resp := types.NotificationPage{
+		Notifications: []types.Notification{},
+		UnreadCount:   page.UnreadCount,
+	}
+	for _, n := range page.Notifications {
+		resp.Notifications = append(resp.Notifications, notificationResponse(n))
+	}
+	if page.Next != 0 {
+		cursor := strconv.FormatInt(page.Next, 10)
+		resp.NextCursor = &cursor
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, resp)
+}
+
+// notificationListParams reads the cursor, limit and unread query
+// parameters.
+func notificationListParams(r *http.Request) (service.ListNotificationsParams, error) {
+	query := r.URL.Query()
+	params := service.ListNotificationsParams{}
+	var fields []problem.FieldError
+	params.Before, params.Limit, fields = pageParams(r)
+	if v := query.Get("unread"); v != "" {
+		unread, err := strconv.ParseBool(v)
+		if err != nil {
+			fields = append(fields, problem.FieldError{Field: "unread", Code: "invalid_bool", Message: "must be true or false"})
+		}
+		params.UnreadOnly = unread
+	}
+	if len(fields) > 0 {
+		return params, problem.Validation(fields...)
+	}
+	return params, nil
+}
+
+// pageParams reads the cursor and limit query parameters of a list that
+// pages by ID. A cursor is a next_cursor from an earlier page.
+func pageParams(r *http.Request) (before int64, limit int32, fields []problem.FieldError) {
+	query := r.URL.Query()
+	limit = defaultPageLimit
+	if v := query.Get("cursor"); v != "" {
+		var err error
+		before, err = strconv.ParseInt(v, 10, 64)
+		if err != nil || before <= 0 {
+			fields = append(fields, problem.FieldError{Field: "cursor", Code: "invalid_cursor", Message: "must be a next_cursor from an earlier page"})
+		}
+	}
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxPageLimit {
+			fields = append(fields, problem.FieldError{Field: "limit", Code: "out_of_range", Message: "must be a whole number from 1 to 100"})
+		}
+		limit = int32(n)
+	}
+	return before, limit, fields
+}
+
+func (h *Handler) NotificationsMarkRead(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.authenticate(r)
+	if err != nil {
+		utils.RespondWithError(w, r, err)
+		return
+	}
+
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		utils.RespondWithError(w, r, problem.Validation(problem.FieldError{Field: "id", Code: "invalid_id", Message: "must be a notification ID"}).WithCause(err))
+		return
+	}
+
+	if err := h.config.Service.MarkNotificationRead(r.Context(), userID, id); err != nil {
+		utils.RespondWithError(w, r, err)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusNoContent, nil)
+}
+
+func (h *Handler) NotificationsMarkAllRead(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.authenticate(r)
+	if err != nil {
+		utils.RespondWithError(w, r, err)
+		return
+	}
+
+	if _, err := h.config.Service.MarkAllNotificationsRead(r.Context(), userID); err != nil {
+		utils.RespondWithError(w, r, err)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusNoContent, nil)
+}
+
+func (h *Handler) NotificationPreferencesGet(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.authenticate(r)
+	if err != nil {
+		utils.RespondWithError(w, r, err)
+		return
+	}
+
+	prefs, err := h.config.Service.NotificationPreferences(r.Context(), userID)
+	if err != nil {
+		utils.RespondWithError(w, r, err)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, prefs)
+}
+
+// NotificationPreferencesUpdate turns the notification types in the body
+// on or off; types it leaves out keep their setting.
+func (h *Handler) NotificationPreferencesUpdate(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.authenticate(r)
+	if err != nil {
+		utils.RespondWithError(w, r, err)
+		return
+	}
+
+	params := map[string]bool{}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		utils.RespondWithError(w, r, problem.InvalidJSON(err))
+		return
+	}
+
+	prefs, err := h.config.Service.SetNotificationPreferences(r.Context(), userID, params)
+	if err != nil {
+		utils.RespondWithError(w, r, err)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, prefs)
+}
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/HemahWeb/chirpy/internal/problem"
+	"github.com/HemahWeb/chirpy/internal/types"
+)
+
+// notifications fetches a page of user's notifications.
+func (s *testServer) notifications(user session, query string) types.NotificationPage {
+	s.t.Helper()
+	rec := s.do(http.MethodGet, "/api/notifications"+query, nil, user.bearer())
+	expectStatus(s.t, rec, http.StatusOK)
+	return decode[types.NotificationPage](s.t, rec)
+}
+
+func TestNotifications(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, s *testServer) {
+		walt := s.signUp("walt@breakingbad.com")
+		jesse := s.signUp("jesse@breakingbad.com")
+		skyler := s.signUp("skyler@breakingbad.com")
+		cook := s.postChirp(walt, "Say my name")
+
+		expectStatus(t, s.do(http.MethodPost, "/api/users/"+walt.ID.String()+"/follow", nil, jesse.bearer()), http.StatusNoContent)
+		expectStatus(t, s.do(http.MethodPost, "/api/chirps/"+cook.ID.String()+"/like", nil, skyler.bearer()), http.StatusNoContent)
+		rec := s.do(http.MethodPost, "/api/chirps", map[string]any{"body": "Heisenberg", "reply_to_id": cook.ID}, jesse.bearer())
+		expectStatus(t, rec, http.StatusCreated)
+		reply := decode[types.Chirp](t, rec)
+		// nobody is notified about what they did themselves
+		expectStatus(t, s.do(http.MethodPost, "/api/chirps/"+cook.ID.String()+"/like", nil, walt.bearer()), http.StatusNoContent)
+
+		page := s.notifications(walt, "?limit=2")
+		if len(page.Notifications) != 2 || page.UnreadCount != 3 || page.NextCursor == nil {
+			t.Fatalf("first page = %+v, want 2 of 3 unread and a cursor", page)
+		}
+		newest := page.Notifications[0]
+		if newest.Type != "reply" || newest.ActorID != jesse.ID || *newest.ChirpID != reply.ID || newest.ReadAt != nil {
+			t.Errorf("newest = %+v, want jesse's unread reply", newest)
+		}
+		if like := page.Notifications[1]; like.Type != "like" || like.ActorID != skyler.ID || *like.ChirpID != cook.ID {
+			t.Errorf("second = %+v, want skyler's like", like)
+		}
+		page = s.notifications(walt, "?limit=2&cursor="+*page.NextCursor)
+		if len(page.Notifications) != 1 || page.Notifications[0].Type != "follow" || page.Notifications[0].ChirpID != nil || page.NextCursor != nil {
+			t.Errorf("last page = %+v, want jesse's follow and no cursor", page)
+		}
+
+		readPath := "/api/notifications/" + strconv.FormatInt(newest.ID, 10) + "/read"
+		expectProblem(t, s.do(http.MethodPost, readPath, nil, jesse.bearer()), http.StatusNotFound, problem.CodeNotFound)
+		expectStatus(t, s.do(http.MethodPost, readPath, nil, walt.bearer()), http.StatusNoContent)
+		expectStatus(t, s.do(http.MethodPost, readPath, nil, walt.bearer()), http.StatusNoContent)
+		page = s.notifications(walt, "?unread=true")
+		if len(page.Notifications) != 2 || page.UnreadCount != 2 {
+			t.Errorf("unread after reading one = %+v, want 2", page)
+		}
+
+		expectStatus(t, s.do(http.MethodPost, "/api/notifications/read", nil, walt.bearer()), http.StatusNoContent)
+		page = s.notifications(walt, "")
+		if len(page.Notifications) != 3 || page.UnreadCount != 0 || page.Notifications[2].ReadAt == nil {
+			t.Errorf("after reading all = %+v, want 3 read", page)
+		}
+		if page := s.notifications(jesse, ""); len(page.Notifications) != 0 || page.UnreadCount != 0 {
+			t.Errorf("jesse's notifications = %+v, want none", page)
+		}
+
+		expectProblem(t, s.do(http.MethodGet, "/api/notifications?limit=0", nil, walt.bearer()), http.StatusBadRequest, problem.CodeValidationFailed)
+		expectProblem(t, s.do(http.MethodGet, "/api/notifications?cursor=soon", nil, walt.bearer()), http.StatusBadRequest, problem.CodeValidationFailed)
+		expectProblem(t, s.do(http.MethodGet, "/api/notifications?unread=maybe", nil, walt.bearer()), http.StatusBadRequest, problem.CodeValidationFailed)
+		expectProblem(t, s.do(http.MethodPost, "/api/notifications/first/read", nil, walt.bearer()), http.StatusBadRequest, problem.CodeValidationFailed)
+		expectProblem(t, s.do(http.MethodGet, "/api/notifications", nil, ""), http.StatusUnauthorized, problem.CodeUnauthenticated)
+		expectProblem(t, s.do(http.MethodPost, "/api/notifications/read", nil, "Bearer forged"), http.StatusUnauthorized, problem.CodeInvalidToken)
+	})
+}
+
+func TestNotificationPreferences(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, s *testServer) {
+		walt := s.signUp("walt@breakingbad.com")
+		jesse := s.signUp("jesse@breakingbad.com")
+		path := "/api/notifications/preferences"
+
+		rec := s.do(http.MethodGet, path, nil, walt.bearer())
+		expectStatus(t, rec, http.StatusOK)
+		if prefs := decode[map[string]bool](t, rec); len(prefs) != 4 || !prefs["follow"] || !prefs["like"] || !prefs["mention"] || !prefs["reply"] {
+			t.Errorf("default preferences = %v, want everything on", prefs)
+		}
+
+		rec = s.do(http.MethodPut, path, map[string]bool{"follow": false}, walt.bearer())
+		expectStatus(t, rec, http.StatusOK)
+		if prefs := decode[map[string]bool](t, rec); prefs["follow"] || !prefs["like"] {
+			t.Errorf("preferences after turning follows off = %v", prefs)
+		}
+
+		expectStatus(t, s.do(http.MethodPost, "/api/users/"+walt.ID.String()+"/follow", nil, jesse.bearer()), http.StatusNoContent)
+		if page := s.notifications(walt, ""); len(page.Notifications) != 0 {
+			t.Errorf("notified with follows off: %+v", page)
+		}
+
+		p := expectProblem(t, s.do(http.MethodPut, path, map[string]bool{"retweet": true, "like": false}, walt.bearer()), http.StatusBadRequest, problem.CodeValidationFailed)
+		if len(p.Errors) != 1 || p.Errors[0].Field != "retweet" {
+			t.Errorf("errors = %+v, want retweet", p.Errors)
+		}
+		expectProblem(t, s.do(http.MethodPut, path, map[string]string{"like": "no"}, walt.bearer()), http.StatusBadRequest, problem.CodeInvalidJSON)
+		expectProblem(t, s.do(http.MethodGet, path, nil, ""), http.StatusUnauthorized, problem.CodeUnauthenticated)
+	})
+}
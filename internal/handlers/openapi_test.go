@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/HemahWeb/chirpy/internal/metrics"
+	"github.com/HemahWeb/chirpy/internal/openapi"
+	"github.com/HemahWeb/chirpy/internal/types"
+)
+
+// patternMux collects the patterns Register adds without serving them.
+type patternMux []string
+
+func (m *patternMux) Handle(pattern string, _ http.Handler) {
+	*m = append(*m, pattern)
+}
+
+func (m *patternMux) HandleFunc(pattern string, _ func(http.ResponseWriter, *http.Request)) {
+	*m = append(*m, pattern)
+}
+
+// specOperations returns "METHOD /path" for every operation in the spec.
+func specOperations(t *testing.T) []string {
+	t.Helper()
+	var doc struct {
+		Paths map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(openapi.Spec(), &doc); err != nil {
+		t.Fatalf("couldn't decode the spec: %v", err)
+	}
+	var ops []string
+	for path, item := range doc.Paths {
+		for method := range item {
+			if method == "parameters" || method == "summary" || method == "description" {
+				continue
+			}
+			ops = append(ops, strings.ToUpper(method)+" "+path)
+		}
+	}
+	return ops
+}
+
+func TestOpenAPICoversEveryRoute(t *testing.T) {
+	var mux patternMux
+	New(&types.ApiConfig{Metrics: metrics.New()}).Register(&mux, http.FS(fstest.MapFS{}))
+
+	var routes []string
+	for _, pattern := range mux {
+		method, path, ok := strings.Cut(pattern, " ")
+		if !ok {
+			// A pattern without a method matches them all; only GET is
+			// meaningful for the file server.
+			method, path = http.MethodGet, pattern
+		}
+		routes = append(routes, method+" "+path)
+	}
+
+	ops := specOperations(t)
+	for _, route := range routes {
+		if !slices.Contains(ops, route) {
+			t.Errorf("route %q has no operation in openapi.yaml", route)
+		}
+	}
+	for _, op := range ops {
+		if !slices.Contains(routes, op) {
+			t.Errorf("openapi.yaml describes %q, which isn't registered", op)
+		}
+	}
+}
+
+func TestOpenAPI(t *testing.T) {
+	s := newTestServer(t, openMemory)
+
+	rec := s.do(http.MethodGet, "/api/openapi.json", nil, "")
+	expectStatus(t, rec, http.StatusOK)
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	if doc := decode[map[string]any](t, rec); doc["openapi"] != "3.1.0" {
+		t.Errorf("openapi = %v, want 3.1.0", doc["openapi"])
+	}
+
+	rec = s.do(http.MethodGet, "/api/docs", nil, "")
+	expectStatus(t, rec, http.StatusOK)
+	if !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/html") || !strings.Contains(rec.Body.String(), "openapi.json") {
+		t.Errorf("docs page = %q %q", rec.Header().Get("Content-Type"), rec.Body.String())
+	}
+}
@@ -1,10 +1,13 @@
 package handlers
 
 import (
+	"crypto/subtle"
 	"encoding/json"
 	"net/http"
 
 	"github.com/HemahWeb/chirpy/internal/auth"
+	"github.com/HemahWeb/chirpy/internal/problem"
+	"github.com/HemahWeb/chirpy/internal/service"
 	"github.com/HemahWeb/chirpy/internal/utils"
 	"github.com/google/uuid"
 )
@@ -19,46 +22,47 @@ func (h *Handler) PolkaUpgrade(w http.ResponseWriter, r *http.Request) {
 
 	apiKey, err := auth.GetAPIKey(r.Header)
 	if err != nil {
-		utils.RespondWithError(w, http.StatusUnauthorized, "Couldn't get API key: "+err.Error(), err)
+		utils.RespondWithError(w, r, err)
 		return
 	}
 
-	if apiKey != h.config.PolkaKey {
-		utils.RespondWithError(w, http.StatusUnauthorized, "Invalid API key", nil)
+	if h.config.PolkaKey == "" || subtle.ConstantTimeCompare([]byte(apiKey), []byte(h.config.PolkaKey)) != 1 {
+		utils.RespondWithError(w, r, problem.New(http.StatusUnauthorized, problem.CodeInvalidAPIKey, "Invalid API key"))
 		return
 	}
 
 	var params parameters
 	err = json.NewDecoder(r.Body).Decode(&params)
 	if err != nil {
-		utils.RespondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
+		utils.RespondWithError(w, r, problem.InvalidJSON(err))
 		return
 	}
+	h.config.Metrics.WebhooksReceived.WithLabelValues("polka", polkaEventLabel(params.Event)).Inc()
 
-	if params.Event != "user.upgraded" {
-		utils.RespondWithJSON(w, http.StatusNoContent, nil)
-		return
+	var userID uuid.UUID
+	if params.Event == service.EventUserUpgraded {
+		userID, err = uuid.Parse(params.Data.UserID)
+		if err != nil {
+			utils.RespondWithError(w, r, invalidUUID("data.user_id", err))
+			return
+		}
 	}
 
-	userID, err := uuid.Parse(params.Data.UserID)
+	err = h.config.Service.HandlePolkaEvent(r.Context(), params.Event, userID)
 	if err != nil {
-		utils.RespondWithError(w, http.StatusBadRequest, "Invalid user ID", err)
+		utils.RespondWithError(w, r, err)
 		return
 	}
 
-	_, err = h.config.DB.GetUserByID(r.Context(), userID)
-	if err != nil {
-		utils.RespondWithError(w, http.StatusNotFound, "User not found", err)
-		return
-	}
+	utils.RespondWithJSON(w, http.StatusNoContent, nil)
+}
 
-	if params.Event == "user.upgraded" {
-		err = h.config.DB.UpgradeUserToChirpyRed(r.Context(), userID)
-		if err != nil {
-			utils.RespondWithError(w, http.StatusInternalServerError, "Couldn't upgrade user", err)
-			return
-		}
+// polkaEventLabel is the metric label for event. The event name comes from
+// the request, so anything we don't handle shares one label rather than
+// each new name adding a series.
+func polkaEventLabel(event string) string {
+	if event == service.EventUserUpgraded {
+		return event
 	}
-
-	utils.RespondWithJSON(w, http.StatusNoContent, nil)
+	return "other"
 }
@@ -8,16 +8,15 @@ import (
 )
 
 func (h *Handler) Revoke(w http.ResponseWriter, r *http.Request) {
-
 	token, err := auth.GetBearerToken(r.Header)
 	if err != nil {
-		utils.RespondWithError(w, http.StatusUnauthorized, "Couldn't get token: "+err.Error(), err)
+		utils.RespondWithError(w, r, err)
 		return
 	}
 
-	err = h.config.DB.RevokeRefreshToken(r.Context(), token)
+	err = h.config.Service.Revoke(r.Context(), token)
 	if err != nil {
-		utils.RespondWithError(w, http.StatusUnauthorized, "Couldn't revoke token", err)
+		utils.RespondWithError(w, r, err)
 		return
 	}
 
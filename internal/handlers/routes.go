@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/HemahWeb/chirpy/internal/openapi"
+)
+
+// Router is the part of *http.ServeMux that Register uses.
+type Router interface {
+	Handle(pattern string, handler http.Handler)
+	HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request))
+}
+
+// Register adds every route to mux. static is served under /app/.
+func (h *Handler) Register(mux Router, static http.FileSystem) {
+	mux.HandleFunc("GET /api/livez", h.Livez)
+	mux.HandleFunc("GET /api/readyz", h.Readyz)
+	mux.HandleFunc("GET /api/healthz", h.Readyz) // kept for existing load balancer configs
+
+	// Chirps
+	mux.HandleFunc("POST /api/chirps", h.PostChirps)
+	mux.HandleFunc("GET /api/chirps", h.GetChirps)
+	mux.HandleFunc("GET /api/chirps/trash", h.ChirpsTrashList)
+	mux.HandleFunc("GET /api/chirps/{id}", h.GetChirpsByID)
+	mux.HandleFunc("DELETE /api/chirps/{id}", h.ChirpsDeleteByID)
+	mux.HandleFunc("POST /api/chirps/{id}/restore", h.ChirpsRestore)
+	mux.HandleFunc("POST /api/chirps/{id}/like", h.ChirpsLike)
+	mux.HandleFunc("DELETE /api/chirps/{id}/like", h.ChirpsUnlike)
+	mux.HandleFunc("GET /api/attachments/{id}", h.AttachmentsGet)
+	mux.HandleFunc("GET /api/attachments/{id}/thumbnail", h.AttachmentsGetThumbnail)
+
+	// Users
+	mux.HandleFunc("POST /api/users", h.UsersCreate)
+	mux.HandleFunc("POST /api/login", h.Login)
+	mux.HandleFunc("POST /api/refresh", h.Refresh)
+	mux.HandleFunc("POST /api/revoke", h.Revoke)
+	mux.HandleFunc("PUT /api/users", h.UsersUpdate)
+	mux.HandleFunc("POST /api/users/{id}/follow", h.UsersFollow)
+	mux.HandleFunc("DELETE /api/users/{id}/follow", h.UsersUnfollow)
+	mux.HandleFunc("GET /api/users/blocks", h.UsersBlocksList)
+	mux.HandleFunc("POST /api/users/{id}/block", h.UsersBlock)
+	mux.HandleFunc("DELETE /api/users/{id}/block", h.UsersUnblock)
+	mux.HandleFunc("GET /api/users/mutes", h.UsersMutesList)
+	mux.HandleFunc("POST /api/users/{id}/mute", h.UsersMute)
+	mux.HandleFunc("DELETE /api/users/{id}/mute", h.UsersUnmute)
+
+	// Notifications
+	mux.HandleFunc("GET /api/notifications", h.NotificationsList)
+	mux.HandleFunc("POST /api/notifications/read", h.NotificationsMarkAllRead)
+	mux.HandleFunc("POST /api/notifications/{id}/read", h.NotificationsMarkRead)
+	mux.HandleFunc("GET /api/notifications/preferences", h.NotificationPreferencesGet)
+	mux.HandleFunc("PUT /api/notifications/preferences", h.NotificationPreferencesUpdate)
+
+	// Direct messages
+	mux.HandleFunc("POST /api/conversations", h.ConversationsCreate)
+	mux.HandleFunc("GET /api/conversations", h.ConversationsList)
+	mux.HandleFunc("GET /api/conversations/{id}", h.ConversationsGet)
+	mux.HandleFunc("POST /api/conversations/{id}/messages", h.MessagesCreate)
+	mux.HandleFunc("GET /api/conversations/{id}/messages", h.MessagesList)
+	mux.HandleFunc("DELETE /api/conversations/{id}/messages/{message_id}", h.MessagesDelete)
+	mux.HandleFunc("POST /api/conversations/{id}/read", h.ConversationsMarkRead)
+	mux.HandleFunc("POST /api/conversations/{id}/mute", h.ConversationsMute)
+	mux.HandleFunc("DELETE /api/conversations/{id}/mute", h.ConversationsUnmute)
+
+	// Reports and moderation
+	mux.HandleFunc("POST /api/reports", h.ReportsCreate)
+	mux.HandleFunc("GET /api/sanctions", h.SanctionsList)
+	mux.HandleFunc("POST /api/sanctions/{id}/appeal", h.SanctionsAppeal)
+	mux.HandleFunc("GET /api/moderation/reports", h.ModerationReportsList)
+	mux.HandleFunc("POST /api/moderation/reports/{id}/claim", h.ModerationReportsClaim)
+	mux.HandleFunc("POST /api/moderation/reports/{id}/resolve", h.ModerationReportsResolve)
+	mux.HandleFunc("GET /api/moderation/actions", h.ModerationActionsList)
+	mux.HandleFunc("GET /api/moderation/appeals", h.ModerationAppealsList)
+	mux.HandleFunc("POST /api/moderation/appeals/{id}/decide", h.ModerationAppealsDecide)
+	mux.HandleFunc("POST /api/moderation/users/{id}/suspend", h.ModerationUsersSuspend)
+	mux.HandleFunc("POST /api/moderation/users/{id}/unsuspend", h.ModerationUsersUnsuspend)
+	mux.HandleFunc("POST /api/moderation/users/{id}/limit", h.ModerationUsersLimit)
+	mux.HandleFunc("POST /api/moderation/users/{id}/unlimit", h.ModerationUsersUnlimit)
+
+	// Live events
+	mux.HandleFunc("GET /api/stream", h.Stream)
+	mux.HandleFunc("GET /api/ws", h.WebSocket)
+
+	// Webhooks
+	mux.HandleFunc("POST /api/polka/webhooks", h.PolkaUpgrade)
+
+	// API description; keep openapi.yaml in step with this file
+	mux.Handle("GET /api/openapi.json", openapi.SpecHandler())
+	mux.Handle("GET /api/docs", openapi.DocsHandler())
+
+	// Admin
+	mux.HandleFunc("POST /admin/reset", h.UsersReset) // resets users and metrics
+	mux.HandleFunc("GET /admin/metrics", h.MetricsView)
+	mux.HandleFunc("POST /admin/refresh_tokens/cleanup", h.RefreshTokensCleanup)
+	mux.HandleFunc("POST /admin/chirps/trash/purge", h.ChirpTrashPurge)
+
+	// Prometheus scrape endpoint
+	mux.HandleFunc("GET /metrics", h.PrometheusMetrics)
+
+	mux.Handle("/app/", http.StripPrefix("/app/", h.MiddlewareMetricsInc(http.FileServer(static))))
+}
@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/HemahWeb/chirpy/internal/events"
+	"github.com/HemahWeb/chirpy/internal/problem"
+	"github.com/HemahWeb/chirpy/internal/utils"
+)
+
+const (
+	// streamRetry is how long EventSource waits before reconnecting.
+	streamRetry = 3 * time.Second
+	// streamWriteTimeout replaces the server's write timeout, which would
+	// cut every stream off; a client that can't take a write this long is
+	// gone.
+	streamWriteTimeout = 10 * time.Second
+)
+
+// Stream sends chirp events as Server-Sent Events until the client goes
+// away. A client reconnecting with Last-Event-ID gets what it missed, or a
+// reset event if that's no longer known and it should reload.
+func (h *Handler) Stream(w http.ResponseWriter, r *http.Request) {
+	filter, err := h.streamFilter(r)
+	if err != nil {
+		utils.RespondWithError(w, r, err)
+		return
+	}
+
+	sub, resumed := h.config.Stream.Subscribe(r.Header.Get("Last-Event-ID"), filter)
+	defer sub.Close()
+
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no") // stop nginx buffering the stream
+	w.WriteHeader(http.StatusOK)
+
+	write := func(format string, args ...any) bool {
+		rc.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+		if _, err := fmt.Fprintf(w, format, args...); err != nil {
+			return false
+		}
+		return rc.Flush() == nil
+	}
+
+	ok := write("retry: %d\n\n", streamRetry.Milliseconds())
+	if ok && !resumed {
+		ok = write("event: reset\ndata: {}\n\n")
+	}
+
+	heartbeat := time.NewTicker(h.config.StreamHeartbeat)
+	defer heartbeat.Stop()
+	for ok {
+		select {
+		case <-r.Context().Done():
+			return
+		case e, open := <-sub.C:
+			// closed when the server shuts down or we fell behind; the
+			// client reconnects and resumes
+			if !open {
+				return
+			}
+			ok = write("id: %s\nevent: %s\ndata: %s\n\n", e.ID, e.Type, e.Data)
+		case <-heartbeat.C:
+			ok = write(": heartbeat\n\n")
+		}
+	}
+}
+
+// streamFilter reads the author_id or timeline query parameter. The
+// timeline is the viewer's own chirps and those of who they follow when the
+// stream opens.
+func (h *Handler) streamFilter(r *http.Request) (events.Filter, error) {
+	query := r.URL.Query()
+	timeline := false
+	if v := query.Get("timeline"); v != "" {
+		var err error
+		if timeline, err = strconv.ParseBool(v); err != nil {
+			return nil, problem.Validation(problem.FieldError{Field: "timeline", Code: "invalid_bool", Message: "must be true or false"}).WithCause(err)
+		}
+	}
+	author := query.Get("author_id")
+	if timeline && author != "" {
+		return nil, problem.Validation(problem.FieldError{Field: "author_id", Code: "conflict", Message: "can't be combined with timeline"})
+	}
+
+	if author != "" {
+		authorID, err := uuid.Parse(author)
+		if err != nil {
+			return nil, invalidUUID("author_id", err)
+		}
+		return events.ByAuthors(authorID), nil
+	}
+	if !timeline {
+		return events.Chirps, nil
+	}
+
+	userID, err := h.authenticate(r)
+	if err != nil {
+		return nil, err
+	}
+
+	followees, err := h.config.Service.Followees(r.Context(), userID)
+	if err != nil {
+		return nil, err
+	}
+	return events.ByAuthors(append(followees, userID)...), nil
+}
@@ -0,0 +1,195 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/HemahWeb/chirpy/internal/problem"
+	"github.com/HemahWeb/chirpy/internal/types"
+)
+
+// sseEvent is one message of an event stream; comment holds the text of a
+// comment-only message such as a heartbeat.
+type sseEvent struct {
+	id, event, data, retry, comment string
+}
+
+type sseStream struct {
+	t     *testing.T
+	lines chan string
+}
+
+// stream opens an event stream on a real server, since the recorder used
+// by do can't be read while the handler is still writing. It's closed when
+// the test ends.
+func (s *testServer) stream(path, auth, lastEventID string) *sseStream {
+	s.t.Helper()
+	server := httptest.NewServer(s.mux)
+	s.t.Cleanup(server.Close)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.t.Cleanup(cancel)
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+path, nil)
+	if auth != "" {
+		req.Header.Set("Authorization", auth)
+	}
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	s.cover(req)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		s.t.Fatalf("GET %s failed: %v", path, err)
+	}
+	s.t.Cleanup(func() { resp.Body.Close() })
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		s.t.Fatalf("GET %s: status %d, Content-Type %q", path, resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			select {
+			case lines <- scanner.Text():
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return &sseStream{t: s.t, lines: lines}
+}
+
+// next returns the next message, failing the test if none arrives soon.
+func (st *sseStream) next() sseEvent {
+	st.t.Helper()
+	var e sseEvent
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case line, ok := <-st.lines:
+			if !ok {
+				st.t.Fatal("stream ended")
+			}
+			if line == "" {
+				return e
+			}
+			field, value, _ := strings.Cut(line, ":")
+			value = strings.TrimPrefix(value, " ")
+			switch field {
+			case "":
+				e.comment = value
+			case "id":
+				e.id = value
+			case "event":
+				e.event = value
+			case "data":
+				e.data = value
+			case "retry":
+				e.retry = value
+			}
+		case <-timeout:
+			st.t.Fatal("timed out waiting for an event")
+		}
+	}
+}
+
+// expect reads the next message and checks it's a typ event about chirp.
+func (st *sseStream) expect(typ string, chirp types.Chirp) sseEvent {
+	st.t.Helper()
+	e := st.next()
+	var got types.Chirp
+	if err := json.Unmarshal([]byte(e.data), &got); err != nil {
+		st.t.Fatalf("couldn't decode event data %q: %v", e.data, err)
+	}
+	if e.event != typ || got.ID != chirp.ID || got.Body != chirp.Body || e.id == "" {
+		st.t.Errorf("got %s event %s about %+v, want %s about %+v", e.event, e.id, got, typ, chirp)
+	}
+	return e
+}
+
+func TestStream(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, s *testServer) {
+		walt := s.signUp("walt@breakingbad.com")
+		jesse := s.signUp("jesse@breakingbad.com")
+		skyler := s.signUp("skyler@breakingbad.com")
+		expectStatus(t, s.do(http.MethodPost, "/api/users/"+walt.ID.String()+"/follow", nil, jesse.bearer()), http.StatusNoContent)
+
+		all := s.stream("/api/stream", "", "")
+		byJesse := s.stream("/api/stream?author_id="+jesse.ID.String(), "", "")
+		timeline := s.stream("/api/stream?timeline=true", jesse.bearer(), "")
+		for _, st := range []*sseStream{all, byJesse, timeline} {
+			if e := st.next(); e.retry != "3000" {
+				t.Errorf("first message = %+v, want the retry interval", e)
+			}
+		}
+
+		cook := s.postChirp(walt, "Say my name")
+		science := s.postChirp(jesse, "Yeah, science!")
+		s.postChirp(skyler, "Ted?")
+		expectStatus(t, s.do(http.MethodDelete, "/api/chirps/"+cook.ID.String(), nil, walt.bearer()), http.StatusNoContent)
+
+		first := all.expect("chirp.created", cook)
+		all.expect("chirp.created", science)
+		all.next() // skyler's
+		all.expect("chirp.deleted", cook)
+
+		byJesse.expect("chirp.created", science)
+
+		timeline.expect("chirp.created", cook)
+		timeline.expect("chirp.created", science)
+		timeline.expect("chirp.deleted", cook)
+
+		// resuming replays what came after the last event seen
+		resumed := s.stream("/api/stream?author_id="+walt.ID.String(), "", first.id)
+		resumed.next() // retry
+		resumed.expect("chirp.deleted", cook)
+
+		stale := s.stream("/api/stream", "", "not-an-event")
+		stale.next() // retry
+		if e := stale.next(); e.event != "reset" {
+			t.Errorf("resuming from an unknown event sent %+v, want a reset", e)
+		}
+
+		expectProblem(t, s.do(http.MethodGet, "/api/stream?timeline=true", nil, ""), http.StatusUnauthorized, problem.CodeUnauthenticated)
+		expectProblem(t, s.do(http.MethodGet, "/api/stream?timeline=maybe", nil, jesse.bearer()), http.StatusBadRequest, problem.CodeValidationFailed)
+		expectProblem(t, s.do(http.MethodGet, "/api/stream?author_id=walt", nil, ""), http.StatusBadRequest, problem.CodeValidationFailed)
+		expectProblem(t, s.do(http.MethodGet, "/api/stream?timeline=true&author_id="+walt.ID.String(), nil, jesse.bearer()), http.StatusBadRequest, problem.CodeValidationFailed)
+	})
+}
+
+func TestStreamHeartbeatAndClose(t *testing.T) {
+	s := newTestServer(t, openMemory)
+	s.cfg.StreamHeartbeat = time.Millisecond
+	st := s.stream("/api/stream", "", "")
+	st.next() // retry
+	if e := st.next(); e.comment != "heartbeat" {
+		t.Errorf("idle stream sent %+v, want a heartbeat", e)
+	}
+
+	s.cfg.Stream.Close()
+	for {
+		select {
+		case line, ok := <-st.lines:
+			if !ok {
+				return
+			}
+			if line != "" && line != ": heartbeat" {
+				t.Errorf("stream sent %q after the broker closed", line)
+			}
+			continue
+		case <-time.After(5 * time.Second):
+			t.Error("stream still open after the broker closed")
+		}
+		return
+	}
+
+}
@@ -4,8 +4,7 @@ import (
 	"encoding/json"
 	"net/http"
 
-	"github.com/HemahWeb/chirpy/internal/auth"
-	"github.com/HemahWeb/chirpy/internal/database"
+	"github.com/HemahWeb/chirpy/internal/problem"
 	"github.com/HemahWeb/chirpy/internal/types"
 	"github.com/HemahWeb/chirpy/internal/utils"
 )
@@ -18,23 +17,14 @@ func (h *Handler) UsersCreate(w http.ResponseWriter, r *http.Request) {
 	var params parameters
 	err := json.NewDecoder(r.Body).Decode(&params)
 	if err != nil {
-		utils.RespondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
-		return
-	}
-
-	hashedPassword, err := auth.HashPassword(params.Password)
-	if err != nil {
-		utils.RespondWithError(w, http.StatusInternalServerError, "Couldn't hash password", err)
+		utils.RespondWithError(w, r, problem.InvalidJSON(err))
 		return
 	}
 
 	// SQLC returns a database.User without API JSON tags
-	user, err := h.config.DB.CreateUser(r.Context(), database.CreateUserParams{
-		Email:          params.Email,
-		HashedPassword: hashedPassword,
-	})
+	user, err := h.config.Service.CreateUser(r.Context(), params.Email, params.Password)
 	if err != nil {
-		utils.RespondWithError(w, http.StatusInternalServerError, "Couldn't create user", err)
+		utils.RespondWithError(w, r, err)
 		return
 	}
 
@@ -49,64 +39,57 @@ func (h *Handler) UsersCreate(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handler) UsersReset(w http.ResponseWriter, r *http.Request) {
-	if h.config.Platform == "" {
-		utils.RespondWithError(w, http.StatusServiceUnavailable, "Platform not set in config", nil)
-		return
-	}
-	if h.config.Platform != "dev" {
-		utils.RespondWithError(w, http.StatusForbidden, "Forbidden in non-dev platform", nil)
+	if !h.devPlatformOnly(w, r) {
 		return
 	}
 
-	err := h.config.DB.ResetUsers(r.Context())
+	err := h.config.Service.ResetUsers(r.Context())
 	if err != nil {
-		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to reset users in database:", err)
+		utils.RespondWithError(w, r, err)
 		return
 	}
 
-	h.config.FileserverHits.Store(0)
+	h.config.Metrics.ResetHits()
 
 	utils.RespondWithJSON(w, http.StatusOK, nil)
 }
 
+// devPlatformOnly refuses admin endpoints that change or expose the
+// database unless PLATFORM is dev, and reports whether to carry on.
+func (h *Handler) devPlatformOnly(w http.ResponseWriter, r *http.Request) bool {
+	if h.config.Platform == "" {
+		utils.RespondWithError(w, r, problem.New(http.StatusServiceUnavailable, problem.CodeUnavailable, "Platform not set in config"))
+		return false
+	}
+	if h.config.Platform != "dev" {
+		utils.RespondWithError(w, r, problem.New(http.StatusForbidden, problem.CodeForbidden, "Forbidden in non-dev platform"))
+		return false
+	}
+	return true
+}
+
 func (h *Handler) UsersUpdate(w http.ResponseWriter, r *http.Request) {
 	type parameters struct {
 		Email    string `json:"email"`
 		Password string `json:"password"`
 	}
 
-	token, err := auth.GetBearerToken(r.Header)
-	if err != nil {
-		utils.RespondWithError(w, http.StatusUnauthorized, "Couldn't get token: "+err.Error(), err)
-		return
-	}
-
-	userID, err := auth.ValidateJWT(token, h.config.JWTSecret)
+	userID, err := h.authenticate(r)
 	if err != nil {
-		utils.RespondWithError(w, http.StatusUnauthorized, "Couldn't validate token", err)
+		utils.RespondWithError(w, r, err)
 		return
 	}
 
 	var params parameters
 	err = json.NewDecoder(r.Body).Decode(&params)
 	if err != nil {
-		utils.RespondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
-		return
-	}
-
-	hashedPassword, err := auth.HashPassword(params.Password)
-	if err != nil {
-		utils.RespondWithError(w, http.StatusInternalServerError, "Couldn't hash password", err)
+		utils.RespondWithError(w, r, problem.InvalidJSON(err))
 		return
 	}
 
-	user, err := h.config.DB.UpdateUserEmailAndPassword(r.Context(), database.UpdateUserEmailAndPasswordParams{
-		ID:             userID,
-		Email:          params.Email,
-		HashedPassword: hashedPassword,
-	})
+	user, err := h.config.Service.UpdateUser(r.Context(), userID, params.Email, params.Password)
 	if err != nil {
-		utils.RespondWithError(w, http.StatusInternalServerError, "Couldn't update user", err)
+		utils.RespondWithError(w, r, err)
 		return
 	}
 
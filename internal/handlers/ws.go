@@ -0,0 +1,386 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+	"github.com/google/uuid"
+
+	"github.com/HemahWeb/chirpy/internal/auth"
+	"github.com/HemahWeb/chirpy/internal/events"
+	"github.com/HemahWeb/chirpy/internal/logging"
+	"github.com/HemahWeb/chirpy/internal/problem"
+	"github.com/HemahWeb/chirpy/internal/utils"
+)
+
+const (
+	// wsAuthTimeout is how long a client that connected without an
+	// Authorization header has to send an auth message.
+	wsAuthTimeout = 10 * time.Second
+	// wsReadLimit caps a client message; they're all small.
+	wsReadLimit = 4 << 10
+	// wsMaxTopics caps the subscriptions on one connection.
+	wsMaxTopics = 100
+	// wsReplyBuffer is how many replies can wait to be written before a
+	// client that sends faster than it reads is disconnected.
+	wsReplyBuffer = 16
+
+	topicNotifications = "notifications"
+	topicMessages      = "messages"
+	topicFollowing     = "following"
+	topicThreadPrefix  = "thread:"
+)
+
+// wsClientMessage is a message from the client.
+type wsClientMessage struct {
+	Type  string `json:"type"`
+	Token string `json:"token"`
+	Topic string `json:"topic"`
+}
+
+// wsMessage is a message to the client; Type says which fields are set.
+type wsMessage struct {
+	Type      string           `json:"type"`
+	UserID    uuid.UUID        `json:"user_id,omitzero"`
+	ExpiresAt time.Time        `json:"expires_at,omitzero"`
+	Topic     string           `json:"topic,omitempty"`
+	Topics    []string         `json:"topics,omitempty"`
+	Event     *events.Event    `json:"event,omitempty"`
+	Error     *problem.Problem `json:"error,omitempty"`
+}
+
+// WebSocket is the realtime API for clients that talk back. A client
+// authenticates with an access token, in the Authorization header or an
+// auth message, then subscribes to topics and is sent the events that match
+// any of them. See the README for the protocol.
+func (h *Handler) WebSocket(w http.ResponseWriter, r *http.Request) {
+	c := &wsConn{
+		h:       h,
+		topics:  map[string]events.Filter{},
+		replies: make(chan wsMessage, wsReplyBuffer),
+		reauth:  make(chan struct{}, 1),
+		done:    make(chan struct{}),
+	}
+	if r.Header.Get("Authorization") != "" {
+		token, err := auth.GetBearerToken(r.Header)
+		if err == nil {
+			err = c.authenticate(r.Context(), token)
+		}
+		if err != nil {
+			utils.RespondWithError(w, r, err)
+			return
+		}
+	}
+
+	// the server's timeouts would cut the connection off; pings find
+	// clients that have gone instead
+	rc := http.NewResponseController(w)
+	rc.SetReadDeadline(time.Time{})
+	rc.SetWriteDeadline(time.Time{})
+	conn, err := websocket.Accept(w, r, nil)
+	if err != nil {
+		return // Accept has responded
+	}
+	defer conn.CloseNow()
+	conn.SetReadLimit(wsReadLimit)
+	c.conn = conn
+
+	ctx := r.Context()
+	if c.userID == uuid.Nil && !c.awaitAuth(ctx) {
+		return
+	}
+	logging.SetUserID(ctx, c.userID)
+	if !c.write(ctx, c.ready()) {
+		return
+	}
+
+	sub, _ := h.config.Stream.Subscribe("", c.match)
+	defer sub.Close()
+	go c.read(ctx)
+	c.run(ctx, sub)
+	conn.CloseNow()
+	<-c.done
+}
+
+// wsConn is one WebSocket client. The handler's goroutine writes to it and
+// another reads from it.
+type wsConn struct {
+	h    *Handler
+	conn *websocket.Conn
+
+	mu        sync.Mutex
+	userID    uuid.UUID
+	expiresAt time.Time
+	topics    map[string]events.Filter
+
+	replies chan wsMessage // from the reader, to write
+	reauth  chan struct{}  // the reader accepted a new token
+	done    chan struct{}  // closed when the reader stops
+}
+
+// authenticate checks an access token, refusing a suspended user's. A
+// connection can renew its token before it expires, but not switch users.
+func (c *wsConn) authenticate(ctx context.Context, token string) error {
+	userID, expiresAt, err := auth.ValidateJWTWithExpiry(token, c.h.config.JWTSecret)
+	if err != nil {
+		return err
+	}
+	if err := c.h.config.Service.CheckActive(ctx, userID); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.userID != uuid.Nil && c.userID != userID {
+		return problem.New(http.StatusForbidden, problem.CodeForbidden, "The connection is authenticated as another user")
+	}
+	c.userID, c.expiresAt = userID, expiresAt
+	return nil
+}
+
+func (c *wsConn) ready() wsMessage {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return wsMessage{Type: "ready", UserID: c.userID, ExpiresAt: c.expiresAt}
+}
+
+// awaitAuth reads the auth message a client without an Authorization
+// header must send first. The connection is closed if it doesn't.
+func (c *wsConn) awaitAuth(ctx context.Context) bool {
+	timeout := time.AfterFunc(wsAuthTimeout, func() {
+		c.conn.Close(websocket.StatusPolicyViolation, "authentication timed out")
+	})
+	defer timeout.Stop()
+
+	var msg wsClientMessage
+	if err := wsjson.Read(ctx, c.conn, &msg); err != nil {
+		return false
+	}
+	if !timeout.Stop() {
+		return false
+	}
+	var err error
+	if msg.Type == "auth" {
+		err = c.authenticate(ctx, msg.Token)
+	} else {
+		err = problem.New(http.StatusUnauthorized, problem.CodeUnauthenticated, "The first message must be an auth message")
+	}
+	if err == nil {
+		return true
+	}
+	c.write(ctx, c.errorMessage(ctx, "", err))
+	c.conn.Close(websocket.StatusPolicyViolation, "authentication failed")
+	return false
+}
+
+// read handles client messages until the connection ends.
+func (c *wsConn) read(ctx context.Context) {
+	defer close(c.done)
+	for {
+		_, data, err := c.conn.Read(ctx)
+		if err != nil {
+			return
+		}
+		var msg wsClientMessage
+		var reply wsMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			reply = c.errorMessage(ctx, "", problem.InvalidJSON(err))
+		} else {
+			reply = c.handle(ctx, msg)
+		}
+		select {
+		case c.replies <- reply:
+		default:
+			c.conn.Close(websocket.StatusPolicyViolation, "sending faster than reading")
+			return
+		}
+	}
+}
+
+func (c *wsConn) handle(ctx context.Context, msg wsClientMessage) wsMessage {
+	switch msg.Type {
+	case "auth":
+		if err := c.authenticate(ctx, msg.Token); err != nil {
+			return c.errorMessage(ctx, "", err)
+		}
+		select {
+		case c.reauth <- struct{}{}:
+		default:
+		}
+		return c.ready()
+
+	case "subscribe":
+		filter, err := c.topicFilter(ctx, msg.Topic)
+		if err != nil {
+			return c.errorMessage(ctx, msg.Topic, err)
+		}
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if _, ok := c.topics[msg.Topic]; !ok && len(c.topics) >= wsMaxTopics {
+			return c.errorMessage(ctx, msg.Topic, problem.New(http.StatusBadRequest, problem.CodeValidationFailed, "Too many subscriptions on this connection"))
+		}
+		c.topics[msg.Topic] = filter
+		return wsMessage{Type: "subscribed", Topic: msg.Topic}
+
+	case "unsubscribe":
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		delete(c.topics, msg.Topic)
+		return wsMessage{Type: "unsubscribed", Topic: msg.Topic}
+	}
+	return c.errorMessage(ctx, "", problem.Validation(problem.FieldError{Field: "type", Code: "invalid_value", Message: "must be auth, subscribe or unsubscribe"}))
+}
+
+// topicFilter returns the filter for topic: the user's notifications or
+// direct messages, the chirps of who they follow when they subscribe, or
+// the chirps in the thread of chirp id.
+func (c *wsConn) topicFilter(ctx context.Context, topic string) (events.Filter, error) {
+	c.mu.Lock()
+	userID := c.userID
+	c.mu.Unlock()
+
+	switch {
+	case topic == topicNotifications:
+		return events.NotificationsFor(userID), nil
+	case topic == topicMessages:
+		return events.MessagesFor(userID), nil
+	case topic == topicFollowing:
+		followees, err := c.h.config.Service.Followees(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		return events.ByAuthors(followees...), nil
+	case strings.HasPrefix(topic, topicThreadPrefix):
+		id, err := uuid.Parse(strings.TrimPrefix(topic, topicThreadPrefix))
+		if err != nil {
+			return nil, invalidUUID("topic", err)
+		}
+		chirp, err := c.h.config.Service.GetChirp(ctx, userID, id)
+		if err != nil {
+			return nil, err
+		}
+		return events.InThread(events.ThreadOf(chirp)), nil
+	}
+	return nil, problem.Validation(problem.FieldError{Field: "topic", Code: "invalid_value", Message: "must be notifications, messages, following or thread:{chirp_id}"})
+}
+
+// match is the connection's broker filter: any of its topics, or its user
+// being suspended.
+func (c *wsConn) match(e events.Event) bool {
+	c.mu.Lock()
+	userID := c.userID
+	c.mu.Unlock()
+	return events.SuspensionOf(userID)(e) || len(c.matching(e)) > 0
+}
+
+// matching returns the topics that want e, sorted.
+func (c *wsConn) matching(e events.Event) []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var topics []string
+	for topic, filter := range c.topics {
+		if filter(e) {
+			topics = append(topics, topic)
+		}
+	}
+	slices.Sort(topics)
+	return topics
+}
+
+// run writes events, replies and pings until the connection ends, closing
+// it when the token expires, the user is suspended or the subscription
+// ends.
+func (c *wsConn) run(ctx context.Context, sub *events.Subscription) {
+	ping := time.NewTicker(c.h.config.StreamHeartbeat)
+	defer ping.Stop()
+	expiry := time.NewTimer(0)
+	defer expiry.Stop()
+	c.resetExpiry(expiry)
+
+	for {
+		select {
+		case <-c.done:
+			return
+		case e, open := <-sub.C:
+			if !open {
+				code, reason := wsCloseReason(sub.Err())
+				c.conn.Close(code, reason)
+				return
+			}
+			if e.Type == events.UserSuspended {
+				c.conn.Close(websocket.StatusPolicyViolation, "account suspended")
+				return
+			}
+			// it may have been queued before an unsubscribe
+			topics := c.matching(e)
+			if len(topics) > 0 && !c.write(ctx, wsMessage{Type: "event", Topics: topics, Event: &e}) {
+				return
+			}
+		case reply := <-c.replies:
+			if !c.write(ctx, reply) {
+				return
+			}
+		case <-c.reauth:
+			c.resetExpiry(expiry)
+		case <-expiry.C:
+			c.conn.Close(websocket.StatusPolicyViolation, "token expired")
+			return
+		case <-ping.C:
+			pingCtx, cancel := context.WithTimeout(ctx, streamWriteTimeout)
+			err := c.conn.Ping(pingCtx)
+			cancel()
+			if err != nil {
+				return
+			}
+		}
+	}
+}
+
+// resetExpiry sets t to fire when the connection's token expires.
+func (c *wsConn) resetExpiry(t *time.Timer) {
+	c.mu.Lock()
+	expiresAt := c.expiresAt
+	c.mu.Unlock()
+	t.Stop()
+	if !expiresAt.IsZero() {
+		t.Reset(time.Until(expiresAt))
+	}
+}
+
+// write sends m, giving up on a client that can't take it in time.
+func (c *wsConn) write(ctx context.Context, m wsMessage) bool {
+	ctx, cancel := context.WithTimeout(ctx, streamWriteTimeout)
+	defer cancel()
+	return wsjson.Write(ctx, c.conn, m) == nil
+}
+
+// errorMessage reports err to the client as a problem, logging it like
+// utils.RespondWithError would.
+func (c *wsConn) errorMessage(ctx context.Context, topic string, err error) wsMessage {
+	p := *problem.From(err)
+	p.RequestID = logging.RequestID(ctx)
+	if p.Status > 499 {
+		slog.ErrorContext(ctx, "Sending 5XX error over WebSocket", "status", p.Status, "code", p.Code, "error", err)
+	}
+	return wsMessage{Type: "error", Topic: topic, Error: &p}
+}
+
+// wsCloseReason says why the server is closing a connection whose
+// subscription ended.
+func wsCloseReason(err error) (websocket.StatusCode, string) {
+	switch {
+	case errors.Is(err, events.ErrSlow):
+		return websocket.StatusTryAgainLater, "fell behind"
+	case errors.Is(err, events.ErrReset):
+		return websocket.StatusServiceRestart, "events may have been missed"
+	}
+	return websocket.StatusGoingAway, "server shutting down"
+}
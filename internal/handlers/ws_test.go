@@ -0,0 +1,262 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+
+	"github.com/HemahWeb/chirpy/internal/events"
+	"github.com/HemahWeb/chirpy/internal/problem"
+	"github.com/HemahWeb/chirpy/internal/types"
+)
+
+type wsClient struct {
+	t    *testing.T
+	conn *websocket.Conn
+}
+
+// dial opens a WebSocket on a real server, with auth as the whole
+// Authorization header if it's set. It's closed when the test ends.
+func (s *testServer) dial(auth string) *wsClient {
+	s.t.Helper()
+	server := httptest.NewServer(s.mux)
+	s.t.Cleanup(server.Close)
+	s.cover(httptest.NewRequest(http.MethodGet, "/api/ws", nil))
+
+	opts := &websocket.DialOptions{HTTPHeader: http.Header{}}
+	if auth != "" {
+		opts.HTTPHeader.Set("Authorization", auth)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, _, err := websocket.Dial(ctx, "ws"+strings.TrimPrefix(server.URL, "http")+"/api/ws", opts)
+	if err != nil {
+		s.t.Fatalf("couldn't open a WebSocket: %v", err)
+	}
+	s.t.Cleanup(func() { conn.CloseNow() })
+	return &wsClient{t: s.t, conn: conn}
+}
+
+func (c *wsClient) send(msg wsClientMessage) {
+	c.t.Helper()
+	if err := wsjson.Write(context.Background(), c.conn, msg); err != nil {
+		c.t.Fatalf("couldn't send %+v: %v", msg, err)
+	}
+}
+
+func (c *wsClient) read() (wsMessage, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var m wsMessage
+	err := wsjson.Read(ctx, c.conn, &m)
+	return m, err
+}
+
+// next returns the next message, failing the test if none arrives soon.
+func (c *wsClient) next(typ string) wsMessage {
+	c.t.Helper()
+	m, err := c.read()
+	if err != nil {
+		c.t.Fatalf("waiting for a %s message: %v", typ, err)
+	}
+	if m.Type != typ {
+		c.t.Fatalf("got %+v, want a %s message", m, typ)
+	}
+	return m
+}
+
+// expectEvent reads the next message and checks it's a typ event for topics.
+func (c *wsClient) expectEvent(typ events.Type, topics ...string) events.Event {
+	c.t.Helper()
+	m := c.next("event")
+	if m.Event.Type != typ || strings.Join(m.Topics, " ") != strings.Join(topics, " ") {
+		c.t.Errorf("got a %s event for %v, want %s for %v", m.Event.Type, m.Topics, typ, topics)
+	}
+	return *m.Event
+}
+
+// expectChirp reads the next message and checks it's a chirp.created event
+// about chirp for topics.
+func (c *wsClient) expectChirp(chirp types.Chirp, topics ...string) {
+	c.t.Helper()
+	e := c.expectEvent(events.ChirpCreated, topics...)
+	var got types.Chirp
+	if err := json.Unmarshal(e.Data, &got); err != nil || got.ID != chirp.ID {
+		c.t.Errorf("event is about %+v, %v; want %+v", got, err, chirp)
+	}
+}
+
+func (c *wsClient) expectError(code problem.Code) {
+	c.t.Helper()
+	if m := c.next("error"); m.Error.Code != code {
+		c.t.Errorf("error = %+v, want %s", m.Error, code)
+	}
+}
+
+// expectClose reads until the server closes the connection with code.
+func (c *wsClient) expectClose(code websocket.StatusCode) {
+	c.t.Helper()
+	for {
+		m, err := c.read()
+		if err == nil {
+			continue
+		}
+		if got := websocket.CloseStatus(err); got != code {
+			c.t.Errorf("connection ended with %v (%v), want %v; last message %+v", got, err, code, m)
+		}
+		return
+	}
+}
+
+func TestWebSocket(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, s *testServer) {
+		walt := s.signUp("walt@breakingbad.com")
+		jesse := s.signUp("jesse@breakingbad.com")
+		skyler := s.signUp("skyler@breakingbad.com")
+		expectStatus(t, s.do(http.MethodPost, "/api/users/"+walt.ID.String()+"/follow", nil, jesse.bearer()), http.StatusNoContent)
+		root := s.postChirp(skyler, "We need to talk")
+
+		// authenticating with a message
+		waltWS := s.dial("")
+		waltWS.send(wsClientMessage{Type: "auth", Token: walt.Token})
+		if m := waltWS.next("ready"); m.UserID != walt.ID || m.ExpiresAt.IsZero() {
+			t.Errorf("ready = %+v, want walt's ID and the token's expiry", m)
+		}
+		waltWS.send(wsClientMessage{Type: "subscribe", Topic: "notifications"})
+		waltWS.next("subscribed")
+
+		// and with the header
+		jesseWS := s.dial(jesse.bearer())
+		jesseWS.next("ready")
+		thread := "thread:" + root.ID.String()
+		for _, topic := range []string{"following", thread} {
+			jesseWS.send(wsClientMessage{Type: "subscribe", Topic: topic})
+			if m := jesseWS.next("subscribed"); m.Topic != topic {
+				t.Errorf("subscribed to %q, want %q", m.Topic, topic)
+			}
+		}
+
+		expectStatus(t, s.do(http.MethodPost, "/api/users/"+walt.ID.String()+"/follow", nil, skyler.bearer()), http.StatusNoContent)
+		e := waltWS.expectEvent(events.NotificationCreated, "notifications")
+		var n events.Notification
+		if err := json.Unmarshal(e.Data, &n); err != nil || n.Type != events.NotifyFollow || n.ActorID != skyler.ID {
+			t.Errorf("notification = %+v, %v; want skyler's follow", n, err)
+		}
+
+		cook := s.postChirp(walt, "Say my name")
+		jesseWS.expectChirp(cook, "following")
+
+		rec := s.do(http.MethodPost, "/api/chirps", map[string]any{"body": "Not now", "reply_to_id": root.ID}, walt.bearer())
+		expectStatus(t, rec, http.StatusCreated)
+		jesseWS.expectChirp(decode[types.Chirp](t, rec), "following", thread)
+
+		jesseWS.send(wsClientMessage{Type: "unsubscribe", Topic: "following"})
+		jesseWS.next("unsubscribed")
+		s.postChirp(walt, "I am the one who knocks")
+		rec = s.do(http.MethodPost, "/api/chirps", map[string]any{"body": "Then when?", "reply_to_id": root.ID}, skyler.bearer())
+		jesseWS.expectChirp(decode[types.Chirp](t, rec), thread)
+
+		jesseWS.send(wsClientMessage{Type: "subscribe", Topic: "everything"})
+		jesseWS.expectError(problem.CodeValidationFailed)
+		jesseWS.send(wsClientMessage{Type: "subscribe", Topic: "thread:walt"})
+		jesseWS.expectError(problem.CodeValidationFailed)
+		jesseWS.send(wsClientMessage{Type: "subscribe", Topic: "thread:" + uuid.NewString()})
+		jesseWS.expectError(problem.CodeNotFound)
+		jesseWS.send(wsClientMessage{Type: "shout"})
+		jesseWS.expectError(problem.CodeValidationFailed)
+		jesseWS.send(wsClientMessage{Type: "auth", Token: walt.Token})
+		jesseWS.expectError(problem.CodeForbidden)
+		jesseWS.conn.Write(context.Background(), websocket.MessageText, []byte("{"))
+		jesseWS.expectError(problem.CodeInvalidJSON)
+
+		// renewing the token keeps the connection
+		jesseWS.send(wsClientMessage{Type: "auth", Token: jesse.Token})
+		jesseWS.next("ready")
+	})
+}
+
+func TestWebSocketAuth(t *testing.T) {
+	s := newTestServer(t, openMemory)
+	user := s.signUp("gus@lospollos.com")
+
+	expectProblem(t, s.do(http.MethodGet, "/api/ws", nil, "Bearer forged"), http.StatusUnauthorized, problem.CodeInvalidToken)
+
+	ws := s.dial("")
+	ws.send(wsClientMessage{Type: "subscribe", Topic: "notifications"})
+	ws.expectError(problem.CodeUnauthenticated)
+	ws.expectClose(websocket.StatusPolicyViolation)
+
+	ws = s.dial("")
+	ws.send(wsClientMessage{Type: "auth", Token: "forged"})
+	ws.expectError(problem.CodeInvalidToken)
+	ws.expectClose(websocket.StatusPolicyViolation)
+
+	// the connection ends with the token unless it's renewed
+	claims := jwt.RegisteredClaims{
+		Subject:   user.ID.String(),
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Second)),
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(testJWTSecret))
+	if err != nil {
+		t.Fatalf("couldn't sign a token: %v", err)
+	}
+	ws = s.dial("Bearer " + token)
+	ws.next("ready")
+	ws.expectClose(websocket.StatusPolicyViolation)
+}
+
+func TestWebSocketSuspension(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, s *testServer) {
+		walt := s.signUp("walt@breakingbad.com")
+		jesse := s.signUp("jesse@breakingbad.com")
+		hank := s.moderator("hank@dea.gov")
+		waltWS := s.dial(walt.bearer())
+		waltWS.next("ready")
+		jesseWS := s.dial(jesse.bearer())
+		jesseWS.next("ready")
+
+		// a suspended user's connections close, and no one else's
+		expectStatus(t, s.do(http.MethodPost, "/api/moderation/users/"+walt.ID.String()+"/suspend", nil, hank.bearer()), http.StatusNoContent)
+		waltWS.expectClose(websocket.StatusPolicyViolation)
+		jesseWS.send(wsClientMessage{Type: "subscribe", Topic: "notifications"})
+		jesseWS.next("subscribed")
+
+		// and they can't connect again
+		expectProblem(t, s.do(http.MethodGet, "/api/ws", nil, walt.bearer()), http.StatusForbidden, problem.CodeAccountSuspended)
+	})
+}
+
+func TestWebSocketServerShutdown(t *testing.T) {
+	s := newTestServer(t, openMemory)
+	s.cfg.StreamHeartbeat = time.Millisecond // pings must not get in the way
+	user := s.signUp("gus@lospollos.com")
+	ws := s.dial(user.bearer())
+	ws.next("ready")
+
+	s.cfg.Stream.Close()
+	ws.expectClose(websocket.StatusGoingAway)
+}
+
+func TestWSCloseReason(t *testing.T) {
+	for _, tt := range []struct {
+		err  error
+		want websocket.StatusCode
+	}{
+		{events.ErrSlow, websocket.StatusTryAgainLater},
+		{events.ErrReset, websocket.StatusServiceRestart},
+		{events.ErrClosed, websocket.StatusGoingAway},
+	} {
+		if got, reason := wsCloseReason(tt.err); got != tt.want || reason == "" {
+			t.Errorf("wsCloseReason(%v) = %v, %q; want %v", tt.err, got, reason, tt.want)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package health
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+const (
+	StatusOK          = "ok"
+	StatusUnavailable = "unavailable"
+	// StatusFail is a failed check. Why it failed is logged rather than
+	// reported, since the report is public.
+	StatusFail = "fail"
+)
+
+// Check reports whether a dependency is usable. It should respect ctx.
+type Check func(ctx context.Context) error
+
+type CheckResult struct {
+	Status    string  `json:"status"`
+	LatencyMS float64 `json:"latency_ms"`
+}
+
+type Report struct {
+	Status    string                 `json:"status"`
+	Checks    map[string]CheckResult `json:"checks"`
+	CheckedAt time.Time              `json:"checked_at"`
+}
+
+func (r Report) OK() bool {
+	return r.Status == StatusOK
+}
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+// Checker runs dependency checks and caches the result for ttl so a busy
+// probe can't turn into a load test of the database.
+type Checker struct {
+	timeout time.Duration
+	ttl     time.Duration
+	now     func() time.Time
+
+	mu       sync.Mutex
+	checks   []namedCheck
+	cached   Report
+	cachedAt time.Time
+}
+
+func NewChecker(timeout, ttl time.Duration) *Checker {
+	return &Checker{timeout: timeout, ttl: ttl, now: time.Now}
+}
+
+func (c *Checker) Add(name string, check Check) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checks = append(c.checks, namedCheck{name: name, check: check})
+	c.cachedAt = time.Time{}
+}
+
+// Run returns the cached report if it is fresh, otherwise runs every check
+// in parallel, each bounded by the checker's timeout. The checks outlive
+// ctx's cancellation, so a probe that hangs up doesn't get its run cached
+// as a failure for everyone else.
+func (c *Checker) Run(ctx context.Context) Report {
+	// holding the lock while checking means concurrent probes wait for one
+	// run instead of each starting their own
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.cachedAt.IsZero() && c.now().Sub(c.cachedAt) < c.ttl {
+		return c.cached
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), c.timeout)
+	defer cancel()
+
+	results := make([]CheckResult, len(c.checks))
+	var wg sync.WaitGroup
+	for i, nc := range c.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			start := time.Now()
+			err := nc.check(ctx)
+			res := CheckResult{
+				Status:    StatusOK,
+				LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+			}
+			if err != nil {
+				res.Status = StatusFail
+				slog.WarnContext(ctx, "Readiness check failed", "check", nc.name, "error", err)
+			}
+			results[i] = res
+		}()
+	}
+	wg.Wait()
+
+	report := Report{
+		Status:    StatusOK,
+		Checks:    make(map[string]CheckResult, len(c.checks)),
+		CheckedAt: c.now().UTC(),
+	}
+	for i, nc := range c.checks {
+		report.Checks[nc.name] = results[i]
+		if results[i].Status != StatusOK {
+			report.Status = StatusUnavailable
+		}
+	}
+
+	c.cached = report
+	c.cachedAt = c.now()
+	return report
+}
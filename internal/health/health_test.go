@@ -0,0 +1,77 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRunReportsEachCheck(t *testing.T) {
+	c := NewChecker(time.Second, 0)
+	c.Add("database", func(ctx context.Context) error { return nil })
+	c.Add("migrations", func(ctx context.Context) error { return errors.New("2 pending") })
+
+	report := c.Run(context.Background())
+	if report.OK() {
+		t.Error("report should not be OK when a check fails")
+	}
+	if got := report.Checks["database"]; got.Status != StatusOK {
+		t.Errorf("database check = %+v, want ok", got)
+	}
+	if got := report.Checks["migrations"]; got.Status != StatusFail {
+		t.Errorf("migrations check = %+v, want fail", got)
+	}
+}
+
+func TestRunCachesResults(t *testing.T) {
+	var calls atomic.Int32
+	c := NewChecker(time.Second, 5*time.Second)
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	c.now = func() time.Time { return now }
+	c.Add("database", func(ctx context.Context) error {
+		calls.Add(1)
+		return nil
+	})
+
+	c.Run(context.Background())
+	c.Run(context.Background())
+	if calls.Load() != 1 {
+		t.Errorf("check ran %d times within the TTL, want 1", calls.Load())
+	}
+
+	now = now.Add(6 * time.Second)
+	c.Run(context.Background())
+	if calls.Load() != 2 {
+		t.Errorf("check ran %d times after the TTL expired, want 2", calls.Load())
+	}
+}
+
+func TestRunTimesOutSlowChecks(t *testing.T) {
+	c := NewChecker(10*time.Millisecond, 0)
+	c.Add("database", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	start := time.Now()
+	report := c.Run(context.Background())
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Run() took %v, the timeout should have stopped it", elapsed)
+	}
+	if report.OK() {
+		t.Error("a timed out check should make the report unavailable")
+	}
+}
+
+func TestRunOutlivesCallerCancellation(t *testing.T) {
+	c := NewChecker(time.Second, time.Minute)
+	c.Add("database", func(ctx context.Context) error { return ctx.Err() })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if report := c.Run(ctx); !report.OK() {
+		t.Errorf("report = %+v, a caller hanging up shouldn't fail the checks", report)
+	}
+}
@@ -0,0 +1,125 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// New builds the process logger. format is "json" or "text".
+func New(w io.Writer, format, level string) (*slog.Logger, error) {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("invalid log level %q", level)
+	}
+	opts := &slog.HandlerOptions{Level: lvl}
+
+	var h slog.Handler
+	switch strings.ToLower(format) {
+	case "json":
+		h = slog.NewJSONHandler(w, opts)
+	case "text":
+		h = slog.NewTextHandler(w, opts)
+	default:
+		return nil, fmt.Errorf("invalid log format %q, use json or text", format)
+	}
+	return slog.New(ContextHandler{h}), nil
+}
+
+type ctxKey struct{}
+
+// requestInfo is shared by everything handling one request. Middleware
+// creates it, handlers fill in the user once they've authenticated them.
+type requestInfo struct {
+	mu        sync.Mutex
+	requestID string
+	route     string
+	userID    string
+}
+
+// WithRequestID starts request-scoped logging for ctx.
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, ctxKey{}, &requestInfo{requestID: requestID})
+}
+
+func info(ctx context.Context) *requestInfo {
+	ri, _ := ctx.Value(ctxKey{}).(*requestInfo)
+	return ri
+}
+
+// RequestID returns the request ID for ctx, or "" outside a request.
+func RequestID(ctx context.Context) string {
+	ri := info(ctx)
+	if ri == nil {
+		return ""
+	}
+	ri.mu.Lock()
+	defer ri.mu.Unlock()
+	return ri.requestID
+}
+
+// UserID returns the authenticated user recorded with SetUserID, or "".
+func UserID(ctx context.Context) string {
+	ri := info(ctx)
+	if ri == nil {
+		return ""
+	}
+	ri.mu.Lock()
+	defer ri.mu.Unlock()
+	return ri.userID
+}
+
+func SetRoute(ctx context.Context, route string) {
+	if ri := info(ctx); ri != nil {
+		ri.mu.Lock()
+		ri.route = route
+		ri.mu.Unlock()
+	}
+}
+
+// SetUserID tags every later log line for this request with the user.
+func SetUserID(ctx context.Context, userID fmt.Stringer) {
+	if ri := info(ctx); ri != nil {
+		ri.mu.Lock()
+		ri.userID = userID.String()
+		ri.mu.Unlock()
+	}
+}
+
+// ContextHandler adds the request ID, route, user ID and trace ID from the
+// context to every record, so callers only need the *Context logging
+// functions.
+type ContextHandler struct {
+	slog.Handler
+}
+
+func (h ContextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if ri := info(ctx); ri != nil {
+		ri.mu.Lock()
+		r.AddAttrs(slog.String("request_id", ri.requestID))
+		if ri.route != "" {
+			r.AddAttrs(slog.String("route", ri.route))
+		}
+		if ri.userID != "" {
+			r.AddAttrs(slog.String("user_id", ri.userID))
+		}
+		ri.mu.Unlock()
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		r.AddAttrs(slog.String("trace_id", sc.TraceID().String()), slog.String("span_id", sc.SpanID().String()))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h ContextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return ContextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h ContextHandler) WithGroup(name string) slog.Handler {
+	return ContextHandler{h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,91 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestContextHandlerAddsRequestFields(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, "json", "info")
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	userID := uuid.New()
+	ctx := WithRequestID(context.Background(), "req-123")
+	SetRoute(ctx, "POST /api/chirps")
+	SetUserID(ctx, userID)
+	logger.InfoContext(ctx, "hello")
+
+	var line map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatalf("log line is not JSON: %v\n%s", err, buf.String())
+	}
+	want := map[string]string{
+		"msg":        "hello",
+		"request_id": "req-123",
+		"route":      "POST /api/chirps",
+		"user_id":    userID.String(),
+	}
+	for k, v := range want {
+		if line[k] != v {
+			t.Errorf("%s = %v, want %q", k, line[k], v)
+		}
+	}
+}
+
+func TestContextHandlerWithoutRequest(t *testing.T) {
+	var buf bytes.Buffer
+	logger, _ := New(&buf, "json", "info")
+	logger.InfoContext(context.Background(), "startup")
+
+	var line map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatalf("log line is not JSON: %v", err)
+	}
+	if _, ok := line["request_id"]; ok {
+		t.Error("request_id should be absent outside a request")
+	}
+}
+
+func TestContextHandlerAddsTraceID(t *testing.T) {
+	var buf bytes.Buffer
+	logger, _ := New(&buf, "json", "info")
+
+	sc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: trace.TraceID{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36},
+		SpanID:  trace.SpanID{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7},
+	})
+	logger.InfoContext(trace.ContextWithSpanContext(context.Background(), sc), "traced")
+
+	var line map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatalf("log line is not JSON: %v", err)
+	}
+	if line["trace_id"] != "4bf92f3577b34da6a3ce929d0e0e4736" || line["span_id"] != "00f067aa0ba902b7" {
+		t.Errorf("trace_id = %v, span_id = %v", line["trace_id"], line["span_id"])
+	}
+}
+
+func TestNewRejectsBadSettings(t *testing.T) {
+	if _, err := New(&bytes.Buffer{}, "xml", "info"); err == nil {
+		t.Error("New() should reject unknown formats")
+	}
+	if _, err := New(&bytes.Buffer{}, "json", "loud"); err == nil {
+		t.Error("New() should reject unknown levels")
+	}
+	logger, err := New(&bytes.Buffer{}, "text", "debug")
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	if !logger.Enabled(context.Background(), slog.LevelDebug) {
+		t.Error("debug level should be enabled")
+	}
+}
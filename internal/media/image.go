@@ -0,0 +1,159 @@
+package media
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+)
+
+// Errors from Process, for the caller to report against the upload.
+var (
+	ErrUnsupportedType = errors.New("not a JPEG, PNG or GIF image")
+	ErrTooLarge        = errors.New("image is too large")
+	ErrInvalidImage    = errors.New("image can't be decoded")
+)
+
+// ThumbnailSize is the most either side of a thumbnail is, in pixels.
+const ThumbnailSize = 320
+
+// Limits bound what Process accepts.
+type Limits struct {
+	// MaxBytes is the largest upload, before its metadata is stripped.
+	MaxBytes int64
+	// MaxPixels caps width times height, so a small file can't decode to
+	// an enormous bitmap.
+	MaxPixels int
+}
+
+func DefaultLimits() Limits {
+	return Limits{
+		MaxBytes:  5 << 20,
+		MaxPixels: 40_000_000,
+	}
+}
+
+// Image is an upload ready to be stored.
+type Image struct {
+	ContentType string
+	Data        []byte
+	Width       int
+	Height      int
+	Thumbnail   Thumbnail
+}
+
+type Thumbnail struct {
+	ContentType string
+	Data        []byte
+	Width       int
+	Height      int
+}
+
+// Process checks that data is a JPEG, PNG or GIF image by its content,
+// whatever the client said it was, and returns it without the metadata it
+// carried (EXIF, including any GPS position, XMP and comments) along with
+// a thumbnail. A JPEG the camera marked as rotated is turned upright.
+func Process(data []byte, limits Limits) (Image, error) {
+	if int64(len(data)) > limits.MaxBytes {
+		return Image{}, ErrTooLarge
+	}
+	contentType := http.DetectContentType(data)
+	switch contentType {
+	case "image/jpeg", "image/png", "image/gif":
+	default:
+		return Image{}, ErrUnsupportedType
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return Image{}, fmt.Errorf("%w: %v", ErrInvalidImage, err)
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 {
+		return Image{}, ErrInvalidImage
+	}
+	if cfg.Width*cfg.Height > limits.MaxPixels {
+		return Image{}, ErrTooLarge
+	}
+
+	img, err := decode(contentType, data)
+	if err != nil {
+		return Image{}, fmt.Errorf("%w: %v", ErrInvalidImage, err)
+	}
+
+	out := Image{ContentType: contentType}
+	switch contentType {
+	case "image/jpeg":
+		if o := jpegOrientation(data); o > 1 {
+			// there's no lossless way to turn the pixels, so re-encode
+			img = orient(img, o)
+			var buf bytes.Buffer
+			if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 90}); err != nil {
+				return Image{}, err
+			}
+			out.Data = buf.Bytes()
+		} else {
+			out.Data, err = stripJPEG(data)
+		}
+	case "image/png":
+		out.Data, err = stripPNG(data)
+	case "image/gif":
+		out.Data, err = stripGIF(data)
+	}
+	if err != nil {
+		return Image{}, fmt.Errorf("%w: %v", ErrInvalidImage, err)
+	}
+	b := img.Bounds()
+	out.Width, out.Height = b.Dx(), b.Dy()
+
+	out.Thumbnail, err = thumbnail(img, contentType)
+	if err != nil {
+		return Image{}, err
+	}
+	return out, nil
+}
+
+// decode returns the image, or a GIF's first frame.
+func decode(contentType string, data []byte) (image.Image, error) {
+	r := bytes.NewReader(data)
+	switch contentType {
+	case "image/jpeg":
+		return jpeg.Decode(r)
+	case "image/png":
+		return png.Decode(r)
+	default:
+		return gif.Decode(r)
+	}
+}
+
+// thumbnail scales img to fit in ThumbnailSize pixels square, keeping its
+// aspect ratio and never enlarging it. A JPEG stays a JPEG; anything else
+// becomes a PNG, which keeps transparency.
+func thumbnail(img image.Image, contentType string) (Thumbnail, error) {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w > ThumbnailSize || h > ThumbnailSize {
+		if w >= h {
+			w, h = ThumbnailSize, max(1, h*ThumbnailSize/w)
+		} else {
+			w, h = max(1, w*ThumbnailSize/h), ThumbnailSize
+		}
+	}
+	small := resize(img, w, h)
+
+	var buf bytes.Buffer
+	t := Thumbnail{Width: w, Height: h}
+	var err error
+	if contentType == "image/jpeg" {
+		t.ContentType = "image/jpeg"
+		err = jpeg.Encode(&buf, small, &jpeg.Options{Quality: 80})
+	} else {
+		t.ContentType = "image/png"
+		err = png.Encode(&buf, small)
+	}
+	t.Data = buf.Bytes()
+	return t, err
+}
@@ -0,0 +1,232 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+// testImage is w by h, red on the left half and blue on the right.
+func testImage(w, h int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := range h {
+		for x := range w {
+			c := color.RGBA{R: 255, A: 255}
+			if x >= w/2 {
+				c = color.RGBA{B: 255, A: 255}
+			}
+			img.SetRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+// exifSegment is an APP1 segment holding an orientation and, like a phone
+// would, something private.
+func exifSegment(orientation uint16) []byte {
+	tiff := []byte("MM\x00\x2a\x00\x00\x00\x08")
+	tiff = binary.BigEndian.AppendUint16(tiff, 1)           // one entry
+	tiff = binary.BigEndian.AppendUint16(tiff, 0x0112)      // Orientation
+	tiff = binary.BigEndian.AppendUint16(tiff, 3)           // SHORT
+	tiff = binary.BigEndian.AppendUint32(tiff, 1)           // count
+	tiff = binary.BigEndian.AppendUint16(tiff, orientation) // value
+	tiff = append(tiff, 0, 0, 0, 0, 0, 0)                   // padding, no next IFD
+	tiff = append(tiff, "GPS 35.0844N 106.6504W"...)
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	seg := []byte{0xFF, markerAPP1}
+	seg = binary.BigEndian.AppendUint16(seg, uint16(len(payload)+2))
+	return append(seg, payload...)
+}
+
+func encodeJPEG(t *testing.T, img image.Image, segments ...[]byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		t.Fatalf("jpeg.Encode() failed: %v", err)
+	}
+	data := buf.Bytes()
+	// insert after SOI
+	out := append([]byte{}, data[:2]...)
+	for _, s := range segments {
+		out = append(out, s...)
+	}
+	return append(out, data[2:]...)
+}
+
+func TestProcessStripsJPEGMetadata(t *testing.T) {
+	comment := []byte{0xFF, markerCOM, 0x00, 0x0A, 's', 'e', 'c', 'r', 'e', 't', '!', '!'}
+	data := encodeJPEG(t, testImage(40, 20), exifSegment(1), comment)
+
+	img, err := Process(data, DefaultLimits())
+	if err != nil {
+		t.Fatalf("Process() failed: %v", err)
+	}
+	if img.ContentType != "image/jpeg" || img.Width != 40 || img.Height != 20 {
+		t.Errorf("Process() = %s %dx%d, want image/jpeg 40x20", img.ContentType, img.Width, img.Height)
+	}
+	for _, leak := range []string{"Exif", "GPS", "secret"} {
+		if bytes.Contains(img.Data, []byte(leak)) {
+			t.Errorf("processed image still contains %q", leak)
+		}
+	}
+	if _, err := jpeg.Decode(bytes.NewReader(img.Data)); err != nil {
+		t.Errorf("processed image doesn't decode: %v", err)
+	}
+	if want := len(data) - len(exifSegment(1)) - len(comment); len(img.Data) != want {
+		t.Errorf("processed image is %d bytes, want %d: the pixels should be copied untouched", len(img.Data), want)
+	}
+}
+
+func TestProcessDropsJPEGTrailer(t *testing.T) {
+	// motion photos append their video after the end of image marker
+	photo := encodeJPEG(t, testImage(40, 20))
+	data := append(append([]byte{}, photo...), "\x00\x00\x00\x18ftypmp42 GPS 35.0844N 106.6504W"...)
+
+	img, err := Process(data, DefaultLimits())
+	if err != nil {
+		t.Fatalf("Process() failed: %v", err)
+	}
+	if !bytes.Equal(img.Data, photo) {
+		t.Errorf("processed image is %d bytes, want the %d before the trailer", len(img.Data), len(photo))
+	}
+}
+
+func TestProcessRotatesJPEG(t *testing.T) {
+	// a phone held upright stores the picture on its side with orientation 6
+	data := encodeJPEG(t, testImage(40, 20), exifSegment(6))
+
+	img, err := Process(data, DefaultLimits())
+	if err != nil {
+		t.Fatalf("Process() failed: %v", err)
+	}
+	if img.Width != 20 || img.Height != 40 {
+		t.Errorf("Process() = %dx%d, want 20x40", img.Width, img.Height)
+	}
+	if bytes.Contains(img.Data, []byte("GPS")) {
+		t.Error("rotated image still has its EXIF")
+	}
+	decoded, err := jpeg.Decode(bytes.NewReader(img.Data))
+	if err != nil {
+		t.Fatalf("rotated image doesn't decode: %v", err)
+	}
+	// turning clockwise puts the red left half on top
+	if r, _, b, _ := decoded.At(10, 5).RGBA(); r < b {
+		t.Error("top of the rotated image should be red")
+	}
+}
+
+func TestOrient(t *testing.T) {
+	// 2x1: red then blue
+	src := testImage(2, 1)
+	red := color.RGBA{R: 255, A: 255}
+	tests := []struct {
+		orientation int
+		w, h        int
+		redAt       image.Point
+	}{
+		{1, 2, 1, image.Pt(0, 0)},
+		{2, 2, 1, image.Pt(1, 0)},
+		{3, 2, 1, image.Pt(1, 0)},
+		{4, 2, 1, image.Pt(0, 0)},
+		{5, 1, 2, image.Pt(0, 0)},
+		{6, 1, 2, image.Pt(0, 0)},
+		{7, 1, 2, image.Pt(0, 1)},
+		{8, 1, 2, image.Pt(0, 1)},
+	}
+	for _, tt := range tests {
+		got := orient(src, tt.orientation)
+		if b := got.Bounds(); b.Dx() != tt.w || b.Dy() != tt.h {
+			t.Errorf("orient(%d) is %dx%d, want %dx%d", tt.orientation, b.Dx(), b.Dy(), tt.w, tt.h)
+			continue
+		}
+		if c := color.RGBAModel.Convert(got.At(tt.redAt.X, tt.redAt.Y)); c != red {
+			t.Errorf("orient(%d) at %v = %v, want red", tt.orientation, tt.redAt, c)
+		}
+	}
+}
+
+func pngChunk(kind string, data []byte) []byte {
+	chunk := binary.BigEndian.AppendUint32(nil, uint32(len(data)))
+	chunk = append(chunk, kind...)
+	chunk = append(chunk, data...)
+	return binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(chunk[4:]))
+}
+
+func TestProcessStripsPNGMetadata(t *testing.T) {
+	var buf bytes.Buffer
+	png.Encode(&buf, testImage(400, 100))
+	data := buf.Bytes()
+	// tEXt goes before the image data, right after IHDR
+	ihdrEnd := len(pngSignature) + 12 + 13
+	withText := append([]byte{}, data[:ihdrEnd]...)
+	withText = append(withText, pngChunk("tEXt", []byte("Author\x00Walter White"))...)
+	withText = append(withText, data[ihdrEnd:]...)
+
+	img, err := Process(withText, DefaultLimits())
+	if err != nil {
+		t.Fatalf("Process() failed: %v", err)
+	}
+	if !bytes.Equal(img.Data, data) {
+		t.Error("processed PNG should be the original without its tEXt chunk")
+	}
+	if img.Thumbnail.ContentType != "image/png" || img.Thumbnail.Width != ThumbnailSize || img.Thumbnail.Height != 80 {
+		t.Errorf("thumbnail = %s %dx%d, want image/png %dx80", img.Thumbnail.ContentType, img.Thumbnail.Width, img.Thumbnail.Height, ThumbnailSize)
+	}
+	thumb, err := png.Decode(bytes.NewReader(img.Thumbnail.Data))
+	if err != nil {
+		t.Fatalf("thumbnail doesn't decode: %v", err)
+	}
+	if b := thumb.Bounds(); b.Dx() != ThumbnailSize || b.Dy() != 80 {
+		t.Errorf("thumbnail decodes to %v", b)
+	}
+}
+
+func TestProcessGIF(t *testing.T) {
+	pal := color.Palette{color.Black, color.White}
+	frame := image.NewPaletted(image.Rect(0, 0, 10, 10), pal)
+	var buf bytes.Buffer
+	gif.EncodeAll(&buf, &gif.GIF{Image: []*image.Paletted{frame, frame}, Delay: []int{10, 10}})
+
+	img, err := Process(buf.Bytes(), DefaultLimits())
+	if err != nil {
+		t.Fatalf("Process() failed: %v", err)
+	}
+	g, err := gif.DecodeAll(bytes.NewReader(img.Data))
+	if err != nil || len(g.Image) != 2 {
+		t.Errorf("processed GIF has lost its frames: %v", err)
+	}
+	// small images aren't enlarged
+	if img.Thumbnail.Width != 10 || img.Thumbnail.Height != 10 {
+		t.Errorf("thumbnail is %dx%d, want 10x10", img.Thumbnail.Width, img.Thumbnail.Height)
+	}
+}
+
+func TestProcessRejects(t *testing.T) {
+	jpg := encodeJPEG(t, testImage(100, 100))
+	tests := []struct {
+		name   string
+		data   []byte
+		limits Limits
+		want   error
+	}{
+		{"text", []byte("<html><body>not an image</body></html>"), DefaultLimits(), ErrUnsupportedType},
+		{"webp", []byte("RIFF\x00\x00\x00\x00WEBPVP8 "), DefaultLimits(), ErrUnsupportedType},
+		{"too many bytes", jpg, Limits{MaxBytes: 10, MaxPixels: 1 << 20}, ErrTooLarge},
+		{"too many pixels", jpg, Limits{MaxBytes: 1 << 20, MaxPixels: 99 * 99}, ErrTooLarge},
+		{"truncated", jpg[:len(jpg)/2], DefaultLimits(), ErrInvalidImage},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Process(tt.data, tt.limits); !errors.Is(err, tt.want) {
+				t.Errorf("Process() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,26 @@
+package media
+
+import (
+	"context"
+	"os"
+	"testing"
+)
+
+func TestLocalStore(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewLocalStore(dir)
+	if err != nil {
+		t.Fatalf("NewLocalStore() failed: %v", err)
+	}
+	testBlobStore(t, store)
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 0 {
+		t.Errorf("directory holds %d files after deleting everything, want none", len(entries))
+	}
+	for _, key := range []string{"", "..", "../escape", `a\b`} {
+		if err := store.Put(context.Background(), key, "image/png", []byte("x")); err == nil {
+			t.Errorf("Put(%q) should fail", key)
+		}
+	}
+}
@@ -0,0 +1,218 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image/gif"
+)
+
+var errTruncated = errors.New("truncated image")
+
+// JPEG markers; every one but these few is followed by a two-byte length.
+const (
+	markerSOI  = 0xD8
+	markerEOI  = 0xD9
+	markerSOS  = 0xDA
+	markerAPP0 = 0xE0
+	markerAPP1 = 0xE1
+	markerAPP2 = 0xE2
+	markerAP14 = 0xEE
+	markerAP15 = 0xEF
+	markerCOM  = 0xFE
+)
+
+// jpegSegments calls fn with each marker segment before the image data,
+// stopping at the start of scan, and returns the offset the scan starts
+// at.
+func jpegSegments(data []byte, fn func(marker byte, segment []byte)) (int, error) {
+	if len(data) < 2 || data[0] != 0xFF || data[1] != markerSOI {
+		return 0, errors.New("missing JPEG start of image")
+	}
+	i := 2
+	for {
+		// markers may be padded with any number of 0xFF bytes
+		for i < len(data) && data[i] == 0xFF && i+1 < len(data) && data[i+1] == 0xFF {
+			i++
+		}
+		if i+4 > len(data) || data[i] != 0xFF {
+			return 0, errTruncated
+		}
+		marker := data[i+1]
+		if marker == markerSOS || marker == markerEOI {
+			return i, nil
+		}
+		if marker >= 0xD0 && marker <= 0xD7 || marker == 0x01 {
+			// standalone markers have no length
+			fn(marker, data[i:i+2])
+			i += 2
+			continue
+		}
+		n := int(binary.BigEndian.Uint16(data[i+2:]))
+		if n < 2 || i+2+n > len(data) {
+			return 0, errTruncated
+		}
+		fn(marker, data[i:i+2+n])
+		i += 2 + n
+	}
+}
+
+// jpegEnd returns the offset just past the end of image marker, given the
+// offset of the first scan. Between scans, progressive JPEGs have more
+// marker segments, which are skipped by their length; within a scan, a
+// 0xFF data byte is always followed by a zero byte or a restart marker.
+func jpegEnd(data []byte, scan int) (int, error) {
+	i := scan
+	for i+1 < len(data) {
+		if data[i] != 0xFF {
+			i++
+			continue
+		}
+		marker := data[i+1]
+		switch {
+		case marker == markerEOI:
+			return i + 2, nil
+		case marker == 0x00, marker == 0xFF, marker >= 0xD0 && marker <= 0xD7:
+			i++
+		default:
+			if i+4 > len(data) {
+				return 0, errTruncated
+			}
+			n := int(binary.BigEndian.Uint16(data[i+2:]))
+			if n < 2 || i+2+n > len(data) {
+				return 0, errTruncated
+			}
+			i += 2 + n
+		}
+	}
+	return 0, errTruncated
+}
+
+// stripJPEG drops the application segments that hold EXIF, XMP and the
+// like, and comments. JFIF (APP0), ICC colour profiles (APP2) and the
+// Adobe colour transform (APP14) are kept since they change how the image
+// looks. Anything after the end of image, such as the video of a motion
+// photo, is dropped too.
+func stripJPEG(data []byte) ([]byte, error) {
+	out := make([]byte, 0, len(data))
+	out = append(out, 0xFF, markerSOI)
+	scan, err := jpegSegments(data, func(marker byte, segment []byte) {
+		switch {
+		case marker == markerCOM:
+			return
+		case marker == markerAPP2 && bytes.HasPrefix(segment[4:], []byte("ICC_PROFILE\x00")):
+		case marker > markerAPP0 && marker <= markerAP15 && marker != markerAP14:
+			return
+		}
+		out = append(out, segment...)
+	})
+	if err != nil {
+		return nil, err
+	}
+	end, err := jpegEnd(data, scan)
+	if err != nil {
+		return nil, err
+	}
+	return append(out, data[scan:end]...), nil
+}
+
+// jpegOrientation returns the EXIF orientation, 1 to 8, or 0 if there is
+// none.
+func jpegOrientation(data []byte) int {
+	orientation := 0
+	jpegSegments(data, func(marker byte, segment []byte) {
+		if marker == markerAPP1 && orientation == 0 && bytes.HasPrefix(segment[4:], []byte("Exif\x00\x00")) {
+			orientation = exifOrientation(segment[10:])
+		}
+	})
+	return orientation
+}
+
+// exifOrientation reads the Orientation tag from the first IFD of a TIFF
+// header, as found in an EXIF segment.
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 0
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 0
+	}
+	if order.Uint16(tiff[2:]) != 42 {
+		return 0
+	}
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd < 8 || ifd+2 > len(tiff) {
+		return 0
+	}
+	entries := int(order.Uint16(tiff[ifd:]))
+	for e := range entries {
+		entry := ifd + 2 + e*12
+		if entry+12 > len(tiff) {
+			return 0
+		}
+		const tagOrientation, typeShort = 0x0112, 3
+		if order.Uint16(tiff[entry:]) == tagOrientation && order.Uint16(tiff[entry+2:]) == typeShort {
+			if o := int(order.Uint16(tiff[entry+8:])); o >= 1 && o <= 8 {
+				return o
+			}
+			return 0
+		}
+	}
+	return 0
+}
+
+// pngSignature starts every PNG file.
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// pngMetadata are the chunks stripPNG drops: text, EXIF and the last
+// modification time.
+var pngMetadata = map[string]bool{"tEXt": true, "zTXt": true, "iTXt": true, "eXIf": true, "tIME": true}
+
+// stripPNG drops metadata chunks, copying the rest unchanged with their
+// checksums.
+func stripPNG(data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, pngSignature) {
+		return nil, errors.New("missing PNG signature")
+	}
+	out := make([]byte, 0, len(data))
+	out = append(out, pngSignature...)
+	for i := len(pngSignature); i < len(data); {
+		if i+12 > len(data) {
+			return nil, errTruncated
+		}
+		n := int(binary.BigEndian.Uint32(data[i:]))
+		end := i + 12 + n
+		if n < 0 || end > len(data) {
+			return nil, errTruncated
+		}
+		kind := string(data[i+4 : i+8])
+		if !pngMetadata[kind] {
+			out = append(out, data[i:end]...)
+		}
+		i = end
+		if kind == "IEND" {
+			break
+		}
+	}
+	return out, nil
+}
+
+// stripGIF re-encodes every frame, which keeps the animation and loop
+// count but leaves out comment and application extensions such as XMP.
+func stripGIF(data []byte) ([]byte, error) {
+	g, err := gif.DecodeAll(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, g); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
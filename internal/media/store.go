@@ -0,0 +1,38 @@
+// Package media stores the images attached to chirps and prepares them for
+// storage: it checks what they really are, strips their metadata and makes
+// thumbnails.
+package media
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/google/uuid"
+)
+
+// ErrNotFound is returned by BlobStore.Get for a key that holds nothing.
+var ErrNotFound = errors.New("blob not found")
+
+// BlobStore keeps blobs by key. Implementations must be safe for
+// concurrent use.
+type BlobStore interface {
+	// Put stores data under key, replacing anything already there.
+	Put(ctx context.Context, key, contentType string, data []byte) error
+	// Get returns a reader for the blob under key, or ErrNotFound. The
+	// caller closes it.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the blob under key. Deleting a missing blob isn't an
+	// error.
+	Delete(ctx context.Context, key string) error
+}
+
+// Key is where the image of the attachment with the ID is stored.
+func Key(id uuid.UUID) string {
+	return id.String()
+}
+
+// ThumbnailKey is where its thumbnail is stored.
+func ThumbnailKey(id uuid.UUID) string {
+	return id.String() + ".thumb"
+}
@@ -0,0 +1,77 @@
+package media
+
+import (
+	"image"
+	"image/color"
+)
+
+// resize scales src to w by h by averaging the source pixels that fall in
+// each destination pixel, which is what downscaling wants. It works in
+// premultiplied alpha so transparent pixels don't bleed colour.
+func resize(src image.Image, w, h int) *image.RGBA {
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	b := src.Bounds()
+	sw, sh := b.Dx(), b.Dy()
+	for y := range h {
+		y0 := b.Min.Y + y*sh/h
+		y1 := max(y0+1, b.Min.Y+(y+1)*sh/h)
+		for x := range w {
+			x0 := b.Min.X + x*sw/w
+			x1 := max(x0+1, b.Min.X+(x+1)*sw/w)
+			var r, g, bl, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					r, g, bl, a = r+uint64(cr), g+uint64(cg), bl+uint64(cb), a+uint64(ca)
+					n++
+				}
+			}
+			dst.SetRGBA(x, y, color.RGBA{
+				R: uint8(r / n >> 8),
+				G: uint8(g / n >> 8),
+				B: uint8(bl / n >> 8),
+				A: uint8(a / n >> 8),
+			})
+		}
+	}
+	return dst
+}
+
+// orient turns src upright given its EXIF orientation, 1 to 8: the
+// mirroring and rotation the camera says the viewer should apply.
+func orient(src image.Image, orientation int) image.Image {
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if orientation < 2 || orientation > 8 {
+		return src
+	}
+	dw, dh := w, h
+	if orientation >= 5 {
+		// the four that rotate by a quarter turn swap the sides
+		dw, dh = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := range dh {
+		for x := range dw {
+			var sx, sy int
+			switch orientation {
+			case 2: // mirrored
+				sx, sy = w-1-x, y
+			case 3: // upside down
+				sx, sy = w-1-x, h-1-y
+			case 4: // mirrored and upside down
+				sx, sy = x, h-1-y
+			case 5: // flipped across the top-left to bottom-right diagonal
+				sx, sy = y, x
+			case 6: // needs turning clockwise
+				sx, sy = y, h-1-x
+			case 7: // flipped across the other diagonal
+				sx, sy = w-1-y, h-1-x
+			case 8: // needs turning anticlockwise
+				sx, sy = w-1-y, x
+			}
+			dst.Set(x, y, src.At(b.Min.X+sx, b.Min.Y+sy))
+		}
+	}
+	return dst
+}
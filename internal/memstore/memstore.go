@@ -0,0 +1,1695 @@
+// Package memstore is an in-memory database.Querier for tests and for
+// trying Chirpy out without a database. It mirrors the behaviour of the
+// Postgres schema: unique emails, cascading deletes and sql.ErrNoRows for
+// missing rows.
+package memstore
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/HemahWeb/chirpy/internal/database"
+)
+
+const refreshTokenLifetime = 60 * 24 * time.Hour
+
+type rateLimitBucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+type Store struct {
+	txMu sync.Mutex // serializes WithTx
+
+	mu            sync.Mutex
+	users         map[uuid.UUID]database.User
+	chirps        []database.Chirp // in insertion order, which is created_at order
+	refreshTokens map[string]database.RefreshToken
+	buckets       map[string]rateLimitBucket
+	webhookEvents []database.WebhookEvent // in ID order
+	follows       []database.Follow       // in created_at order
+	likes         []database.ChirpLike
+	notifications []database.Notification // in ID order
+	notifyPrefs   []database.NotificationPreference
+	notifyID      int64            // the last notification ID handed out
+	blocks        []database.Block // in created_at order
+	mutes         []database.Mute  // in created_at order
+	conversations []database.Conversation
+	members       []database.ConversationMember
+	messages      []database.Message // in ID order
+	messageID     int64              // the last message ID handed out
+	moderators    []database.Moderator
+	reports       []database.Report           // in ID order
+	reportID      int64                       // the last report ID handed out
+	actions       []database.ModerationAction // in ID order, never deleted
+	actionID      int64                       // the last action ID handed out
+	hiddenChirps  []database.HiddenChirp
+	appeals       []database.Appeal     // in ID order
+	appealID      int64                 // the last appeal ID handed out
+	attachments   []database.Attachment // in created_at order
+
+	now func() time.Time
+}
+
+var _ database.Querier = (*Store)(nil)
+
+func New() *Store {
+	return &Store{
+		users:         map[uuid.UUID]database.User{},
+		refreshTokens: map[string]database.RefreshToken{},
+		buckets:       map[string]rateLimitBucket{},
+		now:           time.Now,
+	}
+}
+
+// SetClock replaces the clock used for timestamps and expiry.
+func (s *Store) SetClock(now func() time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.now = now
+}
+
+func (s *Store) AddModerator(ctx context.Context, userID uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.userExists(userID) {
+		return fmt.Errorf("insert on table \"moderators\" violates foreign key constraint: user %s does not exist", userID)
+	}
+	if slices.ContainsFunc(s.moderators, func(m database.Moderator) bool { return m.UserID == userID }) {
+		return nil
+	}
+	s.moderators = append(s.moderators, database.Moderator{UserID: userID, CreatedAt: s.now()})
+	return nil
+}
+
+func (s *Store) ClaimReport(ctx context.Context, arg database.ClaimReportParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, r := range s.reports {
+		if r.ID == arg.ID && r.Status == "open" {
+			s.reports[i].Status = "claimed"
+			s.reports[i].ClaimedBy = arg.ClaimedBy
+			s.reports[i].ClaimedAt = sql.NullTime{Time: s.now(), Valid: true}
+			return 1, nil
+		}
+	}
+	return 0, nil
+}
+
+func (s *Store) CreateAppeal(ctx context.Context, arg database.CreateAppealParams) (database.Appeal, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.action(arg.ActionID) < 0 {
+		return database.Appeal{}, fmt.Errorf("insert on table \"appeals\" violates foreign key constraint: moderation action %d does not exist", arg.ActionID)
+	}
+	if !s.userExists(arg.UserID) {
+		return database.Appeal{}, fmt.Errorf("insert on table \"appeals\" violates foreign key constraint: user %s does not exist", arg.UserID)
+	}
+	if slices.ContainsFunc(s.appeals, func(a database.Appeal) bool { return a.ActionID == arg.ActionID }) {
+		return database.Appeal{}, &database.UniqueViolationError{Constraint: "appeals_action_id_key"}
+	}
+	s.appealID++
+	a := database.Appeal{
+		ID:        s.appealID,
+		CreatedAt: s.now(),
+		ActionID:  arg.ActionID,
+		UserID:    arg.UserID,
+		Body:      arg.Body,
+		Status:    "open",
+	}
+	s.appeals = append(s.appeals, a)
+	return a, nil
+}
+
+func (s *Store) CreateModerationAction(ctx context.Context, arg database.CreateModerationActionParams) (database.ModerationAction, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.actionID++
+	a := database.ModerationAction{
+		ID:          s.actionID,
+		CreatedAt:   s.now(),
+		ModeratorID: arg.ModeratorID,
+		Action:      arg.Action,
+		ReportID:    arg.ReportID,
+		AppealID:    arg.AppealID,
+		UserID:      arg.UserID,
+		ChirpID:     arg.ChirpID,
+		Note:        arg.Note,
+	}
+	s.actions = append(s.actions, a)
+	return a, nil
+}
+
+func (s *Store) CreateReport(ctx context.Context, arg database.CreateReportParams) (database.Report, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, id := range []uuid.UUID{arg.ReporterID, arg.UserID} {
+		if !s.userExists(id) {
+			return database.Report{}, fmt.Errorf("insert on table \"reports\" violates foreign key constraint: user %s does not exist", id)
+		}
+	}
+	if arg.ChirpID.Valid && !s.chirpExists(arg.ChirpID.UUID) {
+		return database.Report{}, fmt.Errorf("insert on table \"reports\" violates foreign key constraint: chirp %s does not exist", arg.ChirpID.UUID)
+	}
+	s.reportID++
+	r := database.Report{
+		ID:         s.reportID,
+		CreatedAt:  s.now(),
+		ReporterID: arg.ReporterID,
+		UserID:     arg.UserID,
+		ChirpID:    arg.ChirpID,
+		Reason:     arg.Reason,
+		Details:    arg.Details,
+		Status:     "open",
+	}
+	s.reports = append(s.reports, r)
+	return r, nil
+}
+
+func (s *Store) DecideAppeal(ctx context.Context, arg database.DecideAppealParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, a := range s.appeals {
+		if a.ID == arg.ID && a.Status == "open" {
+			s.appeals[i].Status = arg.Status
+			s.appeals[i].DecidedBy = arg.DecidedBy
+			s.appeals[i].DecidedAt = sql.NullTime{Time: s.now(), Valid: true}
+			return 1, nil
+		}
+	}
+	return 0, nil
+}
+
+func (s *Store) GetAppeal(ctx context.Context, id int64) (database.Appeal, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, a := range s.appeals {
+		if a.ID == id {
+			return a, nil
+		}
+	}
+	return database.Appeal{}, sql.ErrNoRows
+}
+
+func (s *Store) GetModerationAction(ctx context.Context, id int64) (database.ModerationAction, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.action(id)
+	if i < 0 {
+		return database.ModerationAction{}, sql.ErrNoRows
+	}
+	return s.actions[i], nil
+}
+
+func (s *Store) GetReport(ctx context.Context, id int64) (database.Report, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, r := range s.reports {
+		if r.ID == id {
+			return r, nil
+		}
+	}
+	return database.Report{}, sql.ErrNoRows
+}
+
+func (s *Store) GetUserSuspension(ctx context.Context, id uuid.UUID) (database.GetUserSuspensionRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.users[id]
+	if !ok {
+		return database.GetUserSuspensionRow{}, sql.ErrNoRows
+	}
+	return database.GetUserSuspensionRow{SuspendedAt: u.SuspendedAt, SuspendedUntil: u.SuspendedUntil}, nil
+}
+
+func (s *Store) HideChirp(ctx context.Context, arg database.HideChirpParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.chirpExists(arg.ChirpID) {
+		return fmt.Errorf("insert on table \"hidden_chirps\" violates foreign key constraint: chirp %s does not exist", arg.ChirpID)
+	}
+	if s.action(arg.ActionID) < 0 {
+		return fmt.Errorf("insert on table \"hidden_chirps\" violates foreign key constraint: moderation action %d does not exist", arg.ActionID)
+	}
+	if s.chirpHidden(arg.ChirpID) {
+		return nil
+	}
+	s.hiddenChirps = append(s.hiddenChirps, database.HiddenChirp{
+		ChirpID:   arg.ChirpID,
+		ActionID:  arg.ActionID,
+		CreatedAt: s.now(),
+	})
+	return nil
+}
+
+func (s *Store) IsChirpHidden(ctx context.Context, chirpID uuid.UUID) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.chirpHidden(chirpID), nil
+}
+
+func (s *Store) IsChirpUnderReview(ctx context.Context, chirpID uuid.NullUUID) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return chirpID.Valid && s.chirpUnderReview(chirpID.UUID), nil
+}
+
+func (s *Store) IsModerator(ctx context.Context, userID uuid.UUID) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return slices.ContainsFunc(s.moderators, func(m database.Moderator) bool { return m.UserID == userID }), nil
+}
+
+func (s *Store) IsUserLimited(ctx context.Context, id uuid.UUID) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.users[id].LimitedAt.Valid, nil
+}
+
+func (s *Store) LimitUser(ctx context.Context, id uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if u, ok := s.users[id]; ok && !u.LimitedAt.Valid {
+		u.LimitedAt = sql.NullTime{Time: s.now(), Valid: true}
+		u.UpdatedAt = s.now()
+		s.users[id] = u
+	}
+	return nil
+}
+
+func (s *Store) ListAppeals(ctx context.Context, arg database.ListAppealsParams) ([]database.Appeal, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var out []database.Appeal
+	for _, a := range s.appeals {
+		if len(out) >= int(arg.MaxRows) {
+			break
+		}
+		if a.Status == arg.Status && a.ID > arg.AfterID {
+			out = append(out, a)
+		}
+	}
+	return out, nil
+}
+
+func (s *Store) ListLimitedUserIDs(ctx context.Context) ([]uuid.UUID, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var ids []uuid.UUID
+	for id, u := range s.users {
+		if u.LimitedAt.Valid {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+func (s *Store) ListModerationActions(ctx context.Context, arg database.ListModerationActionsParams) ([]database.ModerationAction, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var out []database.ModerationAction
+	for _, a := range slices.Backward(s.actions) {
+		if len(out) >= int(arg.MaxRows) {
+			break
+		}
+		if a.ID < arg.BeforeID {
+			out = append(out, a)
+		}
+	}
+	return out, nil
+}
+
+func (s *Store) ListReports(ctx context.Context, arg database.ListReportsParams) ([]database.Report, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var out []database.Report
+	for _, r := range s.reports {
+		if len(out) >= int(arg.MaxRows) {
+			break
+		}
+		if r.Status == arg.Status && r.ID > arg.AfterID {
+			out = append(out, r)
+		}
+	}
+	return out, nil
+}
+
+func (s *Store) ListSanctions(ctx context.Context, userID uuid.UUID) ([]database.ListSanctionsRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var out []database.ListSanctionsRow
+	for _, a := range slices.Backward(s.actions) {
+		if a.UserID != userID || (a.Action != "hide_chirp" && a.Action != "suspend_user") {
+			continue
+		}
+		row := database.ListSanctionsRow{
+			ID:        a.ID,
+			CreatedAt: a.CreatedAt,
+			Action:    a.Action,
+			ChirpID:   a.ChirpID,
+			Note:      a.Note,
+		}
+		for _, r := range s.reports {
+			if a.ReportID.Valid && r.ID == a.ReportID.Int64 {
+				row.Reason = sql.NullString{String: r.Reason, Valid: true}
+			}
+		}
+		for _, p := range s.appeals {
+			if p.ActionID == a.ID {
+				row.AppealStatus = sql.NullString{String: p.Status, Valid: true}
+			}
+		}
+		out = append(out, row)
+	}
+	return out, nil
+}
+
+func (s *Store) OpenReportExists(ctx context.Context, arg database.OpenReportExistsParams) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return slices.ContainsFunc(s.reports, func(r database.Report) bool {
+		return r.ReporterID == arg.ReporterID && r.UserID == arg.UserID && r.ChirpID == arg.ChirpID && r.Status != "resolved"
+	}), nil
+}
+
+func (s *Store) RemoveModerator(ctx context.Context, userID uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.moderators = slices.DeleteFunc(s.moderators, func(m database.Moderator) bool { return m.UserID == userID })
+	return nil
+}
+
+func (s *Store) ResolveReport(ctx context.Context, arg database.ResolveReportParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, r := range s.reports {
+		if r.ID == arg.ID && r.Status == "claimed" && arg.ClaimedBy.Valid && r.ClaimedBy == arg.ClaimedBy {
+			s.reports[i].Status = "resolved"
+			s.reports[i].Resolution = arg.Resolution
+			s.reports[i].ResolvedAt = sql.NullTime{Time: s.now(), Valid: true}
+			return 1, nil
+		}
+	}
+	return 0, nil
+}
+
+func (s *Store) SuspendUser(ctx context.Context, arg database.SuspendUserParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if u, ok := s.users[arg.ID]; ok {
+		u.SuspendedAt = sql.NullTime{Time: s.now(), Valid: true}
+		u.SuspendedUntil = arg.SuspendedUntil
+		u.SuspensionActionID = arg.SuspensionActionID
+		u.UpdatedAt = s.now()
+		s.users[arg.ID] = u
+	}
+	return nil
+}
+
+func (s *Store) UnhideChirp(ctx context.Context, chirpID uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.hiddenChirps = slices.DeleteFunc(s.hiddenChirps, func(h database.HiddenChirp) bool { return h.ChirpID == chirpID })
+	return nil
+}
+
+func (s *Store) UnlimitUser(ctx context.Context, id uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if u, ok := s.users[id]; ok {
+		u.LimitedAt = sql.NullTime{}
+		u.UpdatedAt = s.now()
+		s.users[id] = u
+	}
+	return nil
+}
+
+func (s *Store) UnsuspendUser(ctx context.Context, id uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if u, ok := s.users[id]; ok {
+		u.SuspendedAt, u.SuspendedUntil = sql.NullTime{}, sql.NullTime{}
+		u.SuspensionActionID = sql.NullInt64{}
+		u.UpdatedAt = s.now()
+		s.users[id] = u
+	}
+	return nil
+}
+
+// WithTx runs fn while holding a lock that serializes it against other
+// WithTx calls, which is enough isolation for tests. Nothing is rolled back
+// if fn fails.
+func (s *Store) WithTx(ctx context.Context, fn func(q database.Querier) error) error {
+	s.txMu.Lock()
+	defer s.txMu.Unlock()
+	return fn(s)
+}
+
+func (s *Store) emailTaken(email string, except uuid.UUID) bool {
+	for id, u := range s.users {
+		if u.Email == email && id != except {
+			return true
+		}
+	}
+	return false
+}
+
+// deleteUser removes a user and, like ON DELETE CASCADE, everything of theirs.
+func (s *Store) deleteUser(id uuid.UUID) {
+	delete(s.users, id)
+	s.deleteChirps(func(c database.Chirp) bool { return c.UserID == id })
+	for token, rt := range s.refreshTokens {
+		if rt.UserID == id {
+			delete(s.refreshTokens, token)
+		}
+	}
+	s.follows = slices.DeleteFunc(s.follows, func(f database.Follow) bool { return f.FollowerID == id || f.FolloweeID == id })
+	s.likes = slices.DeleteFunc(s.likes, func(l database.ChirpLike) bool { return l.UserID == id })
+	s.notifications = slices.DeleteFunc(s.notifications, func(n database.Notification) bool { return n.UserID == id || n.ActorID == id })
+	s.notifyPrefs = slices.DeleteFunc(s.notifyPrefs, func(p database.NotificationPreference) bool { return p.UserID == id })
+	s.blocks = slices.DeleteFunc(s.blocks, func(b database.Block) bool { return b.BlockerID == id || b.BlockedID == id })
+	s.mutes = slices.DeleteFunc(s.mutes, func(m database.Mute) bool { return m.MuterID == id || m.MutedID == id })
+	s.members = slices.DeleteFunc(s.members, func(m database.ConversationMember) bool { return m.UserID == id })
+	s.messages = slices.DeleteFunc(s.messages, func(m database.Message) bool { return m.SenderID == id })
+	s.moderators = slices.DeleteFunc(s.moderators, func(m database.Moderator) bool { return m.UserID == id })
+	s.reports = slices.DeleteFunc(s.reports, func(r database.Report) bool { return r.ReporterID == id || r.UserID == id })
+	for i, r := range s.reports {
+		if r.ClaimedBy.Valid && r.ClaimedBy.UUID == id {
+			s.reports[i].ClaimedBy = uuid.NullUUID{}
+		}
+	}
+	s.appeals = slices.DeleteFunc(s.appeals, func(a database.Appeal) bool { return a.UserID == id })
+	for i, a := range s.appeals {
+		if a.DecidedBy.Valid && a.DecidedBy.UUID == id {
+			s.appeals[i].DecidedBy = uuid.NullUUID{}
+		}
+	}
+}
+
+// deleteChirps removes the chirps del matches with their likes,
+// notifications, reports and hidden markers and, like ON DELETE SET NULL,
+// clears reply_to_id on the replies to them and chirp_id on their
+// attachments.
+func (s *Store) deleteChirps(del func(database.Chirp) bool) {
+	deleted := map[uuid.UUID]bool{}
+	s.chirps = slices.DeleteFunc(s.chirps, func(c database.Chirp) bool {
+		if del(c) {
+			deleted[c.ID] = true
+			return true
+		}
+		return false
+	})
+	for i, c := range s.chirps {
+		if c.ReplyToID.Valid && deleted[c.ReplyToID.UUID] {
+			s.chirps[i].ReplyToID = uuid.NullUUID{}
+		}
+	}
+	s.likes = slices.DeleteFunc(s.likes, func(l database.ChirpLike) bool { return deleted[l.ChirpID] })
+	s.notifications = slices.DeleteFunc(s.notifications, func(n database.Notification) bool {
+		return n.ChirpID.Valid && deleted[n.ChirpID.UUID]
+	})
+	s.reports = slices.DeleteFunc(s.reports, func(r database.Report) bool { return r.ChirpID.Valid && deleted[r.ChirpID.UUID] })
+	s.hiddenChirps = slices.DeleteFunc(s.hiddenChirps, func(h database.HiddenChirp) bool { return deleted[h.ChirpID] })
+	for i, a := range s.attachments {
+		if a.ChirpID.Valid && deleted[a.ChirpID.UUID] {
+			s.attachments[i].ChirpID = uuid.NullUUID{}
+		}
+	}
+}
+
+func (s *Store) chirpExists(id uuid.UUID) bool {
+	return slices.ContainsFunc(s.chirps, func(c database.Chirp) bool { return c.ID == id })
+}
+
+func (s *Store) userExists(id uuid.UUID) bool {
+	_, ok := s.users[id]
+	return ok
+}
+
+func (s *Store) chirpHidden(id uuid.UUID) bool {
+	return slices.ContainsFunc(s.hiddenChirps, func(h database.HiddenChirp) bool { return h.ChirpID == id })
+}
+
+func (s *Store) chirpUnderReview(id uuid.UUID) bool {
+	return slices.ContainsFunc(s.reports, func(r database.Report) bool {
+		return r.ChirpID == uuid.NullUUID{UUID: id, Valid: true} && r.Status != "resolved"
+	})
+}
+
+// action returns the index of the moderation action with the ID, or -1.
+func (s *Store) action(id int64) int {
+	return slices.IndexFunc(s.actions, func(a database.ModerationAction) bool { return a.ID == id })
+}
+
+func (s *Store) conversationExists(id uuid.UUID) bool {
+	return slices.ContainsFunc(s.conversations, func(c database.Conversation) bool { return c.ID == id })
+}
+
+// member returns the index of userID's membership of conversationID, or -1.
+func (s *Store) member(conversationID, userID uuid.UUID) int {
+	return slices.IndexFunc(s.members, func(m database.ConversationMember) bool {
+		return m.ConversationID == conversationID && m.UserID == userID
+	})
+}
+
+// conversationForUser is the row GetConversationForUser and
+// ListConversationsForUser return for c and m.
+func (s *Store) conversationForUser(c database.Conversation, m database.ConversationMember) database.GetConversationForUserRow {
+	var unread int64
+	for _, msg := range s.messages {
+		if msg.ConversationID == c.ID && msg.ID > m.LastReadID && msg.SenderID != m.UserID {
+			unread++
+		}
+	}
+	return database.GetConversationForUserRow{
+		ID:            c.ID,
+		CreatedAt:     c.CreatedAt,
+		LastMessageAt: c.LastMessageAt,
+		DirectKey:     c.DirectKey,
+		LastReadID:    m.LastReadID,
+		Muted:         m.Muted,
+		UnreadCount:   unread,
+	}
+}
+
+func compareMembers(a, b database.ConversationMember) int {
+	if c := strings.Compare(a.ConversationID.String(), b.ConversationID.String()); c != 0 {
+		return c
+	}
+	return strings.Compare(a.UserID.String(), b.UserID.String())
+}
+
+func (s *Store) AddConversationMember(ctx context.Context, arg database.AddConversationMemberParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.conversationExists(arg.ConversationID) {
+		return fmt.Errorf("insert on table \"conversation_members\" violates foreign key constraint: conversation %s does not exist", arg.ConversationID)
+	}
+	if !s.userExists(arg.UserID) {
+		return fmt.Errorf("insert on table \"conversation_members\" violates foreign key constraint: user %s does not exist", arg.UserID)
+	}
+	if s.member(arg.ConversationID, arg.UserID) >= 0 {
+		return &database.UniqueViolationError{Constraint: "conversation_members_pkey"}
+	}
+	s.members = append(s.members, database.ConversationMember{ConversationID: arg.ConversationID, UserID: arg.UserID})
+	return nil
+}
+
+func (s *Store) AttachToChirp(ctx context.Context, arg database.AttachToChirpParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if arg.ChirpID.Valid && !s.chirpExists(arg.ChirpID.UUID) {
+		return fmt.Errorf("update on table \"attachments\" violates foreign key constraint: chirp %s does not exist", arg.ChirpID.UUID)
+	}
+	for i, a := range s.attachments {
+		if a.ID == arg.ID {
+			s.attachments[i].ChirpID = arg.ChirpID
+			s.attachments[i].Position = arg.Position
+		}
+	}
+	return nil
+}
+
+func (s *Store) BlockExists(ctx context.Context, arg database.BlockExistsParams) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return slices.ContainsFunc(s.blocks, func(b database.Block) bool {
+		return (b.BlockerID == arg.UserID && b.BlockedID == arg.OtherID) || (b.BlockerID == arg.OtherID && b.BlockedID == arg.UserID)
+	}), nil
+}
+
+func (s *Store) BlockUser(ctx context.Context, arg database.BlockUserParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, id := range []uuid.UUID{arg.BlockerID, arg.BlockedID} {
+		if !s.userExists(id) {
+			return 0, fmt.Errorf("insert on table \"blocks\" violates foreign key constraint: user %s does not exist", id)
+		}
+	}
+	for _, b := range s.blocks {
+		if b.BlockerID == arg.BlockerID && b.BlockedID == arg.BlockedID {
+			return 0, nil
+		}
+	}
+	s.blocks = append(s.blocks, database.Block{
+		BlockerID: arg.BlockerID,
+		BlockedID: arg.BlockedID,
+		CreatedAt: s.now(),
+	})
+	return 1, nil
+}
+
+func (s *Store) CountUnreadNotifications(ctx context.Context, userID uuid.UUID) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var count int64
+	for _, n := range s.notifications {
+		if n.UserID == userID && !n.ReadAt.Valid {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (s *Store) CreateAttachment(ctx context.Context, arg database.CreateAttachmentParams) (database.Attachment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a := database.Attachment{
+		ID:                   uuid.New(),
+		CreatedAt:            s.now(),
+		ContentType:          arg.ContentType,
+		SizeBytes:            arg.SizeBytes,
+		Width:                arg.Width,
+		Height:               arg.Height,
+		ThumbnailContentType: arg.ThumbnailContentType,
+		ThumbnailSizeBytes:   arg.ThumbnailSizeBytes,
+		ThumbnailWidth:       arg.ThumbnailWidth,
+		ThumbnailHeight:      arg.ThumbnailHeight,
+	}
+	s.attachments = append(s.attachments, a)
+	return a, nil
+}
+
+func (s *Store) CreateChirp(ctx context.Context, arg database.CreateChirpParams) (database.Chirp, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[arg.UserID]; !ok {
+		return database.Chirp{}, fmt.Errorf("insert on table \"chirps\" violates foreign key constraint: user %s does not exist", arg.UserID)
+	}
+	if arg.ReplyToID.Valid && !s.chirpExists(arg.ReplyToID.UUID) {
+		return database.Chirp{}, fmt.Errorf("insert on table \"chirps\" violates foreign key constraint: chirp %s does not exist", arg.ReplyToID.UUID)
+	}
+	now := s.now()
+	c := database.Chirp{
+		ID:        uuid.New(),
+		CreatedAt: now,
+		UpdatedAt: now,
+		Body:      arg.Body,
+		UserID:    arg.UserID,
+		ReplyToID: arg.ReplyToID,
+		ThreadID:  arg.ThreadID,
+	}
+	s.chirps = append(s.chirps, c)
+	return c, nil
+}
+
+func (s *Store) CreateConversation(ctx context.Context, directKey sql.NullString) (database.Conversation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if directKey.Valid && slices.ContainsFunc(s.conversations, func(c database.Conversation) bool { return c.DirectKey == directKey }) {
+		return database.Conversation{}, sql.ErrNoRows
+	}
+	c := database.Conversation{
+		ID:        uuid.New(),
+		CreatedAt: s.now(),
+		DirectKey: directKey,
+	}
+	s.conversations = append(s.conversations, c)
+	return c, nil
+}
+
+func (s *Store) CreateMessage(ctx context.Context, arg database.CreateMessageParams) (database.Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.conversationExists(arg.ConversationID) {
+		return database.Message{}, fmt.Errorf("insert on table \"messages\" violates foreign key constraint: conversation %s does not exist", arg.ConversationID)
+	}
+	if !s.userExists(arg.SenderID) {
+		return database.Message{}, fmt.Errorf("insert on table \"messages\" violates foreign key constraint: user %s does not exist", arg.SenderID)
+	}
+	s.messageID++
+	m := database.Message{
+		ID:             s.messageID,
+		ConversationID: arg.ConversationID,
+		SenderID:       arg.SenderID,
+		Body:           arg.Body,
+		CreatedAt:      s.now(),
+	}
+	s.messages = append(s.messages, m)
+	return m, nil
+}
+
+func (s *Store) CreateNotification(ctx context.Context, arg database.CreateNotificationParams) (database.Notification, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, id := range []uuid.UUID{arg.UserID, arg.ActorID} {
+		if _, ok := s.users[id]; !ok {
+			return database.Notification{}, fmt.Errorf("insert on table \"notifications\" violates foreign key constraint: user %s does not exist", id)
+		}
+	}
+	if arg.ChirpID.Valid && !s.chirpExists(arg.ChirpID.UUID) {
+		return database.Notification{}, fmt.Errorf("insert on table \"notifications\" violates foreign key constraint: chirp %s does not exist", arg.ChirpID.UUID)
+	}
+	s.notifyID++
+	n := database.Notification{
+		ID:        s.notifyID,
+		CreatedAt: s.now(),
+		UserID:    arg.UserID,
+		Type:      arg.Type,
+		ActorID:   arg.ActorID,
+		ChirpID:   arg.ChirpID,
+	}
+	s.notifications = append(s.notifications, n)
+	return n, nil
+}
+
+func (s *Store) CreateRefreshToken(ctx context.Context, userID uuid.UUID) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[userID]; !ok {
+		return "", fmt.Errorf("insert on table \"refresh_tokens\" violates foreign key constraint: user %s does not exist", userID)
+	}
+	b := make([]byte, 32)
+	rand.Read(b)
+	token := hex.EncodeToString(b)
+
+	now := s.now()
+	s.refreshTokens[token] = database.RefreshToken{
+		Token:     token,
+		CreatedAt: now,
+		UpdatedAt: now,
+		UserID:    userID,
+		ExpiresAt: now.Add(refreshTokenLifetime),
+	}
+	return token, nil
+}
+
+func (s *Store) CreateUser(ctx context.Context, arg database.CreateUserParams) (database.CreateUserRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.emailTaken(arg.Email, uuid.Nil) {
+		return database.CreateUserRow{}, &database.UniqueViolationError{Constraint: "users_email_key"}
+	}
+	now := s.now()
+	u := database.User{
+		ID:             uuid.New(),
+		CreatedAt:      now,
+		UpdatedAt:      now,
+		Email:          arg.Email,
+		HashedPassword: arg.HashedPassword,
+	}
+	s.users[u.ID] = u
+	return database.CreateUserRow{
+		ID:          u.ID,
+		CreatedAt:   u.CreatedAt,
+		UpdatedAt:   u.UpdatedAt,
+		Email:       u.Email,
+		IsChirpyRed: u.IsChirpyRed,
+	}, nil
+}
+
+func (s *Store) CreateWebhookEvent(ctx context.Context, arg database.CreateWebhookEventParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.webhookEvents = append(s.webhookEvents, database.WebhookEvent{
+		ID:         int64(len(s.webhookEvents) + 1),
+		ReceivedAt: s.now(),
+		Source:     arg.Source,
+		Event:      arg.Event,
+		UserID:     arg.UserID,
+		Outcome:    arg.Outcome,
+	})
+	return nil
+}
+
+func (s *Store) DeleteAttachment(ctx context.Context, id uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.attachments = slices.DeleteFunc(s.attachments, func(a database.Attachment) bool { return a.ID == id })
+	return nil
+}
+
+func (s *Store) DeleteChirp(ctx context.Context, id uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, c := range s.chirps {
+		if c.ID == id && !c.DeletedAt.Valid {
+			s.chirps[i].DeletedAt = sql.NullTime{Time: s.now(), Valid: true}
+		}
+	}
+	return nil
+}
+
+func (s *Store) DeleteIdleRateLimitBuckets(ctx context.Context, idleSeconds float64) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cutoff := s.now().Add(-time.Duration(idleSeconds * float64(time.Second)))
+	var removed int64
+	for key, b := range s.buckets {
+		if b.updated.Before(cutoff) {
+			delete(s.buckets, key)
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+func (s *Store) DeleteMessage(ctx context.Context, id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.messages = slices.DeleteFunc(s.messages, func(m database.Message) bool { return m.ID == id })
+	return nil
+}
+
+func (s *Store) DeleteStaleRefreshTokens(ctx context.Context, arg database.DeleteStaleRefreshTokensParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var removed int64
+	for token, rt := range s.refreshTokens {
+		if removed >= int64(arg.BatchSize) {
+			break
+		}
+		if rt.ExpiresAt.Before(arg.Cutoff) || (rt.RevokedAt.Valid && rt.RevokedAt.Time.Before(arg.Cutoff)) {
+			delete(s.refreshTokens, token)
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+func (s *Store) DeleteUser(ctx context.Context, id uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.deleteUser(id)
+	return nil
+}
+
+func (s *Store) FollowUser(ctx context.Context, arg database.FollowUserParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, id := range []uuid.UUID{arg.FollowerID, arg.FolloweeID} {
+		if _, ok := s.users[id]; !ok {
+			return 0, fmt.Errorf("insert on table \"follows\" violates foreign key constraint: user %s does not exist", id)
+		}
+	}
+	for _, f := range s.follows {
+		if f.FollowerID == arg.FollowerID && f.FolloweeID == arg.FolloweeID {
+			return 0, nil
+		}
+	}
+	s.follows = append(s.follows, database.Follow{
+		FollowerID: arg.FollowerID,
+		FolloweeID: arg.FolloweeID,
+		CreatedAt:  s.now(),
+	})
+	return 1, nil
+}
+
+func (s *Store) GetAttachment(ctx context.Context, id uuid.UUID) (database.Attachment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, a := range s.attachments {
+		if a.ID == id {
+			return a, nil
+		}
+	}
+	return database.Attachment{}, sql.ErrNoRows
+}
+
+func (s *Store) GetChirpByID(ctx context.Context, id uuid.UUID) (database.Chirp, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, c := range s.chirps {
+		if c.ID == id {
+			return c, nil
+		}
+	}
+	return database.Chirp{}, sql.ErrNoRows
+}
+
+func (s *Store) GetChirps(ctx context.Context) ([]database.Chirp, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var out []database.Chirp
+	for _, c := range s.chirps {
+		if !c.DeletedAt.Valid && !s.chirpHidden(c.ID) {
+			out = append(out, c)
+		}
+	}
+	return out, nil
+}
+
+func (s *Store) GetChirpsByUserID(ctx context.Context, userID uuid.UUID) ([]database.Chirp, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var out []database.Chirp
+	for _, c := range s.chirps {
+		if c.UserID == userID && !c.DeletedAt.Valid && !s.chirpHidden(c.ID) {
+			out = append(out, c)
+		}
+	}
+	return out, nil
+}
+
+func (s *Store) GetConversationByDirectKey(ctx context.Context, directKey sql.NullString) (database.Conversation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, c := range s.conversations {
+		if directKey.Valid && c.DirectKey == directKey {
+			return c, nil
+		}
+	}
+	return database.Conversation{}, sql.ErrNoRows
+}
+
+func (s *Store) GetConversationForUser(ctx context.Context, arg database.GetConversationForUserParams) (database.GetConversationForUserRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.member(arg.ID, arg.UserID)
+	if i < 0 {
+		return database.GetConversationForUserRow{}, sql.ErrNoRows
+	}
+	for _, c := range s.conversations {
+		if c.ID == arg.ID {
+			return s.conversationForUser(c, s.members[i]), nil
+		}
+	}
+	return database.GetConversationForUserRow{}, sql.ErrNoRows
+}
+
+func (s *Store) GetMessage(ctx context.Context, id int64) (database.Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, m := range s.messages {
+		if m.ID == id {
+			return m, nil
+		}
+	}
+	return database.Message{}, sql.ErrNoRows
+}
+
+func (s *Store) GetStats(ctx context.Context, now time.Time) (database.GetStatsRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := database.GetStatsRow{
+		Users:         int64(len(s.users)),
+		Chirps:        int64(len(s.chirps)),
+		WebhookEvents: int64(len(s.webhookEvents)),
+	}
+	for _, u := range s.users {
+		if u.IsChirpyRed {
+			stats.ChirpyRedUsers++
+		}
+	}
+	for _, rt := range s.refreshTokens {
+		if !rt.RevokedAt.Valid && rt.ExpiresAt.After(now) {
+			stats.ActiveRefreshTokens++
+		}
+	}
+	return stats, nil
+}
+
+func (s *Store) GetUserByEmail(ctx context.Context, email string) (database.GetUserByEmailRow, error) {
+	u, err := s.GetUserByEmailForAuth(ctx, email)
+	if err != nil {
+		return database.GetUserByEmailRow{}, err
+	}
+	return database.GetUserByEmailRow{
+		ID:          u.ID,
+		CreatedAt:   u.CreatedAt,
+		UpdatedAt:   u.UpdatedAt,
+		Email:       u.Email,
+		IsChirpyRed: u.IsChirpyRed,
+	}, nil
+}
+
+func (s *Store) GetUserByEmailForAuth(ctx context.Context, email string) (database.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, u := range s.users {
+		if u.Email == email {
+			return u, nil
+		}
+	}
+	return database.User{}, sql.ErrNoRows
+}
+
+func (s *Store) GetUserByID(ctx context.Context, id uuid.UUID) (database.GetUserByIDRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.users[id]
+	if !ok {
+		return database.GetUserByIDRow{}, sql.ErrNoRows
+	}
+	return database.GetUserByIDRow{
+		ID:          u.ID,
+		CreatedAt:   u.CreatedAt,
+		UpdatedAt:   u.UpdatedAt,
+		Email:       u.Email,
+		IsChirpyRed: u.IsChirpyRed,
+	}, nil
+}
+
+func (s *Store) GetUserIDFromRefreshToken(ctx context.Context, token string) (database.GetUserIDFromRefreshTokenRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rt, ok := s.refreshTokens[token]
+	if !ok {
+		return database.GetUserIDFromRefreshTokenRow{}, sql.ErrNoRows
+	}
+	return database.GetUserIDFromRefreshTokenRow{
+		UserID:    rt.UserID,
+		ExpiresAt: rt.ExpiresAt,
+		RevokedAt: rt.RevokedAt,
+	}, nil
+}
+
+func (s *Store) LikeChirp(ctx context.Context, arg database.LikeChirpParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[arg.UserID]; !ok {
+		return 0, fmt.Errorf("insert on table \"chirp_likes\" violates foreign key constraint: user %s does not exist", arg.UserID)
+	}
+	if !s.chirpExists(arg.ChirpID) {
+		return 0, fmt.Errorf("insert on table \"chirp_likes\" violates foreign key constraint: chirp %s does not exist", arg.ChirpID)
+	}
+	for _, l := range s.likes {
+		if l.UserID == arg.UserID && l.ChirpID == arg.ChirpID {
+			return 0, nil
+		}
+	}
+	s.likes = append(s.likes, database.ChirpLike{
+		UserID:    arg.UserID,
+		ChirpID:   arg.ChirpID,
+		CreatedAt: s.now(),
+	})
+	return 1, nil
+}
+
+func (s *Store) ListAttachmentsByChirpIDs(ctx context.Context, chirpIds []uuid.UUID) ([]database.Attachment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var items []database.Attachment
+	for _, a := range s.attachments {
+		if a.ChirpID.Valid && slices.Contains(chirpIds, a.ChirpID.UUID) {
+			items = append(items, a)
+		}
+	}
+	slices.SortStableFunc(items, func(a, b database.Attachment) int {
+		if c := strings.Compare(a.ChirpID.UUID.String(), b.ChirpID.UUID.String()); c != 0 {
+			return c
+		}
+		return int(a.Position - b.Position)
+	})
+	return items, nil
+}
+
+func (s *Store) ListBlocks(ctx context.Context, blockerID uuid.UUID) ([]database.Block, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var out []database.Block
+	for _, b := range slices.Backward(s.blocks) {
+		if b.BlockerID == blockerID {
+			out = append(out, b)
+		}
+	}
+	return out, nil
+}
+
+func (s *Store) ListConversationMembers(ctx context.Context, conversationID uuid.UUID) ([]database.ConversationMember, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var out []database.ConversationMember
+	for _, m := range s.members {
+		if m.ConversationID == conversationID {
+			out = append(out, m)
+		}
+	}
+	slices.SortFunc(out, compareMembers)
+	return out, nil
+}
+
+func (s *Store) ListConversationMembersForUser(ctx context.Context, userID uuid.UUID) ([]database.ConversationMember, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var out []database.ConversationMember
+	for _, m := range s.members {
+		if s.member(m.ConversationID, userID) >= 0 {
+			out = append(out, m)
+		}
+	}
+	slices.SortFunc(out, compareMembers)
+	return out, nil
+}
+
+func (s *Store) ListConversationsForUser(ctx context.Context, userID uuid.UUID) ([]database.ListConversationsForUserRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var out []database.ListConversationsForUserRow
+	for _, c := range s.conversations {
+		if i := s.member(c.ID, userID); i >= 0 {
+			out = append(out, database.ListConversationsForUserRow(s.conversationForUser(c, s.members[i])))
+		}
+	}
+	lastActive := func(r database.ListConversationsForUserRow) time.Time {
+		if r.LastMessageAt.Valid {
+			return r.LastMessageAt.Time
+		}
+		return r.CreatedAt
+	}
+	slices.SortFunc(out, func(a, b database.ListConversationsForUserRow) int {
+		if c := lastActive(b).Compare(lastActive(a)); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID.String(), b.ID.String())
+	})
+	return out, nil
+}
+
+func (s *Store) ListFolloweeIDs(ctx context.Context, followerID uuid.UUID) ([]uuid.UUID, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var ids []uuid.UUID
+	for _, f := range s.follows {
+		if f.FollowerID == followerID {
+			ids = append(ids, f.FolloweeID)
+		}
+	}
+	return ids, nil
+}
+
+func (s *Store) ListHiddenAuthorIDs(ctx context.Context, userID uuid.UUID) ([]uuid.UUID, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var ids []uuid.UUID
+	add := func(id uuid.UUID) {
+		if !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	for _, b := range s.blocks {
+		if b.BlockerID == userID {
+			add(b.BlockedID)
+		}
+		if b.BlockedID == userID {
+			add(b.BlockerID)
+		}
+	}
+	for _, m := range s.mutes {
+		if m.MuterID == userID {
+			add(m.MutedID)
+		}
+	}
+	return ids, nil
+}
+
+func (s *Store) ListMessages(ctx context.Context, arg database.ListMessagesParams) ([]database.Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var out []database.Message
+	for _, m := range slices.Backward(s.messages) {
+		if len(out) >= int(arg.MaxRows) {
+			break
+		}
+		if m.ConversationID == arg.ConversationID && m.ID < arg.BeforeID {
+			out = append(out, m)
+		}
+	}
+	return out, nil
+}
+
+func (s *Store) ListMutes(ctx context.Context, muterID uuid.UUID) ([]database.Mute, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var out []database.Mute
+	for _, m := range slices.Backward(s.mutes) {
+		if m.MuterID == muterID {
+			out = append(out, m)
+		}
+	}
+	return out, nil
+}
+
+func (s *Store) ListNotificationPreferences(ctx context.Context, userID uuid.UUID) ([]database.NotificationPreference, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var prefs []database.NotificationPreference
+	for _, p := range s.notifyPrefs {
+		if p.UserID == userID {
+			prefs = append(prefs, p)
+		}
+	}
+	slices.SortFunc(prefs, func(a, b database.NotificationPreference) int { return strings.Compare(a.Type, b.Type) })
+	return prefs, nil
+}
+
+func (s *Store) ListNotifications(ctx context.Context, arg database.ListNotificationsParams) ([]database.Notification, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var out []database.Notification
+	for _, n := range slices.Backward(s.notifications) {
+		if len(out) >= int(arg.MaxRows) {
+			break
+		}
+		if n.UserID == arg.UserID && n.ID < arg.BeforeID && (!arg.UnreadOnly || !n.ReadAt.Valid) {
+			out = append(out, n)
+		}
+	}
+	return out, nil
+}
+
+func (s *Store) ListOrphanedAttachments(ctx context.Context, arg database.ListOrphanedAttachmentsParams) ([]database.Attachment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var items []database.Attachment
+	for _, a := range s.attachments {
+		if len(items) == int(arg.BatchSize) {
+			break
+		}
+		if !a.ChirpID.Valid && a.CreatedAt.Before(arg.Cutoff) {
+			items = append(items, a)
+		}
+	}
+	return items, nil
+}
+
+func (s *Store) ListRecentWebhookEvents(ctx context.Context, limit int32) ([]database.WebhookEvent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	recent := slices.Clone(s.webhookEvents[max(0, len(s.webhookEvents)-int(limit)):])
+	slices.Reverse(recent)
+	return recent, nil
+}
+
+func (s *Store) ListTrashedChirps(ctx context.Context, arg database.ListTrashedChirpsParams) ([]database.Chirp, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var out []database.Chirp
+	for _, c := range s.chirps {
+		if c.UserID == arg.UserID && c.DeletedAt.Valid && !c.DeletedAt.Time.Before(arg.Cutoff) && !s.chirpHidden(c.ID) {
+			out = append(out, c)
+		}
+	}
+	slices.SortFunc(out, func(a, b database.Chirp) int {
+		if c := b.DeletedAt.Time.Compare(a.DeletedAt.Time); c != 0 {
+			return c
+		}
+		return strings.Compare(b.ID.String(), a.ID.String())
+	})
+	return out, nil
+}
+
+func (s *Store) ListWebhookEvents(ctx context.Context, arg database.ListWebhookEventsParams) ([]database.WebhookEvent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// IDs are positions + 1
+	start := min(int(max(arg.AfterID, 0)), len(s.webhookEvents))
+	end := min(start+int(arg.MaxRows), len(s.webhookEvents))
+	return slices.Clone(s.webhookEvents[start:end]), nil
+}
+
+func (s *Store) MarkAllNotificationsRead(ctx context.Context, userID uuid.UUID) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var marked int64
+	now := s.now()
+	for i, n := range s.notifications {
+		if n.UserID == userID && !n.ReadAt.Valid {
+			s.notifications[i].ReadAt = sql.NullTime{Time: now, Valid: true}
+			marked++
+		}
+	}
+	return marked, nil
+}
+
+func (s *Store) MarkConversationRead(ctx context.Context, arg database.MarkConversationReadParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.member(arg.ConversationID, arg.UserID)
+	if i < 0 {
+		return 0, nil
+	}
+	s.members[i].LastReadID = max(s.members[i].LastReadID, arg.LastReadID)
+	return 1, nil
+}
+
+func (s *Store) MarkNotificationRead(ctx context.Context, arg database.MarkNotificationReadParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, n := range s.notifications {
+		if n.ID == arg.ID && n.UserID == arg.UserID {
+			if !n.ReadAt.Valid {
+				s.notifications[i].ReadAt = sql.NullTime{Time: s.now(), Valid: true}
+			}
+			return 1, nil
+		}
+	}
+	return 0, nil
+}
+
+func (s *Store) MuteExists(ctx context.Context, arg database.MuteExistsParams) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return slices.ContainsFunc(s.mutes, func(m database.Mute) bool {
+		return m.MuterID == arg.MuterID && m.MutedID == arg.MutedID
+	}), nil
+}
+
+func (s *Store) MuteUser(ctx context.Context, arg database.MuteUserParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, id := range []uuid.UUID{arg.MuterID, arg.MutedID} {
+		if !s.userExists(id) {
+			return 0, fmt.Errorf("insert on table \"mutes\" violates foreign key constraint: user %s does not exist", id)
+		}
+	}
+	for _, m := range s.mutes {
+		if m.MuterID == arg.MuterID && m.MutedID == arg.MutedID {
+			return 0, nil
+		}
+	}
+	s.mutes = append(s.mutes, database.Mute{
+		MuterID:   arg.MuterID,
+		MutedID:   arg.MutedID,
+		CreatedAt: s.now(),
+	})
+	return 1, nil
+}
+
+func (s *Store) OverturnSuspension(ctx context.Context, arg database.OverturnSuspensionParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if u, ok := s.users[arg.ID]; ok && arg.SuspensionActionID.Valid && u.SuspensionActionID == arg.SuspensionActionID {
+		u.SuspendedAt, u.SuspendedUntil = sql.NullTime{}, sql.NullTime{}
+		u.SuspensionActionID = sql.NullInt64{}
+		u.UpdatedAt = s.now()
+		s.users[arg.ID] = u
+	}
+	return nil
+}
+
+func (s *Store) PurgeChirp(ctx context.Context, id uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.deleteChirps(func(c database.Chirp) bool { return c.ID == id })
+	return nil
+}
+
+func (s *Store) PurgeTrashedChirps(ctx context.Context, arg database.PurgeTrashedChirpsParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var removed int64
+	s.deleteChirps(func(c database.Chirp) bool {
+		if removed >= int64(arg.BatchSize) || !c.DeletedAt.Valid || !c.DeletedAt.Time.Before(arg.Cutoff) || s.chirpHidden(c.ID) || s.chirpUnderReview(c.ID) {
+			return false
+		}
+		removed++
+		return true
+	})
+	return removed, nil
+}
+
+func (s *Store) ResetUsers(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id := range s.users {
+		s.deleteUser(id)
+	}
+	return nil
+}
+
+func (s *Store) RestoreChirp(ctx context.Context, id uuid.UUID) (database.Chirp, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, c := range s.chirps {
+		if c.ID == id && c.DeletedAt.Valid {
+			s.chirps[i].DeletedAt = sql.NullTime{}
+			return s.chirps[i], nil
+		}
+	}
+	return database.Chirp{}, sql.ErrNoRows
+}
+
+func (s *Store) RevokeRefreshToken(ctx context.Context, token string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rt, ok := s.refreshTokens[token]
+	if !ok {
+		return nil
+	}
+	now := s.now()
+	rt.RevokedAt = sql.NullTime{Time: now, Valid: true}
+	rt.UpdatedAt = now
+	s.refreshTokens[token] = rt
+	return nil
+}
+
+func (s *Store) RevokeUserRefreshTokens(ctx context.Context, userID uuid.UUID) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var revoked int64
+	now := s.now()
+	for token, rt := range s.refreshTokens {
+		if rt.UserID == userID && !rt.RevokedAt.Valid {
+			rt.RevokedAt = sql.NullTime{Time: now, Valid: true}
+			rt.UpdatedAt = now
+			s.refreshTokens[token] = rt
+			revoked++
+		}
+	}
+	return revoked, nil
+}
+
+func (s *Store) SetConversationLastMessageAt(ctx context.Context, arg database.SetConversationLastMessageAtParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, c := range s.conversations {
+		if c.ID == arg.ID {
+			s.conversations[i].LastMessageAt = arg.LastMessageAt
+		}
+	}
+	return nil
+}
+
+func (s *Store) SetConversationMuted(ctx context.Context, arg database.SetConversationMutedParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.member(arg.ConversationID, arg.UserID)
+	if i < 0 {
+		return 0, nil
+	}
+	s.members[i].Muted = arg.Muted
+	return 1, nil
+}
+
+func (s *Store) SetNotificationPreference(ctx context.Context, arg database.SetNotificationPreferenceParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[arg.UserID]; !ok {
+		return fmt.Errorf("insert on table \"notification_preferences\" violates foreign key constraint: user %s does not exist", arg.UserID)
+	}
+	for i, p := range s.notifyPrefs {
+		if p.UserID == arg.UserID && p.Type == arg.Type {
+			s.notifyPrefs[i].Enabled = arg.Enabled
+			return nil
+		}
+	}
+	s.notifyPrefs = append(s.notifyPrefs, database.NotificationPreference(arg))
+	return nil
+}
+
+func (s *Store) TakeRateLimitToken(ctx context.Context, arg database.TakeRateLimitTokenParams) (database.TakeRateLimitTokenRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	b, ok := s.buckets[arg.Key]
+	if !ok {
+		b = rateLimitBucket{tokens: arg.Burst, updated: now}
+	}
+	if elapsed := now.Sub(b.updated).Seconds(); elapsed > 0 {
+		b.tokens = min(arg.Burst, b.tokens+elapsed*arg.Rate)
+	}
+	b.updated = now
+
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+	s.buckets[arg.Key] = b
+	return database.TakeRateLimitTokenRow{Tokens: b.tokens, Allowed: allowed}, nil
+}
+
+func (s *Store) UnblockUser(ctx context.Context, arg database.UnblockUserParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.blocks = slices.DeleteFunc(s.blocks, func(b database.Block) bool {
+		return b.BlockerID == arg.BlockerID && b.BlockedID == arg.BlockedID
+	})
+	return nil
+}
+
+func (s *Store) UnfollowUser(ctx context.Context, arg database.UnfollowUserParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.follows = slices.DeleteFunc(s.follows, func(f database.Follow) bool {
+		return f.FollowerID == arg.FollowerID && f.FolloweeID == arg.FolloweeID
+	})
+	return nil
+}
+
+func (s *Store) UnlikeChirp(ctx context.Context, arg database.UnlikeChirpParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.likes = slices.DeleteFunc(s.likes, func(l database.ChirpLike) bool {
+		return l.UserID == arg.UserID && l.ChirpID == arg.ChirpID
+	})
+	return nil
+}
+
+func (s *Store) UnmuteUser(ctx context.Context, arg database.UnmuteUserParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.mutes = slices.DeleteFunc(s.mutes, func(m database.Mute) bool {
+		return m.MuterID == arg.MuterID && m.MutedID == arg.MutedID
+	})
+	return nil
+}
+
+func (s *Store) UpdateChirp(ctx context.Context, arg database.UpdateChirpParams) (database.Chirp, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, c := range s.chirps {
+		if c.ID == arg.ID {
+			c.Body = arg.Body
+			c.UpdatedAt = s.now()
+			s.chirps[i] = c
+			return c, nil
+		}
+	}
+	return database.Chirp{}, sql.ErrNoRows
+}
+
+func (s *Store) UpdateUserEmailAndPassword(ctx context.Context, arg database.UpdateUserEmailAndPasswordParams) (database.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.users[arg.ID]
+	if !ok {
+		return database.User{}, sql.ErrNoRows
+	}
+	if s.emailTaken(arg.Email, arg.ID) {
+		return database.User{}, &database.UniqueViolationError{Constraint: "users_email_key"}
+	}
+	u.Email = arg.Email
+	u.HashedPassword = arg.HashedPassword
+	u.UpdatedAt = s.now()
+	s.users[arg.ID] = u
+	return u, nil
+}
+
+func (s *Store) UpgradeUserToChirpyRed(ctx context.Context, id uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if u, ok := s.users[id]; ok {
+		u.IsChirpyRed = true
+		u.UpdatedAt = s.now()
+		s.users[id] = u
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package memstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/HemahWeb/chirpy/internal/database"
+)
+
+func TestUniqueEmail(t *testing.T) {
+	s := New()
+	ctx := context.Background()
+
+	walt, err := s.CreateUser(ctx, database.CreateUserParams{Email: "walt@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser() failed: %v", err)
+	}
+	_, err = s.CreateUser(ctx, database.CreateUserParams{Email: "walt@example.com"})
+	if constraint, ok := database.UniqueViolation(err); !ok || constraint != "users_email_key" {
+		t.Errorf("CreateUser() error = %v, want a users_email_key violation", err)
+	}
+
+	jesse, _ := s.CreateUser(ctx, database.CreateUserParams{Email: "jesse@example.com"})
+	_, err = s.UpdateUserEmailAndPassword(ctx, database.UpdateUserEmailAndPasswordParams{ID: jesse.ID, Email: walt.Email})
+	if _, ok := database.UniqueViolation(err); !ok {
+		t.Errorf("UpdateUserEmailAndPassword() error = %v, want a unique violation", err)
+	}
+	if _, err := s.UpdateUserEmailAndPassword(ctx, database.UpdateUserEmailAndPasswordParams{ID: walt.ID, Email: walt.Email}); err != nil {
+		t.Errorf("keeping your own email failed: %v", err)
+	}
+}
+
+func TestDeleteUserCascades(t *testing.T) {
+	s := New()
+	ctx := context.Background()
+
+	user, _ := s.CreateUser(ctx, database.CreateUserParams{Email: "gus@example.com"})
+	chirp, _ := s.CreateChirp(ctx, database.CreateChirpParams{Body: "hi", UserID: user.ID})
+	token, _ := s.CreateRefreshToken(ctx, user.ID)
+	fan, _ := s.CreateUser(ctx, database.CreateUserParams{Email: "fan@example.com"})
+	s.FollowUser(ctx, database.FollowUserParams{FollowerID: fan.ID, FolloweeID: user.ID})
+	reply, _ := s.CreateChirp(ctx, database.CreateChirpParams{
+		Body:      "hey",
+		UserID:    fan.ID,
+		ReplyToID: uuid.NullUUID{UUID: chirp.ID, Valid: true},
+		ThreadID:  uuid.NullUUID{UUID: chirp.ID, Valid: true},
+	})
+	s.LikeChirp(ctx, database.LikeChirpParams{UserID: fan.ID, ChirpID: chirp.ID})
+	s.CreateNotification(ctx, database.CreateNotificationParams{UserID: user.ID, Type: "follow", ActorID: fan.ID})
+	s.CreateNotification(ctx, database.CreateNotificationParams{UserID: fan.ID, Type: "follow", ActorID: user.ID})
+	s.SetNotificationPreference(ctx, database.SetNotificationPreferenceParams{UserID: user.ID, Type: "like"})
+	s.BlockUser(ctx, database.BlockUserParams{BlockerID: fan.ID, BlockedID: user.ID})
+	s.MuteUser(ctx, database.MuteUserParams{MuterID: user.ID, MutedID: fan.ID})
+	conv, _ := s.CreateConversation(ctx, sql.NullString{})
+	s.AddConversationMember(ctx, database.AddConversationMemberParams{ConversationID: conv.ID, UserID: user.ID})
+	s.AddConversationMember(ctx, database.AddConversationMemberParams{ConversationID: conv.ID, UserID: fan.ID})
+	s.CreateMessage(ctx, database.CreateMessageParams{ConversationID: conv.ID, SenderID: user.ID, Body: "hi"})
+	fanMessage, _ := s.CreateMessage(ctx, database.CreateMessageParams{ConversationID: conv.ID, SenderID: fan.ID, Body: "hey"})
+	s.AddModerator(ctx, user.ID)
+	s.CreateReport(ctx, database.CreateReportParams{ReporterID: fan.ID, UserID: user.ID, ChirpID: uuid.NullUUID{UUID: chirp.ID, Valid: true}, Reason: "spam"})
+	troll, _ := s.CreateUser(ctx, database.CreateUserParams{Email: "troll@example.com"})
+	fanReport, _ := s.CreateReport(ctx, database.CreateReportParams{ReporterID: fan.ID, UserID: troll.ID, Reason: "spam"})
+	s.ClaimReport(ctx, database.ClaimReportParams{ID: fanReport.ID, ClaimedBy: uuid.NullUUID{UUID: user.ID, Valid: true}})
+	action, _ := s.CreateModerationAction(ctx, database.CreateModerationActionParams{ModeratorID: fan.ID, Action: "suspend_user", UserID: user.ID})
+	s.HideChirp(ctx, database.HideChirpParams{ChirpID: chirp.ID, ActionID: action.ID})
+	s.CreateAppeal(ctx, database.CreateAppealParams{ActionID: action.ID, UserID: user.ID, Body: "please"})
+
+	if err := s.DeleteUser(ctx, user.ID); err != nil {
+		t.Fatalf("DeleteUser() failed: %v", err)
+	}
+	if _, err := s.GetChirpByID(ctx, chirp.ID); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetChirpByID() error = %v, want sql.ErrNoRows", err)
+	}
+	if got, _ := s.GetChirpByID(ctx, reply.ID); got.ReplyToID.Valid || got.ThreadID != reply.ThreadID {
+		t.Errorf("reply after deleting its parent = %+v, want reply_to_id cleared and thread_id kept", got)
+	}
+	if _, err := s.GetUserIDFromRefreshToken(ctx, token); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetUserIDFromRefreshToken() error = %v, want sql.ErrNoRows", err)
+	}
+	if ids, _ := s.ListFolloweeIDs(ctx, fan.ID); len(ids) != 0 {
+		t.Errorf("ListFolloweeIDs() = %v, want the follow deleted", ids)
+	}
+	if n, _ := s.ListNotifications(ctx, database.ListNotificationsParams{UserID: fan.ID, BeforeID: 100, MaxRows: 10}); len(n) != 0 {
+		t.Errorf("ListNotifications() = %+v, want the notification from the deleted user gone", n)
+	}
+	if len(s.notifications) != 0 || len(s.notifyPrefs) != 0 || len(s.likes) != 0 {
+		t.Errorf("notifications %+v, preferences %+v and likes %+v left behind", s.notifications, s.notifyPrefs, s.likes)
+	}
+	if len(s.blocks) != 0 || len(s.mutes) != 0 {
+		t.Errorf("blocks %+v and mutes %+v left behind", s.blocks, s.mutes)
+	}
+	if len(s.moderators) != 0 || len(s.hiddenChirps) != 0 || len(s.appeals) != 0 {
+		t.Errorf("moderators %+v, hidden chirps %+v and appeals %+v left behind", s.moderators, s.hiddenChirps, s.appeals)
+	}
+	if r, _ := s.GetReport(ctx, fanReport.ID); len(s.reports) != 1 || r.ClaimedBy.Valid {
+		t.Errorf("reports = %+v, want only the one the fan filed, unclaimed", s.reports)
+	}
+	if a, _ := s.ListModerationActions(ctx, database.ListModerationActionsParams{BeforeID: 100, MaxRows: 10}); len(a) != 1 {
+		t.Errorf("ListModerationActions() = %+v, want the action kept", a)
+	}
+	if members, _ := s.ListConversationMembers(ctx, conv.ID); len(members) != 1 || members[0].UserID != fan.ID {
+		t.Errorf("ListConversationMembers() = %+v, want only the fan", members)
+	}
+	if m, _ := s.ListMessages(ctx, database.ListMessagesParams{ConversationID: conv.ID, BeforeID: 100, MaxRows: 10}); len(m) != 1 || m[0].ID != fanMessage.ID {
+		t.Errorf("ListMessages() = %+v, want only the fan's message", m)
+	}
+	if _, err := s.GetUserByID(ctx, user.ID); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetUserByID() error = %v, want sql.ErrNoRows", err)
+	}
+	if _, err := s.CreateChirp(ctx, database.CreateChirpParams{Body: "hi", UserID: user.ID}); err == nil {
+		t.Error("CreateChirp() for a deleted user should fail like a foreign key")
+	}
+}
@@ -0,0 +1,191 @@
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/HemahWeb/chirpy/internal/cleanup"
+	"github.com/HemahWeb/chirpy/internal/events"
+)
+
+const namespace = "chirpy"
+
+// Metrics owns the Prometheus registry and every collector the server
+// exports. Use a fresh one per server (or test) rather than the global
+// default registry.
+type Metrics struct {
+	Registry *prometheus.Registry
+
+	RequestsTotal    *prometheus.CounterVec
+	RequestDuration  *prometheus.HistogramVec
+	RequestsInFlight prometheus.Gauge
+
+	FileserverHits   prometheus.Counter
+	ChirpsCreated    prometheus.Counter
+	LoginsFailed     prometheus.Counter
+	WebhooksReceived *prometheus.CounterVec
+	RateLimited      *prometheus.CounterVec
+
+	hitsMu     sync.Mutex
+	hitsOffset float64
+}
+
+func New() *Metrics {
+	m := &Metrics{
+		Registry: prometheus.NewRegistry(),
+
+		RequestsTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests handled, by route pattern, method and status code.",
+		}, []string{"route", "method", "code"}),
+		RequestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Time spent handling HTTP requests, by route pattern and method.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"route", "method"}),
+		RequestsInFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "http_requests_in_flight",
+			Help:      "HTTP requests currently being handled.",
+		}),
+
+		FileserverHits: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "fileserver_hits_total",
+			Help:      "Requests served from /app/.",
+		}),
+		ChirpsCreated: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "chirps_created_total",
+			Help:      "Chirps successfully created.",
+		}),
+		LoginsFailed: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "logins_failed_total",
+			Help:      "Login attempts rejected because of a wrong email or password.",
+		}),
+		WebhooksReceived: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "webhooks_received_total",
+			Help:      "Authenticated webhook calls, by source and event.",
+		}, []string{"source", "event"}),
+		RateLimited: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "rate_limited_total",
+			Help:      "Requests rejected with 429, by route pattern and the rule that denied them.",
+		}, []string{"route", "rule"}),
+	}
+
+	m.Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.RequestsTotal,
+		m.RequestDuration,
+		m.RequestsInFlight,
+		m.FileserverHits,
+		m.ChirpsCreated,
+		m.LoginsFailed,
+		m.WebhooksReceived,
+		m.RateLimited,
+	)
+	return m
+}
+
+// RegisterDB exports connection pool stats for db.
+func (m *Metrics) RegisterDB(db *sql.DB, name string) {
+	m.Registry.MustRegister(collectors.NewDBStatsCollector(db, name))
+}
+
+// RegisterTokenCleaner exports the refresh token cleaner's counters.
+func (m *Metrics) RegisterTokenCleaner(c *cleanup.RefreshTokenCleaner) {
+	m.Registry.MustRegister(
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "refresh_tokens_removed_total",
+			Help:      "Expired or revoked refresh tokens deleted by the cleaner.",
+		}, func() float64 { return float64(c.Stats().RowsRemoved) }),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "refresh_token_cleanup_failures_total",
+			Help:      "Refresh token cleanup runs that failed.",
+		}, func() float64 { return float64(c.Stats().Failures) }),
+	)
+}
+
+// RegisterTrashPurger exports the chirp trash purger's counters.
+func (m *Metrics) RegisterTrashPurger(p *cleanup.ChirpTrashPurger) {
+	m.Registry.MustRegister(
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "trashed_chirps_purged_total",
+			Help:      "Deleted chirps purged from the trash once their retention passed.",
+		}, func() float64 { return float64(p.Stats().RowsRemoved) }),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "chirp_trash_purge_failures_total",
+			Help:      "Chirp trash purge runs that failed.",
+		}, func() float64 { return float64(p.Stats().Failures) }),
+	)
+}
+
+// RegisterAttachmentCleaner exports the orphaned attachment cleaner's
+// counters.
+func (m *Metrics) RegisterAttachmentCleaner(c *cleanup.AttachmentCleaner) {
+	m.Registry.MustRegister(
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "orphaned_attachments_removed_total",
+			Help:      "Attachments on no chirp deleted with their images by the cleaner.",
+		}, func() float64 { return float64(c.Stats().RowsRemoved) }),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "attachment_cleanup_failures_total",
+			Help:      "Orphaned attachment cleanup runs that failed.",
+		}, func() float64 { return float64(c.Stats().Failures) }),
+	)
+}
+
+// RegisterStream exports the number of open event streams.
+func (m *Metrics) RegisterStream(b *events.Broker) {
+	m.Registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "stream_subscribers",
+		Help:      "Open /api/stream connections.",
+	}, func() float64 { return float64(b.Subscribers()) }))
+}
+
+// Handler serves the registry in the Prometheus text format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.Registry, promhttp.HandlerOpts{Registry: m.Registry})
+}
+
+// Hits returns the fileserver hits since the last ResetHits. Prometheus
+// counters can't go down, so the admin reset is an offset rather than a
+// real reset.
+func (m *Metrics) Hits() int {
+	m.hitsMu.Lock()
+	defer m.hitsMu.Unlock()
+	return int(counterValue(m.FileserverHits) - m.hitsOffset)
+}
+
+func (m *Metrics) ResetHits() {
+	m.hitsMu.Lock()
+	defer m.hitsMu.Unlock()
+	m.hitsOffset = counterValue(m.FileserverHits)
+}
+
+func counterValue(c prometheus.Counter) float64 {
+	var out dto.Metric
+	if err := c.Write(&out); err != nil {
+		return 0
+	}
+	return out.GetCounter().GetValue()
+}
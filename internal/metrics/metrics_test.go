@@ -0,0 +1,21 @@
+package metrics
+
+import "testing"
+
+func TestResetHits(t *testing.T) {
+	m := New()
+	m.FileserverHits.Add(3)
+	if got := m.Hits(); got != 3 {
+		t.Fatalf("Hits() = %d, want 3", got)
+	}
+
+	m.ResetHits()
+	m.FileserverHits.Inc()
+	if got := m.Hits(); got != 1 {
+		t.Errorf("Hits() after reset = %d, want 1", got)
+	}
+	// the exported counter itself must never go down
+	if got := counterValue(m.FileserverHits); got != 4 {
+		t.Errorf("counter = %v, want 4", got)
+	}
+}
@@ -0,0 +1,21 @@
+package middleware
+
+import (
+	"mime"
+	"net/http"
+)
+
+// MaxBodyBytes caps the size of every request body: multipart/form-data
+// bodies, which carry uploads, at multipartLimit and the rest at limit.
+// Reads past the limit fail with *http.MaxBytesError and the connection is
+// closed afterwards.
+func MaxBodyBytes(limit, multipartLimit int64, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := limit
+		if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "multipart/form-data" {
+			n = multipartLimit
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, n)
+		next.ServeHTTP(w, r)
+	})
+}
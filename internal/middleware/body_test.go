@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMaxBodyBytes(t *testing.T) {
+	var readErr error
+	handler := MaxBodyBytes(8, 12, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, readErr = io.ReadAll(r.Body)
+	}))
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		wantErr     bool
+	}{
+		{"under limit", "application/json", "short", false},
+		{"at limit", "application/json", "12345678", false},
+		{"over limit", "application/json", "123456789", true},
+		{"multipart under limit", "multipart/form-data; boundary=x", "123456789", false},
+		{"multipart over limit", "multipart/form-data; boundary=x", "1234567890123", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			var maxErr *http.MaxBytesError
+			if got := errors.As(readErr, &maxErr); got != tt.wantErr {
+				t.Errorf("read error = %v, want MaxBytesError: %v", readErr, tt.wantErr)
+			}
+		})
+	}
+}
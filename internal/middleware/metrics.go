@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/HemahWeb/chirpy/internal/metrics"
+)
+
+// Metrics records request counts, latencies and in-flight requests for every
+// request routed through mux.
+func Metrics(m *metrics.Metrics, mux *http.ServeMux, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := Route(mux, r)
+		start := time.Now()
+
+		m.RequestsInFlight.Inc()
+		defer m.RequestsInFlight.Dec()
+
+		rec := newResponseRecorder(w)
+		next.ServeHTTP(rec, r)
+
+		m.RequestDuration.WithLabelValues(route, r.Method).Observe(time.Since(start).Seconds())
+		m.RequestsTotal.WithLabelValues(route, r.Method, strconv.Itoa(rec.Status())).Inc()
+	})
+}
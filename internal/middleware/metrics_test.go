@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/HemahWeb/chirpy/internal/metrics"
+)
+
+func TestMetrics(t *testing.T) {
+	m := metrics.New()
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/chirps/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if got := testutil.ToFloat64(m.RequestsInFlight); got != 1 {
+			t.Errorf("in-flight gauge = %v during the request, want 1", got)
+		}
+		w.WriteHeader(http.StatusNotFound)
+	})
+	handler := Metrics(m, mux, mux)
+
+	for _, path := range []string{"/api/chirps/1", "/api/chirps/2", "/nope"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	if got := testutil.ToFloat64(m.RequestsTotal.WithLabelValues("GET /api/chirps/{id}", "GET", "404")); got != 2 {
+		t.Errorf("requests for the chirp route = %v, want 2 (one series per pattern, not per path)", got)
+	}
+	if got := testutil.ToFloat64(m.RequestsTotal.WithLabelValues("unmatched", "GET", "404")); got != 1 {
+		t.Errorf("unmatched requests = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(m.RequestsInFlight); got != 0 {
+		t.Errorf("in-flight gauge = %v after the requests, want 0", got)
+	}
+	if got := testutil.CollectAndCount(m.RequestDuration); got != 2 {
+		t.Errorf("duration histogram has %d series, want 2", got)
+	}
+}
+
+func TestMetricsHandlerServesTextFormat(t *testing.T) {
+	m := metrics.New()
+	m.ChirpsCreated.Inc()
+
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if !strings.Contains(rec.Body.String(), "chirpy_chirps_created_total 1") {
+		t.Errorf("metrics output missing chirps counter:\n%s", rec.Body.String())
+	}
+}
@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/HemahWeb/chirpy/internal/metrics"
+	"github.com/HemahWeb/chirpy/internal/problem"
+	"github.com/HemahWeb/chirpy/internal/ratelimit"
+	"github.com/HemahWeb/chirpy/internal/utils"
+)
+
+// RateLimit rejects requests over their route's limits with 429 and
+// describes the limit that applied in RateLimit-* headers. If the store
+// fails, requests are let through rather than taking the API down with it.
+func RateLimit(l *ratelimit.Limiter, m *metrics.Metrics, mux *http.ServeMux, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := Route(mux, r)
+		d, err := l.Allow(r, route)
+		if err != nil {
+			slog.WarnContext(r.Context(), "Rate limit check failed, allowing request", "error", err)
+		}
+
+		if d.Rule.Name != "" {
+			h := w.Header()
+			h.Set("RateLimit-Limit", strconv.Itoa(d.Rule.Limit))
+			h.Set("RateLimit-Remaining", strconv.Itoa(d.Remaining))
+			h.Set("RateLimit-Reset", ceilSeconds(d.Reset))
+			h.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%s", d.Rule.Limit, ceilSeconds(d.Rule.Period)))
+		}
+		if !d.Allowed {
+			m.RateLimited.WithLabelValues(route, d.Rule.Name).Inc()
+			w.Header().Set("Retry-After", ceilSeconds(d.RetryAfter))
+			utils.RespondWithError(w, r, problem.New(http.StatusTooManyRequests, problem.CodeRateLimited, "Too many requests"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func ceilSeconds(d time.Duration) string {
+	return strconv.FormatInt(int64((d+time.Second-1)/time.Second), 10)
+}
@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/HemahWeb/chirpy/internal/metrics"
+	"github.com/HemahWeb/chirpy/internal/ratelimit"
+)
+
+func TestRateLimit(t *testing.T) {
+	m := metrics.New()
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/login", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("GET /api/livez", func(w http.ResponseWriter, r *http.Request) {})
+
+	l := ratelimit.New(ratelimit.NewMemoryStore(), nil)
+	l.Add("POST /api/login", ratelimit.Rule{Name: "login", Limit: 2, Period: time.Minute, Key: ratelimit.ByIP})
+	l.Add("GET /api/livez")
+	handler := RateLimit(l, m, mux, mux)
+
+	do := func(method, path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+		return rec
+	}
+
+	rec := do(http.MethodPost, "/api/login")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("first login status = %d, want 200", rec.Code)
+	}
+	for header, want := range map[string]string{
+		"RateLimit-Limit":     "2",
+		"RateLimit-Remaining": "1",
+		"RateLimit-Reset":     "30",
+		"RateLimit-Policy":    "2;w=60",
+	} {
+		if got := rec.Header().Get(header); got != want {
+			t.Errorf("%s = %q, want %q", header, got, want)
+		}
+	}
+
+	do(http.MethodPost, "/api/login")
+	rec = do(http.MethodPost, "/api/login")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("third login status = %d, want 429", rec.Code)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "30" {
+		t.Errorf("Retry-After = %q, want 30", got)
+	}
+	if got := testutil.ToFloat64(m.RateLimited.WithLabelValues("POST /api/login", "login")); got != 1 {
+		t.Errorf("rate limited counter = %v, want 1", got)
+	}
+
+	rec = do(http.MethodGet, "/api/livez")
+	if rec.Code != http.StatusOK || rec.Header().Get("RateLimit-Limit") != "" {
+		t.Errorf("exempt route got status %d and headers %v", rec.Code, rec.Header())
+	}
+}
@@ -0,0 +1,97 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/HemahWeb/chirpy/internal/logging"
+)
+
+func TestRequestID(t *testing.T) {
+	tests := []struct {
+		name      string
+		incoming  string
+		propagate bool
+	}{
+		{"generated when missing", "", false},
+		{"propagated when valid", "abc-123", true},
+		{"replaced when too long", strings.Repeat("a", 200), false},
+		{"replaced when it has control characters", "abc\ndef", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var seen string
+			handler := RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				seen = logging.RequestID(r.Context())
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.incoming != "" {
+				req.Header.Set(RequestIDHeader, tt.incoming)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if got := rec.Header().Get(RequestIDHeader); got != seen {
+				t.Errorf("response header %q doesn't match context %q", got, seen)
+			}
+			if tt.propagate {
+				if seen != tt.incoming {
+					t.Errorf("request ID = %q, want %q", seen, tt.incoming)
+				}
+			} else if _, err := uuid.Parse(seen); err != nil {
+				t.Errorf("request ID = %q, want a generated UUID", seen)
+			}
+		})
+	}
+}
+
+func TestAccessLog(t *testing.T) {
+	var buf bytes.Buffer
+	logger, _ := logging.New(&buf, "json", "info")
+	prev := slog.Default()
+	slog.SetDefault(logger)
+	defer slog.SetDefault(prev)
+
+	userID := uuid.New()
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/chirps", func(w http.ResponseWriter, r *http.Request) {
+		logging.SetUserID(r.Context(), userID)
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("{}"))
+	})
+	handler := RequestID(AccessLog(mux, mux))
+
+	req := httptest.NewRequest(http.MethodPost, "/api/chirps", nil)
+	req.Header.Set(RequestIDHeader, "req-1")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	var line map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatalf("access log is not a JSON line: %v\n%s", err, buf.String())
+	}
+	want := map[string]any{
+		"msg":        "request",
+		"request_id": "req-1",
+		"route":      "POST /api/chirps",
+		"user_id":    userID.String(),
+		"status":     float64(http.StatusCreated),
+		"bytes":      float64(2),
+	}
+	for k, v := range want {
+		if line[k] != v {
+			t.Errorf("%s = %v, want %v", k, line[k], v)
+		}
+	}
+	if _, ok := line["latency_ms"]; !ok {
+		t.Error("access log is missing latency_ms")
+	}
+}
@@ -0,0 +1,63 @@
+package middleware
+
+import "net/http"
+
+// responseRecorder remembers the status code and body size written through
+// it. Unwrap lets http.ResponseController reach the underlying writer, so
+// flushing and deadlines keep working.
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func newResponseRecorder(w http.ResponseWriter) *responseRecorder {
+	return &responseRecorder{ResponseWriter: w}
+}
+
+func (r *responseRecorder) WriteHeader(code int) {
+	if r.status == 0 {
+		r.status = code
+	}
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	n, err := r.ResponseWriter.Write(b)
+	r.bytes += n
+	return n, err
+}
+
+func (r *responseRecorder) Flush() {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	http.NewResponseController(r.ResponseWriter).Flush()
+}
+
+func (r *responseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// Status returns the status code sent, or 200 if the handler never wrote.
+func (r *responseRecorder) Status() int {
+	if r.status == 0 {
+		return http.StatusOK
+	}
+	return r.status
+}
+
+// Route returns the mux pattern that will handle r, e.g. "GET /api/chirps/{id}",
+// or "unmatched" so unknown paths can't blow up label cardinality.
+func Route(mux *http.ServeMux, r *http.Request) string {
+	if r.Pattern != "" {
+		return r.Pattern
+	}
+	if _, pattern := mux.Handler(r); pattern != "" {
+		return pattern
+	}
+	return "unmatched"
+}
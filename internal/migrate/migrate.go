@@ -0,0 +1,113 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+
+	"github.com/pressly/goose/v3"
+
+	"github.com/HemahWeb/chirpy/internal/dburl"
+	"github.com/HemahWeb/chirpy/sql/schema"
+	sqliteschema "github.com/HemahWeb/chirpy/sql/sqlite/schema"
+)
+
+// ErrDatabaseAhead means the database has migrations applied that this
+// binary doesn't know about, usually because a newer version already ran.
+var ErrDatabaseAhead = errors.New("database schema is newer than this binary")
+
+// ErrPendingMigrations means the binary has migrations the database hasn't
+// applied yet.
+var ErrPendingMigrations = errors.New("database has pending migrations")
+
+type Migrator struct {
+	provider *goose.Provider
+}
+
+// New loads the migrations for dialect: sql/schema for Postgres,
+// sql/sqlite/schema for SQLite.
+func New(db *sql.DB, dialect dburl.Dialect) (*Migrator, error) {
+	gooseDialect, migrations, err := source(dialect)
+	if err != nil {
+		return nil, err
+	}
+	provider, err := goose.NewProvider(gooseDialect, db, migrations)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't load migrations: %w", err)
+	}
+	return &Migrator{provider: provider}, nil
+}
+
+func source(dialect dburl.Dialect) (goose.Dialect, fs.FS, error) {
+	switch dialect {
+	case dburl.Postgres:
+		return goose.DialectPostgres, schema.FS, nil
+	case dburl.SQLite:
+		return goose.DialectSQLite3, sqliteschema.FS, nil
+	}
+	return "", nil, fmt.Errorf("no migrations for dialect %q", dialect)
+}
+
+// Up applies every pending migration.
+func (m *Migrator) Up(ctx context.Context) ([]*goose.MigrationResult, error) {
+	return m.provider.Up(ctx)
+}
+
+// Down rolls back the most recently applied migration.
+func (m *Migrator) Down(ctx context.Context) (*goose.MigrationResult, error) {
+	return m.provider.Down(ctx)
+}
+
+// Redo rolls back the most recently applied migration and applies it again.
+func (m *Migrator) Redo(ctx context.Context) ([]*goose.MigrationResult, error) {
+	down, err := m.provider.Down(ctx)
+	if err != nil {
+		return nil, err
+	}
+	up, err := m.provider.UpByOne(ctx)
+	if err != nil {
+		return []*goose.MigrationResult{down}, err
+	}
+	return []*goose.MigrationResult{down, up}, nil
+}
+
+func (m *Migrator) Status(ctx context.Context) ([]*goose.MigrationStatus, error) {
+	return m.provider.Status(ctx)
+}
+
+// Versions returns the version the database is at and the latest version
+// embedded in the binary.
+func (m *Migrator) Versions(ctx context.Context) (current, latest int64, err error) {
+	return m.provider.GetVersions(ctx)
+}
+
+// Check returns ErrDatabaseAhead if the database has been migrated past the
+// latest migration embedded in this binary.
+func (m *Migrator) Check(ctx context.Context) error {
+	current, latest, err := m.Versions(ctx)
+	if err != nil {
+		return err
+	}
+	if current > latest {
+		return fmt.Errorf("%w: database is at version %d, binary supports up to %d", ErrDatabaseAhead, current, latest)
+	}
+	return nil
+}
+
+// CheckCurrent returns nil only if the database is at exactly the latest
+// embedded migration.
+func (m *Migrator) CheckCurrent(ctx context.Context) error {
+	current, latest, err := m.Versions(ctx)
+	if err != nil {
+		return err
+	}
+	switch {
+	case current > latest:
+		return fmt.Errorf("%w: database is at version %d, binary supports up to %d", ErrDatabaseAhead, current, latest)
+	case current < latest:
+		return fmt.Errorf("%w: database is at version %d, latest is %d", ErrPendingMigrations, current, latest)
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"io/fs"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/lib/pq"
+
+	"github.com/HemahWeb/chirpy/internal/dburl"
+	"github.com/HemahWeb/chirpy/internal/sqlite"
+	"github.com/HemahWeb/chirpy/sql/schema"
+	sqliteschema "github.com/HemahWeb/chirpy/sql/sqlite/schema"
+)
+
+func TestEmbeddedMigrationsAreSequential(t *testing.T) {
+	// sql.Open doesn't connect, which is all loading sources needs
+	db, err := sql.Open("postgres", "postgres://localhost/unused?sslmode=disable")
+	if err != nil {
+		t.Fatalf("sql.Open() failed: %v", err)
+	}
+	defer db.Close()
+
+	m, err := New(db, dburl.Postgres)
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	files, err := fs.Glob(schema.FS, "*.sql")
+	if err != nil {
+		t.Fatalf("fs.Glob() failed: %v", err)
+	}
+
+	sources := m.provider.ListSources()
+	if len(sources) != len(files) {
+		t.Fatalf("provider has %d migrations, embedded FS has %d files", len(sources), len(files))
+	}
+	for i, src := range sources {
+		if want := int64(i + 1); src.Version != want {
+			t.Errorf("migration %s has version %d, want %d", src.Path, src.Version, want)
+		}
+	}
+}
+
+func TestSQLiteMigrationsMirrorPostgres(t *testing.T) {
+	postgres, err := fs.Glob(schema.FS, "*.sql")
+	if err != nil {
+		t.Fatalf("fs.Glob() failed: %v", err)
+	}
+	sqlite, err := fs.Glob(sqliteschema.FS, "*.sql")
+	if err != nil {
+		t.Fatalf("fs.Glob() failed: %v", err)
+	}
+	if len(sqlite) != len(postgres) {
+		t.Fatalf("SQLite has %d migrations, Postgres has %d", len(sqlite), len(postgres))
+	}
+	for i := range postgres {
+		if sqlite[i] != postgres[i] {
+			t.Errorf("SQLite migration %s doesn't match Postgres migration %s", sqlite[i], postgres[i])
+		}
+	}
+}
+
+func TestSQLiteUpAndDown(t *testing.T) {
+	_, dsn, err := dburl.Parse("sqlite:" + filepath.Join(t.TempDir(), "chirpy.db"))
+	if err != nil {
+		t.Fatalf("dburl.Parse() failed: %v", err)
+	}
+	db, err := sqlite.Open(dsn)
+	if err != nil {
+		t.Fatalf("Open() failed: %v", err)
+	}
+	defer db.Close()
+
+	m, err := New(db, dburl.SQLite)
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	ctx := context.Background()
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatalf("Up() failed: %v", err)
+	}
+	if err := m.CheckCurrent(ctx); err != nil {
+		t.Errorf("CheckCurrent() after Up() = %v", err)
+	}
+	for {
+		if _, err := m.Down(ctx); err != nil {
+			break
+		}
+	}
+	if current, _, err := m.Versions(ctx); err != nil || current != 0 {
+		t.Errorf("Versions() after rolling back = %d, %v; want 0", current, err)
+	}
+}
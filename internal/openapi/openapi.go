@@ -0,0 +1,60 @@
+// Package openapi serves the OpenAPI description of the API and a page that
+// renders it. openapi.yaml is the source of truth; a handlers test fails if
+// it and handlers.Register disagree about which routes exist.
+package openapi
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed openapi.yaml
+var specYAML []byte
+
+//go:embed docs.html
+var docsHTML []byte
+
+// specJSON is converted once at startup; the YAML is embedded, so a broken
+// document is a bug that tests catch, not a runtime condition.
+var specJSON = mustJSON(specYAML)
+
+func mustJSON(doc []byte) []byte {
+	var v any
+	if err := yaml.Unmarshal(doc, &v); err != nil {
+		panic(fmt.Sprintf("openapi.yaml is not valid YAML: %v", err))
+	}
+	out, err := json.Marshal(v)
+	if err != nil {
+		panic(fmt.Sprintf("openapi.yaml can't be represented as JSON: %v", err))
+	}
+	return out
+}
+
+// Spec returns the document as JSON.
+func Spec() []byte {
+	return specJSON
+}
+
+// SpecHandler serves the document as JSON.
+func SpecHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.WriteHeader(http.StatusOK)
+		w.Write(specJSON)
+	})
+}
+
+// DocsHandler serves an HTML page that renders the document. It loads
+// everything from this server, so it works offline.
+func DocsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		w.Write(docsHTML)
+	})
+}
@@ -0,0 +1,52 @@
+package openapi
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestRefsResolve(t *testing.T) {
+	var doc map[string]any
+	if err := json.Unmarshal(Spec(), &doc); err != nil {
+		t.Fatalf("spec isn't valid JSON: %v", err)
+	}
+
+	var walk func(v any)
+	walk = func(v any) {
+		switch v := v.(type) {
+		case map[string]any:
+			if ref, ok := v["$ref"].(string); ok {
+				if _, err := lookup(doc, ref); err != "" {
+					t.Errorf("$ref %q: %s", ref, err)
+				}
+			}
+			for _, child := range v {
+				walk(child)
+			}
+		case []any:
+			for _, child := range v {
+				walk(child)
+			}
+		}
+	}
+	walk(doc)
+}
+
+func lookup(doc map[string]any, ref string) (any, string) {
+	path, ok := strings.CutPrefix(ref, "#/")
+	if !ok {
+		return nil, "only local references are supported"
+	}
+	var cur any = doc
+	for _, key := range strings.Split(path, "/") {
+		m, ok := cur.(map[string]any)
+		if !ok {
+			return nil, "points inside a non-object"
+		}
+		if cur, ok = m[key]; !ok {
+			return nil, "no " + key
+		}
+	}
+	return cur, ""
+}
@@ -0,0 +1,164 @@
+// Package problem implements RFC 9457 (formerly 7807) problem details, the
+// error body every endpoint returns.
+package problem
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/HemahWeb/chirpy/internal/auth"
+	"github.com/HemahWeb/chirpy/internal/database"
+)
+
+// ContentType is the media type of a serialized Problem.
+const ContentType = "application/problem+json"
+
+// Code identifies a kind of error. Codes are part of the API: clients match
+// on them, so never change or reuse one.
+type Code string
+
+const (
+	CodeInvalidJSON        Code = "invalid_json"
+	CodeInvalidForm        Code = "invalid_form"
+	CodeValidationFailed   Code = "validation_failed"
+	CodeBodyTooLarge       Code = "body_too_large"
+	CodeUnauthenticated    Code = "unauthenticated"
+	CodeInvalidToken       Code = "invalid_token"
+	CodeTokenExpired       Code = "token_expired"
+	CodeTokenRevoked       Code = "token_revoked"
+	CodeInvalidCredentials Code = "invalid_credentials"
+	CodeInvalidAPIKey      Code = "invalid_api_key"
+	CodeForbidden          Code = "forbidden"
+	CodeAccountSuspended   Code = "account_suspended"
+	CodeNotFound           Code = "not_found"
+	CodeConflict           Code = "conflict"
+	CodeEmailTaken         Code = "email_taken"
+	CodeRateLimited        Code = "rate_limited"
+	CodeInternal           Code = "internal_error"
+	CodeUnavailable        Code = "unavailable"
+)
+
+// typePrefix makes Type a URI as the RFC requires; it isn't meant to be
+// dereferenced.
+const typePrefix = "urn:chirpy:problem:"
+
+// FieldError describes one invalid field of a request body.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+type Problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail,omitempty"`
+	Instance  string       `json:"instance,omitempty"`
+	Code      Code         `json:"code"`
+	RequestID string       `json:"request_id,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+
+	// cause is logged but never sent to the client
+	cause error
+}
+
+// New returns a problem. detail is shown to clients, so it must not include
+// internal error text.
+func New(status int, code Code, detail string) *Problem {
+	return &Problem{
+		Type:   typePrefix + string(code),
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+		Code:   code,
+	}
+}
+
+// WithCause records the underlying error for the logs.
+func (p *Problem) WithCause(err error) *Problem {
+	p.cause = err
+	return p
+}
+
+func (p *Problem) Error() string {
+	if p.cause != nil {
+		return string(p.Code) + ": " + p.Detail + ": " + p.cause.Error()
+	}
+	return string(p.Code) + ": " + p.Detail
+}
+
+func (p *Problem) Unwrap() error {
+	return p.cause
+}
+
+// Validation reports invalid fields with 400.
+func Validation(errs ...FieldError) *Problem {
+	p := New(http.StatusBadRequest, CodeValidationFailed, "Request body has invalid fields")
+	p.Errors = errs
+	return p
+}
+
+// InvalidJSON reports a request body that couldn't be decoded.
+func InvalidJSON(err error) *Problem {
+	if p := From(err); p.Code == CodeBodyTooLarge {
+		return p
+	}
+	return New(http.StatusBadRequest, CodeInvalidJSON, "Request body is not valid JSON").WithCause(err)
+}
+
+// InvalidForm reports a multipart/form-data request body that couldn't be
+// read.
+func InvalidForm(err error) *Problem {
+	if p := From(err); p.Code == CodeBodyTooLarge {
+		return p
+	}
+	return New(http.StatusBadRequest, CodeInvalidForm, "Request body is not a valid multipart form").WithCause(err)
+}
+
+// From maps err to a problem. A *Problem anywhere in the chain is returned
+// as is; known errors from the database, auth and net/http get their status
+// and code; anything else is a 500 that reveals nothing about the cause.
+func From(err error) *Problem {
+	var p *Problem
+	if errors.As(err, &p) {
+		return p
+	}
+
+	var maxBytes *http.MaxBytesError
+	constraint, unique := database.UniqueViolation(err)
+	switch {
+	case errors.As(err, &maxBytes):
+		return New(http.StatusRequestEntityTooLarge, CodeBodyTooLarge, "Request body is too large").WithCause(err)
+
+	case errors.Is(err, sql.ErrNoRows):
+		return New(http.StatusNotFound, CodeNotFound, "Resource not found").WithCause(err)
+
+	case unique:
+		if constraint == "users_email_key" {
+			return New(http.StatusConflict, CodeEmailTaken, "Email is already in use").WithCause(err)
+		}
+		return New(http.StatusConflict, CodeConflict, "Resource already exists").WithCause(err)
+
+	case errors.Is(err, auth.ErrNoAuthHeader),
+		errors.Is(err, auth.ErrInvalidAuthHeader),
+		errors.Is(err, auth.ErrMalformedAuthHeader),
+		errors.Is(err, auth.ErrEmptyAPIKey):
+		return New(http.StatusUnauthorized, CodeUnauthenticated, "Missing or malformed Authorization header").WithCause(err)
+
+	case errors.Is(err, jwt.ErrTokenExpired):
+		return New(http.StatusUnauthorized, CodeTokenExpired, "Access token has expired").WithCause(err)
+
+	case errors.Is(err, auth.ErrInvalidToken):
+		return New(http.StatusUnauthorized, CodeInvalidToken, "Access token is invalid").WithCause(err)
+
+	case errors.Is(err, bcrypt.ErrMismatchedHashAndPassword):
+		return New(http.StatusUnauthorized, CodeInvalidCredentials, "Incorrect email or password").WithCause(err)
+	}
+
+	return New(http.StatusInternalServerError, CodeInternal, "Something went wrong").WithCause(err)
+}
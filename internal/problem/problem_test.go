@@ -0,0 +1,71 @@
+package problem
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/lib/pq"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/HemahWeb/chirpy/internal/auth"
+	"github.com/HemahWeb/chirpy/internal/database"
+)
+
+func TestFrom(t *testing.T) {
+	custom := New(http.StatusForbidden, CodeForbidden, "Nope")
+
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		code   Code
+	}{
+		{"problem", fmt.Errorf("wrapped: %w", custom), http.StatusForbidden, CodeForbidden},
+		{"body too large", &http.MaxBytesError{Limit: 10}, http.StatusRequestEntityTooLarge, CodeBodyTooLarge},
+		{"no rows", fmt.Errorf("get chirp: %w", sql.ErrNoRows), http.StatusNotFound, CodeNotFound},
+		{"duplicate email", &pq.Error{Code: "23505", Constraint: "users_email_key"}, http.StatusConflict, CodeEmailTaken},
+		{"other unique violation", &pq.Error{Code: "23505", Constraint: "something_key"}, http.StatusConflict, CodeConflict},
+		{"duplicate email in another backend", &database.UniqueViolationError{Constraint: "users_email_key"}, http.StatusConflict, CodeEmailTaken},
+		{"other postgres error", &pq.Error{Code: "42P01"}, http.StatusInternalServerError, CodeInternal},
+		{"missing header", auth.ErrNoAuthHeader, http.StatusUnauthorized, CodeUnauthenticated},
+		{"malformed API key header", auth.ErrMalformedAuthHeader, http.StatusUnauthorized, CodeUnauthenticated},
+		{"expired token", fmt.Errorf("%w: %w", auth.ErrInvalidToken, jwt.ErrTokenExpired), http.StatusUnauthorized, CodeTokenExpired},
+		{"invalid token", fmt.Errorf("%w: %w", auth.ErrInvalidToken, jwt.ErrTokenSignatureInvalid), http.StatusUnauthorized, CodeInvalidToken},
+		{"wrong password", bcrypt.ErrMismatchedHashAndPassword, http.StatusUnauthorized, CodeInvalidCredentials},
+		{"unknown", errors.New("connection refused"), http.StatusInternalServerError, CodeInternal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := From(tt.err)
+			if p.Status != tt.status || p.Code != tt.code {
+				t.Errorf("From() = %d %s, want %d %s", p.Status, p.Code, tt.status, tt.code)
+			}
+			if p.Type != "urn:chirpy:problem:"+string(tt.code) || p.Title != http.StatusText(tt.status) {
+				t.Errorf("Type = %q, Title = %q", p.Type, p.Title)
+			}
+			if p != custom && !errors.Is(p, tt.err) {
+				t.Error("the original error should stay in the chain for logging")
+			}
+		})
+	}
+}
+
+func TestFromHidesInternalErrors(t *testing.T) {
+	p := From(errors.New(`pq: relation "users" does not exist`))
+	if p.Detail != "Something went wrong" {
+		t.Errorf("Detail = %q, internal errors must not reach clients", p.Detail)
+	}
+}
+
+func TestInvalidJSON(t *testing.T) {
+	if p := InvalidJSON(errors.New("unexpected EOF")); p.Status != http.StatusBadRequest || p.Code != CodeInvalidJSON {
+		t.Errorf("InvalidJSON() = %d %s, want 400 invalid_json", p.Status, p.Code)
+	}
+	if p := InvalidJSON(&http.MaxBytesError{Limit: 10}); p.Status != http.StatusRequestEntityTooLarge {
+		t.Errorf("InvalidJSON() = %d, want 413 for an oversized body", p.Status)
+	}
+}
@@ -0,0 +1,54 @@
+package ratelimit
+
+import (
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// ClientIP returns the address of the client that made r. X-Forwarded-For is
+// only consulted when the connection comes from a trusted proxy, and is read
+// right to left so a client can't pick its address by sending the header
+// itself: the first hop that isn't a trusted proxy is the client.
+func ClientIP(r *http.Request, trusted []netip.Prefix) netip.Addr {
+	ip := remoteAddr(r)
+	if !isTrusted(ip, trusted) {
+		return ip
+	}
+
+	var hops []string
+	for _, v := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(v, ",")...)
+	}
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			// nothing left of a malformed hop can be trusted
+			return ip
+		}
+		ip = hop.Unmap()
+		if !isTrusted(ip, trusted) {
+			return ip
+		}
+	}
+	return ip
+}
+
+func remoteAddr(r *http.Request) netip.Addr {
+	if addrPort, err := netip.ParseAddrPort(r.RemoteAddr); err == nil {
+		return addrPort.Addr().Unmap()
+	}
+	if addr, err := netip.ParseAddr(r.RemoteAddr); err == nil {
+		return addr.Unmap()
+	}
+	return netip.Addr{}
+}
+
+func isTrusted(ip netip.Addr, trusted []netip.Prefix) bool {
+	for _, p := range trusted {
+		if p.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,45 @@
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	trusted := []netip.Prefix{
+		netip.MustParsePrefix("10.0.0.0/8"),
+		netip.MustParsePrefix("::1/128"),
+	}
+
+	tests := []struct {
+		name   string
+		remote string
+		xff    []string
+		want   string
+	}{
+		{"direct client", "192.0.2.1:1234", nil, "192.0.2.1"},
+		{"untrusted peer can't spoof", "192.0.2.1:1234", []string{"203.0.113.9"}, "192.0.2.1"},
+		{"trusted proxy", "10.0.0.1:1234", []string{"203.0.113.9"}, "203.0.113.9"},
+		{"client-supplied prefix ignored", "10.0.0.1:1234", []string{"6.6.6.6, 203.0.113.9"}, "203.0.113.9"},
+		{"chain of proxies", "10.0.0.1:1234", []string{"203.0.113.9, 10.0.0.2", "10.0.0.3"}, "203.0.113.9"},
+		{"malformed hop", "10.0.0.1:1234", []string{"6.6.6.6, garbage, 10.0.0.2"}, "10.0.0.2"},
+		{"only proxies", "10.0.0.1:1234", []string{"10.0.0.2"}, "10.0.0.2"},
+		{"no header", "10.0.0.1:1234", nil, "10.0.0.1"},
+		{"ipv6 proxy", "[::1]:1234", []string{"2001:db8::1"}, "2001:db8::1"},
+		{"ipv4-mapped ipv6", "[::ffff:192.0.2.1]:1234", nil, "192.0.2.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = tt.remote
+			for _, v := range tt.xff {
+				r.Header.Add("X-Forwarded-For", v)
+			}
+			if got := ClientIP(r, trusted); got.String() != tt.want {
+				t.Errorf("ClientIP() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// MemoryStore keeps buckets in process memory. Each instance enforces its
+// own limits, so use PostgresStore when running more than one.
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	now     func() time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: map[string]*bucket{}, now: time.Now}
+}
+
+func (s *MemoryStore) Take(_ context.Context, key string, burst int, rate float64) (float64, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(burst), updated: now}
+		s.buckets[key] = b
+	}
+	if elapsed := now.Sub(b.updated).Seconds(); elapsed > 0 {
+		b.tokens = min(float64(burst), b.tokens+elapsed*rate)
+	}
+	b.updated = now
+
+	if b.tokens < 1 {
+		return b.tokens, false, nil
+	}
+	b.tokens--
+	return b.tokens, true, nil
+}
+
+func (s *MemoryStore) Sweep(_ context.Context, idle time.Duration) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cutoff := s.now().Add(-idle)
+	var removed int64
+	for key, b := range s.buckets {
+		if b.updated.Before(cutoff) {
+			delete(s.buckets, key)
+			removed++
+		}
+	}
+	return removed, nil
+}
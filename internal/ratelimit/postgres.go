@@ -0,0 +1,41 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+
+	"github.com/HemahWeb/chirpy/internal/database"
+)
+
+// Queries is the subset of *database.Queries the Postgres store needs.
+type Queries interface {
+	TakeRateLimitToken(ctx context.Context, arg database.TakeRateLimitTokenParams) (database.TakeRateLimitTokenRow, error)
+	DeleteIdleRateLimitBuckets(ctx context.Context, idleSeconds float64) (int64, error)
+}
+
+// PostgresStore keeps buckets in the rate_limit_buckets table so every
+// instance shares them. Refills are timed by the database clock, so clock
+// skew between instances doesn't matter.
+type PostgresStore struct {
+	db Queries
+}
+
+func NewPostgresStore(db Queries) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+func (s *PostgresStore) Take(ctx context.Context, key string, burst int, rate float64) (float64, bool, error) {
+	row, err := s.db.TakeRateLimitToken(ctx, database.TakeRateLimitTokenParams{
+		Key:   key,
+		Burst: float64(burst),
+		Rate:  rate,
+	})
+	if err != nil {
+		return 0, false, err
+	}
+	return row.Tokens, row.Allowed, nil
+}
+
+func (s *PostgresStore) Sweep(ctx context.Context, idle time.Duration) (int64, error) {
+	return s.db.DeleteIdleRateLimitBuckets(ctx, idle.Seconds())
+}
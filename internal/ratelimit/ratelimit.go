@@ -0,0 +1,183 @@
+// Package ratelimit implements per-route token bucket rate limits.
+package ratelimit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"net/netip"
+	"time"
+
+	"github.com/HemahWeb/chirpy/internal/auth"
+)
+
+// Store keeps token buckets. Take refills the bucket for key at rate tokens
+// per second, up to burst, then takes one token if a whole one is left. It
+// returns the tokens remaining afterwards.
+type Store interface {
+	Take(ctx context.Context, key string, burst int, rate float64) (tokens float64, allowed bool, err error)
+	// Sweep forgets buckets unused for longer than idle. A bucket idle for
+	// its whole period is full, which is the same as not existing.
+	Sweep(ctx context.Context, idle time.Duration) (int64, error)
+}
+
+// KeyFunc picks the bucket a request counts against.
+type KeyFunc func(r *http.Request, ip netip.Addr) string
+
+// Rule allows Limit requests per Period, in bursts of up to Limit.
+type Rule struct {
+	Name   string
+	Limit  int
+	Period time.Duration
+	Key    KeyFunc
+}
+
+func (r Rule) rate() float64 {
+	return float64(r.Limit) / r.Period.Seconds()
+}
+
+// Decision is the outcome for the most restrictive rule that applied. A
+// request no rule applies to gets Allowed with a zero Rule.
+type Decision struct {
+	Allowed   bool
+	Rule      Rule
+	Remaining int
+	// Reset is how long until the bucket is full again.
+	Reset time.Duration
+	// RetryAfter is how long until the next request would be allowed; zero
+	// when Allowed.
+	RetryAfter time.Duration
+}
+
+type Limiter struct {
+	store    Store
+	trusted  []netip.Prefix
+	routes   map[string][]Rule
+	fallback []Rule
+}
+
+// New returns a limiter with no rules. trusted lists the proxies whose
+// X-Forwarded-For headers are believed.
+func New(store Store, trusted []netip.Prefix) *Limiter {
+	return &Limiter{store: store, trusted: trusted, routes: map[string][]Rule{}}
+}
+
+// Add sets the rules for a mux route pattern, replacing the defaults. Adding
+// a route with no rules exempts it.
+func (l *Limiter) Add(route string, rules ...Rule) {
+	l.routes[route] = rules
+}
+
+// Default sets the rules for routes without their own.
+func (l *Limiter) Default(rules ...Rule) {
+	l.fallback = rules
+}
+
+// Allow takes a token from every rule for route, stopping at the first that
+// denies the request. On a store error it returns the error along with a
+// Decision allowing the request, so callers can fail open.
+func (l *Limiter) Allow(r *http.Request, route string) (Decision, error) {
+	rules, ok := l.routes[route]
+	if !ok {
+		rules = l.fallback
+	}
+	ip := ClientIP(r, l.trusted)
+
+	decision := Decision{Allowed: true}
+	for i, rule := range rules {
+		key := rule.Name + ":" + rule.Key(r, ip)
+		tokens, allowed, err := l.store.Take(r.Context(), key, rule.Limit, rule.rate())
+		if err != nil {
+			return Decision{Allowed: true}, fmt.Errorf("rate limit %s: %w", rule.Name, err)
+		}
+
+		d := Decision{
+			Allowed:   allowed,
+			Rule:      rule,
+			Remaining: int(math.Floor(tokens)),
+			Reset:     seconds((float64(rule.Limit) - tokens) / rule.rate()),
+		}
+		if !allowed {
+			d.RetryAfter = seconds((1 - tokens) / rule.rate())
+			return d, nil
+		}
+		if i == 0 || d.Remaining < decision.Remaining {
+			decision = d
+		}
+	}
+	return decision, nil
+}
+
+// Run sweeps idle buckets every interval until ctx is done.
+func (l *Limiter) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := l.store.Sweep(ctx, l.longestPeriod()); err != nil && ctx.Err() == nil {
+				slog.ErrorContext(ctx, "Rate limit sweep failed", "error", err)
+			}
+		}
+	}
+}
+
+func (l *Limiter) longestPeriod() time.Duration {
+	var longest time.Duration
+	for _, rule := range l.fallback {
+		longest = max(longest, rule.Period)
+	}
+	for _, rules := range l.routes {
+		for _, rule := range rules {
+			longest = max(longest, rule.Period)
+		}
+	}
+	return longest
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(math.Ceil(s * float64(time.Second)))
+}
+
+// ByIP keys on the client address. IPv6 clients are grouped by /64, since a
+// single host usually has a whole one to rotate through.
+func ByIP(_ *http.Request, ip netip.Addr) string {
+	if ip.Is6() {
+		return "ip:" + netip.PrefixFrom(ip, 64).Masked().String()
+	}
+	return "ip:" + ip.String()
+}
+
+// ByUser keys on the user in a valid access token, falling back to the
+// client address for anonymous requests.
+func ByUser(jwtSecret string) KeyFunc {
+	return func(r *http.Request, ip netip.Addr) string {
+		token, err := auth.GetBearerToken(r.Header)
+		if err != nil {
+			return ByIP(r, ip)
+		}
+		userID, err := auth.ValidateJWT(token, jwtSecret)
+		if err != nil {
+			return ByIP(r, ip)
+		}
+		return "user:" + userID.String()
+	}
+}
+
+// ByToken keys on the credential in the Authorization header, such as a
+// refresh token, without checking it. Pair it with a ByIP rule so clients
+// can't dodge the limit by making up tokens.
+func ByToken(r *http.Request, ip netip.Addr) string {
+	header := r.Header.Get("Authorization")
+	if header == "" {
+		return ByIP(r, ip)
+	}
+	sum := sha256.Sum256([]byte(header))
+	return "token:" + hex.EncodeToString(sum[:16])
+}
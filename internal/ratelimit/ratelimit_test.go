@@ -0,0 +1,176 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/HemahWeb/chirpy/internal/auth"
+)
+
+func newTestStore() (*MemoryStore, *time.Time) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := NewMemoryStore()
+	s.now = func() time.Time { return now }
+	return s, &now
+}
+
+func TestMemoryStoreRefills(t *testing.T) {
+	s, now := newTestStore()
+	ctx := context.Background()
+
+	for i := range 3 {
+		if _, ok, _ := s.Take(ctx, "k", 3, 1); !ok {
+			t.Fatalf("request %d should fit in the burst", i+1)
+		}
+	}
+	if tokens, ok, _ := s.Take(ctx, "k", 3, 1); ok || tokens != 0 {
+		t.Fatalf("Take() = %v, %v; want the empty bucket to deny", tokens, ok)
+	}
+
+	*now = now.Add(1500 * time.Millisecond)
+	if tokens, ok, _ := s.Take(ctx, "k", 3, 1); !ok || tokens != 0.5 {
+		t.Errorf("Take() = %v, %v; want one token refilled with half to spare", tokens, ok)
+	}
+
+	*now = now.Add(time.Hour)
+	if tokens, _, _ := s.Take(ctx, "k", 3, 1); tokens != 2 {
+		t.Errorf("tokens = %v, want the refill capped at the burst", tokens)
+	}
+}
+
+func TestMemoryStoreSweep(t *testing.T) {
+	s, now := newTestStore()
+	ctx := context.Background()
+	s.Take(ctx, "old", 1, 1)
+	*now = now.Add(2 * time.Minute)
+	s.Take(ctx, "new", 1, 1)
+
+	removed, _ := s.Sweep(ctx, time.Minute)
+	if removed != 1 || len(s.buckets) != 1 || s.buckets["new"] == nil {
+		t.Errorf("Sweep() removed %d, left %v; want only the idle bucket gone", removed, s.buckets)
+	}
+}
+
+func TestLimiter(t *testing.T) {
+	s, _ := newTestStore()
+	l := New(s, nil)
+	l.Default(Rule{Name: "default", Limit: 100, Period: time.Minute, Key: ByIP})
+	l.Add("POST /api/refresh",
+		Rule{Name: "refresh", Limit: 2, Period: time.Minute, Key: ByToken},
+		Rule{Name: "refresh-ip", Limit: 5, Period: time.Minute, Key: ByIP},
+	)
+	l.Add("GET /api/livez")
+
+	req := func(token string) *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/api/refresh", nil)
+		r.RemoteAddr = "192.0.2.1:1234"
+		r.Header.Set("Authorization", "Bearer "+token)
+		return r
+	}
+
+	d, err := l.Allow(req("a"), "POST /api/refresh")
+	if err != nil || !d.Allowed {
+		t.Fatalf("Allow() = %+v, %v; want allowed", d, err)
+	}
+	if d.Rule.Name != "refresh" || d.Remaining != 1 || d.Reset != 30*time.Second {
+		t.Errorf("Decision = %+v; want the tighter per-token rule reported", d)
+	}
+
+	l.Allow(req("a"), "POST /api/refresh")
+	d, _ = l.Allow(req("a"), "POST /api/refresh")
+	if d.Allowed || d.Rule.Name != "refresh" || d.RetryAfter != 30*time.Second {
+		t.Errorf("Decision = %+v; want the third request with one token denied for 30s", d)
+	}
+
+	// new tokens get their own bucket but still share the per-IP one, which
+	// the denied request above didn't reach
+	for _, token := range []string{"b", "c", "d"} {
+		l.Allow(req(token), "POST /api/refresh")
+	}
+	d, _ = l.Allow(req("e"), "POST /api/refresh")
+	if d.Allowed || d.Rule.Name != "refresh-ip" {
+		t.Errorf("Decision = %+v; want the per-IP rule to stop made-up tokens", d)
+	}
+
+	d, _ = l.Allow(req("a"), "GET /api/livez")
+	if !d.Allowed || d.Rule.Name != "" {
+		t.Errorf("Decision = %+v; want exempt routes to skip every rule", d)
+	}
+
+	d, _ = l.Allow(req("a"), "GET /api/chirps")
+	if !d.Allowed || d.Rule.Name != "default" || d.Remaining != 99 {
+		t.Errorf("Decision = %+v; want the default rule", d)
+	}
+}
+
+type failingStore struct{}
+
+func (failingStore) Take(context.Context, string, int, float64) (float64, bool, error) {
+	return 0, false, errors.New("connection refused")
+}
+
+func (failingStore) Sweep(context.Context, time.Duration) (int64, error) {
+	return 0, errors.New("connection refused")
+}
+
+func TestLimiterFailsOpen(t *testing.T) {
+	l := New(failingStore{}, nil)
+	l.Default(Rule{Name: "default", Limit: 1, Period: time.Minute, Key: ByIP})
+
+	d, err := l.Allow(httptest.NewRequest(http.MethodGet, "/", nil), "GET /")
+	if err == nil || !d.Allowed {
+		t.Errorf("Allow() = %+v, %v; want the error reported and the request allowed", d, err)
+	}
+}
+
+func TestKeys(t *testing.T) {
+	const secret = "test-secret"
+	userID := uuid.New()
+	jwt, err := auth.MakeJWT(userID, secret)
+	if err != nil {
+		t.Fatalf("MakeJWT() failed: %v", err)
+	}
+	v4 := netip.MustParseAddr("192.0.2.1")
+	v6 := netip.MustParseAddr("2001:db8:1:2:3:4:5:6")
+
+	withAuth := func(header string) *http.Request {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if header != "" {
+			r.Header.Set("Authorization", header)
+		}
+		return r
+	}
+
+	tests := []struct {
+		name string
+		key  KeyFunc
+		r    *http.Request
+		ip   netip.Addr
+		want string
+	}{
+		{"ipv4", ByIP, withAuth(""), v4, "ip:192.0.2.1"},
+		{"ipv6 grouped by /64", ByIP, withAuth(""), v6, "ip:2001:db8:1:2::/64"},
+		{"user from access token", ByUser(secret), withAuth("Bearer " + jwt), v4, "user:" + userID.String()},
+		{"invalid access token", ByUser(secret), withAuth("Bearer nope"), v4, "ip:192.0.2.1"},
+		{"anonymous user", ByUser(secret), withAuth(""), v4, "ip:192.0.2.1"},
+		{"no token", ByToken, withAuth(""), v4, "ip:192.0.2.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.key(tt.r, tt.ip); got != tt.want {
+				t.Errorf("key = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if a, b := ByToken(withAuth("Bearer a"), v4), ByToken(withAuth("Bearer b"), v4); a == b {
+		t.Error("ByToken should give different tokens different buckets")
+	}
+}
@@ -0,0 +1,163 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/google/uuid"
+
+	"github.com/HemahWeb/chirpy/internal/database"
+	"github.com/HemahWeb/chirpy/internal/media"
+	"github.com/HemahWeb/chirpy/internal/problem"
+)
+
+// DefaultMaxImages is how many images a chirp can carry unless SetMedia
+// says otherwise.
+const DefaultMaxImages = 4
+
+// SetMedia makes the service keep chirp images in store, accepting up to
+// maxImages a chirp within limits. Without a store, posting images fails.
+func (s *Service) SetMedia(store media.BlobStore, limits media.Limits, maxImages int) {
+	s.media = store
+	s.mediaLimits = limits
+	s.maxImages = maxImages
+}
+
+// storeImages checks and cleans every image, then stores them on no chirp
+// yet for attachImages to add to one. Nothing is stored unless every image
+// is valid. Whatever is stored before a failure, here or in the chirp's
+// transaction, is left for the attachment cleaner.
+func (s *Service) storeImages(ctx context.Context, images [][]byte) ([]database.Attachment, error) {
+	if len(images) == 0 {
+		return nil, nil
+	}
+	if s.media == nil {
+		return nil, problem.New(http.StatusServiceUnavailable, problem.CodeUnavailable, "Image uploads are not configured")
+	}
+	if len(images) > s.maxImages {
+		return nil, problem.Validation(problem.FieldError{Field: "images", Code: "too_many", Message: fmt.Sprintf("must be at most %d images", s.maxImages)})
+	}
+
+	processed := make([]media.Image, len(images))
+	var errs []problem.FieldError
+	for i, data := range images {
+		img, err := media.Process(data, s.mediaLimits)
+		if err != nil {
+			errs = append(errs, s.imageError(i, err))
+			continue
+		}
+		processed[i] = img
+	}
+	if len(errs) > 0 {
+		return nil, problem.Validation(errs...)
+	}
+
+	attachments := make([]database.Attachment, 0, len(processed))
+	for _, img := range processed {
+		a, err := s.db.CreateAttachment(ctx, database.CreateAttachmentParams{
+			ContentType:          img.ContentType,
+			SizeBytes:            int64(len(img.Data)),
+			Width:                int32(img.Width),
+			Height:               int32(img.Height),
+			ThumbnailContentType: img.Thumbnail.ContentType,
+			ThumbnailSizeBytes:   int64(len(img.Thumbnail.Data)),
+			ThumbnailWidth:       int32(img.Thumbnail.Width),
+			ThumbnailHeight:      int32(img.Thumbnail.Height),
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := s.media.Put(ctx, media.Key(a.ID), img.ContentType, img.Data); err != nil {
+			return nil, err
+		}
+		if err := s.media.Put(ctx, media.ThumbnailKey(a.ID), img.Thumbnail.ContentType, img.Thumbnail.Data); err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, a)
+	}
+	return attachments, nil
+}
+
+// imageError reports why the i-th image was refused.
+func (s *Service) imageError(i int, err error) problem.FieldError {
+	field := fmt.Sprintf("images[%d]", i)
+	switch {
+	case errors.Is(err, media.ErrTooLarge):
+		return problem.FieldError{Field: field, Code: "too_large", Message: fmt.Sprintf("must be at most %d bytes and %d pixels", s.mediaLimits.MaxBytes, s.mediaLimits.MaxPixels)}
+	case errors.Is(err, media.ErrUnsupportedType):
+		return problem.FieldError{Field: field, Code: "unsupported_type", Message: "must be a JPEG, PNG or GIF image"}
+	default:
+		return problem.FieldError{Field: field, Code: "invalid_image", Message: "is not a valid image"}
+	}
+}
+
+// attachImages adds the attachments storeImages made to chirpID, in order.
+func attachImages(ctx context.Context, q database.Querier, chirpID uuid.UUID, attachments []database.Attachment) error {
+	for i, a := range attachments {
+		err := q.AttachToChirp(ctx, database.AttachToChirpParams{
+			ID:       a.ID,
+			ChirpID:  uuid.NullUUID{UUID: chirpID, Valid: true},
+			Position: int32(i),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ChirpAttachments returns the attachments of each of chirps, in the order
+// they were posted, keyed by chirp ID.
+func (s *Service) ChirpAttachments(ctx context.Context, chirps ...database.Chirp) (map[uuid.UUID][]database.Attachment, error) {
+	return chirpAttachments(ctx, s.db, chirps...)
+}
+
+func chirpAttachments(ctx context.Context, q database.Querier, chirps ...database.Chirp) (map[uuid.UUID][]database.Attachment, error) {
+	byChirp := map[uuid.UUID][]database.Attachment{}
+	if len(chirps) == 0 {
+		return byChirp, nil
+	}
+	ids := make([]uuid.UUID, len(chirps))
+	for i, c := range chirps {
+		ids[i] = c.ID
+	}
+	attachments, err := q.ListAttachmentsByChirpIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range attachments {
+		byChirp[a.ChirpID.UUID] = append(byChirp[a.ChirpID.UUID], a)
+	}
+	return byChirp, nil
+}
+
+// GetAttachment returns an attachment and its image, or its thumbnail,
+// for the caller to close. viewerID is the signed-in user, or uuid.Nil;
+// an attachment is seen by whoever can see its chirp.
+func (s *Service) GetAttachment(ctx context.Context, viewerID, id uuid.UUID, thumbnail bool) (database.Attachment, io.ReadCloser, error) {
+	a, err := s.db.GetAttachment(ctx, id)
+	if err != nil {
+		return a, nil, notFound(err, "Attachment")
+	}
+	if !a.ChirpID.Valid {
+		return a, nil, problem.New(http.StatusNotFound, problem.CodeNotFound, "Attachment not found")
+	}
+	if _, err := visibleChirp(ctx, s.db, viewerID, a.ChirpID.UUID); err != nil {
+		return a, nil, notFound(err, "Attachment")
+	}
+	if s.media == nil {
+		return a, nil, problem.New(http.StatusServiceUnavailable, problem.CodeUnavailable, "Image uploads are not configured")
+	}
+	key := media.Key(a.ID)
+	if thumbnail {
+		key = media.ThumbnailKey(a.ID)
+	}
+	blob, err := s.media.Get(ctx, key)
+	if errors.Is(err, media.ErrNotFound) {
+		return a, nil, problem.New(http.StatusNotFound, problem.CodeNotFound, "Attachment not found").WithCause(err)
+	}
+	return a, blob, err
+}
@@ -0,0 +1,84 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+
+	"github.com/HemahWeb/chirpy/internal/auth"
+	"github.com/HemahWeb/chirpy/internal/database"
+	"github.com/HemahWeb/chirpy/internal/problem"
+)
+
+type Session struct {
+	User         database.User
+	AccessToken  string
+	RefreshToken string // empty if Suspended
+	// Suspended users only get an access token, which is refused
+	// everywhere but their sanctions, so they can still appeal
+	Suspended bool
+}
+
+// Login checks a user's password and issues an access and a refresh token.
+// A suspended user only gets the access token. An unknown email gets the
+// same error as a wrong password, so emails can't be probed.
+func (s *Service) Login(ctx context.Context, email, password string) (Session, error) {
+	user, err := s.db.GetUserByEmailForAuth(ctx, email)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Session{}, problem.New(http.StatusUnauthorized, problem.CodeInvalidCredentials, "Incorrect email or password").WithCause(err)
+	}
+	if err != nil {
+		return Session{}, err
+	}
+	if err := auth.CheckPasswordHash(password, user.HashedPassword); err != nil {
+		return Session{}, err
+	}
+
+	accessToken, err := auth.MakeJWT(user.ID, s.jwtSecret)
+	if err != nil {
+		return Session{}, err
+	}
+	if suspended(user.SuspendedAt, user.SuspendedUntil, s.now()) {
+		return Session{User: user, AccessToken: accessToken, Suspended: true}, nil
+	}
+	refreshToken, err := s.db.CreateRefreshToken(ctx, user.ID)
+	if err != nil {
+		return Session{}, err
+	}
+	return Session{User: user, AccessToken: accessToken, RefreshToken: refreshToken}, nil
+}
+
+// Refresh issues a new access token for a valid refresh token and returns
+// the user it belongs to, unless they're suspended.
+func (s *Service) Refresh(ctx context.Context, refreshToken string) (uuid.UUID, string, error) {
+	row, err := s.db.GetUserIDFromRefreshToken(ctx, refreshToken)
+	if errors.Is(err, sql.ErrNoRows) {
+		return uuid.Nil, "", problem.New(http.StatusUnauthorized, problem.CodeInvalidToken, "Refresh token is invalid").WithCause(err)
+	}
+	if err != nil {
+		return uuid.Nil, "", err
+	}
+	if row.RevokedAt.Valid {
+		return uuid.Nil, "", problem.New(http.StatusUnauthorized, problem.CodeTokenRevoked, "Refresh token has been revoked")
+	}
+	if row.ExpiresAt.Before(s.now()) {
+		return uuid.Nil, "", problem.New(http.StatusUnauthorized, problem.CodeTokenExpired, "Refresh token has expired")
+	}
+	if err := s.CheckActive(ctx, row.UserID); err != nil {
+		return uuid.Nil, "", err
+	}
+
+	accessToken, err := auth.MakeJWT(row.UserID, s.jwtSecret)
+	if err != nil {
+		return uuid.Nil, "", err
+	}
+	return row.UserID, accessToken, nil
+}
+
+// Revoke revokes a refresh token. Unknown tokens are ignored.
+func (s *Service) Revoke(ctx context.Context, refreshToken string) error {
+	return s.db.RevokeRefreshToken(ctx, refreshToken)
+}
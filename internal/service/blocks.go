@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"slices"
+
+	"github.com/google/uuid"
+
+	"github.com/HemahWeb/chirpy/internal/database"
+	"github.com/HemahWeb/chirpy/internal/problem"
+)
+
+// checkNotBlocked fails with a 403 saying detail if either user has blocked
+// the other.
+func checkNotBlocked(ctx context.Context, q database.Querier, userID, otherID uuid.UUID, detail string) error {
+	blocked, err := q.BlockExists(ctx, database.BlockExistsParams{UserID: userID, OtherID: otherID})
+	if err != nil {
+		return err
+	}
+	if blocked {
+		return problem.New(http.StatusForbidden, problem.CodeForbidden, detail)
+	}
+	return nil
+}
+
+// BlockUser blocks targetID for userID. Neither can then follow, reply to,
+// like or message the other, mentions between them notify no one, their
+// chirps are hidden from each other's listings, and any follows between
+// them are removed. Blocking someone
+// twice is not an error.
+func (s *Service) BlockUser(ctx context.Context, userID, targetID uuid.UUID) error {
+	if userID == targetID {
+		return problem.New(http.StatusBadRequest, problem.CodeValidationFailed, "You can't block yourself")
+	}
+	return s.tx.WithTx(ctx, func(q database.Querier) error {
+		if _, err := q.GetUserByID(ctx, targetID); err != nil {
+			return notFound(err, "User")
+		}
+		if _, err := q.BlockUser(ctx, database.BlockUserParams{BlockerID: userID, BlockedID: targetID}); err != nil {
+			return err
+		}
+		if err := q.UnfollowUser(ctx, database.UnfollowUserParams{FollowerID: userID, FolloweeID: targetID}); err != nil {
+			return err
+		}
+		return q.UnfollowUser(ctx, database.UnfollowUserParams{FollowerID: targetID, FolloweeID: userID})
+	})
+}
+
+// UnblockUser undoes BlockUser, if userID blocked targetID. Removed follows
+// aren't restored.
+func (s *Service) UnblockUser(ctx context.Context, userID, targetID uuid.UUID) error {
+	return s.db.UnblockUser(ctx, database.UnblockUserParams{BlockerID: userID, BlockedID: targetID})
+}
+
+// ListBlocks returns the users userID blocked, most recent first.
+func (s *Service) ListBlocks(ctx context.Context, userID uuid.UUID) ([]database.Block, error) {
+	return s.db.ListBlocks(ctx, userID)
+}
+
+// MuteUser hides targetID's chirps from userID's listings and stops
+// notifications from them. Unlike a block, targetID can still interact
+// with userID and can't tell they're muted. Muting someone twice is not an
+// error.
+func (s *Service) MuteUser(ctx context.Context, userID, targetID uuid.UUID) error {
+	if userID == targetID {
+		return problem.New(http.StatusBadRequest, problem.CodeValidationFailed, "You can't mute yourself")
+	}
+	return s.tx.WithTx(ctx, func(q database.Querier) error {
+		if _, err := q.GetUserByID(ctx, targetID); err != nil {
+			return notFound(err, "User")
+		}
+		_, err := q.MuteUser(ctx, database.MuteUserParams{MuterID: userID, MutedID: targetID})
+		return err
+	})
+}
+
+// UnmuteUser undoes MuteUser, if userID muted targetID.
+func (s *Service) UnmuteUser(ctx context.Context, userID, targetID uuid.UUID) error {
+	return s.db.UnmuteUser(ctx, database.UnmuteUserParams{MuterID: userID, MutedID: targetID})
+}
+
+// ListMutes returns the users userID muted, most recent first.
+func (s *Service) ListMutes(ctx context.Context, userID uuid.UUID) ([]database.Mute, error) {
+	return s.db.ListMutes(ctx, userID)
+}
+
+// hideAuthors removes from chirps those viewerID shouldn't see: by users
+// they blocked or muted, or who blocked them.
+func (s *Service) hideAuthors(ctx context.Context, viewerID uuid.UUID, chirps []database.Chirp) ([]database.Chirp, error) {
+	hidden, err := s.db.ListHiddenAuthorIDs(ctx, viewerID)
+	if err != nil || len(hidden) == 0 {
+		return chirps, err
+	}
+	return slices.DeleteFunc(chirps, func(c database.Chirp) bool { return slices.Contains(hidden, c.UserID) }), nil
+}
+
+// hideLimited removes from chirps those by limited users, except viewerID's
+// own.
+func (s *Service) hideLimited(ctx context.Context, viewerID uuid.UUID, chirps []database.Chirp) ([]database.Chirp, error) {
+	limited, err := s.db.ListLimitedUserIDs(ctx)
+	if err != nil || len(limited) == 0 {
+		return chirps, err
+	}
+	return slices.DeleteFunc(chirps, func(c database.Chirp) bool { return c.UserID != viewerID && slices.Contains(limited, c.UserID) }), nil
+}
@@ -0,0 +1,250 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/HemahWeb/chirpy/internal/database"
+	"github.com/HemahWeb/chirpy/internal/events"
+	"github.com/HemahWeb/chirpy/internal/problem"
+	"github.com/HemahWeb/chirpy/internal/utils"
+)
+
+// CreateChirp validates body, masks bad words and posts it as userID with
+// images attached, stripped of their metadata. Users it mentions are
+// notified.
+func (s *Service) CreateChirp(ctx context.Context, userID uuid.UUID, body string, images ...[]byte) (database.Chirp, error) {
+	cleaned, err := utils.ValidateChirp(body)
+	if err != nil {
+		return database.Chirp{}, err
+	}
+	attachments, err := s.storeImages(ctx, images)
+	if err != nil {
+		return database.Chirp{}, err
+	}
+	var chirp database.Chirp
+	var mentions []*database.Notification
+	err = s.tx.WithTx(ctx, func(q database.Querier) error {
+		var err error
+		chirp, err = q.CreateChirp(ctx, database.CreateChirpParams{
+			Body:   cleaned,
+			UserID: userID,
+		})
+		if err != nil {
+			return err
+		}
+		if err := attachImages(ctx, q, chirp.ID, attachments); err != nil {
+			return err
+		}
+		mentions, err = notifyMentions(ctx, q, chirp, uuid.Nil)
+		return err
+	})
+	if err != nil {
+		return database.Chirp{}, err
+	}
+	s.publishChirp(ctx, events.ChirpCreated, chirp)
+	for _, n := range mentions {
+		s.publishNotification(ctx, n)
+	}
+	return chirp, nil
+}
+
+// ReplyToChirp posts body as userID in answer to parentID, in the same
+// thread, and notifies the parent's author and the users it mentions, as
+// CreateChirp does. Users who blocked one another can't reply to each
+// other. images are attached as by CreateChirp.
+func (s *Service) ReplyToChirp(ctx context.Context, userID, parentID uuid.UUID, body string, images ...[]byte) (database.Chirp, error) {
+	cleaned, err := utils.ValidateChirp(body)
+	if err != nil {
+		return database.Chirp{}, err
+	}
+	attachments, err := s.storeImages(ctx, images)
+	if err != nil {
+		return database.Chirp{}, err
+	}
+	var chirp database.Chirp
+	var n *database.Notification
+	var mentions []*database.Notification
+	err = s.tx.WithTx(ctx, func(q database.Querier) error {
+		parent, err := visibleChirp(ctx, q, userID, parentID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return problem.Validation(problem.FieldError{Field: "reply_to_id", Code: "not_found", Message: "no such chirp"}).WithCause(err)
+		}
+		if err != nil {
+			return err
+		}
+		if err := checkNotBlocked(ctx, q, userID, parent.UserID, "You can't reply to a user who blocked you or whom you blocked"); err != nil {
+			return err
+		}
+		chirp, err = q.CreateChirp(ctx, database.CreateChirpParams{
+			Body:      cleaned,
+			UserID:    userID,
+			ReplyToID: uuid.NullUUID{UUID: parent.ID, Valid: true},
+			ThreadID:  uuid.NullUUID{UUID: events.ThreadOf(parent), Valid: true},
+		})
+		if err != nil {
+			return err
+		}
+		if err := attachImages(ctx, q, chirp.ID, attachments); err != nil {
+			return err
+		}
+		n, err = notify(ctx, q, parent.UserID, userID, events.NotifyReply, uuid.NullUUID{UUID: chirp.ID, Valid: true})
+		if err != nil {
+			return err
+		}
+		// the parent's author hears about the reply, not a mention too
+		mentions, err = notifyMentions(ctx, q, chirp, parent.UserID)
+		return err
+	})
+	if err != nil {
+		return database.Chirp{}, err
+	}
+	s.publishChirp(ctx, events.ChirpCreated, chirp)
+	s.publishNotification(ctx, n)
+	for _, n := range mentions {
+		s.publishNotification(ctx, n)
+	}
+	return chirp, nil
+}
+
+type ListChirpsParams struct {
+	AuthorID   uuid.UUID // uuid.Nil for every author
+	Descending bool      // newest first
+	// ViewerID is the signed-in user, whose blocks and mutes hide
+	// authors; uuid.Nil for an anonymous request.
+	ViewerID uuid.UUID
+}
+
+func (s *Service) ListChirps(ctx context.Context, arg ListChirpsParams) ([]database.Chirp, error) {
+	var chirps []database.Chirp
+	var err error
+	if arg.AuthorID != uuid.Nil {
+		chirps, err = s.db.GetChirpsByUserID(ctx, arg.AuthorID)
+	} else {
+		chirps, err = s.db.GetChirps(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+	chirps, err = s.hideLimited(ctx, arg.ViewerID, chirps)
+	if err != nil {
+		return nil, err
+	}
+	if arg.ViewerID != uuid.Nil {
+		chirps, err = s.hideAuthors(ctx, arg.ViewerID, chirps)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if arg.Descending {
+		// the queries return oldest first
+		slices.Reverse(chirps)
+	}
+	return chirps, nil
+}
+
+// GetChirp returns a chirp, unless a moderator hid it or limited its
+// author. viewerID is the signed-in user, or uuid.Nil; a limited author
+// still sees their own chirps.
+func (s *Service) GetChirp(ctx context.Context, viewerID, id uuid.UUID) (database.Chirp, error) {
+	chirp, err := visibleChirp(ctx, s.db, viewerID, id)
+	return chirp, notFound(err, "Chirp")
+}
+
+// visibleChirp is GetChirpByID, except that a deleted chirp, one a
+// moderator hid, or one by a limited user other than viewerID, is
+// sql.ErrNoRows too.
+func visibleChirp(ctx context.Context, q database.Querier, viewerID, id uuid.UUID) (database.Chirp, error) {
+	chirp, err := q.GetChirpByID(ctx, id)
+	if err == nil && chirp.DeletedAt.Valid {
+		err = sql.ErrNoRows
+	}
+	if err != nil {
+		return chirp, err
+	}
+	hidden, err := q.IsChirpHidden(ctx, id)
+	if err == nil && !hidden && chirp.UserID != viewerID {
+		hidden, err = q.IsUserLimited(ctx, chirp.UserID)
+	}
+	if err == nil && hidden {
+		err = sql.ErrNoRows
+	}
+	return chirp, err
+}
+
+// DeleteChirp moves a chirp to the trash if userID wrote it. The ownership
+// check and the delete share a transaction.
+func (s *Service) DeleteChirp(ctx context.Context, userID, chirpID uuid.UUID) error {
+	var chirp database.Chirp
+	err := s.tx.WithTx(ctx, func(q database.Querier) error {
+		var err error
+		chirp, err = q.GetChirpByID(ctx, chirpID)
+		if err == nil && chirp.DeletedAt.Valid {
+			err = sql.ErrNoRows
+		}
+		if err != nil {
+			return notFound(err, "Chirp")
+		}
+		if chirp.UserID != userID {
+			return problem.New(http.StatusForbidden, problem.CodeForbidden, "You are not allowed to delete this chirp")
+		}
+		return q.DeleteChirp(ctx, chirpID)
+	})
+	if err != nil {
+		return err
+	}
+	s.publishChirp(ctx, events.ChirpDeleted, chirp)
+	return nil
+}
+
+// ListTrash returns the chirps userID deleted that can still be restored,
+// most recently deleted first.
+func (s *Service) ListTrash(ctx context.Context, userID uuid.UUID) ([]database.Chirp, error) {
+	return s.db.ListTrashedChirps(ctx, database.ListTrashedChirpsParams{
+		UserID: userID,
+		Cutoff: s.now().Add(-s.trashRetention),
+	})
+}
+
+// RestorableUntil is when a chirp in the trash is purged and can no longer
+// be restored.
+func (s *Service) RestorableUntil(chirp database.Chirp) time.Time {
+	return chirp.DeletedAt.Time.Add(s.trashRetention)
+}
+
+// RestoreChirp takes a chirp userID deleted back out of the trash. Once
+// it's been there longer than the trash retention, or if a moderator hid
+// it, it's not found.
+func (s *Service) RestoreChirp(ctx context.Context, userID, chirpID uuid.UUID) (database.Chirp, error) {
+	var chirp database.Chirp
+	err := s.tx.WithTx(ctx, func(q database.Querier) error {
+		var err error
+		chirp, err = q.GetChirpByID(ctx, chirpID)
+		if err == nil && (chirp.UserID != userID || !chirp.DeletedAt.Valid || s.now().After(s.RestorableUntil(chirp))) {
+			err = sql.ErrNoRows
+		}
+		if err != nil {
+			return notFound(err, "Chirp")
+		}
+		hidden, err := q.IsChirpHidden(ctx, chirpID)
+		if err != nil {
+			return err
+		}
+		if hidden {
+			return notFound(sql.ErrNoRows, "Chirp")
+		}
+		chirp, err = q.RestoreChirp(ctx, chirpID)
+		return err
+	})
+	if err != nil {
+		return database.Chirp{}, err
+	}
+	s.publishChirp(ctx, events.ChirpCreated, chirp)
+	return chirp, nil
+}
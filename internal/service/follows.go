@@ -0,0 +1,51 @@
+package service
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+
+	"github.com/HemahWeb/chirpy/internal/database"
+	"github.com/HemahWeb/chirpy/internal/events"
+	"github.com/HemahWeb/chirpy/internal/problem"
+)
+
+// Follow makes followerID follow followeeID, who is notified. Following
+// someone twice is not an error, and doesn't notify them again. Users who
+// blocked one another can't follow each other.
+func (s *Service) Follow(ctx context.Context, followerID, followeeID uuid.UUID) error {
+	if followerID == followeeID {
+		return problem.New(http.StatusBadRequest, problem.CodeValidationFailed, "You can't follow yourself")
+	}
+	var n *database.Notification
+	err := s.tx.WithTx(ctx, func(q database.Querier) error {
+		if _, err := q.GetUserByID(ctx, followeeID); err != nil {
+			return notFound(err, "User")
+		}
+		if err := checkNotBlocked(ctx, q, followerID, followeeID, "You can't follow a user who blocked you or whom you blocked"); err != nil {
+			return err
+		}
+		followed, err := q.FollowUser(ctx, database.FollowUserParams{FollowerID: followerID, FolloweeID: followeeID})
+		if err != nil || followed == 0 {
+			return err
+		}
+		n, err = notify(ctx, q, followeeID, followerID, events.NotifyFollow, uuid.NullUUID{})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	s.publishNotification(ctx, n)
+	return nil
+}
+
+// Unfollow stops followerID following followeeID, if they did.
+func (s *Service) Unfollow(ctx context.Context, followerID, followeeID uuid.UUID) error {
+	return s.db.UnfollowUser(ctx, database.UnfollowUserParams{FollowerID: followerID, FolloweeID: followeeID})
+}
+
+// Followees returns the IDs of the users userID follows.
+func (s *Service) Followees(ctx context.Context, userID uuid.UUID) ([]uuid.UUID, error) {
+	return s.db.ListFolloweeIDs(ctx, userID)
+}
@@ -0,0 +1,42 @@
+package service
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"github.com/HemahWeb/chirpy/internal/database"
+	"github.com/HemahWeb/chirpy/internal/events"
+)
+
+// LikeChirp records that userID likes chirpID, and notifies its author.
+// Liking a chirp twice is not an error, and doesn't notify them again.
+// Users who blocked one another can't like each other's chirps.
+func (s *Service) LikeChirp(ctx context.Context, userID, chirpID uuid.UUID) error {
+	var n *database.Notification
+	err := s.tx.WithTx(ctx, func(q database.Querier) error {
+		chirp, err := visibleChirp(ctx, q, userID, chirpID)
+		if err != nil {
+			return notFound(err, "Chirp")
+		}
+		if err := checkNotBlocked(ctx, q, userID, chirp.UserID, "You can't like chirps of a user who blocked you or whom you blocked"); err != nil {
+			return err
+		}
+		liked, err := q.LikeChirp(ctx, database.LikeChirpParams{UserID: userID, ChirpID: chirpID})
+		if err != nil || liked == 0 {
+			return err
+		}
+		n, err = notify(ctx, q, chirp.UserID, userID, events.NotifyLike, uuid.NullUUID{UUID: chirpID, Valid: true})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	s.publishNotification(ctx, n)
+	return nil
+}
+
+// UnlikeChirp takes back userID's like of chirpID, if they liked it.
+func (s *Service) UnlikeChirp(ctx context.Context, userID, chirpID uuid.UUID) error {
+	return s.db.UnlikeChirp(ctx, database.UnlikeChirpParams{UserID: userID, ChirpID: chirpID})
+}
@@ -0,0 +1,318 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math"
+	"net/http"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+
+	"github.com/HemahWeb/chirpy/internal/database"
+	"github.com/HemahWeb/chirpy/internal/events"
+	"github.com/HemahWeb/chirpy/internal/problem"
+)
+
+const (
+	maxMessageLength       = 1000
+	maxConversationMembers = 50
+
+	cantMessage = "You can't message a user who blocked you or whom you blocked"
+)
+
+// Conversation is a conversation as one of its members sees it.
+type Conversation struct {
+	database.GetConversationForUserRow
+	Members []database.ConversationMember
+}
+
+// CreateConversation starts a conversation between userID and memberIDs.
+// There's only one conversation between any two users, so if memberIDs is
+// one other user they already talk to, that conversation is returned with
+// created false.
+func (s *Service) CreateConversation(ctx context.Context, userID uuid.UUID, memberIDs []uuid.UUID) (conv Conversation, created bool, err error) {
+	others := slices.DeleteFunc(slices.Clone(memberIDs), func(id uuid.UUID) bool { return id == userID })
+	slices.SortFunc(others, func(a, b uuid.UUID) int { return strings.Compare(a.String(), b.String()) })
+	others = slices.Compact(others)
+	if len(others) == 0 {
+		return Conversation{}, false, problem.Validation(problem.FieldError{Field: "member_ids", Code: "required", Message: "must name at least one other user"})
+	}
+	if len(others)+1 > maxConversationMembers {
+		return Conversation{}, false, problem.Validation(problem.FieldError{Field: "member_ids", Code: "out_of_range", Message: "a conversation can have at most 50 members"})
+	}
+
+	var directKey sql.NullString
+	if len(others) == 1 {
+		pair := []string{userID.String(), others[0].String()}
+		slices.Sort(pair)
+		directKey = sql.NullString{String: strings.Join(pair, ":"), Valid: true}
+	}
+
+	err = s.tx.WithTx(ctx, func(q database.Querier) error {
+		for _, id := range others {
+			if _, err := q.GetUserByID(ctx, id); errors.Is(err, sql.ErrNoRows) {
+				return problem.Validation(problem.FieldError{Field: "member_ids", Code: "not_found", Message: "no such user: " + id.String()}).WithCause(err)
+			} else if err != nil {
+				return err
+			}
+			if err := checkNotBlocked(ctx, q, userID, id, cantMessage); err != nil {
+				return err
+			}
+		}
+
+		c, err := q.CreateConversation(ctx, directKey)
+		if errors.Is(err, sql.ErrNoRows) && directKey.Valid {
+			c, err = q.GetConversationByDirectKey(ctx, directKey)
+		} else if err == nil {
+			created = true
+			for _, id := range append(others, userID) {
+				err := q.AddConversationMember(ctx, database.AddConversationMemberParams{ConversationID: c.ID, UserID: id})
+				if err != nil {
+					return err
+				}
+			}
+		}
+		if err != nil {
+			return err
+		}
+		conv, err = conversation(ctx, q, userID, c.ID)
+		return err
+	})
+	return conv, created, err
+}
+
+// conversation returns conversation id as userID sees it, or a 404 if
+// they're not in it.
+func conversation(ctx context.Context, q database.Querier, userID, id uuid.UUID) (Conversation, error) {
+	row, err := q.GetConversationForUser(ctx, database.GetConversationForUserParams{ID: id, UserID: userID})
+	if err != nil {
+		return Conversation{}, notFound(err, "Conversation")
+	}
+	members, err := q.ListConversationMembers(ctx, id)
+	if err != nil {
+		return Conversation{}, err
+	}
+	return Conversation{GetConversationForUserRow: row, Members: members}, nil
+}
+
+// GetConversation returns one of userID's conversations.
+func (s *Service) GetConversation(ctx context.Context, userID, id uuid.UUID) (Conversation, error) {
+	return conversation(ctx, s.db, userID, id)
+}
+
+// ListConversations returns userID's conversations, the most recently
+// active first.
+func (s *Service) ListConversations(ctx context.Context, userID uuid.UUID) ([]Conversation, error) {
+	rows, err := s.db.ListConversationsForUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	members, err := s.db.ListConversationMembersForUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	byConversation := map[uuid.UUID][]database.ConversationMember{}
+	for _, m := range members {
+		byConversation[m.ConversationID] = append(byConversation[m.ConversationID], m)
+	}
+	convs := make([]Conversation, 0, len(rows))
+	for _, row := range rows {
+		convs = append(convs, Conversation{
+			GetConversationForUserRow: database.GetConversationForUserRow(row),
+			Members:                   byConversation[row.ID],
+		})
+	}
+	return convs, nil
+}
+
+// SendMessage posts body to a conversation of userID's, unless they and
+// another member have blocked one another. The members who haven't muted
+// the conversation are sent it live.
+func (s *Service) SendMessage(ctx context.Context, userID, conversationID uuid.UUID, body string) (database.Message, error) {
+	if strings.TrimSpace(body) == "" {
+		return database.Message{}, problem.Validation(problem.FieldError{Field: "body", Code: "required", Message: "must not be empty"})
+	}
+	if utf8.RuneCountInString(body) > maxMessageLength {
+		return database.Message{}, problem.Validation(problem.FieldError{Field: "body", Code: "too_long", Message: "must be at most 1000 characters"})
+	}
+
+	var msg database.Message
+	var conv Conversation
+	err := s.tx.WithTx(ctx, func(q database.Querier) error {
+		var err error
+		conv, err = conversation(ctx, q, userID, conversationID)
+		if err != nil {
+			return err
+		}
+		for _, m := range conv.Members {
+			if m.UserID == userID {
+				continue
+			}
+			if err := checkNotBlocked(ctx, q, userID, m.UserID, cantMessage); err != nil {
+				return err
+			}
+		}
+		msg, err = q.CreateMessage(ctx, database.CreateMessageParams{
+			ConversationID: conversationID,
+			SenderID:       userID,
+			Body:           body,
+		})
+		if err != nil {
+			return err
+		}
+		err = q.SetConversationLastMessageAt(ctx, database.SetConversationLastMessageAtParams{
+			ID:            conversationID,
+			LastMessageAt: sql.NullTime{Time: msg.CreatedAt, Valid: true},
+		})
+		if err != nil {
+			return err
+		}
+		// you've read what you wrote
+		_, err = q.MarkConversationRead(ctx, database.MarkConversationReadParams{
+			ConversationID: conversationID,
+			UserID:         userID,
+			LastReadID:     msg.ID,
+		})
+		return err
+	})
+	if err != nil {
+		return database.Message{}, err
+	}
+	s.publishMessage(ctx, events.MessageCreated, msg, conv.Members)
+	return msg, nil
+}
+
+type ListMessagesParams struct {
+	Before int64 // a MessagePage.Next, or 0 for the newest
+	Limit  int32
+}
+
+// MessagePage is one page of a conversation's messages, newest first.
+type MessagePage struct {
+	Messages []database.Message
+	Next     int64 // Before for the next page, 0 on the last one
+}
+
+// ListMessages returns a page of the messages in one of userID's
+// conversations.
+func (s *Service) ListMessages(ctx context.Context, userID, conversationID uuid.UUID, arg ListMessagesParams) (MessagePage, error) {
+	_, err := s.db.GetConversationForUser(ctx, database.GetConversationForUserParams{ID: conversationID, UserID: userID})
+	if err != nil {
+		return MessagePage{}, notFound(err, "Conversation")
+	}
+	before := arg.Before
+	if before <= 0 {
+		before = math.MaxInt64
+	}
+	// one more than asked for says whether there's another page
+	messages, err := s.db.ListMessages(ctx, database.ListMessagesParams{
+		ConversationID: conversationID,
+		BeforeID:       before,
+		MaxRows:        arg.Limit + 1,
+	})
+	if err != nil {
+		return MessagePage{}, err
+	}
+	page := MessagePage{Messages: messages}
+	if len(messages) > int(arg.Limit) {
+		page.Messages = messages[:arg.Limit]
+		page.Next = page.Messages[arg.Limit-1].ID
+	}
+	return page, nil
+}
+
+// DeleteMessage deletes a message userID sent, for everyone in the
+// conversation.
+func (s *Service) DeleteMessage(ctx context.Context, userID, conversationID uuid.UUID, messageID int64) error {
+	var msg database.Message
+	var conv Conversation
+	err := s.tx.WithTx(ctx, func(q database.Querier) error {
+		var err error
+		conv, err = conversation(ctx, q, userID, conversationID)
+		if err != nil {
+			return err
+		}
+		msg, err = q.GetMessage(ctx, messageID)
+		if err == nil && msg.ConversationID != conversationID {
+			err = sql.ErrNoRows
+		}
+		if err != nil {
+			return notFound(err, "Message")
+		}
+		if msg.SenderID != userID {
+			return problem.New(http.StatusForbidden, problem.CodeForbidden, "You are not allowed to delete this message")
+		}
+		return q.DeleteMessage(ctx, messageID)
+	})
+	if err != nil {
+		return err
+	}
+	s.publishMessage(ctx, events.MessageDeleted, msg, conv.Members)
+	return nil
+}
+
+// MarkConversationRead records that userID has read a conversation up to
+// messageID, or all of it if messageID is 0. Marking an earlier message
+// read doesn't make later ones unread.
+func (s *Service) MarkConversationRead(ctx context.Context, userID, conversationID uuid.UUID, messageID int64) error {
+	return s.tx.WithTx(ctx, func(q database.Querier) error {
+		if _, err := q.GetConversationForUser(ctx, database.GetConversationForUserParams{ID: conversationID, UserID: userID}); err != nil {
+			return notFound(err, "Conversation")
+		}
+		if messageID == 0 {
+			latest, err := q.ListMessages(ctx, database.ListMessagesParams{
+				ConversationID: conversationID,
+				BeforeID:       math.MaxInt64,
+				MaxRows:        1,
+			})
+			if err != nil || len(latest) == 0 {
+				return err
+			}
+			messageID = latest[0].ID
+		} else {
+			msg, err := q.GetMessage(ctx, messageID)
+			if errors.Is(err, sql.ErrNoRows) || (err == nil && msg.ConversationID != conversationID) {
+				return problem.Validation(problem.FieldError{Field: "message_id", Code: "not_found", Message: "no such message in this conversation"})
+			}
+			if err != nil {
+				return err
+			}
+		}
+		_, err := q.MarkConversationRead(ctx, database.MarkConversationReadParams{
+			ConversationID: conversationID,
+			UserID:         userID,
+			LastReadID:     messageID,
+		})
+		return err
+	})
+}
+
+// MuteConversation stops userID being sent a conversation's new messages
+// live. They're still stored and counted as unread.
+func (s *Service) MuteConversation(ctx context.Context, userID, conversationID uuid.UUID) error {
+	return s.setConversationMuted(ctx, userID, conversationID, true)
+}
+
+// UnmuteConversation undoes MuteConversation.
+func (s *Service) UnmuteConversation(ctx context.Context, userID, conversationID uuid.UUID) error {
+	return s.setConversationMuted(ctx, userID, conversationID, false)
+}
+
+func (s *Service) setConversationMuted(ctx context.Context, userID, conversationID uuid.UUID, muted bool) error {
+	updated, err := s.db.SetConversationMuted(ctx, database.SetConversationMutedParams{
+		ConversationID: conversationID,
+		UserID:         userID,
+		Muted:          muted,
+	})
+	if err != nil {
+		return err
+	}
+	if updated == 0 {
+		return problem.New(http.StatusNotFound, problem.CodeNotFound, "Conversation not found")
+	}
+	return nil
+}
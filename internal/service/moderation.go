@@ -0,0 +1,644 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+
+	"github.com/HemahWeb/chirpy/internal/database"
+	"github.com/HemahWeb/chirpy/internal/events"
+	"github.com/HemahWeb/chirpy/internal/problem"
+)
+
+const (
+	maxReportDetailsLength  = 500
+	maxModerationNoteLength = 500
+	maxAppealLength         = 1000
+)
+
+// ReportReasons are the reasons a user can give for a report.
+var ReportReasons = []string{"spam", "harassment", "hate_speech", "violence", "nudity", "impersonation", "other"}
+
+// Moderation actions. A report is resolved with one of the first three;
+// a moderator can also suspend a user, or take one of the next three,
+// without a report. The last two record appeal decisions.
+const (
+	ActionHideChirp      = "hide_chirp"
+	ActionSuspendUser    = "suspend_user"
+	ActionDismiss        = "dismiss"
+	ActionUnsuspendUser  = "unsuspend_user"
+	ActionLimitUser      = "limit_user"
+	ActionUnlimitUser    = "unlimit_user"
+	ActionUpholdAppeal   = "uphold_appeal"
+	ActionOverturnAppeal = "overturn_appeal"
+)
+
+// Report statuses.
+const (
+	ReportOpen     = "open"
+	ReportClaimed  = "claimed"
+	ReportResolved = "resolved"
+)
+
+// Appeal statuses.
+const (
+	AppealOpen       = "open"
+	AppealUpheld     = "upheld"
+	AppealOverturned = "overturned"
+)
+
+// checkModerator fails with a 403 unless userID is a moderator.
+func checkModerator(ctx context.Context, q database.Querier, userID uuid.UUID) error {
+	ok, err := q.IsModerator(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return problem.New(http.StatusForbidden, problem.CodeForbidden, "Only moderators can do this")
+	}
+	return nil
+}
+
+// CheckActive fails with a 403 if userID is suspended. Limited users are
+// active; they aren't told they're limited.
+func (s *Service) CheckActive(ctx context.Context, userID uuid.UUID) error {
+	row, err := s.db.GetUserSuspension(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		// a token can outlive its user; whatever it's used for fails later
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return checkSuspension(row.SuspendedAt, row.SuspendedUntil, s.now())
+}
+
+// checkSuspension fails with a 403 if a user suspended at suspendedAt is
+// still suspended at now. A suspension ends at suspendedUntil, or when a
+// moderator lifts it if that's null.
+func checkSuspension(suspendedAt, suspendedUntil sql.NullTime, now time.Time) error {
+	if !suspended(suspendedAt, suspendedUntil, now) {
+		return nil
+	}
+	if suspendedUntil.Valid {
+		return problem.New(http.StatusForbidden, problem.CodeAccountSuspended, "Your account is suspended until "+suspendedUntil.Time.UTC().Format(time.RFC3339))
+	}
+	return problem.New(http.StatusForbidden, problem.CodeAccountSuspended, "Your account is suspended")
+}
+
+func suspended(suspendedAt, suspendedUntil sql.NullTime, now time.Time) bool {
+	return suspendedAt.Valid && (!suspendedUntil.Valid || suspendedUntil.Time.After(now))
+}
+
+// suspend suspends userID for actionID until until, or until a moderator
+// lifts it if that's zero, and revokes their refresh tokens: they sign in
+// again once it's over.
+func suspend(ctx context.Context, q database.Querier, userID uuid.UUID, actionID int64, until time.Time) error {
+	err := q.SuspendUser(ctx, database.SuspendUserParams{
+		ID:                 userID,
+		SuspendedUntil:     sql.NullTime{Time: until, Valid: !until.IsZero()},
+		SuspensionActionID: sql.NullInt64{Int64: actionID, Valid: true},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = q.RevokeUserRefreshTokens(ctx, userID)
+	return err
+}
+
+type ReportParams struct {
+	// Exactly one of ChirpID and UserID is set; the other is uuid.Nil.
+	ChirpID uuid.UUID
+	UserID  uuid.UUID
+	Reason  string // one of ReportReasons
+	Details string // optional
+}
+
+// Report asks the moderators to look at a chirp or a user. A report about
+// a chirp is also about its author. reporterID can't report themselves,
+// or the same thing again while their last report about it is unresolved.
+func (s *Service) Report(ctx context.Context, reporterID uuid.UUID, arg ReportParams) (database.Report, error) {
+	var fields []problem.FieldError
+	switch {
+	case arg.ChirpID == uuid.Nil && arg.UserID == uuid.Nil:
+		fields = append(fields, problem.FieldError{Field: "chirp_id", Code: "required", Message: "must name a chirp or a user"})
+	case arg.ChirpID != uuid.Nil && arg.UserID != uuid.Nil:
+		fields = append(fields, problem.FieldError{Field: "user_id", Code: "invalid_value", Message: "must not be set with chirp_id"})
+	}
+	if !slices.Contains(ReportReasons, arg.Reason) {
+		fields = append(fields, problem.FieldError{Field: "reason", Code: "invalid_value", Message: "must be one of " + strings.Join(ReportReasons, ", ")})
+	}
+	if utf8.RuneCountInString(arg.Details) > maxReportDetailsLength {
+		fields = append(fields, problem.FieldError{Field: "details", Code: "too_long", Message: "must be at most 500 characters"})
+	}
+	if len(fields) > 0 {
+		return database.Report{}, problem.Validation(fields...)
+	}
+
+	var report database.Report
+	err := s.tx.WithTx(ctx, func(q database.Querier) error {
+		params := database.CreateReportParams{
+			ReporterID: reporterID,
+			UserID:     arg.UserID,
+			Reason:     arg.Reason,
+			Details:    arg.Details,
+		}
+		if arg.ChirpID != uuid.Nil {
+			chirp, err := visibleChirp(ctx, q, reporterID, arg.ChirpID)
+			if errors.Is(err, sql.ErrNoRows) {
+				return problem.Validation(problem.FieldError{Field: "chirp_id", Code: "not_found", Message: "no such chirp"}).WithCause(err)
+			}
+			if err != nil {
+				return err
+			}
+			params.UserID = chirp.UserID
+			params.ChirpID = uuid.NullUUID{UUID: chirp.ID, Valid: true}
+		} else if _, err := q.GetUserByID(ctx, arg.UserID); errors.Is(err, sql.ErrNoRows) {
+			return problem.Validation(problem.FieldError{Field: "user_id", Code: "not_found", Message: "no such user"}).WithCause(err)
+		} else if err != nil {
+			return err
+		}
+		if params.UserID == reporterID {
+			return problem.New(http.StatusBadRequest, problem.CodeValidationFailed, "You can't report yourself")
+		}
+
+		open, err := q.OpenReportExists(ctx, database.OpenReportExistsParams{
+			ReporterID: reporterID,
+			UserID:     params.UserID,
+			ChirpID:    params.ChirpID,
+		})
+		if err != nil {
+			return err
+		}
+		if open {
+			return problem.New(http.StatusConflict, problem.CodeConflict, "You already reported this and it hasn't been resolved yet")
+		}
+		report, err = q.CreateReport(ctx, params)
+		return err
+	})
+	return report, err
+}
+
+type ListReportsParams struct {
+	Status string // ReportOpen, ReportClaimed or ReportResolved
+	After  int64  // a ReportPage.Next, or 0 for the oldest
+	Limit  int32
+}
+
+// ReportPage is one page of the moderation queue, oldest first.
+type ReportPage struct {
+	Reports []database.Report
+	Next    int64 // After for the next page, 0 on the last one
+}
+
+// ListReports returns a page of the reports with a status, for a
+// moderator.
+func (s *Service) ListReports(ctx context.Context, moderatorID uuid.UUID, arg ListReportsParams) (ReportPage, error) {
+	if err := checkModerator(ctx, s.db, moderatorID); err != nil {
+		return ReportPage{}, err
+	}
+	// one more than asked for says whether there's another page
+	reports, err := s.db.ListReports(ctx, database.ListReportsParams{
+		Status:  arg.Status,
+		AfterID: arg.After,
+		MaxRows: arg.Limit + 1,
+	})
+	if err != nil {
+		return ReportPage{}, err
+	}
+	page := ReportPage{Reports: reports}
+	if len(reports) > int(arg.Limit) {
+		page.Reports = reports[:arg.Limit]
+		page.Next = page.Reports[arg.Limit-1].ID
+	}
+	return page, nil
+}
+
+// ClaimReport assigns an open report to moderatorID, so no other moderator
+// works on it. Claiming a report you already hold is not an error.
+func (s *Service) ClaimReport(ctx context.Context, moderatorID uuid.UUID, reportID int64) (database.Report, error) {
+	var report database.Report
+	err := s.tx.WithTx(ctx, func(q database.Querier) error {
+		if err := checkModerator(ctx, q, moderatorID); err != nil {
+			return err
+		}
+		var err error
+		report, err = q.GetReport(ctx, reportID)
+		if err != nil {
+			return notFound(err, "Report")
+		}
+		claimedBy := uuid.NullUUID{UUID: moderatorID, Valid: true}
+		claimed, err := q.ClaimReport(ctx, database.ClaimReportParams{ID: reportID, ClaimedBy: claimedBy})
+		if err != nil {
+			return err
+		}
+		if claimed == 0 {
+			if report.Status == ReportClaimed && report.ClaimedBy == claimedBy {
+				return nil
+			}
+			return problem.New(http.StatusConflict, problem.CodeConflict, "This report is already claimed or resolved")
+		}
+		report, err = q.GetReport(ctx, reportID)
+		return err
+	})
+	return report, err
+}
+
+type ResolveReportParams struct {
+	Action string // ActionHideChirp, ActionSuspendUser or ActionDismiss
+	// Until ends an ActionSuspendUser suspension; zero suspends the user
+	// until a moderator lifts it.
+	Until time.Time
+	Note  string // optional, for the action log
+}
+
+// ResolveReport closes a report moderatorID claimed by taking an action
+// against what it's about, or dismissing it. The action is logged either
+// way.
+func (s *Service) ResolveReport(ctx context.Context, moderatorID uuid.UUID, reportID int64, arg ResolveReportParams) (database.Report, error) {
+	var fields []problem.FieldError
+	if !slices.Contains([]string{ActionHideChirp, ActionSuspendUser, ActionDismiss}, arg.Action) {
+		fields = append(fields, problem.FieldError{Field: "action", Code: "invalid_value", Message: "must be hide_chirp, suspend_user or dismiss"})
+	}
+	if !arg.Until.IsZero() && arg.Action != ActionSuspendUser {
+		fields = append(fields, problem.FieldError{Field: "until", Code: "invalid_value", Message: "must only be set with suspend_user"})
+	} else if !arg.Until.IsZero() && !arg.Until.After(s.now()) {
+		fields = append(fields, problem.FieldError{Field: "until", Code: "invalid_value", Message: "must be in the future"})
+	}
+	if utf8.RuneCountInString(arg.Note) > maxModerationNoteLength {
+		fields = append(fields, problem.FieldError{Field: "note", Code: "too_long", Message: "must be at most 500 characters"})
+	}
+	if len(fields) > 0 {
+		return database.Report{}, problem.Validation(fields...)
+	}
+
+	var report database.Report
+	var hidden *database.Chirp
+	err := s.tx.WithTx(ctx, func(q database.Querier) error {
+		if err := checkModerator(ctx, q, moderatorID); err != nil {
+			return err
+		}
+		var err error
+		report, err = q.GetReport(ctx, reportID)
+		if err != nil {
+			return notFound(err, "Report")
+		}
+		claimedBy := uuid.NullUUID{UUID: moderatorID, Valid: true}
+		if report.Status != ReportClaimed || report.ClaimedBy != claimedBy {
+			return problem.New(http.StatusConflict, problem.CodeConflict, "Claim the report before resolving it")
+		}
+		if arg.Action == ActionHideChirp && !report.ChirpID.Valid {
+			return problem.Validation(problem.FieldError{Field: "action", Code: "invalid_value", Message: "a report about a user has no chirp to hide"})
+		}
+
+		action, err := q.CreateModerationAction(ctx, database.CreateModerationActionParams{
+			ModeratorID: moderatorID,
+			Action:      arg.Action,
+			ReportID:    sql.NullInt64{Int64: report.ID, Valid: true},
+			UserID:      report.UserID,
+			ChirpID:     report.ChirpID,
+			Note:        arg.Note,
+		})
+		if err != nil {
+			return err
+		}
+		switch arg.Action {
+		case ActionHideChirp:
+			chirp, err := q.GetChirpByID(ctx, report.ChirpID.UUID)
+			if err != nil {
+				return err
+			}
+			hidden = &chirp
+			err = q.HideChirp(ctx, database.HideChirpParams{ChirpID: chirp.ID, ActionID: action.ID})
+			if err != nil {
+				return err
+			}
+		case ActionSuspendUser:
+			if err := suspend(ctx, q, report.UserID, action.ID, arg.Until); err != nil {
+				return err
+			}
+		}
+
+		resolved, err := q.ResolveReport(ctx, database.ResolveReportParams{
+			ID:         reportID,
+			ClaimedBy:  claimedBy,
+			Resolution: sql.NullString{String: arg.Action, Valid: true},
+		})
+		if err != nil {
+			return err
+		}
+		if resolved == 0 {
+			return problem.New(http.StatusConflict, problem.CodeConflict, "Claim the report before resolving it")
+		}
+		report, err = q.GetReport(ctx, reportID)
+		return err
+	})
+	if err != nil {
+		return database.Report{}, err
+	}
+	if hidden != nil {
+		// to live subscribers a hidden chirp is gone
+		s.publishChirp(ctx, events.ChirpDeleted, *hidden)
+	}
+	if arg.Action == ActionSuspendUser {
+		s.publishSuspension(ctx, report.UserID)
+	}
+	return report, nil
+}
+
+type SuspendUserParams struct {
+	// Until ends the suspension; zero suspends the user until a moderator
+	// lifts it.
+	Until time.Time
+	Note  string // optional, for the action log
+}
+
+// SuspendUser suspends userID without a report: they can't sign in, and
+// the tokens they hold stop working. Suspending a suspended user replaces
+// when their suspension ends. They can appeal it like any other sanction.
+func (s *Service) SuspendUser(ctx context.Context, moderatorID, userID uuid.UUID, arg SuspendUserParams) error {
+	if !arg.Until.IsZero() && !arg.Until.After(s.now()) {
+		return problem.Validation(problem.FieldError{Field: "until", Code: "invalid_value", Message: "must be in the future"})
+	}
+	err := s.actOnUser(ctx, moderatorID, userID, ActionSuspendUser, arg.Note, nil, func(q database.Querier, actionID int64) error {
+		return suspend(ctx, q, userID, actionID, arg.Until)
+	})
+	if err != nil {
+		return err
+	}
+	s.publishSuspension(ctx, userID)
+	return nil
+}
+
+// UnsuspendUser lifts userID's suspension before it ends.
+func (s *Service) UnsuspendUser(ctx context.Context, moderatorID, userID uuid.UUID, note string) error {
+	return s.actOnUser(ctx, moderatorID, userID, ActionUnsuspendUser, note, func(q database.Querier) error {
+		row, err := q.GetUserSuspension(ctx, userID)
+		if err != nil {
+			return err
+		}
+		if !suspended(row.SuspendedAt, row.SuspendedUntil, s.now()) {
+			return problem.New(http.StatusConflict, problem.CodeConflict, "This user isn't suspended")
+		}
+		return nil
+	}, func(q database.Querier, _ int64) error {
+		return q.UnsuspendUser(ctx, userID)
+	})
+}
+
+// LimitUser shadow-bans userID: their chirps are hidden from everyone but
+// them, and they notify no one. They aren't told, so it isn't one of their
+// sanctions.
+func (s *Service) LimitUser(ctx context.Context, moderatorID, userID uuid.UUID, note string) error {
+	return s.actOnUser(ctx, moderatorID, userID, ActionLimitUser, note, func(q database.Querier) error {
+		limited, err := q.IsUserLimited(ctx, userID)
+		if err != nil {
+			return err
+		}
+		if limited {
+			return problem.New(http.StatusConflict, problem.CodeConflict, "This user is already limited")
+		}
+		return nil
+	}, func(q database.Querier, _ int64) error {
+		return q.LimitUser(ctx, userID)
+	})
+}
+
+// UnlimitUser makes a limited user's chirps visible again.
+func (s *Service) UnlimitUser(ctx context.Context, moderatorID, userID uuid.UUID, note string) error {
+	return s.actOnUser(ctx, moderatorID, userID, ActionUnlimitUser, note, func(q database.Querier) error {
+		limited, err := q.IsUserLimited(ctx, userID)
+		if err != nil {
+			return err
+		}
+		if !limited {
+			return problem.New(http.StatusConflict, problem.CodeConflict, "This user isn't limited")
+		}
+		return nil
+	}, func(q database.Querier, _ int64) error {
+		return q.UnlimitUser(ctx, userID)
+	})
+}
+
+// actOnUser runs check, if any, against userID for moderatorID, logs
+// action, and runs apply with the logged action's id, in one transaction.
+// Moderators can't act on themselves.
+func (s *Service) actOnUser(ctx context.Context, moderatorID, userID uuid.UUID, action, note string, check func(q database.Querier) error, apply func(q database.Querier, actionID int64) error) error {
+	if utf8.RuneCountInString(note) > maxModerationNoteLength {
+		return problem.Validation(problem.FieldError{Field: "note", Code: "too_long", Message: "must be at most 500 characters"})
+	}
+	return s.tx.WithTx(ctx, func(q database.Querier) error {
+		if err := checkModerator(ctx, q, moderatorID); err != nil {
+			return err
+		}
+		if userID == moderatorID {
+			return problem.New(http.StatusBadRequest, problem.CodeValidationFailed, "You can't moderate yourself")
+		}
+		if _, err := q.GetUserByID(ctx, userID); err != nil {
+			return notFound(err, "User")
+		}
+		if check != nil {
+			if err := check(q); err != nil {
+				return err
+			}
+		}
+		logged, err := q.CreateModerationAction(ctx, database.CreateModerationActionParams{
+			ModeratorID: moderatorID,
+			Action:      action,
+			UserID:      userID,
+			Note:        note,
+		})
+		if err != nil {
+			return err
+		}
+		return apply(q, logged.ID)
+	})
+}
+
+type ListModerationActionsParams struct {
+	Before int64 // a ModerationActionPage.Next, or 0 for the newest
+	Limit  int32
+}
+
+// ModerationActionPage is one page of the moderation log, newest first.
+type ModerationActionPage struct {
+	Actions []database.ModerationAction
+	Next    int64 // Before for the next page, 0 on the last one
+}
+
+// ListModerationActions returns a page of the moderation log, for a
+// moderator.
+func (s *Service) ListModerationActions(ctx context.Context, moderatorID uuid.UUID, arg ListModerationActionsParams) (ModerationActionPage, error) {
+	if err := checkModerator(ctx, s.db, moderatorID); err != nil {
+		return ModerationActionPage{}, err
+	}
+	before := arg.Before
+	if before <= 0 {
+		before = math.MaxInt64
+	}
+	actions, err := s.db.ListModerationActions(ctx, database.ListModerationActionsParams{
+		BeforeID: before,
+		MaxRows:  arg.Limit + 1,
+	})
+	if err != nil {
+		return ModerationActionPage{}, err
+	}
+	page := ModerationActionPage{Actions: actions}
+	if len(actions) > int(arg.Limit) {
+		page.Actions = actions[:arg.Limit]
+		page.Next = page.Actions[arg.Limit-1].ID
+	}
+	return page, nil
+}
+
+// ListSanctions returns the actions moderators took against userID,
+// newest first, for them to appeal.
+func (s *Service) ListSanctions(ctx context.Context, userID uuid.UUID) ([]database.ListSanctionsRow, error) {
+	return s.db.ListSanctions(ctx, userID)
+}
+
+// Appeal asks a moderator other than the one who took it to review a
+// sanction against userID. Each sanction can be appealed once.
+func (s *Service) Appeal(ctx context.Context, userID uuid.UUID, actionID int64, body string) (database.Appeal, error) {
+	if strings.TrimSpace(body) == "" {
+		return database.Appeal{}, problem.Validation(problem.FieldError{Field: "body", Code: "required", Message: "must not be empty"})
+	}
+	if utf8.RuneCountInString(body) > maxAppealLength {
+		return database.Appeal{}, problem.Validation(problem.FieldError{Field: "body", Code: "too_long", Message: "must be at most 1000 characters"})
+	}
+
+	var appeal database.Appeal
+	err := s.tx.WithTx(ctx, func(q database.Querier) error {
+		action, err := q.GetModerationAction(ctx, actionID)
+		if err == nil && (action.UserID != userID || !sanction(action.Action)) {
+			err = sql.ErrNoRows
+		}
+		if err != nil {
+			return notFound(err, "Sanction")
+		}
+		appeal, err = q.CreateAppeal(ctx, database.CreateAppealParams{ActionID: actionID, UserID: userID, Body: body})
+		if _, ok := database.UniqueViolation(err); ok {
+			return problem.New(http.StatusConflict, problem.CodeConflict, "You already appealed this sanction").WithCause(err)
+		}
+		return err
+	})
+	return appeal, err
+}
+
+// sanction reports whether a moderation action is one a user can appeal.
+func sanction(action string) bool {
+	return action == ActionHideChirp || action == ActionSuspendUser
+}
+
+type ListAppealsParams struct {
+	Status string // AppealOpen, AppealUpheld or AppealOverturned
+	After  int64  // an AppealPage.Next, or 0 for the oldest
+	Limit  int32
+}
+
+// AppealPage is one page of appeals, oldest first.
+type AppealPage struct {
+	Appeals []database.Appeal
+	Next    int64 // After for the next page, 0 on the last one
+}
+
+// ListAppeals returns a page of the appeals with a status, for a
+// moderator.
+func (s *Service) ListAppeals(ctx context.Context, moderatorID uuid.UUID, arg ListAppealsParams) (AppealPage, error) {
+	if err := checkModerator(ctx, s.db, moderatorID); err != nil {
+		return AppealPage{}, err
+	}
+	appeals, err := s.db.ListAppeals(ctx, database.ListAppealsParams{
+		Status:  arg.Status,
+		AfterID: arg.After,
+		MaxRows: arg.Limit + 1,
+	})
+	if err != nil {
+		return AppealPage{}, err
+	}
+	page := AppealPage{Appeals: appeals}
+	if len(appeals) > int(arg.Limit) {
+		page.Appeals = appeals[:arg.Limit]
+		page.Next = page.Appeals[arg.Limit-1].ID
+	}
+	return page, nil
+}
+
+// DecideAppeal upholds or, if overturn, reverses the sanction an open
+// appeal is against. The moderator who imposed it can't decide the appeal.
+// The decision is logged.
+func (s *Service) DecideAppeal(ctx context.Context, moderatorID uuid.UUID, appealID int64, overturn bool, note string) (database.Appeal, error) {
+	if utf8.RuneCountInString(note) > maxModerationNoteLength {
+		return database.Appeal{}, problem.Validation(problem.FieldError{Field: "note", Code: "too_long", Message: "must be at most 500 characters"})
+	}
+	status, logged := AppealUpheld, ActionUpholdAppeal
+	if overturn {
+		status, logged = AppealOverturned, ActionOverturnAppeal
+	}
+
+	var appeal database.Appeal
+	err := s.tx.WithTx(ctx, func(q database.Querier) error {
+		if err := checkModerator(ctx, q, moderatorID); err != nil {
+			return err
+		}
+		var err error
+		appeal, err = q.GetAppeal(ctx, appealID)
+		if err != nil {
+			return notFound(err, "Appeal")
+		}
+		action, err := q.GetModerationAction(ctx, appeal.ActionID)
+		if err != nil {
+			return err
+		}
+		if action.ModeratorID == moderatorID {
+			return problem.New(http.StatusForbidden, problem.CodeForbidden, "Another moderator must decide an appeal against your own action")
+		}
+		decided, err := q.DecideAppeal(ctx, database.DecideAppealParams{
+			ID:        appealID,
+			Status:    status,
+			DecidedBy: uuid.NullUUID{UUID: moderatorID, Valid: true},
+		})
+		if err != nil {
+			return err
+		}
+		if decided == 0 {
+			return problem.New(http.StatusConflict, problem.CodeConflict, "This appeal has already been decided")
+		}
+
+		_, err = q.CreateModerationAction(ctx, database.CreateModerationActionParams{
+			ModeratorID: moderatorID,
+			Action:      logged,
+			AppealID:    sql.NullInt64{Int64: appealID, Valid: true},
+			UserID:      action.UserID,
+			ChirpID:     action.ChirpID,
+			Note:        note,
+		})
+		if err != nil {
+			return err
+		}
+		switch {
+		case overturn && action.Action == ActionHideChirp:
+			err = q.UnhideChirp(ctx, action.ChirpID.UUID)
+		case overturn && action.Action == ActionSuspendUser:
+			// only if it's still the suspension in force; a newer one stands
+			err = q.OverturnSuspension(ctx, database.OverturnSuspensionParams{
+				ID:                 action.UserID,
+				SuspensionActionID: sql.NullInt64{Int64: action.ID, Valid: true},
+			})
+		}
+		if err != nil {
+			return err
+		}
+		appeal, err = q.GetAppeal(ctx, appealID)
+		return err
+	})
+	return appeal, err
+}
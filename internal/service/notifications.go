@@ -0,0 +1,206 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/HemahWeb/chirpy/internal/database"
+	"github.com/HemahWeb/chirpy/internal/events"
+	"github.com/HemahWeb/chirpy/internal/problem"
+	"github.com/HemahWeb/chirpy/internal/utils"
+)
+
+// notify records, on q, that actorID did typ to recipient, about chirpID if
+// it's set, so the notification commits with what caused it. Nothing is
+// recorded for something users did to themselves, a type the recipient
+// turned off, an actor they muted or a limited one, and nil is returned.
+func notify(ctx context.Context, q database.Querier, recipient, actorID uuid.UUID, typ string, chirpID uuid.NullUUID) (*database.Notification, error) {
+	if recipient == actorID {
+		return nil, nil
+	}
+	prefs, err := q.ListNotificationPreferences(ctx, recipient)
+	if err != nil {
+		return nil, err
+	}
+	if slices.ContainsFunc(prefs, func(p database.NotificationPreference) bool { return p.Type == typ && !p.Enabled }) {
+		return nil, nil
+	}
+	muted, err := q.MuteExists(ctx, database.MuteExistsParams{MuterID: recipient, MutedID: actorID})
+	if err != nil || muted {
+		return nil, err
+	}
+	limited, err := q.IsUserLimited(ctx, actorID)
+	if err != nil || limited {
+		return nil, err
+	}
+	n, err := q.CreateNotification(ctx, database.CreateNotificationParams{
+		UserID:  recipient,
+		Type:    typ,
+		ActorID: actorID,
+		ChirpID: chirpID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &n, nil
+}
+
+// notifyMentions records, on q, a mention notification for each user chirp
+// mentions other than except, who is told about the chirp some other way.
+// Addresses that aren't a user's are ignored, and so are users who blocked
+// the author or whom they blocked: the chirp is posted either way, so the
+// author can't tell who's registered or who blocked them.
+func notifyMentions(ctx context.Context, q database.Querier, chirp database.Chirp, except uuid.UUID) ([]*database.Notification, error) {
+	var notifications []*database.Notification
+	for _, email := range utils.Mentions(chirp.Body) {
+		user, err := q.GetUserByEmail(ctx, email)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if user.ID == except {
+			continue
+		}
+		blocked, err := q.BlockExists(ctx, database.BlockExistsParams{UserID: chirp.UserID, OtherID: user.ID})
+		if err != nil {
+			return nil, err
+		}
+		if blocked {
+			continue
+		}
+		n, err := notify(ctx, q, user.ID, chirp.UserID, events.NotifyMention, uuid.NullUUID{UUID: chirp.ID, Valid: true})
+		if err != nil {
+			return nil, err
+		}
+		if n != nil {
+			notifications = append(notifications, n)
+		}
+	}
+	return notifications, nil
+}
+
+type ListNotificationsParams struct {
+	Before     int64 // a NotificationPage.Next, or 0 for the newest
+	Limit      int32
+	UnreadOnly bool
+}
+
+// NotificationPage is one page of a user's notifications, newest first.
+type NotificationPage struct {
+	Notifications []database.Notification
+	UnreadCount   int64
+	Next          int64 // Before for the next page, 0 on the last one
+}
+
+// ListNotifications returns a page of userID's notifications and how many
+// of all of them are unread.
+func (s *Service) ListNotifications(ctx context.Context, userID uuid.UUID, arg ListNotificationsParams) (NotificationPage, error) {
+	before := arg.Before
+	if before <= 0 {
+		before = math.MaxInt64
+	}
+	// one more than asked for says whether there's another page
+	notifications, err := s.db.ListNotifications(ctx, database.ListNotificationsParams{
+		UserID:     userID,
+		BeforeID:   before,
+		UnreadOnly: arg.UnreadOnly,
+		MaxRows:    arg.Limit + 1,
+	})
+	if err != nil {
+		return NotificationPage{}, err
+	}
+	unread, err := s.db.CountUnreadNotifications(ctx, userID)
+	if err != nil {
+		return NotificationPage{}, err
+	}
+	page := NotificationPage{Notifications: notifications, UnreadCount: unread}
+	if len(notifications) > int(arg.Limit) {
+		page.Notifications = notifications[:arg.Limit]
+		page.Next = page.Notifications[arg.Limit-1].ID
+	}
+	return page, nil
+}
+
+// MarkNotificationRead marks one of userID's notifications read. Marking it
+// again is not an error.
+func (s *Service) MarkNotificationRead(ctx context.Context, userID uuid.UUID, id int64) error {
+	marked, err := s.db.MarkNotificationRead(ctx, database.MarkNotificationReadParams{ID: id, UserID: userID})
+	if err != nil {
+		return err
+	}
+	if marked == 0 {
+		return problem.New(http.StatusNotFound, problem.CodeNotFound, "Notification not found")
+	}
+	return nil
+}
+
+// MarkAllNotificationsRead marks all of userID's notifications read and
+// returns how many weren't already.
+func (s *Service) MarkAllNotificationsRead(ctx context.Context, userID uuid.UUID) (int64, error) {
+	return s.db.MarkAllNotificationsRead(ctx, userID)
+}
+
+// NotificationPreferences returns whether userID gets each type of
+// notification; every type is on until they turn it off.
+func (s *Service) NotificationPreferences(ctx context.Context, userID uuid.UUID) (map[string]bool, error) {
+	return notificationPreferences(ctx, s.db, userID)
+}
+
+// SetNotificationPreferences turns the types in prefs on or off for userID,
+// leaving the others as they were, and returns the result.
+func (s *Service) SetNotificationPreferences(ctx context.Context, userID uuid.UUID, prefs map[string]bool) (map[string]bool, error) {
+	var fields []problem.FieldError
+	for typ := range prefs {
+		if !slices.Contains(events.NotificationTypes, typ) {
+			fields = append(fields, problem.FieldError{Field: typ, Code: "invalid_value", Message: "not a notification type"})
+		}
+	}
+	if len(fields) > 0 {
+		slices.SortFunc(fields, func(a, b problem.FieldError) int { return strings.Compare(a.Field, b.Field) })
+		return nil, problem.Validation(fields...)
+	}
+
+	var updated map[string]bool
+	err := s.tx.WithTx(ctx, func(q database.Querier) error {
+		for typ, enabled := range prefs {
+			err := q.SetNotificationPreference(ctx, database.SetNotificationPreferenceParams{
+				UserID:  userID,
+				Type:    typ,
+				Enabled: enabled,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		var err error
+		updated, err = notificationPreferences(ctx, q, userID)
+		return err
+	})
+	return updated, err
+}
+
+func notificationPreferences(ctx context.Context, q database.Querier, userID uuid.UUID) (map[string]bool, error) {
+	stored, err := q.ListNotificationPreferences(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	prefs := map[string]bool{}
+	for _, typ := range events.NotificationTypes {
+		prefs[typ] = true
+	}
+	for _, p := range stored {
+		if _, ok := prefs[p.Type]; ok {
+			prefs[p.Type] = p.Enabled
+		}
+	}
+	return prefs, nil
+}
@@ -0,0 +1,142 @@
+// Package service holds Chirpy's business rules. Handlers decode requests,
+// call a service method and encode the result; services validate input,
+// enforce ownership and run multi-step operations in one transaction.
+//
+// Errors a client can act on are returned as *problem.Problem; anything
+// else is passed through for problem.From to map.
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/HemahWeb/chirpy/internal/cleanup"
+	"github.com/HemahWeb/chirpy/internal/database"
+	"github.com/HemahWeb/chirpy/internal/events"
+	"github.com/HemahWeb/chirpy/internal/media"
+	"github.com/HemahWeb/chirpy/internal/problem"
+)
+
+type Service struct {
+	db             database.Querier
+	tx             Transactor
+	jwtSecret      string
+	now            func() time.Time
+	publisher      events.Publisher
+	trashRetention time.Duration
+	media          media.BlobStore
+	mediaLimits    media.Limits
+	maxImages      int
+}
+
+// New returns a service running single queries on db and multi-step
+// operations through tx. jwtSecret signs the access tokens it issues.
+func New(db database.Querier, tx Transactor, jwtSecret string) *Service {
+	return &Service{
+		db:             db,
+		tx:             tx,
+		jwtSecret:      jwtSecret,
+		now:            time.Now,
+		trashRetention: cleanup.DefaultChirpTrashConfig().Retention,
+		mediaLimits:    media.DefaultLimits(),
+		maxImages:      DefaultMaxImages,
+	}
+}
+
+// SetPublisher makes the service publish chirp, notification, message and
+// suspension events to p. Without one, nothing is published.
+func (s *Service) SetPublisher(p events.Publisher) {
+	s.publisher = p
+}
+
+// SetTrashRetention sets how long deleted chirps can be restored for. It
+// should match the retention the trash purger runs with.
+func (s *Service) SetTrashRetention(d time.Duration) {
+	s.trashRetention = d
+}
+
+// publishChirp tells live subscribers about a change to c. Nothing is
+// published for a limited author, whose chirps no one else sees.
+func (s *Service) publishChirp(ctx context.Context, t events.Type, c database.Chirp) {
+	if s.publisher == nil {
+		return
+	}
+	limited, err := s.db.IsUserLimited(ctx, c.UserID)
+	if err != nil {
+		slog.WarnContext(ctx, "Couldn't publish event", "type", t, "chirp_id", c.ID, "error", err)
+		return
+	}
+	if limited {
+		return
+	}
+	attachments, err := s.db.ListAttachmentsByChirpIDs(ctx, []uuid.UUID{c.ID})
+	if err != nil {
+		slog.WarnContext(ctx, "Couldn't publish event", "type", t, "chirp_id", c.ID, "error", err)
+		return
+	}
+	e, err := events.NewChirpEvent(t, c, attachments)
+	s.publish(ctx, e, err, "type", t, "chirp_id", c.ID)
+}
+
+// publishNotification tells n's recipient about it if they're listening.
+// n may be nil, for when notify made none.
+func (s *Service) publishNotification(ctx context.Context, n *database.Notification) {
+	if s.publisher == nil || n == nil {
+		return
+	}
+	e, err := events.NewNotificationEvent(*n)
+	s.publish(ctx, e, err, "type", events.NotificationCreated, "notification_id", n.ID)
+}
+
+// publishMessage tells the members of m's conversation about it: the
+// sender, for their other devices, and everyone who hasn't muted it. A
+// deleted message is sent to every member so they can remove it.
+func (s *Service) publishMessage(ctx context.Context, t events.Type, m database.Message, members []database.ConversationMember) {
+	if s.publisher == nil {
+		return
+	}
+	for _, member := range members {
+		if member.Muted && member.UserID != m.SenderID && t == events.MessageCreated {
+			continue
+		}
+		e, err := events.NewMessageEvent(t, member.UserID, m)
+		s.publish(ctx, e, err, "type", t, "message_id", m.ID)
+	}
+}
+
+// publishSuspension tells userID's live connections that they were
+// suspended, so they can be closed.
+func (s *Service) publishSuspension(ctx context.Context, userID uuid.UUID) {
+	if s.publisher == nil {
+		return
+	}
+	e, err := events.NewUserSuspendedEvent(userID)
+	s.publish(ctx, e, err, "type", events.UserSuspended, "user_id", userID)
+}
+
+// publish sends e unless building it failed with err. The change it
+// describes has already been committed, so a failure is logged with attrs
+// rather than returned.
+func (s *Service) publish(ctx context.Context, e events.Event, err error, attrs ...any) {
+	if err == nil {
+		err = s.publisher.Publish(ctx, e)
+	}
+	if err != nil {
+		slog.WarnContext(ctx, "Couldn't publish event", append(attrs, "error", err)...)
+	}
+}
+
+// notFound turns sql.ErrNoRows into a 404 naming what was missing. Other
+// errors are returned unchanged.
+func notFound(err error, what string) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return problem.New(http.StatusNotFound, problem.CodeNotFound, what+" not found").WithCause(err)
+	}
+	return err
+}
@@ -0,0 +1,723 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"image"
+	"image/png"
+	"io"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+
+	"github.com/HemahWeb/chirpy/internal/database"
+	"github.com/HemahWeb/chirpy/internal/dburl"
+	"github.com/HemahWeb/chirpy/internal/events"
+	"github.com/HemahWeb/chirpy/internal/media"
+	"github.com/HemahWeb/chirpy/internal/memstore"
+	"github.com/HemahWeb/chirpy/internal/migrate"
+	"github.com/HemahWeb/chirpy/internal/problem"
+	"github.com/HemahWeb/chirpy/internal/sqlite"
+)
+
+const testSecret = "0123456789abcdef0123456789abcdef"
+
+func newMemoryService() *Service {
+	store := memstore.New()
+	return New(store, store, testSecret)
+}
+
+func newSQLiteService(t *testing.T) (*Service, *SQLTransactor) {
+	t.Helper()
+	db, err := sqlite.Open("file:" + filepath.Join(t.TempDir(), "chirpy.db"))
+	if err != nil {
+		t.Fatalf("sqlite.Open() failed: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	m, err := migrate.New(db, dburl.SQLite)
+	if err != nil {
+		t.Fatalf("migrate.New() failed: %v", err)
+	}
+	if _, err := m.Up(context.Background()); err != nil {
+		t.Fatalf("Up() failed: %v", err)
+	}
+
+	// Postgres' conflicts are retried too, so the retry tests can fake them
+	retryable := func(err error) bool { return sqlite.Busy(err) || database.SerializationFailure(err) }
+	tx := NewSQLTransactor(db, func(tx *sql.Tx) database.Querier { return sqlite.New(tx) }, retryable)
+	tx.backoff = time.Millisecond
+	return New(sqlite.New(db), tx, testSecret), tx
+}
+
+func expectCode(t *testing.T, err error, code problem.Code) {
+	t.Helper()
+	if err == nil {
+		t.Fatalf("got no error, want %s", code)
+	}
+	if got := problem.From(err).Code; got != code {
+		t.Errorf("error %v has code %s, want %s", err, got, code)
+	}
+}
+
+func mustCreateUser(t *testing.T, s *Service, email string) uuid.UUID {
+	t.Helper()
+	user, err := s.CreateUser(context.Background(), email, "hunter2")
+	if err != nil {
+		t.Fatalf("CreateUser() failed: %v", err)
+	}
+	return user.ID
+}
+
+func TestDeleteChirp(t *testing.T) {
+	s := newMemoryService()
+	ctx := context.Background()
+	owner := mustCreateUser(t, s, "owner@example.com")
+	other := mustCreateUser(t, s, "other@example.com")
+	chirp, err := s.CreateChirp(ctx, owner, "hello")
+	if err != nil {
+		t.Fatalf("CreateChirp() failed: %v", err)
+	}
+
+	expectCode(t, s.DeleteChirp(ctx, other, chirp.ID), problem.CodeForbidden)
+	if err := s.DeleteChirp(ctx, owner, chirp.ID); err != nil {
+		t.Fatalf("DeleteChirp() by the owner failed: %v", err)
+	}
+	expectCode(t, s.DeleteChirp(ctx, owner, chirp.ID), problem.CodeNotFound)
+}
+
+func TestChirpEvents(t *testing.T) {
+	s := newMemoryService()
+	broker := events.NewBroker(10)
+	s.SetPublisher(broker)
+	sub, _ := broker.Subscribe("", nil)
+	ctx := context.Background()
+	owner := mustCreateUser(t, s, "owner@example.com")
+	other := mustCreateUser(t, s, "other@example.com")
+
+	chirp, _ := s.CreateChirp(ctx, owner, "hello")
+	s.DeleteChirp(ctx, other, chirp.ID) // forbidden, so no event
+	s.DeleteChirp(ctx, owner, chirp.ID)
+	s.RestoreChirp(ctx, other, chirp.ID) // not theirs, so no event
+	s.RestoreChirp(ctx, owner, chirp.ID)
+	broker.Close()
+
+	var got []events.Type
+	for e := range sub.C {
+		if e.UserID != owner || !strings.Contains(string(e.Data), chirp.ID.String()) {
+			t.Errorf("event %+v isn't about the chirp", e)
+		}
+		got = append(got, e.Type)
+	}
+	if want := []events.Type{events.ChirpCreated, events.ChirpDeleted, events.ChirpCreated}; !slices.Equal(got, want) {
+		t.Errorf("published %v, want %v", got, want)
+	}
+}
+
+func TestChirpImages(t *testing.T) {
+	s := newMemoryService()
+	ctx := context.Background()
+	walt := mustCreateUser(t, s, "walt@example.com")
+	var buf bytes.Buffer
+	png.Encode(&buf, image.NewGray(image.Rect(0, 0, 4, 4)))
+	img := buf.Bytes()
+
+	_, err := s.CreateChirp(ctx, walt, "Look", img)
+	expectCode(t, err, problem.CodeUnavailable)
+
+	blobs, err := media.NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocalStore() failed: %v", err)
+	}
+	s.SetMedia(blobs, media.DefaultLimits(), 1)
+	broker := events.NewBroker(10)
+	s.SetPublisher(broker)
+	sub, _ := broker.Subscribe("", nil)
+
+	_, err = s.CreateChirp(ctx, walt, "Look", img, img)
+	expectCode(t, err, problem.CodeValidationFailed)
+
+	chirp, err := s.CreateChirp(ctx, walt, "Look", img)
+	if err != nil {
+		t.Fatalf("CreateChirp() failed: %v", err)
+	}
+	attachments, err := s.ChirpAttachments(ctx, chirp)
+	if err != nil || len(attachments[chirp.ID]) != 1 {
+		t.Fatalf("ChirpAttachments() = %v, %v; want one", attachments, err)
+	}
+	a := attachments[chirp.ID][0]
+	if e := <-sub.C; !strings.Contains(string(e.Data), events.AttachmentURL(a.ID)) {
+		t.Errorf("event %s doesn't carry the attachment", e.Data)
+	}
+	_, blob, err := s.GetAttachment(ctx, uuid.Nil, a.ID, false)
+	if err != nil {
+		t.Fatalf("GetAttachment() failed: %v", err)
+	}
+	if got, _ := io.ReadAll(blob); !bytes.Equal(got, img) {
+		t.Error("stored image differs from the upload")
+	}
+	blob.Close()
+
+	// a reply that fails leaves its image stored on no chirp, for the cleaner
+	_, err = s.ReplyToChirp(ctx, walt, uuid.New(), "Look", img)
+	expectCode(t, err, problem.CodeValidationFailed)
+	orphans, _ := s.db.ListOrphanedAttachments(ctx, database.ListOrphanedAttachmentsParams{Cutoff: time.Now().Add(time.Minute), BatchSize: 10})
+	if len(orphans) != 1 {
+		t.Fatalf("%d orphaned attachments, want 1", len(orphans))
+	}
+	_, _, err = s.GetAttachment(ctx, uuid.Nil, orphans[0].ID, true)
+	expectCode(t, err, problem.CodeNotFound)
+}
+
+func TestReplyToChirp(t *testing.T) {
+	s, _ := newSQLiteService(t)
+	ctx := context.Background()
+	walt := mustCreateUser(t, s, "walt@example.com")
+	jesse := mustCreateUser(t, s, "jesse@example.com")
+	root, _ := s.CreateChirp(ctx, walt, "We need to cook")
+	reply, err := s.ReplyToChirp(ctx, jesse, root.ID, "Yeah, science!")
+	if err != nil {
+		t.Fatalf("ReplyToChirp() failed: %v", err)
+	}
+	nested, err := s.ReplyToChirp(ctx, walt, reply.ID, "Say my name")
+	if err != nil {
+		t.Fatalf("ReplyToChirp() to a reply failed: %v", err)
+	}
+	if reply.ReplyToID.UUID != root.ID || reply.ThreadID.UUID != root.ID || nested.ReplyToID.UUID != reply.ID || nested.ThreadID.UUID != root.ID {
+		t.Errorf("replies %+v and %+v aren't threaded under %v", reply, nested, root.ID)
+	}
+
+	_, err = s.ReplyToChirp(ctx, jesse, uuid.New(), "Hello?")
+	expectCode(t, err, problem.CodeValidationFailed)
+	_, err = s.ReplyToChirp(ctx, jesse, root.ID, "")
+	expectCode(t, err, problem.CodeValidationFailed)
+
+	// the reply outlives what it answered and stays in the thread; it only
+	// loses reply_to_id once the parent is purged from the trash
+	if err := s.DeleteChirp(ctx, walt, root.ID); err != nil {
+		t.Fatalf("DeleteChirp() failed: %v", err)
+	}
+	got, err := s.GetChirp(ctx, uuid.Nil, reply.ID)
+	if err != nil || got.ReplyToID.UUID != root.ID || got.ThreadID.UUID != root.ID {
+		t.Errorf("reply after deleting the parent = %+v, %v", got, err)
+	}
+	if err := s.db.PurgeChirp(ctx, root.ID); err != nil {
+		t.Fatalf("PurgeChirp() failed: %v", err)
+	}
+	got, err = s.GetChirp(ctx, uuid.Nil, reply.ID)
+	if err != nil || got.ReplyToID.Valid || got.ThreadID.UUID != root.ID {
+		t.Errorf("reply after purging the parent = %+v, %v", got, err)
+	}
+}
+
+func TestChirpTrash(t *testing.T) {
+	s, _ := newSQLiteService(t)
+	s.SetTrashRetention(time.Hour)
+	ctx := context.Background()
+	walt := mustCreateUser(t, s, "walt@example.com")
+	jesse := mustCreateUser(t, s, "jesse@example.com")
+	mod := mustCreateUser(t, s, "mod@example.com")
+	s.db.AddModerator(ctx, mod)
+
+	chirp, _ := s.CreateChirp(ctx, walt, "Say my name")
+	hidden, _ := s.CreateChirp(ctx, walt, "Tread lightly")
+	action, err := s.db.CreateModerationAction(ctx, database.CreateModerationActionParams{
+		ModeratorID: mod,
+		Action:      ActionHideChirp,
+		UserID:      walt,
+		ChirpID:     uuid.NullUUID{UUID: hidden.ID, Valid: true},
+	})
+	if err != nil {
+		t.Fatalf("CreateModerationAction() failed: %v", err)
+	}
+	if err := s.db.HideChirp(ctx, database.HideChirpParams{ChirpID: hidden.ID, ActionID: action.ID}); err != nil {
+		t.Fatalf("HideChirp() failed: %v", err)
+	}
+	for _, c := range []database.Chirp{chirp, hidden} {
+		if err := s.DeleteChirp(ctx, walt, c.ID); err != nil {
+			t.Fatalf("DeleteChirp() failed: %v", err)
+		}
+	}
+
+	// a deleted chirp is gone for everyone but shows up in its author's trash
+	_, err = s.GetChirp(ctx, walt, chirp.ID)
+	expectCode(t, err, problem.CodeNotFound)
+	if chirps, _ := s.ListChirps(ctx, ListChirpsParams{AuthorID: walt}); len(chirps) != 0 {
+		t.Errorf("ListChirps() = %v, want deleted chirps left out", chirps)
+	}
+	trash, err := s.ListTrash(ctx, walt)
+	if err != nil || len(trash) != 1 || trash[0].ID != chirp.ID || !trash[0].DeletedAt.Valid {
+		t.Errorf("ListTrash() = %+v, %v, want only the chirp a moderator didn't hide", trash, err)
+	}
+	if trash, _ := s.ListTrash(ctx, jesse); len(trash) != 0 {
+		t.Errorf("ListTrash() for someone else = %+v, want none", trash)
+	}
+
+	_, err = s.RestoreChirp(ctx, jesse, chirp.ID)
+	expectCode(t, err, problem.CodeNotFound)
+	_, err = s.RestoreChirp(ctx, walt, hidden.ID)
+	expectCode(t, err, problem.CodeNotFound)
+	restored, err := s.RestoreChirp(ctx, walt, chirp.ID)
+	if err != nil || restored.DeletedAt.Valid {
+		t.Fatalf("RestoreChirp() = %+v, %v", restored, err)
+	}
+	if _, err := s.GetChirp(ctx, uuid.Nil, chirp.ID); err != nil {
+		t.Errorf("GetChirp() after restoring = %v", err)
+	}
+	_, err = s.RestoreChirp(ctx, walt, chirp.ID)
+	expectCode(t, err, problem.CodeNotFound)
+
+	// past the retention it can't be restored, and the purge takes it but
+	// keeps the hidden one
+	s.DeleteChirp(ctx, walt, chirp.ID)
+	later := time.Now().Add(2 * time.Hour)
+	s.now = func() time.Time { return later }
+	if trash, _ := s.ListTrash(ctx, walt); len(trash) != 0 {
+		t.Errorf("ListTrash() after the retention = %+v, want none", trash)
+	}
+	_, err = s.RestoreChirp(ctx, walt, chirp.ID)
+	expectCode(t, err, problem.CodeNotFound)
+
+	n, err := s.db.PurgeTrashedChirps(ctx, database.PurgeTrashedChirpsParams{Cutoff: later.Add(-time.Hour), BatchSize: 10})
+	if err != nil || n != 1 {
+		t.Errorf("PurgeTrashedChirps() = %d, %v, want 1", n, err)
+	}
+	if _, err := s.db.GetChirpByID(ctx, chirp.ID); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetChirpByID() after the purge = %v, want sql.ErrNoRows", err)
+	}
+	if _, err := s.db.GetChirpByID(ctx, hidden.ID); err != nil {
+		t.Errorf("GetChirpByID() for the hidden chirp = %v, want it kept", err)
+	}
+
+	// a reported chirp is kept until a moderator resolves the report
+	reported, _ := s.CreateChirp(ctx, walt, "I am the one who knocks")
+	report, err := s.Report(ctx, jesse, ReportParams{ChirpID: reported.ID, Reason: "violence"})
+	if err != nil {
+		t.Fatalf("Report() failed: %v", err)
+	}
+	s.DeleteChirp(ctx, walt, reported.ID)
+	purge := database.PurgeTrashedChirpsParams{Cutoff: later.Add(time.Hour), BatchSize: 10}
+	s.now = func() time.Time { return purge.Cutoff.Add(time.Hour) }
+	if n, err := s.db.PurgeTrashedChirps(ctx, purge); err != nil || n != 0 {
+		t.Errorf("PurgeTrashedChirps() with an open report = %d, %v, want 0", n, err)
+	}
+	if _, err := s.ClaimReport(ctx, mod, report.ID); err != nil {
+		t.Fatalf("ClaimReport() failed: %v", err)
+	}
+	if n, err := s.db.PurgeTrashedChirps(ctx, purge); err != nil || n != 0 {
+		t.Errorf("PurgeTrashedChirps() with a claimed report = %d, %v, want 0", n, err)
+	}
+	if _, err := s.ResolveReport(ctx, mod, report.ID, ResolveReportParams{Action: ActionDismiss}); err != nil {
+		t.Fatalf("ResolveReport() failed: %v", err)
+	}
+	if n, err := s.db.PurgeTrashedChirps(ctx, purge); err != nil || n != 1 {
+		t.Errorf("PurgeTrashedChirps() after resolving the report = %d, %v, want 1", n, err)
+	}
+}
+
+func TestFollow(t *testing.T) {
+	s, _ := newSQLiteService(t)
+	broker := events.NewBroker(10)
+	s.SetPublisher(broker)
+	ctx := context.Background()
+	fan := mustCreateUser(t, s, "fan@example.com")
+	walt := mustCreateUser(t, s, "walt@example.com")
+	jesse := mustCreateUser(t, s, "jesse@example.com")
+	sub, _ := broker.Subscribe("", events.NotificationsFor(walt))
+
+	expectCode(t, s.Follow(ctx, fan, fan), problem.CodeValidationFailed)
+	expectCode(t, s.Follow(ctx, fan, uuid.New()), problem.CodeNotFound)
+	for _, id := range []uuid.UUID{walt, jesse, walt} {
+		if err := s.Follow(ctx, fan, id); err != nil {
+			t.Fatalf("Follow() failed: %v", err)
+		}
+	}
+	if err := s.Unfollow(ctx, fan, jesse); err != nil {
+		t.Fatalf("Unfollow() failed: %v", err)
+	}
+	if err := s.Unfollow(ctx, fan, jesse); err != nil {
+		t.Errorf("Unfollow() of someone not followed failed: %v", err)
+	}
+
+	followees, err := s.Followees(ctx, fan)
+	if err != nil {
+		t.Fatalf("Followees() failed: %v", err)
+	}
+	if !slices.Equal(followees, []uuid.UUID{walt}) {
+		t.Errorf("Followees() = %v, want just walt", followees)
+	}
+
+	// following again doesn't notify again
+	broker.Close()
+	var notified []events.Event
+	for e := range sub.C {
+		notified = append(notified, e)
+	}
+	if len(notified) != 1 || !strings.Contains(string(notified[0].Data), fan.String()) {
+		t.Errorf("walt was notified with %+v, want one follow notification from fan", notified)
+	}
+}
+
+func TestNotifications(t *testing.T) {
+	s, _ := newSQLiteService(t)
+	broker := events.NewBroker(10)
+	s.SetPublisher(broker)
+	ctx := context.Background()
+	walt := mustCreateUser(t, s, "walt@example.com")
+	jesse := mustCreateUser(t, s, "jesse@example.com")
+	sub, _ := broker.Subscribe("", events.NotificationsFor(walt))
+	cook, _ := s.CreateChirp(ctx, walt, "Say my name")
+
+	if _, err := s.SetNotificationPreferences(ctx, walt, map[string]bool{events.NotifyLike: false}); err != nil {
+		t.Fatalf("SetNotificationPreferences() failed: %v", err)
+	}
+	s.LikeChirp(ctx, jesse, cook.ID) // turned off
+	s.LikeChirp(ctx, walt, cook.ID)  // his own
+	reply, err := s.ReplyToChirp(ctx, jesse, cook.ID, "Heisenberg")
+	if err != nil {
+		t.Fatalf("ReplyToChirp() failed: %v", err)
+	}
+	s.ReplyToChirp(ctx, walt, reply.ID, "You're goddamn right") // notifies jesse
+	s.Follow(ctx, jesse, walt)
+
+	page, err := s.ListNotifications(ctx, walt, ListNotificationsParams{Limit: 1})
+	if err != nil {
+		t.Fatalf("ListNotifications() failed: %v", err)
+	}
+	if len(page.Notifications) != 1 || page.Notifications[0].Type != events.NotifyFollow || page.UnreadCount != 2 || page.Next == 0 {
+		t.Errorf("first page = %+v, want the follow of 2 unread and more to come", page)
+	}
+	page, _ = s.ListNotifications(ctx, walt, ListNotificationsParams{Before: page.Next, Limit: 1})
+	if len(page.Notifications) != 1 || page.Notifications[0].ChirpID.UUID != reply.ID || page.Next != 0 {
+		t.Errorf("last page = %+v, want the reply and no more", page)
+	}
+
+	broker.Close()
+	var published []string
+	for e := range sub.C {
+		var n events.Notification
+		json.Unmarshal(e.Data, &n)
+		published = append(published, n.Type)
+	}
+	if want := []string{events.NotifyReply, events.NotifyFollow}; !slices.Equal(published, want) {
+		t.Errorf("published %v to walt, want %v", published, want)
+	}
+
+	_, err = s.SetNotificationPreferences(ctx, walt, map[string]bool{"retweet": true})
+	expectCode(t, err, problem.CodeValidationFailed)
+	expectCode(t, s.MarkNotificationRead(ctx, jesse, page.Notifications[0].ID), problem.CodeNotFound)
+}
+
+func TestMentionNotifications(t *testing.T) {
+	s := newMemoryService()
+	ctx := context.Background()
+	walt := mustCreateUser(t, s, "walt@example.com")
+	jesse := mustCreateUser(t, s, "jesse@example.com")
+	skyler := mustCreateUser(t, s, "skyler@example.com")
+
+	cook, err := s.CreateChirp(ctx, walt, "@jesse@example.com @skyler@example.com @walt@example.com @saul@example.com we cook tonight.")
+	if err != nil {
+		t.Fatalf("CreateChirp() failed: %v", err)
+	}
+	// walt is the author of the chirp jesse answers, so he gets a reply
+	// rather than a mention
+	if _, err := s.ReplyToChirp(ctx, jesse, cook.ID, "Yo @walt@example.com, ask @skyler@example.com!"); err != nil {
+		t.Fatalf("ReplyToChirp() failed: %v", err)
+	}
+
+	types := func(userID uuid.UUID) []string {
+		t.Helper()
+		page, err := s.ListNotifications(ctx, userID, ListNotificationsParams{Limit: 10})
+		if err != nil {
+			t.Fatalf("ListNotifications() failed: %v", err)
+		}
+		var got []string
+		for _, n := range page.Notifications {
+			got = append(got, n.Type)
+		}
+		return got
+	}
+	if got := types(jesse); !slices.Equal(got, []string{events.NotifyMention}) {
+		t.Errorf("jesse's notifications = %v, want one mention", got)
+	}
+	if got := types(skyler); !slices.Equal(got, []string{events.NotifyMention, events.NotifyMention}) {
+		t.Errorf("skyler's notifications = %v, want two mentions", got)
+	}
+	if got := types(walt); !slices.Equal(got, []string{events.NotifyReply}) {
+		t.Errorf("walt's notifications = %v, want only the reply", got)
+	}
+
+	// mentioning someone who blocked you posts the chirp like any other, but
+	// they don't hear about it
+	if err := s.BlockUser(ctx, skyler, walt); err != nil {
+		t.Fatalf("BlockUser() failed: %v", err)
+	}
+	if _, err := s.CreateChirp(ctx, walt, "@skyler@example.com I did it for me"); err != nil {
+		t.Errorf("CreateChirp() mentioning someone who blocked the author = %v", err)
+	}
+	if got := types(skyler); len(got) != 2 {
+		t.Errorf("skyler's notifications after blocking walt = %v, want no new mention", got)
+	}
+}
+
+func TestListChirps(t *testing.T) {
+	s := newMemoryService()
+	ctx := context.Background()
+	a := mustCreateUser(t, s, "a@example.com")
+	b := mustCreateUser(t, s, "b@example.com")
+	first, _ := s.CreateChirp(ctx, a, "first")
+	second, _ := s.CreateChirp(ctx, b, "second")
+	third, _ := s.CreateChirp(ctx, a, "third")
+
+	chirps, _ := s.ListChirps(ctx, ListChirpsParams{Descending: true})
+	if len(chirps) != 3 || chirps[0].ID != third.ID || chirps[1].ID != second.ID || chirps[2].ID != first.ID {
+		t.Errorf("ListChirps(desc) = %v, want newest first", chirps)
+	}
+	chirps, _ = s.ListChirps(ctx, ListChirpsParams{AuthorID: a})
+	if len(chirps) != 2 || chirps[0].ID != first.ID || chirps[1].ID != third.ID {
+		t.Errorf("ListChirps(author) = %v, want a's chirps oldest first", chirps)
+	}
+}
+
+func TestUpgradeToChirpyRed(t *testing.T) {
+	s := newMemoryService()
+	ctx := context.Background()
+	id := mustCreateUser(t, s, "red@example.com")
+
+	expectCode(t, s.UpgradeToChirpyRed(ctx, uuid.New()), problem.CodeNotFound)
+	if err := s.UpgradeToChirpyRed(ctx, id); err != nil {
+		t.Fatalf("UpgradeToChirpyRed() failed: %v", err)
+	}
+	if user, _ := s.db.GetUserByID(ctx, id); !user.IsChirpyRed {
+		t.Error("user should be Chirpy Red")
+	}
+}
+
+func TestLoginAndRefresh(t *testing.T) {
+	s := newMemoryService()
+	ctx := context.Background()
+	id := mustCreateUser(t, s, "login@example.com")
+
+	_, err := s.Login(ctx, "login@example.com", "wrong")
+	expectCode(t, err, problem.CodeInvalidCredentials)
+	_, err = s.Login(ctx, "nobody@example.com", "hunter2")
+	expectCode(t, err, problem.CodeInvalidCredentials)
+
+	session, err := s.Login(ctx, "login@example.com", "hunter2")
+	if err != nil {
+		t.Fatalf("Login() failed: %v", err)
+	}
+	if userID, token, err := s.Refresh(ctx, session.RefreshToken); err != nil || userID != id || token == "" {
+		t.Errorf("Refresh() = %v, %q, %v; want a token for %v", userID, token, err, id)
+	}
+
+	s.now = func() time.Time { return time.Now().Add(61 * 24 * time.Hour) }
+	_, _, err = s.Refresh(ctx, session.RefreshToken)
+	expectCode(t, err, problem.CodeTokenExpired)
+
+	s.now = time.Now
+	if err := s.Revoke(ctx, session.RefreshToken); err != nil {
+		t.Fatalf("Revoke() failed: %v", err)
+	}
+	_, _, err = s.Refresh(ctx, session.RefreshToken)
+	expectCode(t, err, problem.CodeTokenRevoked)
+}
+
+func TestSuspension(t *testing.T) {
+	s, _ := newSQLiteService(t)
+	ctx := context.Background()
+	mod := mustCreateUser(t, s, "mod@example.com")
+	id := mustCreateUser(t, s, "user@example.com")
+	if err := s.db.AddModerator(ctx, mod); err != nil {
+		t.Fatalf("AddModerator() failed: %v", err)
+	}
+	session, _ := s.Login(ctx, "user@example.com", "hunter2")
+
+	until := time.Now().Add(24 * time.Hour)
+	if err := s.SuspendUser(ctx, mod, id, SuspendUserParams{Until: until}); err != nil {
+		t.Fatalf("SuspendUser() failed: %v", err)
+	}
+	expectCode(t, s.CheckActive(ctx, id), problem.CodeAccountSuspended)
+	_, _, err := s.Refresh(ctx, session.RefreshToken)
+	expectCode(t, err, problem.CodeTokenRevoked)
+
+	// signing in still works, so they can appeal, but with nothing to refresh
+	if session, err := s.Login(ctx, "user@example.com", "hunter2"); err != nil || !session.Suspended || session.AccessToken == "" || session.RefreshToken != "" {
+		t.Errorf("Login() while suspended = %+v, %v; want only an access token", session, err)
+	}
+
+	// it ends by itself
+	s.now = func() time.Time { return until.Add(time.Second) }
+	if err := s.CheckActive(ctx, id); err != nil {
+		t.Errorf("CheckActive() after the suspension ended = %v", err)
+	}
+	if session, err := s.Login(ctx, "user@example.com", "hunter2"); err != nil || session.Suspended || session.RefreshToken == "" {
+		t.Errorf("Login() after the suspension ended = %+v, %v", session, err)
+	}
+	expectCode(t, s.UnsuspendUser(ctx, mod, id, ""), problem.CodeConflict)
+}
+
+func TestOverturnOlderSuspension(t *testing.T) {
+	s, _ := newSQLiteService(t)
+	ctx := context.Background()
+	mod := mustCreateUser(t, s, "mod@example.com")
+	judge := mustCreateUser(t, s, "judge@example.com")
+	id := mustCreateUser(t, s, "user@example.com")
+	for _, m := range []uuid.UUID{mod, judge} {
+		if err := s.db.AddModerator(ctx, m); err != nil {
+			t.Fatalf("AddModerator() failed: %v", err)
+		}
+	}
+
+	for _, until := range []time.Time{time.Now().Add(time.Hour), {}} {
+		if err := s.SuspendUser(ctx, mod, id, SuspendUserParams{Until: until}); err != nil {
+			t.Fatalf("SuspendUser() failed: %v", err)
+		}
+	}
+	sanctions, err := s.ListSanctions(ctx, id)
+	if err != nil || len(sanctions) != 2 {
+		t.Fatalf("ListSanctions() = %v, %v; want both suspensions", sanctions, err)
+	}
+
+	// overturning the first leaves the second, which replaced it, in force
+	older, newer := sanctions[1].ID, sanctions[0].ID
+	for _, actionID := range []int64{older, newer} {
+		appeal, err := s.Appeal(ctx, id, actionID, "I did nothing")
+		if err != nil {
+			t.Fatalf("Appeal() failed: %v", err)
+		}
+		if _, err := s.DecideAppeal(ctx, judge, appeal.ID, true, ""); err != nil {
+			t.Fatalf("DecideAppeal() failed: %v", err)
+		}
+		if actionID == older {
+			expectCode(t, s.CheckActive(ctx, id), problem.CodeAccountSuspended)
+		}
+	}
+	if err := s.CheckActive(ctx, id); err != nil {
+		t.Errorf("CheckActive() after overturning the suspension in force = %v", err)
+	}
+}
+
+func TestLimitUser(t *testing.T) {
+	s := newMemoryService()
+	broker := events.NewBroker(10)
+	s.SetPublisher(broker)
+	sub, _ := broker.Subscribe("", nil)
+	ctx := context.Background()
+	mod := mustCreateUser(t, s, "mod@example.com")
+	limited := mustCreateUser(t, s, "limited@example.com")
+	other := mustCreateUser(t, s, "other@example.com")
+	s.db.AddModerator(ctx, mod)
+
+	if err := s.LimitUser(ctx, mod, limited, "spam"); err != nil {
+		t.Fatalf("LimitUser() failed: %v", err)
+	}
+	hidden, _ := s.CreateChirp(ctx, limited, "buy now")
+	seen, _ := s.CreateChirp(ctx, other, "hello")
+	broker.Close()
+
+	var got []uuid.UUID
+	for e := range sub.C {
+		got = append(got, e.UserID)
+	}
+	if !slices.Equal(got, []uuid.UUID{other}) {
+		t.Errorf("published events by %v, want only the other user's", got)
+	}
+	if _, err := s.GetChirp(ctx, other, hidden.ID); err == nil {
+		t.Error("GetChirp() of a limited user's chirp succeeded for someone else")
+	}
+	if _, err := s.GetChirp(ctx, limited, hidden.ID); err != nil {
+		t.Errorf("GetChirp() of their own chirp = %v", err)
+	}
+	chirps, _ := s.ListChirps(ctx, ListChirpsParams{ViewerID: other})
+	if len(chirps) != 1 || chirps[0].ID != seen.ID {
+		t.Errorf("ListChirps() = %v, want only the other user's chirp", chirps)
+	}
+}
+
+func TestSQLTransactorRetriesAndRollsBack(t *testing.T) {
+	s, tx := newSQLiteService(t)
+	ctx := context.Background()
+
+	attempts := 0
+	err := tx.WithTx(ctx, func(q database.Querier) error {
+		attempts++
+		if _, err := q.CreateUser(ctx, database.CreateUserParams{Email: "retry@example.com"}); err != nil {
+			return err
+		}
+		if attempts < 3 {
+			return &pq.Error{Code: "40001"}
+		}
+		return nil
+	})
+	// a failed attempt that wasn't rolled back would make the retry hit
+	// the unique email constraint
+	if err != nil || attempts != 3 {
+		t.Fatalf("WithTx() = %v after %d attempts, want success on the third", err, attempts)
+	}
+	if _, err := s.db.GetUserByEmail(ctx, "retry@example.com"); err != nil {
+		t.Errorf("the committed user is missing: %v", err)
+	}
+
+	attempts = 0
+	err = tx.WithTx(ctx, func(q database.Querier) error {
+		attempts++
+		return &pq.Error{Code: "40P01"}
+	})
+	if !database.SerializationFailure(err) || attempts != maxTxAttempts {
+		t.Errorf("WithTx() = %v after %d attempts, want the deadlock returned after %d", err, attempts, maxTxAttempts)
+	}
+
+	attempts = 0
+	boom := errors.New("boom")
+	err = tx.WithTx(ctx, func(q database.Querier) error {
+		attempts++
+		q.CreateUser(ctx, database.CreateUserParams{Email: "rollback@example.com"})
+		return boom
+	})
+	if !errors.Is(err, boom) || attempts != 1 {
+		t.Errorf("WithTx() = %v after %d attempts, want other errors returned at once", err, attempts)
+	}
+	if _, err := s.db.GetUserByEmail(ctx, "rollback@example.com"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetUserByEmail() error = %v, want the insert rolled back", err)
+	}
+}
+
+func TestConcurrentDeleteChirp(t *testing.T) {
+	s, _ := newSQLiteService(t)
+	ctx := context.Background()
+	owner := mustCreateUser(t, s, "owner@example.com")
+	chirp, err := s.CreateChirp(ctx, owner, "only once")
+	if err != nil {
+		t.Fatalf("CreateChirp() failed: %v", err)
+	}
+
+	const n = 8
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Go(func() { errs[i] = s.DeleteChirp(ctx, owner, chirp.ID) })
+	}
+	wg.Wait()
+
+	deleted := 0
+	for _, err := range errs {
+		switch {
+		case err == nil:
+			deleted++
+		case problem.From(err).Code != problem.CodeNotFound:
+			t.Errorf("DeleteChirp() error = %v, want success or not found", err)
+		}
+	}
+	if deleted != 1 {
+		t.Errorf("%d deletes succeeded, want exactly one", deleted)
+	}
+}
@@ -0,0 +1,65 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"math/rand/v2"
+	"time"
+
+	"github.com/HemahWeb/chirpy/internal/database"
+)
+
+// Transactor runs fn with queries bound to a single transaction. fn may be
+// called more than once, so it must not have effects outside the database.
+type Transactor interface {
+	WithTx(ctx context.Context, fn func(q database.Querier) error) error
+}
+
+// maxTxAttempts bounds retries of a transaction that keeps conflicting.
+const maxTxAttempts = 5
+
+// SQLTransactor runs serializable transactions on a *sql.DB, retrying them
+// from the start when the database reports a conflict such as a
+// serialization failure, deadlock or lock timeout.
+type SQLTransactor struct {
+	db        *sql.DB
+	queries   func(tx *sql.Tx) database.Querier
+	retryable func(err error) bool
+	backoff   time.Duration
+}
+
+// NewSQLTransactor returns a Transactor for db. queries binds the queries
+// for db's dialect to a transaction, e.g. database.Queries.WithTx, and
+// retryable reports the dialect's conflicts, e.g.
+// database.SerializationFailure.
+func NewSQLTransactor(db *sql.DB, queries func(tx *sql.Tx) database.Querier, retryable func(err error) bool) *SQLTransactor {
+	return &SQLTransactor{db: db, queries: queries, retryable: retryable, backoff: 10 * time.Millisecond}
+}
+
+func (t *SQLTransactor) WithTx(ctx context.Context, fn func(q database.Querier) error) error {
+	for attempt := 1; ; attempt++ {
+		err := t.run(ctx, fn)
+		if err == nil || attempt == maxTxAttempts || !t.retryable(err) {
+			return err
+		}
+		// jitter so the transactions that conflicted don't collide again
+		wait := time.Duration(attempt)*t.backoff + rand.N(t.backoff)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(wait):
+		}
+	}
+}
+
+func (t *SQLTransactor) run(ctx context.Context, fn func(q database.Querier) error) error {
+	tx, err := t.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	if err != nil {
+		return err
+	}
+	if err := fn(t.queries(tx)); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
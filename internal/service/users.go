@@ -0,0 +1,60 @@
+package service
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"github.com/HemahWeb/chirpy/internal/auth"
+	"github.com/HemahWeb/chirpy/internal/database"
+	"github.com/HemahWeb/chirpy/internal/utils"
+)
+
+// CreateUser validates the credentials and signs a user up.
+func (s *Service) CreateUser(ctx context.Context, email, password string) (database.CreateUserRow, error) {
+	if err := utils.ValidateCredentials(email, password); err != nil {
+		return database.CreateUserRow{}, err
+	}
+	hashedPassword, err := auth.HashPassword(password)
+	if err != nil {
+		return database.CreateUserRow{}, err
+	}
+	return s.db.CreateUser(ctx, database.CreateUserParams{
+		Email:          email,
+		HashedPassword: hashedPassword,
+	})
+}
+
+// UpdateUser replaces a user's email and password.
+func (s *Service) UpdateUser(ctx context.Context, id uuid.UUID, email, password string) (database.User, error) {
+	if err := utils.ValidateCredentials(email, password); err != nil {
+		return database.User{}, err
+	}
+	hashedPassword, err := auth.HashPassword(password)
+	if err != nil {
+		return database.User{}, err
+	}
+	user, err := s.db.UpdateUserEmailAndPassword(ctx, database.UpdateUserEmailAndPasswordParams{
+		ID:             id,
+		Email:          email,
+		HashedPassword: hashedPassword,
+	})
+	return user, notFound(err, "User")
+}
+
+// ResetUsers deletes every user and, through cascades, everything else.
+func (s *Service) ResetUsers(ctx context.Context) error {
+	return s.db.ResetUsers(ctx)
+}
+
+// UpgradeToChirpyRed upgrades a user, reporting a 404 if they don't exist.
+// The lookup and the upgrade share a transaction so a concurrent delete
+// can't slip in between.
+func (s *Service) UpgradeToChirpyRed(ctx context.Context, userID uuid.UUID) error {
+	return s.tx.WithTx(ctx, func(q database.Querier) error {
+		if _, err := q.GetUserByID(ctx, userID); err != nil {
+			return notFound(err, "User")
+		}
+		return q.UpgradeUserToChirpyRed(ctx, userID)
+	})
+}
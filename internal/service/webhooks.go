@@ -0,0 +1,48 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/google/uuid"
+
+	"github.com/HemahWeb/chirpy/internal/database"
+	"github.com/HemahWeb/chirpy/internal/problem"
+)
+
+// EventUserUpgraded is the Polka event that makes a user Chirpy Red.
+const EventUserUpgraded = "user.upgraded"
+
+// Outcomes recorded for webhook events that didn't fail. Failures record
+// the problem code, e.g. not_found.
+const (
+	OutcomeApplied = "applied"
+	OutcomeIgnored = "ignored"
+)
+
+// HandlePolkaEvent applies a Polka webhook event and records it in
+// webhook_events. userID is only read for events that need it. A failure
+// to record is logged rather than returned, so Polka doesn't redeliver an
+// event that was applied.
+func (s *Service) HandlePolkaEvent(ctx context.Context, event string, userID uuid.UUID) error {
+	var err error
+	outcome := OutcomeIgnored
+	if event == EventUserUpgraded {
+		err = s.UpgradeToChirpyRed(ctx, userID)
+		outcome = OutcomeApplied
+		if err != nil {
+			outcome = string(problem.From(err).Code)
+		}
+	}
+
+	recordErr := s.db.CreateWebhookEvent(ctx, database.CreateWebhookEventParams{
+		Source:  "polka",
+		Event:   event,
+		UserID:  uuid.NullUUID{UUID: userID, Valid: userID != uuid.Nil},
+		Outcome: outcome,
+	})
+	if recordErr != nil {
+		slog.WarnContext(ctx, "Couldn't record webhook event", "event", event, "error", recordErr)
+	}
+	return err
+}
@@ -0,0 +1,113 @@
+package sqlite
+
+import (
+	"context"
+
+	"github.com/HemahWeb/chirpy/internal/database"
+)
+
+const createAppeal = `-- name: CreateAppeal :one
+INSERT INTO appeals (action_id, user_id, body, created_at)
+VALUES (?1, ?2, ?3, ?4)
+RETURNING id, created_at, action_id, user_id, body, status, decided_by, decided_at
+`
+
+func (s *Store) CreateAppeal(ctx context.Context, arg database.CreateAppealParams) (database.Appeal, error) {
+	row := s.db.QueryRowContext(ctx, createAppeal,
+		arg.ActionID,
+		arg.UserID,
+		arg.Body,
+		s.timestamp(),
+	)
+	var i database.Appeal
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.ActionID,
+		&i.UserID,
+		&i.Body,
+		&i.Status,
+		&i.DecidedBy,
+		&i.DecidedAt,
+	)
+	return i, convertError(err)
+}
+
+const decideAppeal = `-- name: DecideAppeal :execrows
+UPDATE appeals
+SET status = ?2, decided_by = ?3, decided_at = ?4
+WHERE id = ?1 AND status = 'open'
+`
+
+func (s *Store) DecideAppeal(ctx context.Context, arg database.DecideAppealParams) (int64, error) {
+	result, err := s.db.ExecContext(ctx, decideAppeal,
+		arg.ID,
+		arg.Status,
+		arg.DecidedBy,
+		s.timestamp(),
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getAppeal = `-- name: GetAppeal :one
+SELECT id, created_at, action_id, user_id, body, status, decided_by, decided_at FROM appeals
+WHERE id = ?1
+`
+
+func (s *Store) GetAppeal(ctx context.Context, id int64) (database.Appeal, error) {
+	row := s.db.QueryRowContext(ctx, getAppeal, id)
+	var i database.Appeal
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.ActionID,
+		&i.UserID,
+		&i.Body,
+		&i.Status,
+		&i.DecidedBy,
+		&i.DecidedAt,
+	)
+	return i, err
+}
+
+const listAppeals = `-- name: ListAppeals :many
+SELECT id, created_at, action_id, user_id, body, status, decided_by, decided_at FROM appeals
+WHERE status = ?1 AND id > ?2
+ORDER BY id
+LIMIT ?3
+`
+
+func (s *Store) ListAppeals(ctx context.Context, arg database.ListAppealsParams) ([]database.Appeal, error) {
+	rows, err := s.db.QueryContext(ctx, listAppeals, arg.Status, arg.AfterID, arg.MaxRows)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []database.Appeal
+	for rows.Next() {
+		var i database.Appeal
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.ActionID,
+			&i.UserID,
+			&i.Body,
+			&i.Status,
+			&i.DecidedBy,
+			&i.DecidedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -0,0 +1,138 @@
+package sqlite
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/google/uuid"
+
+	"github.com/HemahWeb/chirpy/internal/database"
+)
+
+const attachmentColumns = `id, created_at, chirp_id, position, content_type, size_bytes, width, height, thumbnail_content_type, thumbnail_size_bytes, thumbnail_width, thumbnail_height`
+
+const attachToChirp = `-- name: AttachToChirp :exec
+UPDATE attachments
+SET chirp_id = ?2, position = ?3
+WHERE id = ?1
+`
+
+func (s *Store) AttachToChirp(ctx context.Context, arg database.AttachToChirpParams) error {
+	_, err := s.db.ExecContext(ctx, attachToChirp, arg.ID, arg.ChirpID, arg.Position)
+	return err
+}
+
+const createAttachment = `-- name: CreateAttachment :one
+INSERT INTO attachments (
+    id, created_at, content_type, size_bytes, width, height,
+    thumbnail_content_type, thumbnail_size_bytes, thumbnail_width, thumbnail_height
+)
+VALUES (?1, ?2, ?3, ?4, ?5, ?6, ?7, ?8, ?9, ?10)
+RETURNING ` + attachmentColumns
+
+// Creates an attachment on no chirp yet; AttachToChirp adds it to one once
+// its blobs are stored.
+func (s *Store) CreateAttachment(ctx context.Context, arg database.CreateAttachmentParams) (database.Attachment, error) {
+	return scanAttachment(s.db.QueryRowContext(ctx, createAttachment,
+		uuid.New(),
+		s.timestamp(),
+		arg.ContentType,
+		arg.SizeBytes,
+		arg.Width,
+		arg.Height,
+		arg.ThumbnailContentType,
+		arg.ThumbnailSizeBytes,
+		arg.ThumbnailWidth,
+		arg.ThumbnailHeight,
+	))
+}
+
+const deleteAttachment = `-- name: DeleteAttachment :exec
+DELETE FROM attachments
+WHERE id = ?1
+`
+
+func (s *Store) DeleteAttachment(ctx context.Context, id uuid.UUID) error {
+	_, err := s.db.ExecContext(ctx, deleteAttachment, id)
+	return err
+}
+
+const getAttachment = `-- name: GetAttachment :one
+SELECT ` + attachmentColumns + ` FROM attachments
+WHERE id = ?1
+`
+
+func (s *Store) GetAttachment(ctx context.Context, id uuid.UUID) (database.Attachment, error) {
+	return scanAttachment(s.db.QueryRowContext(ctx, getAttachment, id))
+}
+
+// SQLite has no arrays, so the ids go in as a JSON array.
+const listAttachmentsByChirpIDs = `-- name: ListAttachmentsByChirpIDs :many
+SELECT ` + attachmentColumns + ` FROM attachments
+WHERE chirp_id IN (SELECT value FROM json_each(?1))
+ORDER BY chirp_id, position
+`
+
+func (s *Store) ListAttachmentsByChirpIDs(ctx context.Context, chirpIds []uuid.UUID) ([]database.Attachment, error) {
+	ids, err := json.Marshal(chirpIds)
+	if err != nil {
+		return nil, err
+	}
+	return s.queryAttachments(ctx, listAttachmentsByChirpIDs, string(ids))
+}
+
+const listOrphanedAttachments = `-- name: ListOrphanedAttachments :many
+SELECT ` + attachmentColumns + ` FROM attachments
+WHERE chirp_id IS NULL AND created_at < ?1
+ORDER BY created_at
+LIMIT ?2
+`
+
+// Attachments created before cutoff that never made it onto a chirp, or
+// whose chirp was purged, oldest first.
+func (s *Store) ListOrphanedAttachments(ctx context.Context, arg database.ListOrphanedAttachmentsParams) ([]database.Attachment, error) {
+	return s.queryAttachments(ctx, listOrphanedAttachments, arg.Cutoff.UTC(), arg.BatchSize)
+}
+
+func (s *Store) queryAttachments(ctx context.Context, query string, args ...interface{}) ([]database.Attachment, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []database.Attachment
+	for rows.Next() {
+		i, err := scanAttachment(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// scanAttachment scans attachmentColumns from a *sql.Row or *sql.Rows.
+func scanAttachment(row interface{ Scan(...any) error }) (database.Attachment, error) {
+	var i database.Attachment
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.ChirpID,
+		&i.Position,
+		&i.ContentType,
+		&i.SizeBytes,
+		&i.Width,
+		&i.Height,
+		&i.ThumbnailContentType,
+		&i.ThumbnailSizeBytes,
+		&i.ThumbnailWidth,
+		&i.ThumbnailHeight,
+	)
+	return i, err
+}
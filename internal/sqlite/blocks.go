@@ -0,0 +1,77 @@
+package sqlite
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"github.com/HemahWeb/chirpy/internal/database"
+)
+
+const blockExists = `-- name: BlockExists :one
+SELECT EXISTS (
+    SELECT 1 FROM blocks
+    WHERE (blocker_id = ?1 AND blocked_id = ?2)
+    OR (blocker_id = ?2 AND blocked_id = ?1)
+)
+`
+
+func (s *Store) BlockExists(ctx context.Context, arg database.BlockExistsParams) (bool, error) {
+	row := s.db.QueryRowContext(ctx, blockExists, arg.UserID, arg.OtherID)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const blockUser = `-- name: BlockUser :execrows
+INSERT INTO blocks (blocker_id, blocked_id, created_at)
+VALUES (?1, ?2, ?3)
+ON CONFLICT DO NOTHING
+`
+
+func (s *Store) BlockUser(ctx context.Context, arg database.BlockUserParams) (int64, error) {
+	result, err := s.db.ExecContext(ctx, blockUser, arg.BlockerID, arg.BlockedID, s.timestamp())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const listBlocks = `-- name: ListBlocks :many
+SELECT blocker_id, blocked_id, created_at FROM blocks
+WHERE blocker_id = ?1
+ORDER BY created_at DESC, blocked_id
+`
+
+func (s *Store) ListBlocks(ctx context.Context, blockerID uuid.UUID) ([]database.Block, error) {
+	rows, err := s.db.QueryContext(ctx, listBlocks, blockerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []database.Block
+	for rows.Next() {
+		var i database.Block
+		if err := rows.Scan(&i.BlockerID, &i.BlockedID, &i.CreatedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const unblockUser = `-- name: UnblockUser :exec
+DELETE FROM blocks
+WHERE blocker_id = ?1 AND blocked_id = ?2
+`
+
+func (s *Store) UnblockUser(ctx context.Context, arg database.UnblockUserParams) error {
+	_, err := s.db.ExecContext(ctx, unblockUser, arg.BlockerID, arg.BlockedID)
+	return err
+}
@@ -0,0 +1,204 @@
+package sqlite
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"github.com/HemahWeb/chirpy/internal/database"
+)
+
+const createChirp = `-- name: CreateChirp :one
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, reply_to_id, thread_id) VALUES (?1, ?2, ?2, ?3, ?4, ?5, ?6)
+RETURNING id, created_at, updated_at, body, user_id, reply_to_id, thread_id, deleted_at
+`
+
+func (s *Store) CreateChirp(ctx context.Context, arg database.CreateChirpParams) (database.Chirp, error) {
+	row := s.db.QueryRowContext(ctx, createChirp,
+		uuid.New(),
+		s.timestamp(),
+		arg.Body,
+		arg.UserID,
+		arg.ReplyToID,
+		arg.ThreadID,
+	)
+	var i database.Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.ReplyToID,
+		&i.ThreadID,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
+const deleteChirp = `-- name: DeleteChirp :exec
+UPDATE chirps SET deleted_at = ?2 WHERE id = ?1 AND deleted_at IS NULL
+`
+
+// Moves a chirp to the trash; PurgeChirp removes it for good.
+func (s *Store) DeleteChirp(ctx context.Context, id uuid.UUID) error {
+	_, err := s.db.ExecContext(ctx, deleteChirp, id, s.timestamp())
+	return err
+}
+
+const getChirpByID = `-- name: GetChirpByID :one
+SELECT id, created_at, updated_at, body, user_id, reply_to_id, thread_id, deleted_at FROM chirps WHERE id = ?1 LIMIT 1
+`
+
+// Finds deleted chirps too.
+func (s *Store) GetChirpByID(ctx context.Context, id uuid.UUID) (database.Chirp, error) {
+	row := s.db.QueryRowContext(ctx, getChirpByID, id)
+	var i database.Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.ReplyToID,
+		&i.ThreadID,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
+const getChirps = `-- name: GetChirps :many
+SELECT id, created_at, updated_at, body, user_id, reply_to_id, thread_id, deleted_at FROM chirps WHERE deleted_at IS NULL AND id NOT IN (SELECT chirp_id FROM hidden_chirps) ORDER BY created_at ASC
+`
+
+// Leaves out deleted chirps and those a moderator hid.
+func (s *Store) GetChirps(ctx context.Context) ([]database.Chirp, error) {
+	return s.queryChirps(ctx, getChirps)
+}
+
+const getChirpsByUserID = `-- name: GetChirpsByUserID :many
+SELECT id, created_at, updated_at, body, user_id, reply_to_id, thread_id, deleted_at FROM chirps WHERE user_id = ?1 AND deleted_at IS NULL AND id NOT IN (SELECT chirp_id FROM hidden_chirps) ORDER BY created_at ASC
+`
+
+// Leaves out deleted chirps and those a moderator hid.
+func (s *Store) GetChirpsByUserID(ctx context.Context, userID uuid.UUID) ([]database.Chirp, error) {
+	return s.queryChirps(ctx, getChirpsByUserID, userID)
+}
+
+const listTrashedChirps = `-- name: ListTrashedChirps :many
+SELECT id, created_at, updated_at, body, user_id, reply_to_id, thread_id, deleted_at FROM chirps
+WHERE user_id = ?1
+  AND deleted_at >= ?2
+  AND id NOT IN (SELECT chirp_id FROM hidden_chirps)
+ORDER BY deleted_at DESC, id DESC
+`
+
+// The chirps user_id deleted since cutoff, most recently deleted first.
+// Chirps a moderator hid aren't theirs to restore, so they're left out.
+func (s *Store) ListTrashedChirps(ctx context.Context, arg database.ListTrashedChirpsParams) ([]database.Chirp, error) {
+	return s.queryChirps(ctx, listTrashedChirps, arg.UserID, arg.Cutoff.UTC())
+}
+
+const purgeChirp = `-- name: PurgeChirp :exec
+DELETE FROM chirps WHERE id = ?1
+`
+
+func (s *Store) PurgeChirp(ctx context.Context, id uuid.UUID) error {
+	_, err := s.db.ExecContext(ctx, purgeChirp, id)
+	return err
+}
+
+const purgeTrashedChirps = `-- name: PurgeTrashedChirps :execrows
+DELETE FROM chirps
+WHERE id IN (
+    SELECT id FROM chirps
+    WHERE deleted_at < ?1
+      AND id NOT IN (SELECT chirp_id FROM hidden_chirps)
+      AND NOT EXISTS (SELECT 1 FROM reports WHERE chirp_id = chirps.id AND status <> 'resolved')
+    LIMIT ?2
+)
+`
+
+// Chirps a moderator hid are kept for the moderation log, and reported ones
+// until the reports are resolved.
+func (s *Store) PurgeTrashedChirps(ctx context.Context, arg database.PurgeTrashedChirpsParams) (int64, error) {
+	result, err := s.db.ExecContext(ctx, purgeTrashedChirps, arg.Cutoff.UTC(), arg.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func (s *Store) queryChirps(ctx context.Context, query string, args ...interface{}) ([]database.Chirp, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []database.Chirp
+	for rows.Next() {
+		var i database.Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.ReplyToID,
+			&i.ThreadID,
+			&i.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const restoreChirp = `-- name: RestoreChirp :one
+UPDATE chirps SET deleted_at = NULL WHERE id = ?1 AND deleted_at IS NOT NULL
+RETURNING id, created_at, updated_at, body, user_id, reply_to_id, thread_id, deleted_at
+`
+
+func (s *Store) RestoreChirp(ctx context.Context, id uuid.UUID) (database.Chirp, error) {
+	row := s.db.QueryRowContext(ctx, restoreChirp, id)
+	var i database.Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.ReplyToID,
+		&i.ThreadID,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
+const updateChirp = `-- name: UpdateChirp :one
+UPDATE chirps SET body = ?2, updated_at = ?3 WHERE id = ?1
+RETURNING id, created_at, updated_at, body, user_id, reply_to_id, thread_id, deleted_at
+`
+
+func (s *Store) UpdateChirp(ctx context.Context, arg database.UpdateChirpParams) (database.Chirp, error) {
+	row := s.db.QueryRowContext(ctx, updateChirp, arg.ID, arg.Body, s.timestamp())
+	var i database.Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.ReplyToID,
+		&i.ThreadID,
+		&i.DeletedAt,
+	)
+	return i, err
+}
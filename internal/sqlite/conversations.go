@@ -0,0 +1,209 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+
+	"github.com/HemahWeb/chirpy/internal/database"
+)
+
+const addConversationMember = `-- name: AddConversationMember :exec
+INSERT INTO conversation_members (conversation_id, user_id)
+VALUES (?1, ?2)
+`
+
+func (s *Store) AddConversationMember(ctx context.Context, arg database.AddConversationMemberParams) error {
+	_, err := s.db.ExecContext(ctx, addConversationMember, arg.ConversationID, arg.UserID)
+	return err
+}
+
+const createConversation = `-- name: CreateConversation :one
+INSERT INTO conversations (id, created_at, direct_key)
+VALUES (?1, ?2, ?3)
+ON CONFLICT (direct_key) DO NOTHING
+RETURNING id, created_at, last_message_at, direct_key
+`
+
+func (s *Store) CreateConversation(ctx context.Context, directKey sql.NullString) (database.Conversation, error) {
+	row := s.db.QueryRowContext(ctx, createConversation, uuid.New(), s.timestamp(), directKey)
+	return scanConversation(row)
+}
+
+const getConversationByDirectKey = `-- name: GetConversationByDirectKey :one
+SELECT id, created_at, last_message_at, direct_key FROM conversations WHERE direct_key = ?1
+`
+
+func (s *Store) GetConversationByDirectKey(ctx context.Context, directKey sql.NullString) (database.Conversation, error) {
+	return scanConversation(s.db.QueryRowContext(ctx, getConversationByDirectKey, directKey))
+}
+
+func scanConversation(row *sql.Row) (database.Conversation, error) {
+	var i database.Conversation
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.LastMessageAt,
+		&i.DirectKey,
+	)
+	return i, err
+}
+
+const getConversationForUser = `-- name: GetConversationForUser :one
+SELECT c.id, c.created_at, c.last_message_at, c.direct_key, m.last_read_id, m.muted, (
+    SELECT COUNT(*) FROM messages
+    WHERE messages.conversation_id = c.id
+    AND messages.id > m.last_read_id
+    AND messages.sender_id <> m.user_id
+) AS unread_count
+FROM conversations c
+JOIN conversation_members m ON m.conversation_id = c.id
+WHERE c.id = ?1 AND m.user_id = ?2
+`
+
+func (s *Store) GetConversationForUser(ctx context.Context, arg database.GetConversationForUserParams) (database.GetConversationForUserRow, error) {
+	row := s.db.QueryRowContext(ctx, getConversationForUser, arg.ID, arg.UserID)
+	var i database.GetConversationForUserRow
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.LastMessageAt,
+		&i.DirectKey,
+		&i.LastReadID,
+		&i.Muted,
+		&i.UnreadCount,
+	)
+	return i, err
+}
+
+const listConversationMembers = `-- name: ListConversationMembers :many
+SELECT conversation_id, user_id, last_read_id, muted FROM conversation_members
+WHERE conversation_id = ?1
+ORDER BY user_id
+`
+
+func (s *Store) ListConversationMembers(ctx context.Context, conversationID uuid.UUID) ([]database.ConversationMember, error) {
+	return s.queryConversationMembers(ctx, listConversationMembers, conversationID)
+}
+
+const listConversationMembersForUser = `-- name: ListConversationMembersForUser :many
+SELECT m.conversation_id, m.user_id, m.last_read_id, m.muted FROM conversation_members m
+JOIN conversation_members me ON me.conversation_id = m.conversation_id
+WHERE me.user_id = ?1
+ORDER BY m.conversation_id, m.user_id
+`
+
+func (s *Store) ListConversationMembersForUser(ctx context.Context, userID uuid.UUID) ([]database.ConversationMember, error) {
+	return s.queryConversationMembers(ctx, listConversationMembersForUser, userID)
+}
+
+func (s *Store) queryConversationMembers(ctx context.Context, query string, args ...any) ([]database.ConversationMember, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []database.ConversationMember
+	for rows.Next() {
+		var i database.ConversationMember
+		if err := rows.Scan(
+			&i.ConversationID,
+			&i.UserID,
+			&i.LastReadID,
+			&i.Muted,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listConversationsForUser = `-- name: ListConversationsForUser :many
+SELECT c.id, c.created_at, c.last_message_at, c.direct_key, m.last_read_id, m.muted, (
+    SELECT COUNT(*) FROM messages
+    WHERE messages.conversation_id = c.id
+    AND messages.id > m.last_read_id
+    AND messages.sender_id <> m.user_id
+) AS unread_count
+FROM conversations c
+JOIN conversation_members m ON m.conversation_id = c.id
+WHERE m.user_id = ?1
+ORDER BY COALESCE(c.last_message_at, c.created_at) DESC, c.id
+`
+
+func (s *Store) ListConversationsForUser(ctx context.Context, userID uuid.UUID) ([]database.ListConversationsForUserRow, error) {
+	rows, err := s.db.QueryContext(ctx, listConversationsForUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []database.ListConversationsForUserRow
+	for rows.Next() {
+		var i database.ListConversationsForUserRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.LastMessageAt,
+			&i.DirectKey,
+			&i.LastReadID,
+			&i.Muted,
+			&i.UnreadCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markConversationRead = `-- name: MarkConversationRead :execrows
+UPDATE conversation_members SET last_read_id = MAX(last_read_id, ?3)
+WHERE conversation_id = ?1 AND user_id = ?2
+`
+
+func (s *Store) MarkConversationRead(ctx context.Context, arg database.MarkConversationReadParams) (int64, error) {
+	result, err := s.db.ExecContext(ctx, markConversationRead, arg.ConversationID, arg.UserID, arg.LastReadID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const setConversationLastMessageAt = `-- name: SetConversationLastMessageAt :exec
+UPDATE conversations SET last_message_at = ?2
+WHERE id = ?1
+`
+
+func (s *Store) SetConversationLastMessageAt(ctx context.Context, arg database.SetConversationLastMessageAtParams) error {
+	lastMessageAt := arg.LastMessageAt
+	lastMessageAt.Time = lastMessageAt.Time.UTC()
+	_, err := s.db.ExecContext(ctx, setConversationLastMessageAt, arg.ID, lastMessageAt)
+	return err
+}
+
+const setConversationMuted = `-- name: SetConversationMuted :execrows
+UPDATE conversation_members SET muted = ?3
+WHERE conversation_id = ?1 AND user_id = ?2
+`
+
+func (s *Store) SetConversationMuted(ctx context.Context, arg database.SetConversationMutedParams) (int64, error) {
+	result, err := s.db.ExecContext(ctx, setConversationMuted, arg.ConversationID, arg.UserID, arg.Muted)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
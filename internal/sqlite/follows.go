@@ -0,0 +1,62 @@
+package sqlite
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"github.com/HemahWeb/chirpy/internal/database"
+)
+
+const followUser = `-- name: FollowUser :execrows
+INSERT INTO follows (follower_id, followee_id, created_at)
+VALUES (?1, ?2, ?3)
+ON CONFLICT DO NOTHING
+`
+
+func (s *Store) FollowUser(ctx context.Context, arg database.FollowUserParams) (int64, error) {
+	result, err := s.db.ExecContext(ctx, followUser, arg.FollowerID, arg.FolloweeID, s.timestamp())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const listFolloweeIDs = `-- name: ListFolloweeIDs :many
+SELECT followee_id FROM follows
+WHERE follower_id = ?1
+ORDER BY created_at
+`
+
+func (s *Store) ListFolloweeIDs(ctx context.Context, followerID uuid.UUID) ([]uuid.UUID, error) {
+	rows, err := s.db.QueryContext(ctx, listFolloweeIDs, followerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []uuid.UUID
+	for rows.Next() {
+		var followeeID uuid.UUID
+		if err := rows.Scan(&followeeID); err != nil {
+			return nil, err
+		}
+		items = append(items, followeeID)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const unfollowUser = `-- name: UnfollowUser :exec
+DELETE FROM follows
+WHERE follower_id = ?1 AND followee_id = ?2
+`
+
+func (s *Store) UnfollowUser(ctx context.Context, arg database.UnfollowUserParams) error {
+	_, err := s.db.ExecContext(ctx, unfollowUser, arg.FollowerID, arg.FolloweeID)
+	return err
+}
@@ -0,0 +1,31 @@
+package sqlite
+
+import (
+	"context"
+
+	"github.com/HemahWeb/chirpy/internal/database"
+)
+
+const likeChirp = `-- name: LikeChirp :execrows
+INSERT INTO chirp_likes (user_id, chirp_id, created_at)
+VALUES (?1, ?2, ?3)
+ON CONFLICT DO NOTHING
+`
+
+func (s *Store) LikeChirp(ctx context.Context, arg database.LikeChirpParams) (int64, error) {
+	result, err := s.db.ExecContext(ctx, likeChirp, arg.UserID, arg.ChirpID, s.timestamp())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const unlikeChirp = `-- name: UnlikeChirp :exec
+DELETE FROM chirp_likes
+WHERE user_id = ?1 AND chirp_id = ?2
+`
+
+func (s *Store) UnlikeChirp(ctx context.Context, arg database.UnlikeChirpParams) error {
+	_, err := s.db.ExecContext(ctx, unlikeChirp, arg.UserID, arg.ChirpID)
+	return err
+}
@@ -0,0 +1,94 @@
+package sqlite
+
+import (
+	"context"
+
+	"github.com/HemahWeb/chirpy/internal/database"
+)
+
+const createMessage = `-- name: CreateMessage :one
+INSERT INTO messages (conversation_id, sender_id, body, created_at)
+VALUES (?1, ?2, ?3, ?4)
+RETURNING id, conversation_id, sender_id, body, created_at
+`
+
+func (s *Store) CreateMessage(ctx context.Context, arg database.CreateMessageParams) (database.Message, error) {
+	row := s.db.QueryRowContext(ctx, createMessage,
+		arg.ConversationID,
+		arg.SenderID,
+		arg.Body,
+		s.timestamp(),
+	)
+	var i database.Message
+	err := row.Scan(
+		&i.ID,
+		&i.ConversationID,
+		&i.SenderID,
+		&i.Body,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const deleteMessage = `-- name: DeleteMessage :exec
+DELETE FROM messages WHERE id = ?1
+`
+
+func (s *Store) DeleteMessage(ctx context.Context, id int64) error {
+	_, err := s.db.ExecContext(ctx, deleteMessage, id)
+	return err
+}
+
+const getMessage = `-- name: GetMessage :one
+SELECT id, conversation_id, sender_id, body, created_at FROM messages WHERE id = ?1
+`
+
+func (s *Store) GetMessage(ctx context.Context, id int64) (database.Message, error) {
+	row := s.db.QueryRowContext(ctx, getMessage, id)
+	var i database.Message
+	err := row.Scan(
+		&i.ID,
+		&i.ConversationID,
+		&i.SenderID,
+		&i.Body,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const listMessages = `-- name: ListMessages :many
+SELECT id, conversation_id, sender_id, body, created_at FROM messages
+WHERE conversation_id = ?1
+AND id < ?2
+ORDER BY id DESC
+LIMIT ?3
+`
+
+func (s *Store) ListMessages(ctx context.Context, arg database.ListMessagesParams) ([]database.Message, error) {
+	rows, err := s.db.QueryContext(ctx, listMessages, arg.ConversationID, arg.BeforeID, arg.MaxRows)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []database.Message
+	for rows.Next() {
+		var i database.Message
+		if err := rows.Scan(
+			&i.ID,
+			&i.ConversationID,
+			&i.SenderID,
+			&i.Body,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -0,0 +1,325 @@
+package sqlite
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"github.com/HemahWeb/chirpy/internal/database"
+)
+
+const addModerator = `-- name: AddModerator :exec
+INSERT INTO moderators (user_id, created_at)
+VALUES (?1, ?2)
+ON CONFLICT DO NOTHING
+`
+
+func (s *Store) AddModerator(ctx context.Context, userID uuid.UUID) error {
+	_, err := s.db.ExecContext(ctx, addModerator, userID, s.timestamp())
+	return err
+}
+
+const createModerationAction = `-- name: CreateModerationAction :one
+INSERT INTO moderation_actions (moderator_id, action, report_id, appeal_id, user_id, chirp_id, note, created_at)
+VALUES (?1, ?2, ?3, ?4, ?5, ?6, ?7, ?8)
+RETURNING id, created_at, moderator_id, action, report_id, appeal_id, user_id, chirp_id, note
+`
+
+func (s *Store) CreateModerationAction(ctx context.Context, arg database.CreateModerationActionParams) (database.ModerationAction, error) {
+	row := s.db.QueryRowContext(ctx, createModerationAction,
+		arg.ModeratorID,
+		arg.Action,
+		arg.ReportID,
+		arg.AppealID,
+		arg.UserID,
+		arg.ChirpID,
+		arg.Note,
+		s.timestamp(),
+	)
+	var i database.ModerationAction
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.ModeratorID,
+		&i.Action,
+		&i.ReportID,
+		&i.AppealID,
+		&i.UserID,
+		&i.ChirpID,
+		&i.Note,
+	)
+	return i, err
+}
+
+const getModerationAction = `-- name: GetModerationAction :one
+SELECT id, created_at, moderator_id, action, report_id, appeal_id, user_id, chirp_id, note FROM moderation_actions
+WHERE id = ?1
+`
+
+func (s *Store) GetModerationAction(ctx context.Context, id int64) (database.ModerationAction, error) {
+	row := s.db.QueryRowContext(ctx, getModerationAction, id)
+	var i database.ModerationAction
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.ModeratorID,
+		&i.Action,
+		&i.ReportID,
+		&i.AppealID,
+		&i.UserID,
+		&i.ChirpID,
+		&i.Note,
+	)
+	return i, err
+}
+
+const getUserSuspension = `-- name: GetUserSuspension :one
+SELECT suspended_at, suspended_until FROM users
+WHERE id = ?1
+`
+
+func (s *Store) GetUserSuspension(ctx context.Context, id uuid.UUID) (database.GetUserSuspensionRow, error) {
+	row := s.db.QueryRowContext(ctx, getUserSuspension, id)
+	var i database.GetUserSuspensionRow
+	err := row.Scan(&i.SuspendedAt, &i.SuspendedUntil)
+	return i, err
+}
+
+const hideChirp = `-- name: HideChirp :exec
+INSERT INTO hidden_chirps (chirp_id, action_id, created_at)
+VALUES (?1, ?2, ?3)
+ON CONFLICT DO NOTHING
+`
+
+func (s *Store) HideChirp(ctx context.Context, arg database.HideChirpParams) error {
+	_, err := s.db.ExecContext(ctx, hideChirp, arg.ChirpID, arg.ActionID, s.timestamp())
+	return err
+}
+
+const isChirpHidden = `-- name: IsChirpHidden :one
+SELECT EXISTS (
+    SELECT 1 FROM hidden_chirps
+    WHERE chirp_id = ?1
+)
+`
+
+func (s *Store) IsChirpHidden(ctx context.Context, chirpID uuid.UUID) (bool, error) {
+	row := s.db.QueryRowContext(ctx, isChirpHidden, chirpID)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const isModerator = `-- name: IsModerator :one
+SELECT EXISTS (
+    SELECT 1 FROM moderators
+    WHERE user_id = ?1
+)
+`
+
+func (s *Store) IsModerator(ctx context.Context, userID uuid.UUID) (bool, error) {
+	row := s.db.QueryRowContext(ctx, isModerator, userID)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const isUserLimited = `-- name: IsUserLimited :one
+SELECT EXISTS (
+    SELECT 1 FROM users
+    WHERE id = ?1 AND limited_at IS NOT NULL
+)
+`
+
+func (s *Store) IsUserLimited(ctx context.Context, id uuid.UUID) (bool, error) {
+	row := s.db.QueryRowContext(ctx, isUserLimited, id)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const limitUser = `-- name: LimitUser :exec
+UPDATE users SET limited_at = ?2, updated_at = ?2
+WHERE id = ?1 AND limited_at IS NULL
+`
+
+func (s *Store) LimitUser(ctx context.Context, id uuid.UUID) error {
+	_, err := s.db.ExecContext(ctx, limitUser, id, s.timestamp())
+	return err
+}
+
+const listLimitedUserIDs = `-- name: ListLimitedUserIDs :many
+SELECT id FROM users
+WHERE limited_at IS NOT NULL
+`
+
+func (s *Store) ListLimitedUserIDs(ctx context.Context) ([]uuid.UUID, error) {
+	rows, err := s.db.QueryContext(ctx, listLimitedUserIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listModerationActions = `-- name: ListModerationActions :many
+SELECT id, created_at, moderator_id, action, report_id, appeal_id, user_id, chirp_id, note FROM moderation_actions
+WHERE id < ?1
+ORDER BY id DESC
+LIMIT ?2
+`
+
+func (s *Store) ListModerationActions(ctx context.Context, arg database.ListModerationActionsParams) ([]database.ModerationAction, error) {
+	rows, err := s.db.QueryContext(ctx, listModerationActions, arg.BeforeID, arg.MaxRows)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []database.ModerationAction
+	for rows.Next() {
+		var i database.ModerationAction
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.ModeratorID,
+			&i.Action,
+			&i.ReportID,
+			&i.AppealID,
+			&i.UserID,
+			&i.ChirpID,
+			&i.Note,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listSanctions = `-- name: ListSanctions :many
+SELECT a.id, a.created_at, a.action, a.chirp_id, a.note,
+    r.reason AS reason, p.status AS appeal_status
+FROM moderation_actions a
+LEFT JOIN reports r ON r.id = a.report_id
+LEFT JOIN appeals p ON p.action_id = a.id
+WHERE a.user_id = ?1 AND a.action IN ('hide_chirp', 'suspend_user')
+ORDER BY a.id DESC
+`
+
+// The hide_chirp and suspend_user actions taken against user_id, newest
+// first, with the reason reported and the status of any appeal.
+func (s *Store) ListSanctions(ctx context.Context, userID uuid.UUID) ([]database.ListSanctionsRow, error) {
+	rows, err := s.db.QueryContext(ctx, listSanctions, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []database.ListSanctionsRow
+	for rows.Next() {
+		var i database.ListSanctionsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.Action,
+			&i.ChirpID,
+			&i.Note,
+			&i.Reason,
+			&i.AppealStatus,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const overturnSuspension = `-- name: OverturnSuspension :exec
+UPDATE users SET suspended_at = NULL, suspended_until = NULL, suspension_action_id = NULL, updated_at = ?3
+WHERE id = ?1 AND suspension_action_id = ?2
+`
+
+// Lifts the suspension only if suspension_action_id put it in force, so a
+// newer one stays.
+func (s *Store) OverturnSuspension(ctx context.Context, arg database.OverturnSuspensionParams) error {
+	_, err := s.db.ExecContext(ctx, overturnSuspension, arg.ID, arg.SuspensionActionID, s.timestamp())
+	return err
+}
+
+const removeModerator = `-- name: RemoveModerator :exec
+DELETE FROM moderators
+WHERE user_id = ?1
+`
+
+func (s *Store) RemoveModerator(ctx context.Context, userID uuid.UUID) error {
+	_, err := s.db.ExecContext(ctx, removeModerator, userID)
+	return err
+}
+
+const suspendUser = `-- name: SuspendUser :exec
+UPDATE users SET suspended_at = ?3, updated_at = ?3, suspended_until = ?2, suspension_action_id = ?4
+WHERE id = ?1
+`
+
+// A null suspended_until suspends them until a moderator lifts it.
+func (s *Store) SuspendUser(ctx context.Context, arg database.SuspendUserParams) error {
+	until := arg.SuspendedUntil
+	until.Time = until.Time.UTC()
+	_, err := s.db.ExecContext(ctx, suspendUser, arg.ID, until, s.timestamp(), arg.SuspensionActionID)
+	return err
+}
+
+const unhideChirp = `-- name: UnhideChirp :exec
+DELETE FROM hidden_chirps
+WHERE chirp_id = ?1
+`
+
+func (s *Store) UnhideChirp(ctx context.Context, chirpID uuid.UUID) error {
+	_, err := s.db.ExecContext(ctx, unhideChirp, chirpID)
+	return err
+}
+
+const unlimitUser = `-- name: UnlimitUser :exec
+UPDATE users SET limited_at = NULL, updated_at = ?2
+WHERE id = ?1
+`
+
+func (s *Store) UnlimitUser(ctx context.Context, id uuid.UUID) error {
+	_, err := s.db.ExecContext(ctx, unlimitUser, id, s.timestamp())
+	return err
+}
+
+const unsuspendUser = `-- name: UnsuspendUser :exec
+UPDATE users SET suspended_at = NULL, suspended_until = NULL, suspension_action_id = NULL, updated_at = ?2
+WHERE id = ?1
+`
+
+func (s *Store) UnsuspendUser(ctx context.Context, id uuid.UUID) error {
+	_, err := s.db.ExecContext(ctx, unsuspendUser, id, s.timestamp())
+	return err
+}
@@ -0,0 +1,109 @@
+package sqlite
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"github.com/HemahWeb/chirpy/internal/database"
+)
+
+const listHiddenAuthorIDs = `-- name: ListHiddenAuthorIDs :many
+SELECT blocked_id AS user_id FROM blocks WHERE blocker_id = ?1
+UNION
+SELECT blocker_id FROM blocks WHERE blocked_id = ?1
+UNION
+SELECT muted_id FROM mutes WHERE muter_id = ?1
+`
+
+// The users whose chirps user_id shouldn't see: those they blocked or
+// muted, and those who blocked them.
+func (s *Store) ListHiddenAuthorIDs(ctx context.Context, userID uuid.UUID) ([]uuid.UUID, error) {
+	rows, err := s.db.QueryContext(ctx, listHiddenAuthorIDs, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listMutes = `-- name: ListMutes :many
+SELECT muter_id, muted_id, created_at FROM mutes
+WHERE muter_id = ?1
+ORDER BY created_at DESC, muted_id
+`
+
+func (s *Store) ListMutes(ctx context.Context, muterID uuid.UUID) ([]database.Mute, error) {
+	rows, err := s.db.QueryContext(ctx, listMutes, muterID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []database.Mute
+	for rows.Next() {
+		var i database.Mute
+		if err := rows.Scan(&i.MuterID, &i.MutedID, &i.CreatedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const muteExists = `-- name: MuteExists :one
+SELECT EXISTS (
+    SELECT 1 FROM mutes
+    WHERE muter_id = ?1 AND muted_id = ?2
+)
+`
+
+func (s *Store) MuteExists(ctx context.Context, arg database.MuteExistsParams) (bool, error) {
+	row := s.db.QueryRowContext(ctx, muteExists, arg.MuterID, arg.MutedID)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const muteUser = `-- name: MuteUser :execrows
+INSERT INTO mutes (muter_id, muted_id, created_at)
+VALUES (?1, ?2, ?3)
+ON CONFLICT DO NOTHING
+`
+
+func (s *Store) MuteUser(ctx context.Context, arg database.MuteUserParams) (int64, error) {
+	result, err := s.db.ExecContext(ctx, muteUser, arg.MuterID, arg.MutedID, s.timestamp())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const unmuteUser = `-- name: UnmuteUser :exec
+DELETE FROM mutes
+WHERE muter_id = ?1 AND muted_id = ?2
+`
+
+func (s *Store) UnmuteUser(ctx context.Context, arg database.UnmuteUserParams) error {
+	_, err := s.db.ExecContext(ctx, unmuteUser, arg.MuterID, arg.MutedID, s.timestamp())
+	return err
+}
@@ -0,0 +1,159 @@
+package sqlite
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"github.com/HemahWeb/chirpy/internal/database"
+)
+
+const countUnreadNotifications = `-- name: CountUnreadNotifications :one
+SELECT COUNT(*) FROM notifications
+WHERE user_id = ?1 AND read_at IS NULL
+`
+
+func (s *Store) CountUnreadNotifications(ctx context.Context, userID uuid.UUID) (int64, error) {
+	row := s.db.QueryRowContext(ctx, countUnreadNotifications, userID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const createNotification = `-- name: CreateNotification :one
+INSERT INTO notifications (created_at, user_id, type, actor_id, chirp_id)
+VALUES (?1, ?2, ?3, ?4, ?5)
+RETURNING id, created_at, user_id, type, actor_id, chirp_id, read_at
+`
+
+func (s *Store) CreateNotification(ctx context.Context, arg database.CreateNotificationParams) (database.Notification, error) {
+	row := s.db.QueryRowContext(ctx, createNotification,
+		s.timestamp(),
+		arg.UserID,
+		arg.Type,
+		arg.ActorID,
+		arg.ChirpID,
+	)
+	var i database.Notification
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UserID,
+		&i.Type,
+		&i.ActorID,
+		&i.ChirpID,
+		&i.ReadAt,
+	)
+	return i, err
+}
+
+const listNotificationPreferences = `-- name: ListNotificationPreferences :many
+SELECT user_id, type, enabled FROM notification_preferences
+WHERE user_id = ?1
+ORDER BY type
+`
+
+func (s *Store) ListNotificationPreferences(ctx context.Context, userID uuid.UUID) ([]database.NotificationPreference, error) {
+	rows, err := s.db.QueryContext(ctx, listNotificationPreferences, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []database.NotificationPreference
+	for rows.Next() {
+		var i database.NotificationPreference
+		if err := rows.Scan(&i.UserID, &i.Type, &i.Enabled); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listNotifications = `-- name: ListNotifications :many
+SELECT id, created_at, user_id, type, actor_id, chirp_id, read_at FROM notifications
+WHERE user_id = ?1
+AND id < ?2
+AND (NOT ?3 OR read_at IS NULL)
+ORDER BY id DESC
+LIMIT ?4
+`
+
+func (s *Store) ListNotifications(ctx context.Context, arg database.ListNotificationsParams) ([]database.Notification, error) {
+	rows, err := s.db.QueryContext(ctx, listNotifications,
+		arg.UserID,
+		arg.BeforeID,
+		arg.UnreadOnly,
+		arg.MaxRows,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []database.Notification
+	for rows.Next() {
+		var i database.Notification
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UserID,
+			&i.Type,
+			&i.ActorID,
+			&i.ChirpID,
+			&i.ReadAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markAllNotificationsRead = `-- name: MarkAllNotificationsRead :execrows
+UPDATE notifications SET read_at = ?2
+WHERE user_id = ?1 AND read_at IS NULL
+`
+
+func (s *Store) MarkAllNotificationsRead(ctx context.Context, userID uuid.UUID) (int64, error) {
+	result, err := s.db.ExecContext(ctx, markAllNotificationsRead, userID, s.timestamp())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const markNotificationRead = `-- name: MarkNotificationRead :execrows
+UPDATE notifications SET read_at = COALESCE(read_at, ?3)
+WHERE id = ?1 AND user_id = ?2
+`
+
+func (s *Store) MarkNotificationRead(ctx context.Context, arg database.MarkNotificationReadParams) (int64, error) {
+	result, err := s.db.ExecContext(ctx, markNotificationRead, arg.ID, arg.UserID, s.timestamp())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const setNotificationPreference = `-- name: SetNotificationPreference :exec
+INSERT INTO notification_preferences (user_id, type, enabled)
+VALUES (?1, ?2, ?3)
+ON CONFLICT (user_id, type) DO UPDATE SET enabled = excluded.enabled
+`
+
+func (s *Store) SetNotificationPreference(ctx context.Context, arg database.SetNotificationPreferenceParams) error {
+	_, err := s.db.ExecContext(ctx, setNotificationPreference, arg.UserID, arg.Type, arg.Enabled)
+	return err
+}
@@ -0,0 +1,53 @@
+package sqlite
+
+import (
+	"context"
+	"time"
+
+	"github.com/HemahWeb/chirpy/internal/database"
+)
+
+const deleteIdleRateLimitBuckets = `-- name: DeleteIdleRateLimitBuckets :execrows
+DELETE FROM rate_limit_buckets
+WHERE updated_at < ?1
+`
+
+func (s *Store) DeleteIdleRateLimitBuckets(ctx context.Context, idleSeconds float64) (int64, error) {
+	result, err := s.db.ExecContext(ctx, deleteIdleRateLimitBuckets, unixSeconds(s.now())-idleSeconds)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const takeRateLimitToken = `-- name: TakeRateLimitToken :one
+INSERT INTO rate_limit_buckets (key, tokens, allowed, updated_at)
+VALUES (?1, ?2 - 1, TRUE, ?4)
+ON CONFLICT (key) DO UPDATE SET (tokens, allowed, updated_at) = (
+    SELECT
+        CASE WHEN refilled >= 1 THEN refilled - 1 ELSE refilled END,
+        refilled >= 1,
+        ?4
+    FROM (
+        SELECT MIN(
+            ?2,
+            rate_limit_buckets.tokens + ?3 * MAX(?4 - rate_limit_buckets.updated_at, 0)
+        ) AS refilled
+    )
+)
+RETURNING tokens, allowed
+`
+
+// TakeRateLimitToken refills the bucket for the time since it was last
+// used, then takes a token if a whole one is available. SQLite serializes
+// writes, so two requests can't both take the last token.
+func (s *Store) TakeRateLimitToken(ctx context.Context, arg database.TakeRateLimitTokenParams) (database.TakeRateLimitTokenRow, error) {
+	row := s.db.QueryRowContext(ctx, takeRateLimitToken, arg.Key, arg.Burst, arg.Rate, unixSeconds(s.now()))
+	var i database.TakeRateLimitTokenRow
+	err := row.Scan(&i.Tokens, &i.Allowed)
+	return i, err
+}
+
+func unixSeconds(t time.Time) float64 {
+	return float64(t.UnixNano()) / float64(time.Second)
+}
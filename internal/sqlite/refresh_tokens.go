@@ -0,0 +1,74 @@
+package sqlite
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"github.com/HemahWeb/chirpy/internal/database"
+)
+
+const createRefreshToken = `-- name: CreateRefreshToken :one
+INSERT INTO refresh_tokens (token, created_at, updated_at, user_id, expires_at)
+VALUES (?1, ?2, ?2, ?3, ?4)
+RETURNING token
+`
+
+func (s *Store) CreateRefreshToken(ctx context.Context, userID uuid.UUID) (string, error) {
+	now := s.timestamp()
+	row := s.db.QueryRowContext(ctx, createRefreshToken, newToken(), now, userID, now.Add(refreshTokenLifetime))
+	var token string
+	err := row.Scan(&token)
+	return token, err
+}
+
+const deleteStaleRefreshTokens = `-- name: DeleteStaleRefreshTokens :execrows
+DELETE FROM refresh_tokens
+WHERE token IN (
+    SELECT token FROM refresh_tokens
+    WHERE expires_at < ?1 OR revoked_at < ?1
+    LIMIT ?2
+)
+`
+
+func (s *Store) DeleteStaleRefreshTokens(ctx context.Context, arg database.DeleteStaleRefreshTokensParams) (int64, error) {
+	result, err := s.db.ExecContext(ctx, deleteStaleRefreshTokens, arg.Cutoff.UTC(), arg.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getUserIDFromRefreshToken = `-- name: GetUserIDFromRefreshToken :one
+SELECT user_id, expires_at, revoked_at FROM refresh_tokens WHERE token = ?1
+`
+
+func (s *Store) GetUserIDFromRefreshToken(ctx context.Context, token string) (database.GetUserIDFromRefreshTokenRow, error) {
+	row := s.db.QueryRowContext(ctx, getUserIDFromRefreshToken, token)
+	var i database.GetUserIDFromRefreshTokenRow
+	err := row.Scan(&i.UserID, &i.ExpiresAt, &i.RevokedAt)
+	return i, err
+}
+
+const revokeRefreshToken = `-- name: RevokeRefreshToken :exec
+UPDATE refresh_tokens SET revoked_at = ?2, updated_at = ?2
+WHERE token = ?1
+`
+
+func (s *Store) RevokeRefreshToken(ctx context.Context, token string) error {
+	_, err := s.db.ExecContext(ctx, revokeRefreshToken, token, s.timestamp())
+	return err
+}
+
+const revokeUserRefreshTokens = `-- name: RevokeUserRefreshTokens :execrows
+UPDATE refresh_tokens SET revoked_at = ?2, updated_at = ?2
+WHERE user_id = ?1 AND revoked_at IS NULL
+`
+
+func (s *Store) RevokeUserRefreshTokens(ctx context.Context, userID uuid.UUID) (int64, error) {
+	result, err := s.db.ExecContext(ctx, revokeUserRefreshTokens, userID, s.timestamp())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,176 @@
+package sqlite
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"github.com/HemahWeb/chirpy/internal/database"
+)
+
+const claimReport = `-- name: ClaimReport :execrows
+UPDATE reports
+SET status = 'claimed', claimed_by = ?2, claimed_at = ?3
+WHERE id = ?1 AND status = 'open'
+`
+
+func (s *Store) ClaimReport(ctx context.Context, arg database.ClaimReportParams) (int64, error) {
+	result, err := s.db.ExecContext(ctx, claimReport, arg.ID, arg.ClaimedBy, s.timestamp())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const createReport = `-- name: CreateReport :one
+INSERT INTO reports (reporter_id, user_id, chirp_id, reason, details, created_at)
+VALUES (?1, ?2, ?3, ?4, ?5, ?6)
+RETURNING id, created_at, reporter_id, user_id, chirp_id, reason, details, status, claimed_by, claimed_at, resolution, resolved_at
+`
+
+func (s *Store) CreateReport(ctx context.Context, arg database.CreateReportParams) (database.Report, error) {
+	row := s.db.QueryRowContext(ctx, createReport,
+		arg.ReporterID,
+		arg.UserID,
+		arg.ChirpID,
+		arg.Reason,
+		arg.Details,
+		s.timestamp(),
+	)
+	var i database.Report
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.ReporterID,
+		&i.UserID,
+		&i.ChirpID,
+		&i.Reason,
+		&i.Details,
+		&i.Status,
+		&i.ClaimedBy,
+		&i.ClaimedAt,
+		&i.Resolution,
+		&i.ResolvedAt,
+	)
+	return i, err
+}
+
+const getReport = `-- name: GetReport :one
+SELECT id, created_at, reporter_id, user_id, chirp_id, reason, details, status, claimed_by, claimed_at, resolution, resolved_at FROM reports
+WHERE id = ?1
+`
+
+func (s *Store) GetReport(ctx context.Context, id int64) (database.Report, error) {
+	row := s.db.QueryRowContext(ctx, getReport, id)
+	var i database.Report
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.ReporterID,
+		&i.UserID,
+		&i.ChirpID,
+		&i.Reason,
+		&i.Details,
+		&i.Status,
+		&i.ClaimedBy,
+		&i.ClaimedAt,
+		&i.Resolution,
+		&i.ResolvedAt,
+	)
+	return i, err
+}
+
+const isChirpUnderReview = `-- name: IsChirpUnderReview :one
+SELECT EXISTS (
+    SELECT 1 FROM reports
+    WHERE chirp_id = ?1 AND status <> 'resolved'
+)
+`
+
+// Whether a report about chirp_id is waiting for a moderator.
+func (s *Store) IsChirpUnderReview(ctx context.Context, chirpID uuid.NullUUID) (bool, error) {
+	row := s.db.QueryRowContext(ctx, isChirpUnderReview, chirpID)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const listReports = `-- name: ListReports :many
+SELECT id, created_at, reporter_id, user_id, chirp_id, reason, details, status, claimed_by, claimed_at, resolution, resolved_at FROM reports
+WHERE status = ?1 AND id > ?2
+ORDER BY id
+LIMIT ?3
+`
+
+func (s *Store) ListReports(ctx context.Context, arg database.ListReportsParams) ([]database.Report, error) {
+	rows, err := s.db.QueryContext(ctx, listReports, arg.Status, arg.AfterID, arg.MaxRows)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []database.Report
+	for rows.Next() {
+		var i database.Report
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.ReporterID,
+			&i.UserID,
+			&i.ChirpID,
+			&i.Reason,
+			&i.Details,
+			&i.Status,
+			&i.ClaimedBy,
+			&i.ClaimedAt,
+			&i.Resolution,
+			&i.ResolvedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const openReportExists = `-- name: OpenReportExists :one
+SELECT EXISTS (
+    SELECT 1 FROM reports
+    WHERE reporter_id = ?1 AND user_id = ?2 AND chirp_id IS ?3
+    AND status <> 'resolved'
+)
+`
+
+// Whether reporter_id already has an unresolved report about the same user
+// or chirp.
+func (s *Store) OpenReportExists(ctx context.Context, arg database.OpenReportExistsParams) (bool, error) {
+	row := s.db.QueryRowContext(ctx, openReportExists, arg.ReporterID, arg.UserID, arg.ChirpID)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const resolveReport = `-- name: ResolveReport :execrows
+UPDATE reports
+SET status = 'resolved', resolution = ?3, resolved_at = ?4
+WHERE id = ?1 AND status = 'claimed' AND claimed_by = ?2
+`
+
+// Only the moderator who claimed the report can resolve it.
+func (s *Store) ResolveReport(ctx context.Context, arg database.ResolveReportParams) (int64, error) {
+	result, err := s.db.ExecContext(ctx, resolveReport,
+		arg.ID,
+		arg.ClaimedBy,
+		arg.Resolution,
+		s.timestamp(),
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
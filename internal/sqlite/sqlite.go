@@ -0,0 +1,106 @@
+// Package sqlite is a database.Querier backed by SQLite, for running Chirpy
+// without a Postgres server. It runs the same queries as the sqlc-generated
+// Postgres code, but generates ids, refresh tokens and timestamps in Go
+// because SQLite has no gen_random_uuid() or pgcrypto.
+package sqlite
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+
+	"github.com/HemahWeb/chirpy/internal/database"
+	"github.com/HemahWeb/chirpy/internal/dburl"
+)
+
+// defaultParams are added to the DSN unless it sets them: cascading deletes
+// need foreign keys, WAL lets readers run alongside the writer, and
+// immediate transactions wait for the write lock up front instead of failing
+// with SQLITE_BUSY when they try to upgrade.
+var defaultParams = map[string]string{
+	"_foreign_keys": "on",
+	"_busy_timeout": "5000",
+	"_journal_mode": "WAL",
+	"_txlock":       "immediate",
+}
+
+// Open opens the database at dsn, a go-sqlite3 file: DSN as returned by
+// dburl.Parse.
+func Open(dsn string) (*sql.DB, error) {
+	path, query, _ := strings.Cut(dsn, "?")
+	params, err := url.ParseQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range defaultParams {
+		if !params.Has(k) {
+			params.Set(k, v)
+		}
+	}
+	return sql.Open(string(dburl.SQLite), path+"?"+params.Encode())
+}
+
+const refreshTokenLifetime = 60 * 24 * time.Hour
+
+type Store struct {
+	db  database.DBTX
+	now func() time.Time
+}
+
+var _ database.Querier = (*Store)(nil)
+
+func New(db database.DBTX) *Store {
+	return &Store{db: db, now: time.Now}
+}
+
+// SetClock replaces the clock used for timestamps and expiry.
+func (s *Store) SetClock(now func() time.Time) {
+	s.now = now
+}
+
+// timestamp is the current time in UTC; go-sqlite3 stores times as text in
+// their own zone, so mixing zones would break ordering and comparisons.
+func (s *Store) timestamp() time.Time {
+	return s.now().UTC()
+}
+
+func newToken() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// convertError turns SQLite unique violations into
+// database.UniqueViolationError, naming the constraint the way Postgres
+// does, e.g. "UNIQUE constraint failed: users.email" becomes users_email_key.
+func convertError(err error) error {
+	var sqliteErr sqlite3.Error
+	if !errors.As(err, &sqliteErr) || sqliteErr.ExtendedCode != sqlite3.ErrConstraintUnique {
+		return err
+	}
+	_, columns, ok := strings.Cut(sqliteErr.Error(), "UNIQUE constraint failed: ")
+	if !ok {
+		return &database.UniqueViolationError{}
+	}
+	// a composite key lists every column: "t.a, t.b" becomes t_a_b_key
+	table, _, _ := strings.Cut(columns, ".")
+	name := []string{table}
+	for _, col := range strings.Split(columns, ", ") {
+		name = append(name, strings.TrimPrefix(col, table+"."))
+	}
+	return &database.UniqueViolationError{Constraint: strings.Join(name, "_") + "_key"}
+}
+
+// Busy reports whether err means the database was locked by another
+// connection for longer than the busy timeout. The transaction that got it
+// can be retried.
+func Busy(err error) bool {
+	var sqliteErr sqlite3.Error
+	return errors.As(err, &sqliteErr) && (sqliteErr.Code == sqlite3.ErrBusy || sqliteErr.Code == sqlite3.ErrLocked)
+}
@@ -0,0 +1,177 @@
+package sqlite
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/HemahWeb/chirpy/internal/database"
+	"github.com/HemahWeb/chirpy/internal/dburl"
+	"github.com/HemahWeb/chirpy/internal/migrate"
+)
+
+func newTestStore(t *testing.T) (*Store, *time.Time) {
+	t.Helper()
+	db, err := Open("file:" + filepath.Join(t.TempDir(), "chirpy.db"))
+	if err != nil {
+		t.Fatalf("Open() failed: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	m, err := migrate.New(db, dburl.SQLite)
+	if err != nil {
+		t.Fatalf("migrate.New() failed: %v", err)
+	}
+	if _, err := m.Up(context.Background()); err != nil {
+		t.Fatalf("Up() failed: %v", err)
+	}
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.FixedZone("CET", 3600))
+	s := New(db)
+	s.SetClock(func() time.Time { return now })
+	return s, &now
+}
+
+func TestUniqueViolation(t *testing.T) {
+	s, _ := newTestStore(t)
+	ctx := context.Background()
+
+	if _, err := s.CreateUser(ctx, database.CreateUserParams{Email: "walt@example.com"}); err != nil {
+		t.Fatalf("CreateUser() failed: %v", err)
+	}
+	_, err := s.CreateUser(ctx, database.CreateUserParams{Email: "walt@example.com"})
+	if constraint, ok := database.UniqueViolation(err); !ok || constraint != "users_email_key" {
+		t.Errorf("CreateUser() error = %v, want a users_email_key violation", err)
+	}
+}
+
+func TestTimestampsAreUTC(t *testing.T) {
+	s, now := newTestStore(t)
+	ctx := context.Background()
+
+	user, err := s.CreateUser(ctx, database.CreateUserParams{Email: "jesse@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser() failed: %v", err)
+	}
+	if !user.CreatedAt.Equal(*now) || user.CreatedAt.Location() != time.UTC {
+		t.Errorf("CreatedAt = %v, want %v in UTC", user.CreatedAt, *now)
+	}
+
+	token, _ := s.CreateRefreshToken(ctx, user.ID)
+	s.RevokeRefreshToken(ctx, token)
+	removed, err := s.DeleteStaleRefreshTokens(ctx, database.DeleteStaleRefreshTokensParams{
+		Cutoff:    now.Add(time.Minute).In(time.FixedZone("PST", -8*3600)),
+		BatchSize: 10,
+	})
+	if err != nil || removed != 1 {
+		t.Errorf("DeleteStaleRefreshTokens() = %d, %v; want the revoked token removed whatever the cutoff's zone", removed, err)
+	}
+}
+
+func TestTakeRateLimitToken(t *testing.T) {
+	s, now := newTestStore(t)
+	ctx := context.Background()
+	take := func() database.TakeRateLimitTokenRow {
+		t.Helper()
+		row, err := s.TakeRateLimitToken(ctx, database.TakeRateLimitTokenParams{Key: "k", Burst: 2, Rate: 1})
+		if err != nil {
+			t.Fatalf("TakeRateLimitToken() failed: %v", err)
+		}
+		return row
+	}
+
+	if row := take(); !row.Allowed || row.Tokens != 1 {
+		t.Errorf("first take = %+v, want allowed with 1 left", row)
+	}
+	take()
+	if row := take(); row.Allowed || row.Tokens != 0 {
+		t.Errorf("third take = %+v, want denied", row)
+	}
+
+	*now = now.Add(1500 * time.Millisecond)
+	if row := take(); !row.Allowed || row.Tokens != 0.5 {
+		t.Errorf("take after 1.5s = %+v, want allowed with half a token left", row)
+	}
+
+	*now = now.Add(time.Hour)
+	if removed, err := s.DeleteIdleRateLimitBuckets(ctx, 60); err != nil || removed != 1 {
+		t.Errorf("DeleteIdleRateLimitBuckets() = %d, %v; want the idle bucket removed", removed, err)
+	}
+}
+
+func TestModerationActionsAppendOnly(t *testing.T) {
+	s, _ := newTestStore(t)
+	ctx := context.Background()
+
+	user, _ := s.CreateUser(ctx, database.CreateUserParams{Email: "walt@example.com"})
+	action, err := s.CreateModerationAction(ctx, database.CreateModerationActionParams{
+		ModeratorID: user.ID,
+		Action:      "suspend_user",
+		UserID:      user.ID,
+	})
+	if err != nil {
+		t.Fatalf("CreateModerationAction() failed: %v", err)
+	}
+	if _, err := s.db.ExecContext(ctx, "UPDATE moderation_actions SET note = 'edited' WHERE id = ?", action.ID); err == nil {
+		t.Error("updating a moderation action succeeded")
+	}
+	if _, err := s.db.ExecContext(ctx, "DELETE FROM moderation_actions WHERE id = ?", action.ID); err == nil {
+		t.Error("deleting a moderation action succeeded")
+	}
+
+	// a sanction can be appealed once
+	arg := database.CreateAppealParams{ActionID: action.ID, UserID: user.ID, Body: "Please"}
+	if _, err := s.CreateAppeal(ctx, arg); err != nil {
+		t.Fatalf("CreateAppeal() failed: %v", err)
+	}
+	_, err = s.CreateAppeal(ctx, arg)
+	if constraint, ok := database.UniqueViolation(err); !ok || constraint != "appeals_action_id_key" {
+		t.Errorf("CreateAppeal() again error = %v, want an appeals_action_id_key violation", err)
+	}
+}
+
+func TestAttachments(t *testing.T) {
+	s, now := newTestStore(t)
+	ctx := context.Background()
+
+	user, _ := s.CreateUser(ctx, database.CreateUserParams{Email: "walt@example.com"})
+	chirp, _ := s.CreateChirp(ctx, database.CreateChirpParams{Body: "Say my name", UserID: user.ID})
+	other, _ := s.CreateChirp(ctx, database.CreateChirpParams{Body: "Heisenberg", UserID: user.ID})
+
+	var ids []uuid.UUID
+	for range 3 {
+		a, err := s.CreateAttachment(ctx, database.CreateAttachmentParams{ContentType: "image/png", SizeBytes: 100, Width: 10, Height: 10})
+		if err != nil {
+			t.Fatalf("CreateAttachment() failed: %v", err)
+		}
+		ids = append(ids, a.ID)
+	}
+	// attached in reverse, to check they come back by position
+	for i, id := range ids[:2] {
+		err := s.AttachToChirp(ctx, database.AttachToChirpParams{ID: id, ChirpID: uuid.NullUUID{UUID: chirp.ID, Valid: true}, Position: int32(1 - i)})
+		if err != nil {
+			t.Fatalf("AttachToChirp() failed: %v", err)
+		}
+	}
+
+	got, err := s.ListAttachmentsByChirpIDs(ctx, []uuid.UUID{chirp.ID, other.ID})
+	if err != nil || len(got) != 2 || got[0].ID != ids[1] || got[1].ID != ids[0] {
+		t.Errorf("ListAttachmentsByChirpIDs() = %v, %v; want the first two attachments by position", got, err)
+	}
+
+	// the third never made it onto a chirp; purging the chirp orphans the rest
+	if err := s.PurgeChirp(ctx, chirp.ID); err != nil {
+		t.Fatalf("PurgeChirp() failed: %v", err)
+	}
+	orphans, err := s.ListOrphanedAttachments(ctx, database.ListOrphanedAttachmentsParams{Cutoff: now.Add(time.Minute), BatchSize: 10})
+	if err != nil || len(orphans) != 3 {
+		t.Errorf("ListOrphanedAttachments() = %d attachments, %v; want 3", len(orphans), err)
+	}
+	orphans, _ = s.ListOrphanedAttachments(ctx, database.ListOrphanedAttachmentsParams{Cutoff: *now, BatchSize: 10})
+	if len(orphans) != 0 {
+		t.Errorf("ListOrphanedAttachments() before they were made = %d attachments, want 0", len(orphans))
+	}
+}
@@ -0,0 +1,31 @@
+package sqlite
+
+import (
+	"context"
+	"time"
+
+	"github.com/HemahWeb/chirpy/internal/database"
+)
+
+const getStats = `-- name: GetStats :one
+SELECT
+    (SELECT count(*) FROM users) AS users,
+    (SELECT count(*) FROM users WHERE is_chirpy_red) AS chirpy_red_users,
+    (SELECT count(*) FROM chirps) AS chirps,
+    (SELECT count(*) FROM refresh_tokens
+        WHERE revoked_at IS NULL AND expires_at > ?1) AS active_refresh_tokens,
+    (SELECT count(*) FROM webhook_events) AS webhook_events
+`
+
+func (s *Store) GetStats(ctx context.Context, now time.Time) (database.GetStatsRow, error) {
+	row := s.db.QueryRowContext(ctx, getStats, now.UTC())
+	var i database.GetStatsRow
+	err := row.Scan(
+		&i.Users,
+		&i.ChirpyRedUsers,
+		&i.Chirps,
+		&i.ActiveRefreshTokens,
+		&i.WebhookEvents,
+	)
+	return i, err
+}
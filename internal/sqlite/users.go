@@ -0,0 +1,138 @@
+package sqlite
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"github.com/HemahWeb/chirpy/internal/database"
+)
+
+const createUser = `-- name: CreateUser :one
+INSERT INTO users (id, created_at, updated_at, email, hashed_password)
+VALUES (?1, ?2, ?2, ?3, ?4)
+RETURNING id, created_at, updated_at, email, is_chirpy_red
+`
+
+func (s *Store) CreateUser(ctx context.Context, arg database.CreateUserParams) (database.CreateUserRow, error) {
+	row := s.db.QueryRowContext(ctx, createUser, uuid.New(), s.timestamp(), arg.Email, arg.HashedPassword)
+	var i database.CreateUserRow
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.IsChirpyRed,
+	)
+	return i, convertError(err)
+}
+
+const deleteUser = `-- name: DeleteUser :exec
+DELETE FROM users WHERE id = ?1
+`
+
+func (s *Store) DeleteUser(ctx context.Context, id uuid.UUID) error {
+	_, err := s.db.ExecContext(ctx, deleteUser, id)
+	return err
+}
+
+const getUserByEmail = `-- name: GetUserByEmail :one
+SELECT id, created_at, updated_at, email, is_chirpy_red
+FROM users WHERE email = ?1
+LIMIT 1
+`
+
+func (s *Store) GetUserByEmail(ctx context.Context, email string) (database.GetUserByEmailRow, error) {
+	row := s.db.QueryRowContext(ctx, getUserByEmail, email)
+	var i database.GetUserByEmailRow
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.IsChirpyRed,
+	)
+	return i, err
+}
+
+const getUserByEmailForAuth = `-- name: GetUserByEmailForAuth :one
+SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red, suspended_at, suspended_until, limited_at, suspension_action_id FROM users WHERE email = ?1 LIMIT 1
+`
+
+func (s *Store) GetUserByEmailForAuth(ctx context.Context, email string) (database.User, error) {
+	row := s.db.QueryRowContext(ctx, getUserByEmailForAuth, email)
+	var i database.User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.SuspendedAt,
+		&i.SuspendedUntil,
+		&i.LimitedAt,
+		&i.SuspensionActionID,
+	)
+	return i, err
+}
+
+const getUserByID = `-- name: GetUserByID :one
+SELECT id, created_at, updated_at, email, is_chirpy_red
+FROM users WHERE id = ?1
+LIMIT 1
+`
+
+func (s *Store) GetUserByID(ctx context.Context, id uuid.UUID) (database.GetUserByIDRow, error) {
+	row := s.db.QueryRowContext(ctx, getUserByID, id)
+	var i database.GetUserByIDRow
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.IsChirpyRed,
+	)
+	return i, err
+}
+
+const resetUsers = `-- name: ResetUsers :exec
+DELETE FROM users
+`
+
+func (s *Store) ResetUsers(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, resetUsers)
+	return err
+}
+
+const updateUserEmailAndPassword = `-- name: UpdateUserEmailAndPassword :one
+UPDATE users SET email = ?2, hashed_password = ?3, updated_at = ?4 WHERE id = ?1
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, suspended_at, suspended_until, limited_at, suspension_action_id
+`
+
+func (s *Store) UpdateUserEmailAndPassword(ctx context.Context, arg database.UpdateUserEmailAndPasswordParams) (database.User, error) {
+	row := s.db.QueryRowContext(ctx, updateUserEmailAndPassword, arg.ID, arg.Email, arg.HashedPassword, s.timestamp())
+	var i database.User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.SuspendedAt,
+		&i.SuspendedUntil,
+		&i.LimitedAt,
+		&i.SuspensionActionID,
+	)
+	return i, convertError(err)
+}
+
+const upgradeUserToChirpyRed = `-- name: UpgradeUserToChirpyRed :exec
+UPDATE users SET is_chirpy_red = TRUE, updated_at = ?2 WHERE id = ?1
+`
+
+func (s *Store) UpgradeUserToChirpyRed(ctx context.Context, id uuid.UUID) error {
+	_, err := s.db.ExecContext(ctx, upgradeUserToChirpyRed, id, s.timestamp())
+	return err
+}
@@ -0,0 +1,74 @@
+package sqlite
+
+import (
+	"context"
+
+	"github.com/HemahWeb/chirpy/internal/database"
+)
+
+const createWebhookEvent = `-- name: CreateWebhookEvent :exec
+INSERT INTO webhook_events (received_at, source, event, user_id, outcome)
+VALUES (?1, ?2, ?3, ?4, ?5)
+`
+
+func (s *Store) CreateWebhookEvent(ctx context.Context, arg database.CreateWebhookEventParams) error {
+	_, err := s.db.ExecContext(ctx, createWebhookEvent,
+		s.timestamp(),
+		arg.Source,
+		arg.Event,
+		arg.UserID,
+		arg.Outcome,
+	)
+	return err
+}
+
+const listRecentWebhookEvents = `-- name: ListRecentWebhookEvents :many
+SELECT id, received_at, source, event, user_id, outcome FROM webhook_events
+ORDER BY id DESC
+LIMIT ?1
+`
+
+func (s *Store) ListRecentWebhookEvents(ctx context.Context, limit int32) ([]database.WebhookEvent, error) {
+	return s.queryWebhookEvents(ctx, listRecentWebhookEvents, limit)
+}
+
+const listWebhookEvents = `-- name: ListWebhookEvents :many
+SELECT id, received_at, source, event, user_id, outcome FROM webhook_events
+WHERE id > ?1
+ORDER BY id
+LIMIT ?2
+`
+
+func (s *Store) ListWebhookEvents(ctx context.Context, arg database.ListWebhookEventsParams) ([]database.WebhookEvent, error) {
+	return s.queryWebhookEvents(ctx, listWebhookEvents, arg.AfterID, arg.MaxRows)
+}
+
+func (s *Store) queryWebhookEvents(ctx context.Context, query string, args ...any) ([]database.WebhookEvent, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []database.WebhookEvent
+	for rows.Next() {
+		var i database.WebhookEvent
+		if err := rows.Scan(
+			&i.ID,
+			&i.ReceivedAt,
+			&i.Source,
+			&i.Event,
+			&i.UserID,
+			&i.Outcome,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -0,0 +1,105 @@
+package tracing
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.41.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/HemahWeb/chirpy/internal/database"
+	"github.com/HemahWeb/chirpy/internal/dburl"
+)
+
+// DB wraps a database.DBTX so every query sqlc runs gets a client span.
+type DB struct {
+	db     database.DBTX
+	system attribute.KeyValue
+	tracer trace.Tracer
+}
+
+// WrapDB traces db, a connection to a dialect database, with the global
+// tracer provider.
+func WrapDB(db database.DBTX, dialect dburl.Dialect) *DB {
+	return WrapDBWithProvider(db, dialect, otel.GetTracerProvider())
+}
+
+func WrapDBWithProvider(db database.DBTX, dialect dburl.Dialect, tp trace.TracerProvider) *DB {
+	system := semconv.DBSystemNamePostgreSQL
+	if dialect == dburl.SQLite {
+		system = semconv.DBSystemNameSQLite
+	}
+	return &DB{db: db, system: system, tracer: tp.Tracer(instrumentationName)}
+}
+
+func (d *DB) start(ctx context.Context, query string) (context.Context, trace.Span) {
+	name := queryName(query)
+	attrs := []attribute.KeyValue{
+		d.system,
+		semconv.DBQueryText(query),
+	}
+	if name != "" {
+		attrs = append(attrs, semconv.DBOperationName(name))
+	} else {
+		name = "db.query"
+	}
+	return d.tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+}
+
+func end(span trace.Span, err error) {
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+func (d *DB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	ctx, span := d.start(ctx, query)
+	res, err := d.db.ExecContext(ctx, query, args...)
+	end(span, err)
+	return res, err
+}
+
+func (d *DB) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	ctx, span := d.start(ctx, query)
+	stmt, err := d.db.PrepareContext(ctx, query)
+	end(span, err)
+	return stmt, err
+}
+
+func (d *DB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	ctx, span := d.start(ctx, query)
+	rows, err := d.db.QueryContext(ctx, query, args...)
+	end(span, err)
+	return rows, err
+}
+
+// QueryRowContext's span only covers running the query; errors surface
+// from Scan, after the span has ended.
+func (d *DB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	ctx, span := d.start(ctx, query)
+	row := d.db.QueryRowContext(ctx, query, args...)
+	end(span, row.Err())
+	return row
+}
+
+// queryName pulls CreateChirp out of sqlc's "-- name: CreateChirp :one"
+// header, or returns "" for hand-written SQL.
+func queryName(query string) string {
+	line, _, _ := strings.Cut(query, "\n")
+	rest, ok := strings.CutPrefix(strings.TrimSpace(line), "-- name:")
+	if !ok {
+		return ""
+	}
+	fields := strings.Fields(rest)
+	if len(fields) == 0 {
+		return ""
+	}
+	return fields[0]
+}
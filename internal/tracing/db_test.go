@@ -0,0 +1,102 @@
+package tracing
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/HemahWeb/chirpy/internal/dburl"
+)
+
+type fakeDB struct {
+	err     error
+	sawSpan bool
+}
+
+func (f *fakeDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	f.sawSpan = trace.SpanContextFromContext(ctx).IsValid()
+	return nil, f.err
+}
+
+func (f *fakeDB) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return nil, f.err
+}
+
+func (f *fakeDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return nil, f.err
+}
+
+func (f *fakeDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return &sql.Row{}
+}
+
+func TestQueryName(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"-- name: CreateChirp :one\nINSERT INTO chirps ...", "CreateChirp"},
+		{"  -- name: DeleteStaleRefreshTokens :execrows\nDELETE ...", "DeleteStaleRefreshTokens"},
+		{"SELECT 1", ""},
+		{"-- name:\nSELECT 1", ""},
+	}
+	for _, tt := range tests {
+		if got := queryName(tt.query); got != tt.want {
+			t.Errorf("queryName(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestWrapDB(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	fake := &fakeDB{}
+	db := WrapDBWithProvider(fake, dburl.Postgres, tp)
+
+	const query = "-- name: RevokeRefreshToken :exec\nUPDATE refresh_tokens SET revoked_at = NOW() WHERE token = $1\n"
+	if _, err := db.ExecContext(context.Background(), query, "token"); err != nil {
+		t.Fatalf("ExecContext() failed: %v", err)
+	}
+	if !fake.sawSpan {
+		t.Error("the wrapped DBTX should receive the span in its context")
+	}
+
+	fake.err = errors.New("connection refused")
+	if _, err := db.QueryContext(context.Background(), "SELECT 1"); err == nil {
+		t.Fatal("QueryContext() should pass the error through")
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans, want 2", len(spans))
+	}
+
+	exec := spans[0]
+	if exec.Name() != "RevokeRefreshToken" {
+		t.Errorf("span name = %q, want RevokeRefreshToken", exec.Name())
+	}
+	if exec.SpanKind() != trace.SpanKindClient {
+		t.Errorf("span kind = %v, want client", exec.SpanKind())
+	}
+	attrs := map[string]string{}
+	for _, kv := range exec.Attributes() {
+		attrs[string(kv.Key)] = kv.Value.Emit()
+	}
+	if attrs["db.system.name"] != "postgresql" || attrs["db.operation.name"] != "RevokeRefreshToken" || attrs["db.query.text"] != query {
+		t.Errorf("unexpected attributes: %v", attrs)
+	}
+
+	failed := spans[1]
+	if failed.Name() != "db.query" {
+		t.Errorf("span name = %q, want db.query for unnamed SQL", failed.Name())
+	}
+	if failed.Status().Code != codes.Error {
+		t.Errorf("span status = %v, want error", failed.Status())
+	}
+}
@@ -0,0 +1,27 @@
+package tracing
+
+import (
+	"net/http"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/HemahWeb/chirpy/internal/middleware"
+)
+
+// Handler starts a server span for every request, continuing any trace
+// passed in the traceparent header. Spans are named after the route
+// pattern rather than the raw path.
+func Handler(mux *http.ServeMux, next http.Handler) http.Handler {
+	return otelhttp.NewHandler(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			trace.SpanFromContext(r.Context()).SetAttributes(attribute.String("http.route", middleware.Route(mux, r)))
+			next.ServeHTTP(w, r)
+		}),
+		"http.server",
+		otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+			return middleware.Route(mux, r)
+		}),
+	)
+}
@@ -0,0 +1,34 @@
+package tracing
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// Streaming handlers need to flush and lift the server's write deadline
+// through the otelhttp wrapper.
+func TestHandlerKeepsResponseControl(t *testing.T) {
+	var deadlineErr, flushErr error
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /stream", func(w http.ResponseWriter, r *http.Request) {
+		rc := http.NewResponseController(w)
+		deadlineErr = rc.SetWriteDeadline(time.Time{})
+		flushErr = rc.Flush()
+	})
+	server := httptest.NewServer(Handler(mux, mux))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/stream")
+	if err != nil {
+		t.Fatalf("GET failed: %v", err)
+	}
+	resp.Body.Close()
+	if deadlineErr != nil {
+		t.Errorf("SetWriteDeadline() failed: %v", deadlineErr)
+	}
+	if flushErr != nil {
+		t.Errorf("Flush() failed: %v", flushErr)
+	}
+}
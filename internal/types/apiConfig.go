@@ -2,14 +2,27 @@ package types
 
 import (
 	"sync/atomic"
+	"time"
 
-	"github.com/HemahWeb/chirpy/internal/database"
+	"github.com/HemahWeb/chirpy/internal/cleanup"
+	"github.com/HemahWeb/chirpy/internal/events"
+	"github.com/HemahWeb/chirpy/internal/health"
+	"github.com/HemahWeb/chirpy/internal/metrics"
+	"github.com/HemahWeb/chirpy/internal/service"
 )
 
 type ApiConfig struct {
-	FileserverHits atomic.Int32
-	DB             *database.Queries
-	Platform       string
-	JWTSecret      string
-	PolkaKey       string
+	Service      *service.Service
+	Platform     string
+	JWTSecret    string
+	PolkaKey     string
+	MetricsToken string
+	TokenCleaner *cleanup.RefreshTokenCleaner
+	TrashPurger  *cleanup.ChirpTrashPurger
+	ShuttingDown atomic.Bool
+	Readiness    *health.Checker
+	Metrics      *metrics.Metrics
+
+	Stream          *events.Broker
+	StreamHeartbeat time.Duration
 }
@@ -12,4 +12,40 @@ type Chirp struct {
 	UpdatedAt time.Time `json:"updated_at"`
 	Body      string    `json:"body"`
 	UserID    uuid.UUID `json:"user_id"`
+	// ReplyToID is the chirp this answers, nil if it doesn't or that chirp
+	// was purged. ThreadID is the first chirp of the conversation, this one's
+	// own ID if it started it.
+	ReplyToID *uuid.UUID `json:"reply_to_id"`
+	ThreadID  uuid.UUID  `json:"thread_id"`
+	// Attachments are the chirp's images in the order they were posted,
+	// empty if it has none.
+	Attachments []Attachment `json:"attachments"`
+}
+
+// Attachment is an image on a chirp. Size is in bytes, after its metadata
+// was stripped; URL serves it, to whoever can see the chirp.
+type Attachment struct {
+	ID          uuid.UUID `json:"id"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	Width       int       `json:"width"`
+	Height      int       `json:"height"`
+	URL         string    `json:"url"`
+	Thumbnail   Thumbnail `json:"thumbnail"`
+}
+
+// Thumbnail is a smaller copy of an attachment, at most 320 pixels a side.
+type Thumbnail struct {
+	ContentType string `json:"content_type"`
+	Width       int    `json:"width"`
+	Height      int    `json:"height"`
+	URL         string `json:"url"`
+}
+
+// TrashedChirp is a chirp its author deleted, which they can restore until
+// RestorableUntil.
+type TrashedChirp struct {
+	Chirp
+	DeletedAt       time.Time `json:"deleted_at"`
+	RestorableUntil time.Time `json:"restorable_until"`
 }
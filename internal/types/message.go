@@ -0,0 +1,38 @@
+package types
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Conversation struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	// LastMessageAt is nil until someone sends a message.
+	LastMessageAt *time.Time           `json:"last_message_at"`
+	Members       []ConversationMember `json:"members"`
+	// Muted and UnreadCount are the caller's own.
+	Muted       bool  `json:"muted"`
+	UnreadCount int64 `json:"unread_count"`
+}
+
+type ConversationMember struct {
+	UserID uuid.UUID `json:"user_id"`
+	// LastReadMessageID is the newest message they've read, 0 for none.
+	LastReadMessageID int64 `json:"last_read_message_id"`
+}
+
+type Message struct {
+	ID             int64     `json:"id"`
+	ConversationID uuid.UUID `json:"conversation_id"`
+	SenderID       uuid.UUID `json:"sender_id"`
+	Body           string    `json:"body"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+type MessagePage struct {
+	Messages []Message `json:"messages"`
+	// NextCursor fetches the next page, nil on the last one.
+	NextCursor *string `json:"next_cursor"`
+}
@@ -0,0 +1,89 @@
+package types
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Report struct {
+	ID         int64     `json:"id"`
+	CreatedAt  time.Time `json:"created_at"`
+	ReporterID uuid.UUID `json:"reporter_id"`
+	// UserID is the user reported, or the author of ChirpID; ChirpID is
+	// nil for a report about a user.
+	UserID  uuid.UUID  `json:"user_id"`
+	ChirpID *uuid.UUID `json:"chirp_id"`
+	Reason  string     `json:"reason"`
+	Details string     `json:"details"`
+	// Status is open, claimed or resolved. ClaimedBy and ClaimedAt are nil
+	// until a moderator claims the report, Resolution and ResolvedAt until
+	// they resolve it.
+	Status     string     `json:"status"`
+	ClaimedBy  *uuid.UUID `json:"claimed_by"`
+	ClaimedAt  *time.Time `json:"claimed_at"`
+	Resolution *string    `json:"resolution"`
+	ResolvedAt *time.Time `json:"resolved_at"`
+}
+
+type ReportPage struct {
+	Reports []Report `json:"reports"`
+	// NextCursor fetches the next page, nil on the last one.
+	NextCursor *string `json:"next_cursor"`
+}
+
+type ModerationAction struct {
+	ID          int64     `json:"id"`
+	CreatedAt   time.Time `json:"created_at"`
+	ModeratorID uuid.UUID `json:"moderator_id"`
+	// Action is hide_chirp, suspend_user, dismiss, unsuspend_user,
+	// limit_user, unlimit_user, uphold_appeal or overturn_appeal. ReportID
+	// or AppealID is what it decided, if anything.
+	Action   string     `json:"action"`
+	ReportID *int64     `json:"report_id"`
+	AppealID *int64     `json:"appeal_id"`
+	UserID   uuid.UUID  `json:"user_id"`
+	ChirpID  *uuid.UUID `json:"chirp_id"`
+	Note     string     `json:"note"`
+}
+
+type ModerationActionPage struct {
+	Actions []ModerationAction `json:"actions"`
+	// NextCursor fetches the next page, nil on the last one.
+	NextCursor *string `json:"next_cursor"`
+}
+
+// Sanction is a moderation action against the caller, as they see it.
+type Sanction struct {
+	ID        int64     `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	// Action is hide_chirp or suspend_user. ChirpID is the chirp hidden,
+	// or the one reported that got the user suspended.
+	Action  string     `json:"action"`
+	ChirpID *uuid.UUID `json:"chirp_id"`
+	Note    string     `json:"note"`
+	// Reason is the reason given in the report it resolved.
+	Reason *string `json:"reason"`
+	// AppealStatus is nil until the sanction is appealed.
+	AppealStatus *string `json:"appeal_status"`
+}
+
+type Appeal struct {
+	ID        int64     `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	// ActionID is the sanction appealed against.
+	ActionID int64     `json:"action_id"`
+	UserID   uuid.UUID `json:"user_id"`
+	Body     string    `json:"body"`
+	// Status is open, upheld or overturned. DecidedBy and DecidedAt are nil
+	// while it's open.
+	Status    string     `json:"status"`
+	DecidedBy *uuid.UUID `json:"decided_by"`
+	DecidedAt *time.Time `json:"decided_at"`
+}
+
+type AppealPage struct {
+	Appeals []Appeal `json:"appeals"`
+	// NextCursor fetches the next page, nil on the last one.
+	NextCursor *string `json:"next_cursor"`
+}
@@ -0,0 +1,27 @@
+package types
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Notification struct {
+	ID        int64     `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	// Type is follow, like, mention or reply. ChirpID is the liked chirp,
+	// the chirp with the mention or the reply, nil for a follow.
+	Type    string     `json:"type"`
+	ActorID uuid.UUID  `json:"actor_id"`
+	ChirpID *uuid.UUID `json:"chirp_id"`
+	// ReadAt is nil until the notification is marked read.
+	ReadAt *time.Time `json:"read_at"`
+}
+
+type NotificationPage struct {
+	Notifications []Notification `json:"notifications"`
+	// UnreadCount counts every unread notification, not just this page's.
+	UnreadCount int64 `json:"unread_count"`
+	// NextCursor fetches the next page, nil on the last one.
+	NextCursor *string `json:"next_cursor"`
+}
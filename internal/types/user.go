@@ -13,3 +13,9 @@ type User struct {
 	Email       string    `json:"email"`
 	IsChirpyRed bool      `json:"is_chirpy_red"`
 }
+
+// BlockedUser is a user the caller blocked or muted.
+type BlockedUser struct {
+	UserID    uuid.UUID `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
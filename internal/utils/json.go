@@ -2,30 +2,39 @@ package utils
 
 import (
 	"encoding/json"
-	"log"
+	"log/slog"
 	"net/http"
+
+	"github.com/HemahWeb/chirpy/internal/logging"
+	"github.com/HemahWeb/chirpy/internal/problem"
 )
 
-func RespondWithError(w http.ResponseWriter, code int, msg string, err error) {
-	if err != nil {
-		log.Println(err)
-	}
-	if code > 499 {
-		log.Printf("Responding with 5XX error: %s", msg)
-	}
-	type errorResponse struct {
-		Error string `json:"error"`
+// RespondWithError writes err as an application/problem+json body. Pass a
+// *problem.Problem to pick the status and code; any other error is mapped
+// by problem.From.
+func RespondWithError(w http.ResponseWriter, r *http.Request, err error) {
+	ctx := r.Context()
+	p := *problem.From(err)
+	p.Instance = r.URL.Path
+	p.RequestID = logging.RequestID(ctx)
+
+	if p.Status > 499 {
+		slog.ErrorContext(ctx, "Responding with 5XX error", "status", p.Status, "code", p.Code, "error", err)
+	} else if p.Unwrap() != nil {
+		slog.InfoContext(ctx, "Responding with error", "status", p.Status, "code", p.Code, "error", err)
 	}
-	RespondWithJSON(w, code, errorResponse{
-		Error: msg,
-	})
+	writeJSON(w, p.Status, problem.ContentType, p)
 }
 
 func RespondWithJSON(w http.ResponseWriter, code int, payload any) {
-	w.Header().Set("Content-Type", "application/json")
+	writeJSON(w, code, "application/json", payload)
+}
+
+func writeJSON(w http.ResponseWriter, code int, contentType string, payload any) {
+	w.Header().Set("Content-Type", contentType)
 	dat, err := json.Marshal(payload)
 	if err != nil {
-		log.Printf("Error marshalling JSON: %s", err)
+		slog.Error("Error marshalling JSON", "error", err)
 		w.WriteHeader(500)
 		return
 	}
@@ -0,0 +1,61 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/HemahWeb/chirpy/internal/logging"
+	"github.com/HemahWeb/chirpy/internal/problem"
+)
+
+func TestRespondWithError(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/api/users", nil)
+	req = req.WithContext(logging.WithRequestID(req.Context(), "req-1"))
+	rec := httptest.NewRecorder()
+
+	RespondWithError(rec, req, problem.Validation(
+		problem.FieldError{Field: "email", Code: "required", Message: "must not be empty"},
+	))
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", rec.Code)
+	}
+	if got := rec.Header().Get("Content-Type"); got != "application/problem+json" {
+		t.Errorf("Content-Type = %q", got)
+	}
+	var body map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("body is not JSON: %v", err)
+	}
+	for k, want := range map[string]any{
+		"type":       "urn:chirpy:problem:validation_failed",
+		"title":      "Bad Request",
+		"status":     float64(400),
+		"code":       "validation_failed",
+		"instance":   "/api/users",
+		"request_id": "req-1",
+	} {
+		if body[k] != want {
+			t.Errorf("%s = %v, want %v", k, body[k], want)
+		}
+	}
+	if errs, _ := body["errors"].([]any); len(errs) != 1 {
+		t.Errorf("errors = %v, want the field error", body["errors"])
+	}
+}
+
+func TestRespondWithErrorHidesCause(t *testing.T) {
+	rec := httptest.NewRecorder()
+	RespondWithError(rec, httptest.NewRequest(http.MethodGet, "/", nil), errors.New("dial tcp 10.0.0.5:5432: connection refused"))
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want 500", rec.Code)
+	}
+	if strings.Contains(rec.Body.String(), "10.0.0.5") {
+		t.Errorf("response leaks the internal error: %s", rec.Body.String())
+	}
+}
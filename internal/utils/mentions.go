@@ -0,0 +1,28 @@
+package utils
+
+import (
+	"net/mail"
+	"slices"
+	"strings"
+)
+
+// Mentions returns the email addresses body mentions as @address, such as
+// "@walt@breakingbad.com", once each in the order they first appear.
+// Punctuation after an address, as at the end of a sentence, is left out.
+func Mentions(body string) []string {
+	var mentions []string
+	for _, word := range strings.Fields(body) {
+		address, ok := strings.CutPrefix(word, "@")
+		if !ok {
+			continue
+		}
+		address = strings.TrimRight(address, `.,;:!?)"'`)
+		if addr, err := mail.ParseAddress(address); err != nil || addr.Address != address {
+			continue
+		}
+		if !slices.Contains(mentions, address) {
+			mentions = append(mentions, address)
+		}
+	}
+	return mentions
+}
@@ -0,0 +1,25 @@
+package utils
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestMentions(t *testing.T) {
+	tests := []struct {
+		body string
+		want []string
+	}{
+		{"no one here", nil},
+		{"hey @walt@breakingbad.com, cook?", []string{"walt@breakingbad.com"}},
+		{"@jesse@breakingbad.com and @walt@breakingbad.com. Bye @jesse@breakingbad.com!", []string{"jesse@breakingbad.com", "walt@breakingbad.com"}},
+		{"mail walt@breakingbad.com or @heisenberg", nil},
+		{"(@saul@bettercall.com)", nil},
+		{"@@walt@breakingbad.com", nil},
+	}
+	for _, tt := range tests {
+		if got := Mentions(tt.body); !slices.Equal(got, tt.want) {
+			t.Errorf("Mentions(%q) = %q, want %q", tt.body, got, tt.want)
+		}
+	}
+}
@@ -1,15 +1,22 @@
 package utils
 
 import (
-	"errors"
+	"net/mail"
 	"strings"
+	"unicode/utf8"
+
+	"github.com/HemahWeb/chirpy/internal/problem"
 )
 
+// ValidateChirp returns body with bad words masked, or a validation problem.
 func ValidateChirp(body string) (string, error) {
 
 	const maxChirpLength = 140
-	if len(body) > maxChirpLength {
-		return "", errors.New("chirp is too long")
+	if strings.TrimSpace(body) == "" {
+		return "", problem.Validation(problem.FieldError{Field: "body", Code: "required", Message: "must not be empty"})
+	}
+	if utf8.RuneCountInString(body) > maxChirpLength {
+		return "", problem.Validation(problem.FieldError{Field: "body", Code: "too_long", Message: "must be at most 140 characters"})
 	}
 
 	badWords := map[string]struct{}{
@@ -33,3 +40,21 @@ func getCleanedBody(body string, badWords map[string]struct{}) string {
 	cleaned := strings.Join(words, " ")
 	return cleaned
 }
+
+// ValidateCredentials checks the email and password sent to create or
+// update a user, reporting every invalid field at once.
+func ValidateCredentials(email, password string) error {
+	var errs []problem.FieldError
+	if email == "" {
+		errs = append(errs, problem.FieldError{Field: "email", Code: "required", Message: "must not be empty"})
+	} else if addr, err := mail.ParseAddress(email); err != nil || addr.Address != email {
+		errs = append(errs, problem.FieldError{Field: "email", Code: "invalid_format", Message: "must be an email address"})
+	}
+	if password == "" {
+		errs = append(errs, problem.FieldError{Field: "password", Code: "required", Message: "must not be empty"})
+	}
+	if len(errs) > 0 {
+		return problem.Validation(errs...)
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package utils
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/HemahWeb/chirpy/internal/problem"
+)
+
+func fieldCodes(t *testing.T, err error) map[string]string {
+	t.Helper()
+	var p *problem.Problem
+	if !errors.As(err, &p) || p.Code != problem.CodeValidationFailed {
+		t.Fatalf("error = %v, want a validation problem", err)
+	}
+	codes := map[string]string{}
+	for _, fe := range p.Errors {
+		codes[fe.Field] = fe.Code
+	}
+	return codes
+}
+
+func TestValidateChirp(t *testing.T) {
+	cleaned, err := ValidateChirp("what a Kerfuffle this is")
+	if err != nil || cleaned != "what a **** this is" {
+		t.Errorf("ValidateChirp() = %q, %v", cleaned, err)
+	}
+
+	if _, err := ValidateChirp(strings.Repeat("a", 141)); fieldCodes(t, err)["body"] != "too_long" {
+		t.Errorf("a 141 character chirp should be too_long")
+	}
+	if _, err := ValidateChirp(strings.Repeat("é", 140)); err != nil {
+		t.Errorf("a 140 character chirp of 280 bytes should be allowed: %v", err)
+	}
+	if _, err := ValidateChirp("  "); fieldCodes(t, err)["body"] != "required" {
+		t.Errorf("a blank chirp should be required")
+	}
+}
+
+func TestValidateCredentials(t *testing.T) {
+	if err := ValidateCredentials("walt@breakingbad.com", "123456"); err != nil {
+		t.Errorf("ValidateCredentials() failed: %v", err)
+	}
+
+	codes := fieldCodes(t, ValidateCredentials("Walt <walt@breakingbad.com>", ""))
+	if codes["email"] != "invalid_format" || codes["password"] != "required" {
+		t.Errorf("field codes = %v, want every field reported", codes)
+	}
+}
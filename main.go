@@ -1,75 +1,328 @@
 package main
 
 import (
+	"context"
 	"database/sql"
-	"log"
+	"errors"
+	"log/slog"
 	"net/http"
 	"os"
-	"sync/atomic"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
 
-	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
 
+	"github.com/HemahWeb/chirpy/internal/cleanup"
+	"github.com/HemahWeb/chirpy/internal/config"
 	"github.com/HemahWeb/chirpy/internal/database"
+	"github.com/HemahWeb/chirpy/internal/dburl"
+	"github.com/HemahWeb/chirpy/internal/events"
 	"github.com/HemahWeb/chirpy/internal/handlers"
+	"github.com/HemahWeb/chirpy/internal/health"
+	"github.com/HemahWeb/chirpy/internal/logging"
+	"github.com/HemahWeb/chirpy/internal/media"
+	"github.com/HemahWeb/chirpy/internal/metrics"
+	"github.com/HemahWeb/chirpy/internal/middleware"
+	"github.com/HemahWeb/chirpy/internal/migrate"
+	"github.com/HemahWeb/chirpy/internal/service"
+	"github.com/HemahWeb/chirpy/internal/sqlite"
+	"github.com/HemahWeb/chirpy/internal/tracing"
 	"github.com/HemahWeb/chirpy/internal/types"
 )
 
 func main() {
-	godotenv.Load()
+	switch {
+	case len(os.Args) > 1 && os.Args[1] == "migrate":
+		runMigrate(os.Args[2:])
+	case len(os.Args) > 1 && isAdminCommand(os.Args[1]):
+		runAdmin(os.Args[1:])
+	default:
+		serve(os.Args[1:])
+	}
+}
+
+// openDB connects to Postgres or SQLite depending on the DB_URL scheme.
+func openDB(cfg config.Config) (*sql.DB, dburl.Dialect) {
+	if cfg.DBURL == "" {
+		fatal("DB_URL is not set")
+	}
+	dialect, dsn, err := dburl.Parse(string(cfg.DBURL))
+	if err != nil {
+		fatal("Invalid DB_URL", "error", err)
+	}
 
-	dbURL := os.Getenv("DB_URL")
-	if dbURL == "" {
-		log.Fatal("DB_URL is not set")
+	var dbConn *sql.DB
+	if dialect == dburl.SQLite {
+		dbConn, err = sqlite.Open(dsn)
+	} else {
+		dbConn, err = sql.Open(string(dialect), dsn)
+	}
+	if err != nil {
+		fatal("Error opening database connection", "error", err)
 	}
+	dbConn.SetMaxOpenConns(cfg.DBMaxOpenConns)
+	dbConn.SetMaxIdleConns(cfg.DBMaxIdleConns)
+	dbConn.SetConnMaxLifetime(cfg.DBConnMaxLifetime)
+	dbConn.SetConnMaxIdleTime(cfg.DBConnMaxIdleTime)
+	return dbConn, dialect
+}
+
+// newQuerier returns the queries for dialect, traced. db is the pool or a
+// transaction on it.
+func newQuerier(db database.DBTX, dialect dburl.Dialect) database.Querier {
+	traced := tracing.WrapDB(db, dialect)
+	if dialect == dburl.SQLite {
+		return sqlite.New(traced)
+	}
+	return database.New(traced)
+}
+
+// retryable reports the errors after which a transaction on dialect is
+// worth retrying.
+func retryable(dialect dburl.Dialect) func(err error) bool {
+	if dialect == dburl.SQLite {
+		return sqlite.Busy
+	}
+	return database.SerializationFailure
+}
 
-	dbConn, err := sql.Open("postgres", dbURL)
+func serve(args []string) {
+	cfg, err := config.Load("chirpy", args)
 	if err != nil {
-		log.Fatalf("Error opening database connection: %v", err)
+		fatal("Error loading config", "error", err)
 	}
-	dbQueries := database.New(dbConn)
+	if err := cfg.Validate(); err != nil {
+		fatal("Invalid config", "error", err)
+	}
+	logger := setupLogging(cfg)
+	logger.Info("Loaded config", "config", cfg)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	shutdownTracing, err := tracing.Setup(ctx, tracing.Config{
+		Exporter:    cfg.TracingExporter,
+		FilePath:    cfg.TracingFile,
+		SampleRatio: cfg.TracingSampleRatio,
+		ServiceName: "chirpy",
+	})
+	if err != nil {
+		fatal("Error configuring tracing", "error", err)
+	}
+
+	dbConn, dialect := openDB(cfg)
+	defer dbConn.Close()
+
+	migrator, err := migrate.New(dbConn, dialect)
+	if err != nil {
+		fatal("Error loading migrations", "error", err)
+	}
+	if err := migrator.Check(ctx); err != nil {
+		if errors.Is(err, migrate.ErrDatabaseAhead) {
+			fatal("Refusing to serve", "error", err)
+		}
+		fatal("Error checking schema version", "error", err)
+	}
+	if cfg.SkipMigrations {
+		current, latest, err := migrator.Versions(ctx)
+		if err != nil {
+			fatal("Error checking schema version", "error", err)
+		}
+		if current < latest {
+			logger.Warn("Database has pending migrations; run `chirpy migrate up`", "current", current, "latest", latest)
+		}
+	} else {
+		results, err := migrator.Up(ctx)
+		if err != nil {
+			fatal("Error applying migrations", "error", err)
+		}
+		for _, res := range results {
+			logger.Info("Applied migration", "migration", res.Source.Path, "duration", res.Duration)
+		}
+	}
+
+	dbQueries := newQuerier(dbConn, dialect)
+
+	tokenCleaner := cleanup.NewRefreshTokenCleaner(dbQueries, cleanup.Config{
+		Interval:  cfg.RefreshTokenCleanupInterval,
+		Retention: cfg.RefreshTokenRetention,
+		BatchSize: int32(cfg.RefreshTokenCleanupBatchSize),
+	})
+	go tokenCleaner.Start(ctx)
+
+	trashPurger := cleanup.NewChirpTrashPurger(dbQueries, cleanup.Config{
+		Interval:  cfg.ChirpTrashPurgeInterval,
+		Retention: cfg.ChirpTrashRetention,
+		BatchSize: int32(cfg.ChirpTrashPurgeBatchSize),
+	})
+	go trashPurger.Start(ctx)
+
+	blobs := newBlobStore(cfg)
+	attachmentCleaner := cleanup.NewAttachmentCleaner(dbQueries, blobs, cleanup.Config{
+		Interval:  cfg.MediaOrphanCleanupInterval,
+		Retention: cfg.MediaOrphanRetention,
+		BatchSize: int32(cfg.MediaOrphanCleanupBatchSize),
+	})
+	go attachmentCleaner.Start(ctx)
+
+	appMetrics := metrics.New()
+	appMetrics.RegisterDB(dbConn, "chirpy")
+	appMetrics.RegisterTokenCleaner(tokenCleaner)
+	appMetrics.RegisterTrashPurger(trashPurger)
+	appMetrics.RegisterAttachmentCleaner(attachmentCleaner)
+
+	readiness := health.NewChecker(cfg.ReadinessTimeout, cfg.ReadinessCacheTTL)
+	readiness.Add("database", dbConn.PingContext)
+	readiness.Add("migrations", migrator.CheckCurrent)
+
+	transactor := service.NewSQLTransactor(dbConn, func(tx *sql.Tx) database.Querier {
+		return newQuerier(tx, dialect)
+	}, retryable(dialect))
+	svc := service.New(dbQueries, transactor, string(cfg.JWTSecret))
+	svc.SetTrashRetention(cfg.ChirpTrashRetention)
+	svc.SetMedia(blobs, media.Limits{MaxBytes: cfg.MediaMaxBytes, MaxPixels: media.DefaultLimits().MaxPixels}, cfg.MediaMaxImages)
+
+	broker := events.NewBroker(cfg.StreamBufferSize)
+	appMetrics.RegisterStream(broker)
+	svc.SetPublisher(newPublisher(ctx, cfg, dbConn, dialect, broker))
 
 	apiCfg := types.ApiConfig{
-		FileserverHits: atomic.Int32{},
-		DB:             dbQueries,
-		Platform:       os.Getenv("PLATFORM"),
-		JWTSecret:      os.Getenv("JWT_SECRET"),
-		PolkaKey:       os.Getenv("POLKA_KEY"),
+		Service:      svc,
+		Platform:     cfg.Platform,
+		JWTSecret:    string(cfg.JWTSecret),
+		PolkaKey:     string(cfg.PolkaKey),
+		MetricsToken: string(cfg.MetricsToken),
+		TokenCleaner: tokenCleaner,
+		TrashPurger:  trashPurger,
+		Readiness:    readiness,
+		Metrics:      appMetrics,
+
+		Stream:          broker,
+		StreamHeartbeat: cfg.StreamHeartbeatInterval,
 	}
 
 	handler := handlers.New(&apiCfg)
 
 	mux := http.NewServeMux()
+	handler.Register(mux, http.Dir("app"))
 
-	mux.HandleFunc("GET /api/healthz", handler.Healthz)
+	// a form with images carries each of them besides the other fields
+	multipartLimit := cfg.MaxBodyBytes + int64(cfg.MediaMaxImages)*cfg.MediaMaxBytes
+	var limited http.Handler = middleware.MaxBodyBytes(cfg.MaxBodyBytes, multipartLimit, mux)
+	if cfg.RateLimitEnabled {
+		limited = middleware.RateLimit(newRateLimiter(ctx, cfg, dbQueries), appMetrics, mux, limited)
+	}
 
-	// Chirps
-	mux.HandleFunc("POST /api/chirps", handler.PostChirps)
-	mux.HandleFunc("GET /api/chirps", handler.GetChirps)
-	mux.HandleFunc("GET /api/chirps/{id}", handler.GetChirpsByID)
-	mux.HandleFunc("DELETE /api/chirps/{id}", handler.ChirpsDeleteByID)
+	// outermost first: the span starts before anything logs, so every line
+	// carries the trace ID as well as the request ID
+	root := tracing.Handler(mux,
+		middleware.RequestID(
+			middleware.AccessLog(mux,
+				middleware.Metrics(appMetrics, mux, limited))))
 
-	// Users
-	mux.HandleFunc("POST /api/users", handler.UsersCreate)
-	mux.HandleFunc("POST /api/login", handler.Login)
-	mux.HandleFunc("POST /api/refresh", handler.Refresh)
-	mux.HandleFunc("POST /api/revoke", handler.Revoke)
-	mux.HandleFunc("PUT /api/users", handler.UsersUpdate)
+	server := &http.Server{
+		Addr:              ":" + strconv.Itoa(cfg.Port),
+		Handler:           root,
+		ReadTimeout:       cfg.ReadTimeout,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+		MaxHeaderBytes:    cfg.MaxHeaderBytes,
+		ErrorLog:          slog.NewLogLogger(logger.Handler(), slog.LevelWarn),
+	}
+
+	serverErr := make(chan error, 1)
+	go func() {
+		logger.Info("Starting server", "port", cfg.Port)
+		serverErr <- server.ListenAndServe()
+	}()
 
-	// Webhooks
-	mux.HandleFunc("POST /api/polka/webhooks", handler.PolkaUpgrade)
+	select {
+	case err := <-serverErr:
+		fatal("Server failed", "error", err)
+	case <-ctx.Done():
+	}
+	// a second signal kills the process immediately
+	stop()
 
-	// Admin
-	mux.HandleFunc("POST /admin/reset", handler.UsersReset) // resets users and metrics
-	mux.HandleFunc("GET /admin/metrics", handler.MetricsView)
+	logger.Info("Shutting down: reporting unready", "delay", cfg.ShutdownDelay)
+	apiCfg.ShuttingDown.Store(true)
+	time.Sleep(cfg.ShutdownDelay)
 
-	mux.Handle("/app/", http.StripPrefix("/app/", handler.MiddlewareMetricsInc(http.FileServer(http.Dir("app")))))
+	// open streams would otherwise hold up draining until the timeout
+	broker.Close()
 
-	server := &http.Server{
-		Addr:    ":8080",
-		Handler: mux,
+	logger.Info("Draining in-flight requests", "timeout", cfg.ShutdownTimeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		logger.Warn("Drain deadline exceeded, closing remaining connections", "error", err)
+		server.Close()
 	}
 
-	log.Println("Starting server on port 8080")
-	log.Fatal(server.ListenAndServe())
+	flushCtx, cancelFlush := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelFlush()
+	if err := shutdownTracing(flushCtx); err != nil {
+		logger.Warn("Error flushing traces", "error", err)
+	}
+	logger.Info("Server stopped")
+}
+
+// newBlobStore returns where chirp images are kept.
+func newBlobStore(cfg config.Config) media.BlobStore {
+	if cfg.MediaStore == "s3" {
+		store, err := media.NewS3Store(media.S3Config{
+			Endpoint:        cfg.MediaS3Endpoint,
+			Region:          cfg.MediaS3Region,
+			Bucket:          cfg.MediaS3Bucket,
+			AccessKeyID:     cfg.MediaS3AccessKeyID,
+			SecretAccessKey: string(cfg.MediaS3SecretAccessKey),
+		})
+		if err != nil {
+			fatal("Error configuring S3 media store", "error", err)
+		}
+		return store
+	}
+	store, err := media.NewLocalStore(cfg.MediaDir)
+	if err != nil {
+		fatal("Error creating media directory", "error", err)
+	}
+	return store
+}
+
+// newPublisher returns where chirp events are published. With Postgres they
+// go through NOTIFY so every instance's broker gets them; otherwise there's
+// only this instance and the broker is fed directly.
+func newPublisher(ctx context.Context, cfg config.Config, db *sql.DB, dialect dburl.Dialect, broker *events.Broker) events.Publisher {
+	if dialect != dburl.Postgres {
+		return broker
+	}
+	_, dsn, err := dburl.Parse(string(cfg.DBURL))
+	if err != nil {
+		fatal("Invalid DB_URL", "error", err)
+	}
+	go func() {
+		if err := events.Listen(ctx, dsn, broker); err != nil {
+			slog.Error("Event listener stopped; live streams won't get new events", "error", err)
+		}
+	}()
+	return events.NewPostgresPublisher(tracing.WrapDB(db, dialect))
+}
+
+// setupLogging installs the configured logger as the slog default, which
+// also routes the standard log package through it.
+func setupLogging(cfg config.Config) *slog.Logger {
+	logger, err := logging.New(os.Stdout, cfg.LogFormat, cfg.LogLevel)
+	if err != nil {
+		fatal("Error configuring logging", "error", err)
+	}
+	slog.SetDefault(logger)
+	return logger
+}
+
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
 }
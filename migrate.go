@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pressly/goose/v3"
+
+	"github.com/HemahWeb/chirpy/internal/config"
+	"github.com/HemahWeb/chirpy/internal/migrate"
+)
+
+const migrateUsage = `usage: chirpy migrate <command> [flags]
+
+commands:
+  up       apply all pending migrations
+  down     roll back the most recent migration
+  redo     roll back the most recent migration and apply it again
+  status   print the state of every migration`
+
+func runMigrate(args []string) {
+	if len(args) < 1 {
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		os.Exit(2)
+	}
+
+	cfg, err := config.Load("chirpy migrate "+args[0], args[1:])
+	if err != nil {
+		fatal("Error loading config", "error", err)
+	}
+	setupLogging(cfg)
+
+	dbConn, dialect := openDB(cfg)
+	defer dbConn.Close()
+
+	migrator, err := migrate.New(dbConn, dialect)
+	if err != nil {
+		fatal("Error loading migrations", "error", err)
+	}
+
+	ctx := context.Background()
+	switch args[0] {
+	case "up":
+		results, err := migrator.Up(ctx)
+		printResults(results)
+		if err != nil {
+			fatal("Error applying migrations", "error", err)
+		}
+		if len(results) == 0 {
+			fmt.Println("No pending migrations")
+		}
+	case "down":
+		result, err := migrator.Down(ctx)
+		if err != nil {
+			fatal("Error rolling back migration", "error", err)
+		}
+		printResults([]*goose.MigrationResult{result})
+	case "redo":
+		results, err := migrator.Redo(ctx)
+		printResults(results)
+		if err != nil {
+			fatal("Error redoing migration", "error", err)
+		}
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			fatal("Error getting migration status", "error", err)
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "VERSION\tSTATE\tAPPLIED AT\tMIGRATION")
+		for _, st := range statuses {
+			appliedAt := "-"
+			if st.State == goose.StateApplied {
+				appliedAt = st.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(tw, "%d\t%s\t%s\t%s\n", st.Source.Version, st.State, appliedAt, st.Source.Path)
+		}
+		tw.Flush()
+	default:
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		os.Exit(2)
+	}
+}
+
+func printResults(results []*goose.MigrationResult) {
+	for _, res := range results {
+		fmt.Println(res)
+	}
+}
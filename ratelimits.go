@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/HemahWeb/chirpy/internal/config"
+	"github.com/HemahWeb/chirpy/internal/database"
+	"github.com/HemahWeb/chirpy/internal/ratelimit"
+)
+
+// newRateLimiter builds the per-route limits. Routes not listed get the
+// default rule; routes listed with no rules are never limited.
+func newRateLimiter(ctx context.Context, cfg config.Config, db database.Querier) *ratelimit.Limiter {
+	trusted, err := config.ParseTrustedProxies(cfg.TrustedProxies)
+	if err != nil {
+		fatal("Invalid TRUSTED_PROXIES", "error", err)
+	}
+
+	var store ratelimit.Store = ratelimit.NewMemoryStore()
+	if cfg.RateLimitStore == "postgres" {
+		store = ratelimit.NewPostgresStore(db)
+	}
+
+	byUser := ratelimit.ByUser(string(cfg.JWTSecret))
+	l := ratelimit.New(store, trusted)
+	l.Default(ratelimit.Rule{Name: "default", Limit: 300, Period: time.Minute, Key: byUser})
+
+	l.Add("POST /api/login", ratelimit.Rule{Name: "login", Limit: 10, Period: time.Minute, Key: ratelimit.ByIP})
+	l.Add("POST /api/users", ratelimit.Rule{Name: "signup", Limit: 10, Period: time.Hour, Key: ratelimit.ByIP})
+	l.Add("POST /api/chirps", ratelimit.Rule{Name: "chirps", Limit: 30, Period: time.Minute, Key: byUser})
+	l.Add("POST /api/refresh",
+		ratelimit.Rule{Name: "refresh", Limit: 30, Period: time.Minute, Key: ratelimit.ByToken},
+		ratelimit.Rule{Name: "refresh-ip", Limit: 120, Period: time.Minute, Key: ratelimit.ByIP},
+	)
+
+	// probes, scrapers and Polka's retries
+	for _, route := range []string{"GET /api/livez", "GET /api/readyz", "GET /api/healthz", "GET /metrics", "POST /api/polka/webhooks"} {
+		l.Add(route)
+	}
+
+	go l.Run(ctx, 10*time.Minute)
+	return l
+}
@@ -0,0 +1,19 @@
+-- name: CreateAppeal :one
+INSERT INTO appeals (action_id, user_id, body)
+VALUES ($1, $2, $3)
+RETURNING *;
+
+-- name: GetAppeal :one
+SELECT * FROM appeals
+WHERE id = $1;
+
+-- name: ListAppeals :many
+SELECT * FROM appeals
+WHERE status = $1 AND id > sqlc.arg(after_id)
+ORDER BY id
+LIMIT sqlc.arg(max_rows);
+
+-- name: DecideAppeal :execrows
+UPDATE appeals
+SET status = $2, decided_by = $3, decided_at = CURRENT_TIMESTAMP
+WHERE id = $1 AND status = 'open';
@@ -0,0 +1,35 @@
+-- name: CreateAttachment :one
+-- Creates an attachment on no chirp yet; AttachToChirp adds it to one once
+-- its blobs are stored.
+INSERT INTO attachments (
+    content_type, size_bytes, width, height,
+    thumbnail_content_type, thumbnail_size_bytes, thumbnail_width, thumbnail_height
+)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+RETURNING *;
+
+-- name: AttachToChirp :exec
+UPDATE attachments
+SET chirp_id = $2, position = $3
+WHERE id = $1;
+
+-- name: GetAttachment :one
+SELECT * FROM attachments
+WHERE id = $1;
+
+-- name: ListAttachmentsByChirpIDs :many
+SELECT * FROM attachments
+WHERE chirp_id = ANY(sqlc.arg(chirp_ids)::uuid[])
+ORDER BY chirp_id, position;
+
+-- name: ListOrphanedAttachments :many
+-- Attachments created before cutoff that never made it onto a chirp, or
+-- whose chirp was purged, oldest first.
+SELECT * FROM attachments
+WHERE chirp_id IS NULL AND created_at < sqlc.arg(cutoff)::timestamp
+ORDER BY created_at
+LIMIT sqlc.arg(batch_size);
+
+-- name: DeleteAttachment :exec
+DELETE FROM attachments
+WHERE id = $1;
@@ -0,0 +1,20 @@
+-- name: BlockUser :execrows
+INSERT INTO blocks (blocker_id, blocked_id)
+VALUES ($1, $2)
+ON CONFLICT DO NOTHING;
+
+-- name: BlockExists :one
+SELECT EXISTS (
+    SELECT 1 FROM blocks
+    WHERE (blocker_id = sqlc.arg(user_id) AND blocked_id = sqlc.arg(other_id))
+    OR (blocker_id = sqlc.arg(other_id) AND blocked_id = sqlc.arg(user_id))
+);
+
+-- name: UnblockUser :exec
+DELETE FROM blocks
+WHERE blocker_id = $1 AND blocked_id = $2;
+
+-- name: ListBlocks :many
+SELECT * FROM blocks
+WHERE blocker_id = $1
+ORDER BY created_at DESC, blocked_id;
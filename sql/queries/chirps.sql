@@ -1,18 +1,48 @@
 -- name: CreateChirp :one
-INSERT INTO chirps (body, user_id) VALUES ($1, $2) RETURNING *;
+INSERT INTO chirps (body, user_id, reply_to_id, thread_id) VALUES ($1, $2, $3, $4) RETURNING *;
 
 -- name: GetChirps :many
-SELECT * FROM chirps ORDER BY created_at ASC;
+-- Leaves out deleted chirps and those a moderator hid.
+SELECT * FROM chirps WHERE deleted_at IS NULL AND id NOT IN (SELECT chirp_id FROM hidden_chirps) ORDER BY created_at ASC;
 
 -- name: GetChirpsByUserID :many
-SELECT * FROM chirps WHERE user_id = $1 ORDER BY created_at ASC;
+-- Leaves out deleted chirps and those a moderator hid.
+SELECT * FROM chirps WHERE user_id = $1 AND deleted_at IS NULL AND id NOT IN (SELECT chirp_id FROM hidden_chirps) ORDER BY created_at ASC;
 
 -- name: GetChirpByID :one
+-- Finds deleted chirps too.
 SELECT * FROM chirps WHERE id = $1 LIMIT 1;
 
 -- name: UpdateChirp :one
 UPDATE chirps SET body = $2 WHERE id = $1 RETURNING *;
 
 -- name: DeleteChirp :exec
+-- Moves a chirp to the trash; PurgeChirp removes it for good.
+UPDATE chirps SET deleted_at = CURRENT_TIMESTAMP WHERE id = $1 AND deleted_at IS NULL;
+
+-- name: RestoreChirp :one
+UPDATE chirps SET deleted_at = NULL WHERE id = $1 AND deleted_at IS NOT NULL RETURNING *;
+
+-- name: ListTrashedChirps :many
+-- The chirps user_id deleted since cutoff, most recently deleted first.
+-- Chirps a moderator hid aren't theirs to restore, so they're left out.
+SELECT * FROM chirps
+WHERE user_id = sqlc.arg(user_id)
+  AND deleted_at >= sqlc.arg(cutoff)::timestamp
+  AND id NOT IN (SELECT chirp_id FROM hidden_chirps)
+ORDER BY deleted_at DESC, id DESC;
+
+-- name: PurgeChirp :exec
 DELETE FROM chirps WHERE id = $1;
 
+-- name: PurgeTrashedChirps :execrows
+-- Chirps a moderator hid are kept for the moderation log, and reported ones
+-- until the reports are resolved.
+DELETE FROM chirps
+WHERE id IN (
+    SELECT id FROM chirps
+    WHERE deleted_at < sqlc.arg(cutoff)::timestamp
+      AND id NOT IN (SELECT chirp_id FROM hidden_chirps)
+      AND NOT EXISTS (SELECT 1 FROM reports WHERE chirp_id = chirps.id AND status <> 'resolved')
+    LIMIT sqlc.arg(batch_size)
+);
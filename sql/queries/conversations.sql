@@ -0,0 +1,60 @@
+-- name: CreateConversation :one
+-- Returns no row if direct_key is taken.
+INSERT INTO conversations (direct_key)
+VALUES ($1)
+ON CONFLICT (direct_key) DO NOTHING
+RETURNING *;
+
+-- name: GetConversationByDirectKey :one
+SELECT * FROM conversations WHERE direct_key = $1;
+
+-- name: AddConversationMember :exec
+INSERT INTO conversation_members (conversation_id, user_id)
+VALUES ($1, $2);
+
+-- name: GetConversationForUser :one
+SELECT c.*, m.last_read_id, m.muted, (
+    SELECT COUNT(*) FROM messages
+    WHERE messages.conversation_id = c.id
+    AND messages.id > m.last_read_id
+    AND messages.sender_id <> m.user_id
+) AS unread_count
+FROM conversations c
+JOIN conversation_members m ON m.conversation_id = c.id
+WHERE c.id = $1 AND m.user_id = $2;
+
+-- name: ListConversationsForUser :many
+SELECT c.*, m.last_read_id, m.muted, (
+    SELECT COUNT(*) FROM messages
+    WHERE messages.conversation_id = c.id
+    AND messages.id > m.last_read_id
+    AND messages.sender_id <> m.user_id
+) AS unread_count
+FROM conversations c
+JOIN conversation_members m ON m.conversation_id = c.id
+WHERE m.user_id = $1
+ORDER BY COALESCE(c.last_message_at, c.created_at) DESC, c.id;
+
+-- name: ListConversationMembers :many
+SELECT * FROM conversation_members
+WHERE conversation_id = $1
+ORDER BY user_id;
+
+-- name: ListConversationMembersForUser :many
+-- The members of every conversation user_id is in.
+SELECT m.* FROM conversation_members m
+JOIN conversation_members me ON me.conversation_id = m.conversation_id
+WHERE me.user_id = $1
+ORDER BY m.conversation_id, m.user_id;
+
+-- name: SetConversationMuted :execrows
+UPDATE conversation_members SET muted = $3
+WHERE conversation_id = $1 AND user_id = $2;
+
+-- name: MarkConversationRead :execrows
+UPDATE conversation_members SET last_read_id = GREATEST(last_read_id, $3)
+WHERE conversation_id = $1 AND user_id = $2;
+
+-- name: SetConversationLastMessageAt :exec
+UPDATE conversations SET last_message_at = $2
+WHERE id = $1;
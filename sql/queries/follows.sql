@@ -0,0 +1,13 @@
+-- name: FollowUser :execrows
+INSERT INTO follows (follower_id, followee_id)
+VALUES ($1, $2)
+ON CONFLICT DO NOTHING;
+
+-- name: UnfollowUser :exec
+DELETE FROM follows
+WHERE follower_id = $1 AND followee_id = $2;
+
+-- name: ListFolloweeIDs :many
+SELECT followee_id FROM follows
+WHERE follower_id = $1
+ORDER BY created_at;
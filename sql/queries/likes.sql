@@ -0,0 +1,8 @@
+-- name: LikeChirp :execrows
+INSERT INTO chirp_likes (user_id, chirp_id)
+VALUES ($1, $2)
+ON CONFLICT DO NOTHING;
+
+-- name: UnlikeChirp :exec
+DELETE FROM chirp_likes
+WHERE user_id = $1 AND chirp_id = $2;
@@ -0,0 +1,17 @@
+-- name: CreateMessage :one
+INSERT INTO messages (conversation_id, sender_id, body)
+VALUES ($1, $2, $3)
+RETURNING *;
+
+-- name: GetMessage :one
+SELECT * FROM messages WHERE id = $1;
+
+-- name: ListMessages :many
+SELECT * FROM messages
+WHERE conversation_id = sqlc.arg(conversation_id)
+AND id < sqlc.arg(before_id)
+ORDER BY id DESC
+LIMIT sqlc.arg(max_rows);
+
+-- name: DeleteMessage :exec
+DELETE FROM messages WHERE id = $1;
@@ -0,0 +1,92 @@
+-- name: AddModerator :exec
+INSERT INTO moderators (user_id)
+VALUES ($1)
+ON CONFLICT DO NOTHING;
+
+-- name: RemoveModerator :exec
+DELETE FROM moderators
+WHERE user_id = $1;
+
+-- name: IsModerator :one
+SELECT EXISTS (
+    SELECT 1 FROM moderators
+    WHERE user_id = $1
+);
+
+-- name: CreateModerationAction :one
+INSERT INTO moderation_actions (moderator_id, action, report_id, appeal_id, user_id, chirp_id, note)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING *;
+
+-- name: GetModerationAction :one
+SELECT * FROM moderation_actions
+WHERE id = $1;
+
+-- name: ListModerationActions :many
+SELECT * FROM moderation_actions
+WHERE id < sqlc.arg(before_id)
+ORDER BY id DESC
+LIMIT sqlc.arg(max_rows);
+
+-- name: ListSanctions :many
+-- The hide_chirp and suspend_user actions taken against user_id, newest
+-- first, with the reason reported and the status of any appeal.
+SELECT a.id, a.created_at, a.action, a.chirp_id, a.note,
+    r.reason AS reason, p.status AS appeal_status
+FROM moderation_actions a
+LEFT JOIN reports r ON r.id = a.report_id
+LEFT JOIN appeals p ON p.action_id = a.id
+WHERE a.user_id = $1 AND a.action IN ('hide_chirp', 'suspend_user')
+ORDER BY a.id DESC;
+
+-- name: HideChirp :exec
+INSERT INTO hidden_chirps (chirp_id, action_id)
+VALUES ($1, $2)
+ON CONFLICT DO NOTHING;
+
+-- name: UnhideChirp :exec
+DELETE FROM hidden_chirps
+WHERE chirp_id = $1;
+
+-- name: IsChirpHidden :one
+SELECT EXISTS (
+    SELECT 1 FROM hidden_chirps
+    WHERE chirp_id = $1
+);
+
+-- name: SuspendUser :exec
+-- A null suspended_until suspends them until a moderator lifts it.
+UPDATE users SET suspended_at = CURRENT_TIMESTAMP, suspended_until = $2, suspension_action_id = $3
+WHERE id = $1;
+
+-- name: UnsuspendUser :exec
+UPDATE users SET suspended_at = NULL, suspended_until = NULL, suspension_action_id = NULL
+WHERE id = $1;
+
+-- name: OverturnSuspension :exec
+-- Lifts the suspension only if suspension_action_id put it in force, so a
+-- newer one stays.
+UPDATE users SET suspended_at = NULL, suspended_until = NULL, suspension_action_id = NULL
+WHERE id = $1 AND suspension_action_id = $2;
+
+-- name: GetUserSuspension :one
+SELECT suspended_at, suspended_until FROM users
+WHERE id = $1;
+
+-- name: LimitUser :exec
+UPDATE users SET limited_at = CURRENT_TIMESTAMP
+WHERE id = $1 AND limited_at IS NULL;
+
+-- name: UnlimitUser :exec
+UPDATE users SET limited_at = NULL
+WHERE id = $1;
+
+-- name: IsUserLimited :one
+SELECT EXISTS (
+    SELECT 1 FROM users
+    WHERE id = $1 AND limited_at IS NOT NULL
+);
+
+-- name: ListLimitedUserIDs :many
+SELECT id FROM users
+WHERE limited_at IS NOT NULL;
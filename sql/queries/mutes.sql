@@ -0,0 +1,28 @@
+-- name: MuteUser :execrows
+INSERT INTO mutes (muter_id, muted_id)
+VALUES ($1, $2)
+ON CONFLICT DO NOTHING;
+
+-- name: UnmuteUser :exec
+DELETE FROM mutes
+WHERE muter_id = $1 AND muted_id = $2;
+
+-- name: ListMutes :many
+SELECT * FROM mutes
+WHERE muter_id = $1
+ORDER BY created_at DESC, muted_id;
+
+-- name: MuteExists :one
+SELECT EXISTS (
+    SELECT 1 FROM mutes
+    WHERE muter_id = $1 AND muted_id = $2
+);
+
+-- name: ListHiddenAuthorIDs :many
+-- The users whose chirps user_id shouldn't see: those they blocked or
+-- muted, and those who blocked them.
+SELECT blocked_id AS user_id FROM blocks WHERE blocker_id = sqlc.arg(user_id)
+UNION
+SELECT blocker_id FROM blocks WHERE blocked_id = sqlc.arg(user_id)
+UNION
+SELECT muted_id FROM mutes WHERE muter_id = sqlc.arg(user_id);
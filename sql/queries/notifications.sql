@@ -0,0 +1,34 @@
+-- name: CreateNotification :one
+INSERT INTO notifications (user_id, type, actor_id, chirp_id)
+VALUES ($1, $2, $3, $4)
+RETURNING *;
+
+-- name: ListNotifications :many
+SELECT * FROM notifications
+WHERE user_id = sqlc.arg(user_id)
+AND id < sqlc.arg(before_id)
+AND (NOT sqlc.arg(unread_only)::boolean OR read_at IS NULL)
+ORDER BY id DESC
+LIMIT sqlc.arg(max_rows);
+
+-- name: CountUnreadNotifications :one
+SELECT COUNT(*) FROM notifications
+WHERE user_id = $1 AND read_at IS NULL;
+
+-- name: MarkNotificationRead :execrows
+UPDATE notifications SET read_at = COALESCE(read_at, CURRENT_TIMESTAMP)
+WHERE id = $1 AND user_id = $2;
+
+-- name: MarkAllNotificationsRead :execrows
+UPDATE notifications SET read_at = CURRENT_TIMESTAMP
+WHERE user_id = $1 AND read_at IS NULL;
+
+-- name: ListNotificationPreferences :many
+SELECT * FROM notification_preferences
+WHERE user_id = $1
+ORDER BY type;
+
+-- name: SetNotificationPreference :exec
+INSERT INTO notification_preferences (user_id, type, enabled)
+VALUES ($1, $2, $3)
+ON CONFLICT (user_id, type) DO UPDATE SET enabled = excluded.enabled;
@@ -0,0 +1,24 @@
+-- name: TakeRateLimitToken :one
+-- Refills the bucket for the time since it was last used, then takes a token
+-- if a whole one is available. ON CONFLICT locks the row, so concurrent
+-- requests from several instances can't both take the last token.
+INSERT INTO rate_limit_buckets (key, tokens, allowed, updated_at)
+VALUES (sqlc.arg(key), sqlc.arg(burst)::float8 - 1, TRUE, CURRENT_TIMESTAMP)
+ON CONFLICT (key) DO UPDATE SET (tokens, allowed, updated_at) = (
+    SELECT
+        CASE WHEN refilled >= 1 THEN refilled - 1 ELSE refilled END,
+        refilled >= 1,
+        CURRENT_TIMESTAMP
+    FROM (
+        SELECT LEAST(
+            sqlc.arg(burst)::float8,
+            rate_limit_buckets.tokens + sqlc.arg(rate)::float8 *
+                GREATEST(EXTRACT(EPOCH FROM CURRENT_TIMESTAMP - rate_limit_buckets.updated_at)::float8, 0)
+        ) AS refilled
+    ) AS bucket
+)
+RETURNING tokens, allowed;
+
+-- name: DeleteIdleRateLimitBuckets :execrows
+DELETE FROM rate_limit_buckets
+WHERE updated_at < CURRENT_TIMESTAMP - make_interval(secs => sqlc.arg(idle_seconds)::float8);
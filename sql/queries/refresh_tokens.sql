@@ -7,4 +7,16 @@ UPDATE refresh_tokens SET revoked_at = CURRENT_TIMESTAMP
 WHERE token = $1;
 
 -- name: GetUserIDFromRefreshToken :one
-SELECT user_id, expires_at, revoked_at FROM refresh_tokens WHERE token = $1;
\ No newline at end of file
+SELECT user_id, expires_at, revoked_at FROM refresh_tokens WHERE token = $1;
+
+-- name: DeleteStaleRefreshTokens :execrows
+DELETE FROM refresh_tokens
+WHERE token IN (
+    SELECT token FROM refresh_tokens
+    WHERE expires_at < sqlc.arg(cutoff) OR revoked_at < sqlc.arg(cutoff)
+    LIMIT sqlc.arg(batch_size)
+);
+
+-- name: RevokeUserRefreshTokens :execrows
+UPDATE refresh_tokens SET revoked_at = CURRENT_TIMESTAMP
+WHERE user_id = $1 AND revoked_at IS NULL;
@@ -0,0 +1,41 @@
+-- name: CreateReport :one
+INSERT INTO reports (reporter_id, user_id, chirp_id, reason, details)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING *;
+
+-- name: OpenReportExists :one
+-- Whether reporter_id already has an unresolved report about the same user
+-- or chirp.
+SELECT EXISTS (
+    SELECT 1 FROM reports
+    WHERE reporter_id = $1 AND user_id = $2 AND chirp_id IS NOT DISTINCT FROM $3
+    AND status <> 'resolved'
+);
+
+-- name: IsChirpUnderReview :one
+-- Whether a report about chirp_id is waiting for a moderator.
+SELECT EXISTS (
+    SELECT 1 FROM reports
+    WHERE chirp_id = $1 AND status <> 'resolved'
+);
+
+-- name: GetReport :one
+SELECT * FROM reports
+WHERE id = $1;
+
+-- name: ListReports :many
+SELECT * FROM reports
+WHERE status = $1 AND id > sqlc.arg(after_id)
+ORDER BY id
+LIMIT sqlc.arg(max_rows);
+
+-- name: ClaimReport :execrows
+UPDATE reports
+SET status = 'claimed', claimed_by = $2, claimed_at = CURRENT_TIMESTAMP
+WHERE id = $1 AND status = 'open';
+
+-- name: ResolveReport :execrows
+-- Only the moderator who claimed the report can resolve it.
+UPDATE reports
+SET status = 'resolved', resolution = $3, resolved_at = CURRENT_TIMESTAMP
+WHERE id = $1 AND status = 'claimed' AND claimed_by = $2;
@@ -0,0 +1,8 @@
+-- name: GetStats :one
+SELECT
+    (SELECT count(*) FROM users) AS users,
+    (SELECT count(*) FROM users WHERE is_chirpy_red) AS chirpy_red_users,
+    (SELECT count(*) FROM chirps) AS chirps,
+    (SELECT count(*) FROM refresh_tokens
+        WHERE revoked_at IS NULL AND expires_at > sqlc.arg(now)) AS active_refresh_tokens,
+    (SELECT count(*) FROM webhook_events) AS webhook_events;
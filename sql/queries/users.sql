@@ -15,7 +15,7 @@ LIMIT 1;
 
 -- name: UpdateUserEmailAndPassword :one
 UPDATE users SET email = $2, hashed_password = $3 WHERE id = $1 
-RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red;
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, suspended_at, suspended_until, limited_at, suspension_action_id;
 
 -- name: UpgradeUserToChirpyRed :exec
 UPDATE users SET is_chirpy_red = TRUE WHERE id = $1;
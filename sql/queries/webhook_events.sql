@@ -0,0 +1,14 @@
+-- name: CreateWebhookEvent :exec
+INSERT INTO webhook_events (source, event, user_id, outcome)
+VALUES ($1, $2, $3, $4);
+
+-- name: ListWebhookEvents :many
+SELECT * FROM webhook_events
+WHERE id > sqlc.arg(after_id)
+ORDER BY id
+LIMIT sqlc.arg(max_rows);
+
+-- name: ListRecentWebhookEvents :many
+SELECT * FROM webhook_events
+ORDER BY id DESC
+LIMIT $1;
@@ -0,0 +1,14 @@
+-- +goose Up
+-- token buckets shared by every instance when RATE_LIMIT_STORE=postgres;
+-- unlogged because losing them in a crash only resets the limits
+CREATE UNLOGGED TABLE rate_limit_buckets (
+    key TEXT PRIMARY KEY,
+    tokens DOUBLE PRECISION NOT NULL,
+    allowed BOOLEAN NOT NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX rate_limit_buckets_updated_at_idx ON rate_limit_buckets (updated_at);
+
+-- +goose Down
+DROP TABLE IF EXISTS rate_limit_buckets;
@@ -0,0 +1,14 @@
+-- +goose Up
+-- every webhook delivery and what came of it, for `chirpy webhooks tail`;
+-- user_id has no foreign key so the history outlives deleted users
+CREATE TABLE webhook_events (
+    id BIGSERIAL PRIMARY KEY,
+    received_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    source TEXT NOT NULL,
+    event TEXT NOT NULL,
+    user_id UUID,
+    outcome TEXT NOT NULL
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS webhook_events;
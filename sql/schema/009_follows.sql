@@ -0,0 +1,15 @@
+-- +goose Up
+-- who follows whom, for timelines; a follow ends when either user is deleted
+CREATE TABLE follows (
+    follower_id uuid NOT NULL,
+    followee_id uuid NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (follower_id, followee_id),
+    FOREIGN KEY (follower_id) REFERENCES users(id)
+    ON DELETE CASCADE,
+    FOREIGN KEY (followee_id) REFERENCES users(id)
+    ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS follows;
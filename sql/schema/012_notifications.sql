@@ -0,0 +1,28 @@
+-- +goose Up
+-- what happened to user_id and who did it; chirp_id is the reply or the
+-- liked chirp. A notification goes with its recipient, its actor or its
+-- chirp, and is unread until read_at is set.
+CREATE TABLE notifications (
+    id BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    type TEXT NOT NULL,
+    actor_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    chirp_id UUID REFERENCES chirps(id) ON DELETE CASCADE,
+    read_at TIMESTAMP
+);
+
+CREATE INDEX notifications_user_id_id_idx ON notifications (user_id, id);
+
+-- the notification types a user has turned off or back on; a type with no
+-- row is on
+CREATE TABLE notification_preferences (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    type TEXT NOT NULL,
+    enabled BOOLEAN NOT NULL,
+    PRIMARY KEY (user_id, type)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS notification_preferences;
+DROP TABLE IF EXISTS notifications;
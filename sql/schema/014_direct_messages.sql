@@ -0,0 +1,38 @@
+-- +goose Up
+-- private conversations, kept apart from chirps so no public query can
+-- return them. direct_key is the two members' IDs, sorted, for a
+-- conversation between two users, so there is only one of those per pair;
+-- it's NULL for a group.
+CREATE TABLE conversations (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_message_at TIMESTAMP,
+    direct_key TEXT UNIQUE
+);
+
+-- last_read_id is the last message the member has read, for read receipts
+-- and unread counts; muted members aren't sent new messages live
+CREATE TABLE conversation_members (
+    conversation_id UUID NOT NULL REFERENCES conversations(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    last_read_id BIGINT NOT NULL DEFAULT 0,
+    muted BOOLEAN NOT NULL DEFAULT FALSE,
+    PRIMARY KEY (conversation_id, user_id)
+);
+
+CREATE INDEX conversation_members_user_id_idx ON conversation_members (user_id);
+
+CREATE TABLE messages (
+    id BIGSERIAL PRIMARY KEY,
+    conversation_id UUID NOT NULL REFERENCES conversations(id) ON DELETE CASCADE,
+    sender_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    body TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX messages_conversation_id_id_idx ON messages (conversation_id, id);
+
+-- +goose Down
+DROP TABLE IF EXISTS messages;
+DROP TABLE IF EXISTS conversation_members;
+DROP TABLE IF EXISTS conversations;
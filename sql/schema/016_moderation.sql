@@ -0,0 +1,99 @@
+-- +goose Up
+-- users who can work the moderation queue
+CREATE TABLE moderators (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- a report about user_id, or about their chirp chirp_id. status goes from
+-- open to claimed, once a moderator takes it, to resolved, when resolution
+-- says what they did about it.
+CREATE TABLE reports (
+    id BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    reporter_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    chirp_id UUID REFERENCES chirps(id) ON DELETE CASCADE,
+    reason TEXT NOT NULL,
+    details TEXT NOT NULL DEFAULT '',
+    status TEXT NOT NULL DEFAULT 'open',
+    claimed_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    claimed_at TIMESTAMP,
+    resolution TEXT,
+    resolved_at TIMESTAMP
+);
+
+CREATE INDEX reports_status_id_idx ON reports (status, id);
+
+-- every moderation decision, append-only. It has no foreign keys, so
+-- deleting a user or chirp leaves the record of what was done to them,
+-- and the trigger refuses updates and deletes.
+CREATE TABLE moderation_actions (
+    id BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    moderator_id UUID NOT NULL,
+    action TEXT NOT NULL,
+    report_id BIGINT,
+    appeal_id BIGINT,
+    user_id UUID NOT NULL,
+    chirp_id UUID,
+    note TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX moderation_actions_user_id_idx ON moderation_actions (user_id, id);
+
+-- +goose StatementBegin
+CREATE FUNCTION moderation_actions_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'moderation_actions is append-only';
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER moderation_actions_append_only
+BEFORE UPDATE OR DELETE ON moderation_actions
+FOR EACH ROW EXECUTE FUNCTION moderation_actions_append_only();
+
+-- chirps a moderator hid; they're left out of listings until an appeal
+-- overturns action_id
+CREATE TABLE hidden_chirps (
+    chirp_id UUID PRIMARY KEY REFERENCES chirps(id) ON DELETE CASCADE,
+    action_id BIGINT NOT NULL REFERENCES moderation_actions(id),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- a suspended user is locked out until suspended_until, or until a
+-- moderator lifts it when that's null; a limited user's chirps are seen
+-- by no one but them. suspension_action_id is the action behind the
+-- suspension in force, so overturning an older one leaves it be.
+ALTER TABLE users ADD COLUMN suspended_at TIMESTAMP;
+ALTER TABLE users ADD COLUMN suspended_until TIMESTAMP;
+ALTER TABLE users ADD COLUMN limited_at TIMESTAMP;
+ALTER TABLE users ADD COLUMN suspension_action_id BIGINT REFERENCES moderation_actions(id);
+
+-- a sanctioned user's one appeal against action_id. status goes from open
+-- to upheld or overturned.
+CREATE TABLE appeals (
+    id BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    action_id BIGINT NOT NULL UNIQUE REFERENCES moderation_actions(id),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    body TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'open',
+    decided_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    decided_at TIMESTAMP
+);
+
+CREATE INDEX appeals_status_id_idx ON appeals (status, id);
+
+-- +goose Down
+DROP TABLE IF EXISTS appeals;
+ALTER TABLE users DROP COLUMN suspension_action_id;
+ALTER TABLE users DROP COLUMN limited_at;
+ALTER TABLE users DROP COLUMN suspended_until;
+ALTER TABLE users DROP COLUMN suspended_at;
+DROP TABLE IF EXISTS hidden_chirps;
+DROP TABLE IF EXISTS moderation_actions;
+DROP FUNCTION IF EXISTS moderation_actions_append_only;
+DROP TABLE IF EXISTS reports;
+DROP TABLE IF EXISTS moderators;
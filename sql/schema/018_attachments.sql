@@ -0,0 +1,27 @@
+-- +goose Up
+-- an image uploaded with a chirp; the blobs live in the media store under
+-- the attachment's id. A row is made before its blobs are stored and only
+-- then attached, so the orphan cleaner finds whatever a failed or abandoned
+-- upload, or a purged chirp, left behind: every row with no chirp_id.
+CREATE TABLE attachments (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    chirp_id uuid REFERENCES chirps(id) ON DELETE SET NULL,
+    position INTEGER NOT NULL DEFAULT 0,
+    content_type TEXT NOT NULL,
+    size_bytes BIGINT NOT NULL,
+    width INTEGER NOT NULL,
+    height INTEGER NOT NULL,
+    thumbnail_content_type TEXT NOT NULL,
+    thumbnail_size_bytes BIGINT NOT NULL,
+    thumbnail_width INTEGER NOT NULL,
+    thumbnail_height INTEGER NOT NULL
+);
+
+CREATE INDEX attachments_chirp_id_idx ON attachments (chirp_id, position);
+
+CREATE INDEX attachments_orphaned_idx ON attachments (created_at)
+    WHERE chirp_id IS NULL;
+
+-- +goose Down
+DROP TABLE IF EXISTS attachments;
@@ -0,0 +1,12 @@
+-- +goose Up
+-- ids, tokens and timestamps are generated by the application, and
+-- timestamps are stored as UTC text that sorts chronologically
+CREATE TABLE users (
+    id TEXT PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    email VARCHAR(255) NOT NULL UNIQUE
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS users;
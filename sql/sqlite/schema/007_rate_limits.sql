@@ -0,0 +1,13 @@
+-- +goose Up
+-- updated_at is Unix seconds so the refill can be computed in SQL
+CREATE TABLE rate_limit_buckets (
+    key TEXT PRIMARY KEY,
+    tokens DOUBLE PRECISION NOT NULL,
+    allowed BOOLEAN NOT NULL,
+    updated_at DOUBLE PRECISION NOT NULL
+);
+
+CREATE INDEX rate_limit_buckets_updated_at_idx ON rate_limit_buckets (updated_at);
+
+-- +goose Down
+DROP TABLE IF EXISTS rate_limit_buckets;
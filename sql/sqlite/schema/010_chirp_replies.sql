@@ -0,0 +1,19 @@
+-- +goose Up
+-- reply_to_id is the chirp being answered; thread_id is the chirp that
+-- started the conversation, NULL for one that started its own. A reply
+-- outlives what it answered, and thread_id has no foreign key so a thread
+-- holds together when its first chirp goes.
+ALTER TABLE chirps
+ADD COLUMN reply_to_id TEXT
+REFERENCES chirps(id)
+ON DELETE SET NULL;
+
+ALTER TABLE chirps
+ADD COLUMN thread_id TEXT;
+
+-- +goose Down
+ALTER TABLE chirps
+DROP COLUMN thread_id;
+
+ALTER TABLE chirps
+DROP COLUMN reply_to_id;
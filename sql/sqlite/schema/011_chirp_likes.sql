@@ -0,0 +1,15 @@
+-- +goose Up
+-- who liked which chirp; a like goes with the chirp or the user
+CREATE TABLE chirp_likes (
+    user_id TEXT NOT NULL,
+    chirp_id TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (user_id, chirp_id),
+    FOREIGN KEY (user_id) REFERENCES users(id)
+    ON DELETE CASCADE,
+    FOREIGN KEY (chirp_id) REFERENCES chirps(id)
+    ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS chirp_likes;
@@ -0,0 +1,17 @@
+-- +goose Up
+-- blocker_id has blocked blocked_id; neither can reach the other
+CREATE TABLE blocks (
+    blocker_id TEXT NOT NULL,
+    blocked_id TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (blocker_id, blocked_id),
+    FOREIGN KEY (blocker_id) REFERENCES users(id)
+    ON DELETE CASCADE,
+    FOREIGN KEY (blocked_id) REFERENCES users(id)
+    ON DELETE CASCADE
+);
+
+CREATE INDEX blocks_blocked_id_idx ON blocks (blocked_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS blocks;
@@ -0,0 +1,15 @@
+-- +goose Up
+-- muter_id doesn't see muted_id's chirps or notifications; muted_id isn't told
+CREATE TABLE mutes (
+    muter_id TEXT NOT NULL,
+    muted_id TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (muter_id, muted_id),
+    FOREIGN KEY (muter_id) REFERENCES users(id)
+    ON DELETE CASCADE,
+    FOREIGN KEY (muted_id) REFERENCES users(id)
+    ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS mutes;
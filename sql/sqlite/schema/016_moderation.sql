@@ -0,0 +1,102 @@
+-- +goose Up
+-- users who can work the moderation queue
+CREATE TABLE moderators (
+    user_id TEXT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL
+);
+
+-- a report about user_id, or about their chirp chirp_id. status goes from
+-- open to claimed, once a moderator takes it, to resolved, when resolution
+-- says what they did about it.
+CREATE TABLE reports (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    created_at TIMESTAMP NOT NULL,
+    reporter_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    chirp_id TEXT REFERENCES chirps(id) ON DELETE CASCADE,
+    reason TEXT NOT NULL,
+    details TEXT NOT NULL DEFAULT '',
+    status TEXT NOT NULL DEFAULT 'open',
+    claimed_by TEXT REFERENCES users(id) ON DELETE SET NULL,
+    claimed_at TIMESTAMP,
+    resolution TEXT,
+    resolved_at TIMESTAMP
+);
+
+CREATE INDEX reports_status_id_idx ON reports (status, id);
+
+-- every moderation decision, append-only. It has no foreign keys, so
+-- deleting a user or chirp leaves the record of what was done to them,
+-- and the triggers refuse updates and deletes.
+CREATE TABLE moderation_actions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    created_at TIMESTAMP NOT NULL,
+    moderator_id TEXT NOT NULL,
+    action TEXT NOT NULL,
+    report_id INTEGER,
+    appeal_id INTEGER,
+    user_id TEXT NOT NULL,
+    chirp_id TEXT,
+    note TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX moderation_actions_user_id_idx ON moderation_actions (user_id, id);
+
+-- +goose StatementBegin
+CREATE TRIGGER moderation_actions_no_update
+BEFORE UPDATE ON moderation_actions
+BEGIN
+    SELECT RAISE(ABORT, 'moderation_actions is append-only');
+END;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER moderation_actions_no_delete
+BEFORE DELETE ON moderation_actions
+BEGIN
+    SELECT RAISE(ABORT, 'moderation_actions is append-only');
+END;
+-- +goose StatementEnd
+
+-- chirps a moderator hid; they're left out of listings until an appeal
+-- overturns action_id
+CREATE TABLE hidden_chirps (
+    chirp_id TEXT PRIMARY KEY REFERENCES chirps(id) ON DELETE CASCADE,
+    action_id INTEGER NOT NULL REFERENCES moderation_actions(id),
+    created_at TIMESTAMP NOT NULL
+);
+
+-- a suspended user is locked out until suspended_until, or until a
+-- moderator lifts it when that's null; a limited user's chirps are seen
+-- by no one but them. suspension_action_id is the action behind the
+-- suspension in force, so overturning an older one leaves it be.
+ALTER TABLE users ADD COLUMN suspended_at TIMESTAMP;
+ALTER TABLE users ADD COLUMN suspended_until TIMESTAMP;
+ALTER TABLE users ADD COLUMN limited_at TIMESTAMP;
+ALTER TABLE users ADD COLUMN suspension_action_id INTEGER;
+
+-- a sanctioned user's one appeal against action_id. status goes from open
+-- to upheld or overturned.
+CREATE TABLE appeals (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    created_at TIMESTAMP NOT NULL,
+    action_id INTEGER NOT NULL UNIQUE REFERENCES moderation_actions(id),
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    body TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'open',
+    decided_by TEXT REFERENCES users(id) ON DELETE SET NULL,
+    decided_at TIMESTAMP
+);
+
+CREATE INDEX appeals_status_id_idx ON appeals (status, id);
+
+-- +goose Down
+DROP TABLE IF EXISTS appeals;
+ALTER TABLE users DROP COLUMN suspension_action_id;
+ALTER TABLE users DROP COLUMN limited_at;
+ALTER TABLE users DROP COLUMN suspended_until;
+ALTER TABLE users DROP COLUMN suspended_at;
+DROP TABLE IF EXISTS hidden_chirps;
+DROP TABLE IF EXISTS moderation_actions;
+DROP TABLE IF EXISTS reports;
+DROP TABLE IF EXISTS moderators;
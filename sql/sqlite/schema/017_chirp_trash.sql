@@ -0,0 +1,15 @@
+-- +goose Up
+-- a deleted chirp stays in its author's trash until the purger removes it,
+-- or for good if a moderator hid it
+ALTER TABLE chirps ADD COLUMN deleted_at TIMESTAMP;
+
+CREATE INDEX chirps_deleted_at_idx ON chirps (deleted_at)
+    WHERE deleted_at IS NOT NULL;
+
+-- +goose Down
+DROP INDEX IF EXISTS chirps_deleted_at_idx;
+
+-- without the column they'd come back, so honour the deletes
+DELETE FROM chirps WHERE deleted_at IS NOT NULL;
+
+ALTER TABLE chirps DROP COLUMN deleted_at;
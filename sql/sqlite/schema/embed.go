@@ -0,0 +1,9 @@
+// Package schema embeds the SQLite goose migrations. They mirror the
+// Postgres migrations in sql/schema version for version, so a migration
+// added to one must be added to the other with the same number.
+package schema
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS